@@ -0,0 +1,154 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/namestnikoff/payment-system/payments"
+)
+
+// ===== ЧТЕНИЕ ПЕРЕМЕННЫХ ОКРУЖЕНИЯ =====
+//
+// Вспомогательные функции возвращают значение по умолчанию,
+// если переменная не задана, и ошибку, если значение некорректное.
+// Ошибку main превращает в log.Fatal — падаем при старте (fail fast)
+
+// envInt читает целое число из переменной окружения
+func envInt(name string, def int) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s %q: expected non-negative integer", name, value)
+	}
+	return n, nil
+}
+
+// envDuration читает длительность из переменной окружения
+// Формат как у time.ParseDuration: "100ms", "5s", "1m30s"
+func envDuration(name string, def time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s %q: expected duration like 500ms or 5s", name, value)
+	}
+	return d, nil
+}
+
+// envBool читает флаг из переменной окружения
+// Формат как у strconv.ParseBool: "true", "false", "1", "0"
+func envBool(name string, def bool) (bool, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s %q: expected true or false", name, value)
+	}
+	return b, nil
+}
+
+// envSchemaCheck читает SCHEMA_CHECK — проверку формата данных
+// хранилища при старте (см. RedisStore.CheckSchema):
+//   - не задано или false — не проверять
+//   - true — проверить и остановиться, если формат не тот
+//   - only — только проверить и выйти (сухой прогон перед выкладкой)
+func envSchemaCheck() (check, only bool, err error) {
+	value := os.Getenv("SCHEMA_CHECK")
+	if strings.EqualFold(value, "only") {
+		return true, true, nil
+	}
+	check, err = envBool("SCHEMA_CHECK", false)
+	if err != nil {
+		return false, false, fmt.Errorf("invalid SCHEMA_CHECK %q: expected true, false or only", value)
+	}
+	return check, false, nil
+}
+
+// envFloat читает дробное число из переменной окружения
+func envFloat(name string, def float64) (float64, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: expected number like 0.1", name, value)
+	}
+	return f, nil
+}
+
+// envList читает список через запятую: "a, b,,c" → [a b c]
+// Пустые элементы и пробелы по краям отбрасываются
+func envList(name string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(name), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// loadFeatures читает флаги возможностей: FEATURE_REFUNDS=false и т.д.
+// Не заданная переменная = возможность включена, кроме служебных
+// эндпоинтов администратора: их включает только FEATURE_ADMIN=true
+func loadFeatures() (payments.Features, error) {
+	f := payments.AllFeatures
+	flags := []struct {
+		name  string
+		value *bool
+	}{
+		{"FEATURE_REFUNDS", &f.Refunds},
+		{"FEATURE_CAPTURE", &f.Capture},
+		{"FEATURE_RECEIPTS", &f.Receipts},
+		{"FEATURE_STREAM", &f.Stream},
+		{"FEATURE_RETRY", &f.Retry},
+		{"FEATURE_SPLIT", &f.Split},
+		{"FEATURE_EXPORT", &f.Export},
+		{"FEATURE_IMPORT", &f.Import},
+		{"FEATURE_RECONCILE", &f.Reconcile},
+		{"FEATURE_CUSTOMERS", &f.Customers},
+		{"FEATURE_WEBHOOKS", &f.Webhooks},
+		{"FEATURE_INDEX", &f.Index},
+		{"FEATURE_ADMIN", &f.Admin},
+	}
+	for _, flag := range flags {
+		enabled, err := envBool(flag.name, *flag.value)
+		if err != nil {
+			return f, err
+		}
+		*flag.value = enabled
+	}
+	return f, nil
+}
+
+// loadTLSConfig загружает сертификат и ключ (PEM) для HTTPS
+// Оба пути пустые — nil, сервер работает по HTTP
+// Задан только один путь или пара не подходит друг к другу — ошибка
+func loadTLSConfig(certFile, keyFile string) (*tls.Config, error) {
+	if certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf("TLS_CERT and TLS_KEY must be set together")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS certificate or key: %w", err)
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/namestnikoff/payment-system/payments"
+)
+
+// writeSelfSigned записывает самоподписанный сертификат для 127.0.0.1
+// и его ключ в PEM файлы во временном каталоге теста
+func writeSelfSigned(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+// TestTLSServerSmoke — сервер с самоподписанным сертификатом поднимается
+// и отвечает по HTTPS через HTTP/2
+func TestTLSServerSmoke(t *testing.T) {
+	certFile, keyFile := writeSelfSigned(t)
+	tlsConfig, err := loadTLSConfig(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{
+		Handler:   payments.NewServer(payments.NewMemoryStore(), nil, nil, payments.Config{}),
+		TLSConfig: tlsConfig,
+	}
+	go srv.ServeTLS(ln, "", "")
+	t.Cleanup(func() { srv.Close() })
+
+	client := &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			ForceAttemptHTTP2: true,
+		},
+	}
+	resp, err := client.Get("https://" + ln.Addr().String() + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.TLS == nil {
+		t.Fatalf("status = %d, TLS = %v", resp.StatusCode, resp.TLS != nil)
+	}
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("protocol = %s, want HTTP/2", resp.Proto)
+	}
+}
+
+func TestLoadTLSConfigErrors(t *testing.T) {
+	if cfg, err := loadTLSConfig("", ""); cfg != nil || err != nil {
+		t.Fatalf("unset = %v, %v; want plain HTTP", cfg, err)
+	}
+
+	certFile, keyFile := writeSelfSigned(t)
+	if _, err := loadTLSConfig(certFile, ""); err == nil {
+		t.Error("cert without key accepted")
+	}
+	// Ключ от другой пары не подходит к сертификату
+	_, otherKey := writeSelfSigned(t)
+	if _, err := loadTLSConfig(certFile, otherKey); err == nil {
+		t.Error("mismatched key accepted")
+	}
+	if _, err := loadTLSConfig(keyFile, keyFile); err == nil {
+		t.Error("key file accepted as certificate")
+	}
+}
+
+// TestLoadFeatures — не заданный флаг = включено (кроме FEATURE_ADMIN),
+// некорректное значение — ошибка
+func TestLoadFeatures(t *testing.T) {
+	t.Setenv("FEATURE_REFUNDS", "false")
+	t.Setenv("FEATURE_ADMIN", "true")
+	f, err := loadFeatures()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Refunds || !f.Admin || !f.Export || !f.Webhooks {
+		t.Fatalf("features = %+v", f)
+	}
+
+	t.Setenv("FEATURE_EXPORT", "maybe")
+	if _, err := loadFeatures(); err == nil {
+		t.Fatal("invalid FEATURE_EXPORT accepted")
+	}
+}
+
+func TestEnvSchemaCheck(t *testing.T) {
+	for _, c := range []struct {
+		value       string
+		check, only bool
+	}{
+		{"", false, false},
+		{"false", false, false},
+		{"true", true, false},
+		{"only", true, true},
+	} {
+		t.Setenv("SCHEMA_CHECK", c.value)
+		check, only, err := envSchemaCheck()
+		if err != nil || check != c.check || only != c.only {
+			t.Errorf("%q: check=%v only=%v err=%v", c.value, check, only, err)
+		}
+	}
+	t.Setenv("SCHEMA_CHECK", "sometimes")
+	if _, _, err := envSchemaCheck(); err == nil {
+		t.Fatal("invalid SCHEMA_CHECK accepted")
+	}
+}
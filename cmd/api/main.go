@@ -8,6 +8,12 @@ package main
 // import группирует все подключаемые библиотеки
 // Скобки () позволяют импортировать несколько пакетов сразу
 import (
+	// "context" — отмена и дедлайны для горутин и запросов
+	"context"
+
+	// "errors" — проверка ошибок через errors.Is
+	"errors"
+
 	// "fmt" — стандартный пакет Go для форматированного ввода/вывода
 	// f = format, mt = multi-type (работает с разными типами данных)
 	// Используется для печати в консоль, форматирования строк
@@ -25,296 +31,674 @@ import (
 	// В Go HTTP сервер входит в стандартную библиотеку (в отличие от Python/Java)
 	"net/http"
 
-	// "encoding/json" — стандартный пакет для работы с JSON
-	// encoding = кодирование/декодирование
-	// Marshal = Go struct → JSON (сериализация)
-	// Unmarshal = JSON → Go struct (десериализация)
-	"encoding/json"
+	// "os" — доступ к окружению процесса (переменные окружения)
+	"os"
+
+	// "os/signal" — подписка на сигналы ОС (Ctrl+C, SIGTERM)
+	"os/signal"
+
+	// "syscall" — константы сигналов (SIGTERM)
+	"syscall"
+
+	// "path" — работа со слэш-путями (очистка "//", "..")
+	"path"
+
+	// "slices" — обобщенные функции для срезов (поиск, сортировка)
+	"slices"
+
+	// "strings" — функции для строк (префиксы, поиск)
+	"strings"
+
+	// "time" — длительности для настроек (задержки, таймауты)
+	"time"
+
+	// Клиент Redis (внешняя библиотека go-redis)
+	"github.com/redis/go-redis/v9"
+
+	// Наш собственный пакет: модель платежа, хранилище и обработчики
+	// Путь = имя модуля из go.mod + путь к папке пакета
+	"github.com/namestnikoff/payment-system/payments"
 )
 
-// ===== СТРУКТУРЫ ДАННЫХ =====
+// ===== ТОЧКА ВХОДА В ПРОГРАММУ =====
 
-// Payment представляет платеж в системе
-//
-// ЧТО ТАКОЕ СТРУКТУРА (struct):
-// - Это способ группировки связанных данных
-// - Похоже на класс без методов (методы добавляются отдельно)
-// - Похоже на словарь с фиксированными ключами и типами
-//
-// СИНТАКСИС:
-// type ИмяСтруктуры struct { поля }
-type Payment struct {
-	// ПОЛЯ СТРУКТУРЫ:
-	// ИмяПоля ТипДанных `тег`
-
-	// ID — уникальный идентификатор платежа
-	// string = текстовая строка (как str в Python)
-	// `json:"id"` = JSON тег, указывает:
-	//   - При конвертации в JSON это поле будет называться "id" (маленькими)
-	//   - При парсинге JSON ключ "id" попадет в это поле
-	// ВАЖНО: ID с большой буквы = публичное поле (видно из других пакетов)
-	//         id с маленькой = приватное (только внутри этого пакета)
-	ID string `json:"id"`
-
-	// Amount — сумма платежа
-	// float64 = число с плавающей точкой, 64 бита точности
-	// Для денег в продакшене лучше использовать int64 (копейки/центы)
-	// Причина: float64 имеет ошибки округления (0.1 + 0.2 ≠ 0.3)
-	// Пример: вместо 100.50 RUB хранить 10050 копеек
-	Amount float64 `json:"amount"`
-
-	// Currency — код валюты
-	// Формат: ISO 4217 (USD, EUR, RUB, GBP и т.д.)
-	// 3 буквы, всегда в верхнем регистре
-	Currency string `json:"currency"`
-
-	// Status — статус платежа
-	// Возможные значения: "pending", "succeeded", "failed"
-	// В продакшене лучше использовать enum (константы)
-	Status      string `json:"status"`
-	Description string `json:"description,omitempty"`
-}
+// main — специальная функция, выполняется при запуске программы
+// Это как if __name__ == "__main__" в Python
+// Должна быть в пакете main, иначе Go не запустится
+func main() {
+	// Выводим сообщение о запуске сервера
+	// Это не обязательно, но полезно для отладки
+	fmt.Println("Payment System API starting...")
 
-// ===== HTTP ОБРАБОТЧИКИ (HANDLERS) =====
+	// ===== КОНФИГУРАЦИЯ =====
 
-// handleCreatePayment обрабатывает POST запрос для создания платежа
-//
-// СИГНАТУРА ФУНКЦИИ:
-// func = ключевое слово объявления функции
-// handleCreatePayment = имя функции (с маленькой буквы = приватная)
-// (w http.ResponseWriter, r *http.Request) = параметры:
-//   - w = куда писать ответ (response)
-//   - r = откуда читать запрос (request), * = указатель (объясню ниже)
-//
-// УКАЗАТЕЛЬ (*):
-// В Go данные передаются ПО ЗНАЧЕНИЮ (копируются)
-// Звездочка * означает "передать ссылку, а не копию"
-// Зачем: http.Request большой объект, копировать его дорого
-func handleCreatePayment(w http.ResponseWriter, r *http.Request) {
-	// Проверяем HTTP метод
-	// r.Method = строка с методом запроса ("GET", "POST", "PUT" и т.д.)
-	// != означает "не равно"
-	if r.Method != http.MethodPost {
-		// http.Error отправляет HTTP ответ с ошибкой
-		// Параметры:
-		// 1. w = куда писать
-		// 2. "Invalid method" = текст ошибки (будет в body ответа)
-		// 3. http.StatusMethodNotAllowed = HTTP код 405
-		//    (правильный код для "метод не поддерживается")
-		http.Error(w, "Invalid method", http.StatusMethodNotAllowed)
-
-		// return = прекратить выполнение функции
-		// Без return код ниже выполнился бы (это ошибка!)
-		return
+	// Курсы валют берем из переменной окружения FX_RATES
+	// Пример: FX_RATES="USD/RUB=92.5,EUR/RUB=100.1"
+	// os.Getenv возвращает "" если переменная не задана
+	rates, err := payments.ParseFXRates(os.Getenv("FX_RATES"))
+	if err != nil {
+		// Ошибка конфигурации — падаем сразу при старте (fail fast),
+		// а не на первом запросе клиента
+		log.Fatal("Invalid FX_RATES: ", err)
+	}
+	fxProvider := payments.NewStaticFXProvider(rates)
+
+	// Список принимаемых валют: SUPPORTED_CURRENCIES="RUB,USD"
+	// Если переменная не задана — встроенный список по умолчанию
+	// Так разные инсталляции поддерживают разные валюты без перекомпиляции
+	currencies := payments.DefaultSupportedCurrencies
+	if value := os.Getenv("SUPPORTED_CURRENCIES"); value != "" {
+		currencies, err = payments.ParseCurrencies(value)
+		if err != nil {
+			log.Fatal("Invalid SUPPORTED_CURRENCIES: ", err)
+		}
 	}
 
-	// Создаем переменную для хранения распарсенных данных
-	// var = полная форма объявления переменной
-	// payment = имя переменной
-	// Payment = тип (наша структура выше)
-	// Значение по умолчанию: пустая структура {ID:"", Amount:0, Currency:"", Status:""}
-	var payment Payment
+	// Временно запрещенные валюты: BLOCKED_CURRENCIES="USD,EUR"
+	// Платежи в них получают 403, даже если валюта в списке принимаемых
+	var blockedCurrencies []string
+	if value := os.Getenv("BLOCKED_CURRENCIES"); value != "" {
+		blockedCurrencies, err = payments.ParseCurrencies(value)
+		if err != nil {
+			log.Fatal("Invalid BLOCKED_CURRENCIES: ", err)
+		}
+	}
 
-	// Декодируем JSON из тела запроса в структуру
-	//
-	// json.NewDecoder(r.Body) = создает декодер, читающий из тела запроса
-	// r.Body = io.Reader, поток данных (как файл)
-	//
-	// .Decode(&payment) = декодировать JSON → структуру
-	// &payment = АДРЕС переменной payment (не копия, а именно она!)
-	// Зачем &: Decode должен ИЗМЕНИТЬ payment, поэтому нужна ссылка
-	//
-	// ВАЖНО: Decode возвращает error
-	// В Go НЕТ исключений (exceptions), вместо них — ошибки (error)
-	// Если JSON невалидный, err будет содержать описание проблемы
-	err := json.NewDecoder(r.Body).Decode(&payment)
-
-	// := это "короткая форма объявления"
-	// Эквивалентно: var err error = json.NewDecoder(...).Decode(...)
-	// Go сам определяет тип (здесь error)
-
-	// Проверяем, была ли ошибка при декодировании
-	// nil = "ничего", "null", "нет значения"
-	// err != nil означает "ошибка произошла"
-	if err != nil {
-		// Логируем ошибку в консоль (для разработчика/администратора)
-		// log.Printf = форматированный вывод в лог с timestamp
-		// "Error decoding JSON: %v" = строка формата
-		// %v = подставить значение в любом формате (универсальный placeholder)
-		// err = что подставить
-		log.Printf("Error decoding JSON: %v", err)
-
-		// Отправляем HTTP 400 (Bad Request) клиенту
-		// "Invalid JSON" = понятное сообщение для клиента API
-		// НЕ отправляем детали err клиенту (это детали реализации)
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
-		return
+	// Валюта по умолчанию для "одновалютных" инсталляций
+	// Проверяем ее сразу: опечатка в конфиге не должна всплыть
+	// только на первом платеже
+	// slices.Contains = "есть ли элемент в срезе"
+	defaultCurrency := os.Getenv("DEFAULT_CURRENCY")
+	if defaultCurrency != "" && !slices.Contains(currencies, defaultCurrency) {
+		log.Fatalf("Invalid DEFAULT_CURRENCY %q: not a supported ISO 4217 code", defaultCurrency)
 	}
 
-	// ===== ВАЛИДАЦИЯ ДАННЫХ =====
-	// КРИТИЧЕСКИ ВАЖНО ДЛЯ ФИНТЕХА!
+	// Защита от случайных дублей: DUPLICATE_WINDOW="30s"
+	// Не задано = выключено, чтобы не удивлять существующих клиентов
+	duplicateWindow, err := envDuration("DUPLICATE_WINDOW", 0)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	// Проверяем сумму платежа
-	// <= 0 означает "меньше или равно нулю"
-	// Нельзя принимать платежи с отрицательной/нулевой суммой
-	if payment.Amount <= 0 {
-		http.Error(w, "Amount must be positive", http.StatusBadRequest)
-		return
+	// Округление дробных копеек: ROUNDING_MODE — общий режим
+	// (half_even по умолчанию, half_up, floor), ROUNDING_MODES — исключения
+	// по валютам, например "JPY:floor,USD:half_up"
+	rounding := payments.Rounding{Default: payments.RoundHalfEven}
+	if value := os.Getenv("ROUNDING_MODE"); value != "" {
+		rounding.Default, err = payments.ParseRoundingMode(value)
+		if err != nil {
+			log.Fatal("Invalid ROUNDING_MODE: ", err)
+		}
+	}
+	rounding.PerCurrency, err = payments.ParseRoundingModes(os.Getenv("ROUNDING_MODES"))
+	if err != nil {
+		log.Fatal("Invalid ROUNDING_MODES: ", err)
+	}
+
+	// Разделители разрядов в строковых суммах ("1,000.50"):
+	// AMOUNT_THOUSANDS_SEPARATORS=true — принимать, иначе 400
+	thousandsSeparators, err := envBool("AMOUNT_THOUSANDS_SEPARATORS", false)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Наибольшая сумма в минорных единицах, которую можно прислать
+	// числом, а не строкой: MAX_FLOAT_AMOUNT_MINOR, по умолчанию 0 = без
+	// проверки (рекомендуемое значение — 10000000000, то есть 1e10)
+	maxFloatAmountMinor, err := envInt("MAX_FLOAT_AMOUNT_MINOR", 0)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	// Проверяем валюту
-	// payment.Currency == "" проверяет пустую строку
-	// В продакшене добавили бы проверку на валидный ISO код (USD, EUR и т.д.)
-	if payment.Currency == "" {
-		http.Error(w, "Currency is required", http.StatusBadRequest)
+	// Журнал аудита изменений платежей: AUDIT_LOG="/var/log/payments/audit.log"
+	// дописывает записи в файл, не задано = stdout
+	// Журнал отделен от обычного лога (log пишет в stderr)
+	auditLog := os.Stdout
+	if path := os.Getenv("AUDIT_LOG"); path != "" {
+		// O_APPEND: записи только дописываются в конец файла
+		auditLog, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+		if err != nil {
+			log.Fatal("Invalid AUDIT_LOG: ", err)
+		}
+		defer auditLog.Close()
+	}
+
+	// Префикс маршрутов для публикации за reverse proxy: BASE_PATH="/api/v1"
+	// Тогда платежи доступны по /api/v1/payments
+	// Не задано = маршруты в корне
+	basePath := os.Getenv("BASE_PATH")
+	if basePath != "" && (!strings.HasPrefix(basePath, "/") || path.Clean(basePath) != basePath) {
+		log.Fatalf("Invalid BASE_PATH %q: expected path like /api/v1", basePath)
+	}
+
+	// Сколько запросов обрабатывать одновременно: MAX_CONCURRENCY=100
+	// Остальные сразу получают 503, а не ждут в очереди; 0 = без лимита
+	maxConcurrency, err := envInt("MAX_CONCURRENCY", 100)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Наибольшая длина адреса и строки запроса в байтах:
+	// MAX_URL_LENGTH=8192, MAX_QUERY_LENGTH=4096
+	// Длиннее — 414 URI Too Long; 0 = без лимита
+	maxURLLength, err := envInt("MAX_URL_LENGTH", 8192)
+	if err != nil {
+		log.Fatal(err)
+	}
+	maxQueryLength, err := envInt("MAX_QUERY_LENGTH", 4096)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Группы эндпоинтов можно выключить: FEATURE_REFUNDS=false,
+	// FEATURE_WEBHOOKS=false и т.д. (полный список — loadFeatures)
+	// Выключенный эндпоинт отвечает 404, как будто его нет
+	// Эндпоинты поддержки (/admin/…) наоборот включает FEATURE_ADMIN=true
+	features, err := loadFeatures()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Дедлайн обработки одного запроса: REQUEST_TIMEOUT=10s
+	// Не уложился — 503 request_timeout; 0 = без ограничения
+	requestTimeout, err := envDuration("REQUEST_TIMEOUT", 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Уведомления шлюза POST /webhooks/gateway: WEBHOOK_SECRET — общий
+	// со шлюзом секрет подписи (не задан = эндпоинт выключен),
+	// WEBHOOK_REPLAY_WINDOW — сколько помнить ID событий (по умолчанию 24h)
+	webhookReplayWindow, err := envDuration("WEBHOOK_REPLAY_WINDOW", 24*time.Hour)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Комиссии процессинга по валютам: FEES="RUB:2.9%+30,USD:2.9%+30"
+	// (процент плюс фикс в минорных единицах); не задано = без комиссий
+	fees, err := payments.ParseFees(os.Getenv("FEES"))
+	if err != nil {
+		log.Fatal("Invalid FEES: ", err)
+	}
+
+	// Минимальная сумма списания по валютам: MIN_AMOUNTS="RUB:10,USD:0.50"
+	// Действует при создании платежа и при частичном списании;
+	// не задано = без минимума
+	minAmounts, err := payments.ParseCurrencyAmounts(os.Getenv("MIN_AMOUNTS"))
+	if err != nil {
+		log.Fatal("Invalid MIN_AMOUNTS: ", err)
+	}
+
+	// Необычно крупные суммы: WARN_AMOUNTS="RUB:1000000,USD:10000"
+	// Такой платеж создается, но в ответе будет предупреждение
+	// в поле warnings; не задано = без предупреждений
+	warnAmounts, err := payments.ParseCurrencyAmounts(os.Getenv("WARN_AMOUNTS"))
+	if err != nil {
+		log.Fatal("Invalid WARN_AMOUNTS: ", err)
+	}
+
+	// ===== СБОРКА ЗАВИСИМОСТЕЙ =====
+
+	// Хранилище: REDIS_URL="redis://localhost:6379/0" — Redis, общий
+	// для нескольких экземпляров API (горизонтальное масштабирование)
+	// Не задано = хранилище в памяти: данные живут, пока работает процесс
+	// SCHEMA_CHECK=true — проверить формат данных в Redis при старте
+	// и упасть с понятной ошибкой, если миграция не применена, а не
+	// на первом запросе; SCHEMA_CHECK=only — проверить и выйти
+	checkSchema, checkSchemaOnly, err := envSchemaCheck()
+	if err != nil {
+		log.Fatal(err)
+	}
+	var store payments.Store
+	var memoryStore *payments.MemoryStore
+	if redisURL := os.Getenv("REDIS_URL"); redisURL != "" {
+		opts, err := redis.ParseURL(redisURL)
+		if err != nil {
+			log.Fatal("Invalid REDIS_URL: ", err)
+		}
+		client := redis.NewClient(opts)
+		defer client.Close()
+
+		// Проверяем подключение сразу (fail fast), а не на первом запросе
+		pingCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err = client.Ping(pingCtx).Err()
+		cancel()
+		if err != nil {
+			log.Fatal("Cannot connect to Redis: ", err)
+		}
+		redisStore := payments.NewRedisStore(client)
+		if checkSchema {
+			checkCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			err := redisStore.CheckSchema(checkCtx)
+			cancel()
+			if err != nil {
+				log.Fatal("Redis schema check failed, apply migrations before starting: ", err)
+			}
+			log.Println("Redis schema check passed")
+		}
+		store = redisStore
+	} else {
+		memoryStore = payments.NewMemoryStore()
+		// MAX_PAYMENTS=100000 — не больше 100000 платежей в памяти:
+		// сверх лимита создание отвечает 503 (не задано = без лимита)
+		// Место освобождает уборщик (PAYMENT_RETENTION)
+		maxPayments, err := envInt("MAX_PAYMENTS", 0)
+		if err != nil {
+			log.Fatal(err)
+		}
+		memoryStore.SetMaxPayments(maxPayments)
+		store = memoryStore
+	}
+	if checkSchemaOnly {
+		if memoryStore != nil {
+			log.Println("Schema check skipped: in-memory store has no schema")
+		}
 		return
 	}
 
-	// ===== БИЗНЕС-ЛОГИКА =====
-
-	// Генерируем ID платежа (в продакшене используй UUID)
-	// fmt.Sprintf = форматирование строки (как f-string в Python)
-	// "pay_%s" = шаблон строки
-	// %s = подставить строку
-	// "12345" = временное значение (в реальности: uuid.New().String())
-	payment.ID = fmt.Sprintf("pay_%s", "12345")
-
-	// Устанавливаем начальный статус
-	// В реальной системе здесь был бы вызов платежного шлюза
-	// (Stripe, CloudPayments и т.д.)
-	payment.Status = "pending"
-
-	// Логируем успешное создание (для мониторинга)
-	// %+v = подробный вывод структуры со всеми полями
-	// Вывод: {ID:pay_12345 Amount:100.5 Currency:RUB Status:pending}
-	//log.Printf("Payment created: %+v", payment)
-	// Замени на:
-	// Логируем создание платежа с детальной информацией
-	// %s = строка (string)
-	// %f = число с плавающей точкой (float)
-	// %q = строка в кавычках (удобно для текстовых полей)
-	log.Printf("Payment created: ID=%s, Amount=%.2f %s, Status=%s, Description=%q",
-		payment.ID,          // ID платежа
-		payment.Amount,      // Сумма (%.2f = 2 знака после запятой)
-		payment.Currency,    // Валюта
-		payment.Status,      // Статус
-		payment.Description) // Описание (в кавычках)
-
-	// ===== ОТПРАВКА ОТВЕТА =====
-
-	// Устанавливаем заголовок Content-Type
-	// w.Header() = map с HTTP заголовками (как dict в Python)
-	// .Set("ключ", "значение") = установить заголовок
-	// "application/json" = сообщаем клиенту что отправляем JSON
-	w.Header().Set("Content-Type", "application/json")
-
-	// Устанавливаем HTTP статус код 201 (Created)
-	// 201 = "ресурс успешно создан" (правильный код для POST)
-	// НЕ 200, потому что 200 = "ok, но ничего не создано"
-	w.WriteHeader(http.StatusCreated)
-
-	// Кодируем структуру payment в JSON и отправляем клиенту
-	// json.NewEncoder(w) = создает энкодер, пишущий в w (ResponseWriter)
-	// .Encode(payment) = структуру → JSON → отправить
-	// Если ошибка кодирования — игнорируем (поздно что-то менять)
-	json.NewEncoder(w).Encode(payment)
-
-	// Что увидит клиент:
-	// HTTP/1.1 201 Created
-	// Content-Type: application/json
+	// Сколько помнить ключи Idempotency-Key (по умолчанию сутки)
+	idempotencyTTL, err := envDuration("IDEMPOTENCY_TTL", 24*time.Hour)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Сколько действует авторизация двухшагового платежа ("capture": false):
+	// CAPTURE_EXPIRY=168h (7 дней); потом платеж аннулируется (voided)
+	captureExpiry, err := envDuration("CAPTURE_EXPIRY", 7*24*time.Hour)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Платежный шлюз: PAYMENT_GATEWAY=mock включает тестовую заглушку
+	// Без шлюза (по умолчанию) платежи создаются в статусе pending
+	// Временные сбои шлюза повторяются с экспоненциальной задержкой:
+	// GATEWAY_MAX_RETRIES (по умолчанию 3) и GATEWAY_RETRY_BASE_DELAY (100ms)
+	// TEST_MODE=true позволяет интеграционным тестам заказать отказ
+	// через metadata.test_outcome = "fail", "insufficient_funds"
+	// или "expired_card" (только для mock)
+	var gateway payments.PaymentGateway
+	switch name := os.Getenv("PAYMENT_GATEWAY"); name {
+	case "":
+	case "mock":
+		maxRetries, err := envInt("GATEWAY_MAX_RETRIES", 3)
+		if err != nil {
+			log.Fatal(err)
+		}
+		baseDelay, err := envDuration("GATEWAY_RETRY_BASE_DELAY", 100*time.Millisecond)
+		if err != nil {
+			log.Fatal(err)
+		}
+		testMode, err := envBool("TEST_MODE", false)
+		if err != nil {
+			log.Fatal(err)
+		}
+		mock := &payments.MockGateway{DeclineAbove: 1_000_000, TestMode: testMode}
+		gateway = payments.NewRetryingGateway(mock, maxRetries, baseDelay)
+	default:
+		log.Fatalf("Unknown PAYMENT_GATEWAY %q: expected \"mock\" or empty", name)
+	}
+
+	// GATEWAY_ASYNC=true — списывать новые платежи в фоне: клиент сразу
+	// получает платеж в статусе processing (см. payments/async.go)
+	asyncGateway, err := envBool("GATEWAY_ASYNC", false)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Сколько раз клиент может повторить отклоненный платеж
+	// (POST /payments/{id}/retry): PAYMENT_MAX_RETRIES, по умолчанию 3
+	// Это не GATEWAY_MAX_RETRIES: те повторы делает сам сервер при
+	// временных сбоях шлюза внутри одной попытки
+	maxPaymentRetries, err := envInt("PAYMENT_MAX_RETRIES", 3)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Лимит числа возвратов по одному платежу: MAX_REFUNDS_PER_PAYMENT=5
+	// 0 (по умолчанию) = без ограничения, остаток проверяется всегда
+	maxRefunds, err := envInt("MAX_REFUNDS_PER_PAYMENT", 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Причины отказа по кодам ошибок шлюза: FAILURE_REASONS=
+	// "do_not_honor:card_declined,51:insufficient_funds" дополняет
+	// встроенную таблицу (payments.DefaultFailureReasons)
+	failureReasons, err := payments.ParseFailureReasons(os.Getenv("FAILURE_REASONS"))
+	if err != nil {
+		log.Fatal("Invalid FAILURE_REASONS: ", err)
+	}
+
+	// Уведомления об изменении статуса: EVENTS_WEBHOOK_URL включает outbox
+	// и фоновую доставку событий POST-запросом на этот адрес
+	// (проверка очереди каждые OUTBOX_POLL_INTERVAL, по умолчанию 1s;
+	// повторы с задержкой от OUTBOX_RETRY_BASE_DELAY до OUTBOX_RETRY_MAX_DELAY)
+	var outbox payments.Outbox
+	var outboxWorker *payments.OutboxWorker
+	if eventsURL := os.Getenv("EVENTS_WEBHOOK_URL"); eventsURL != "" {
+		memoryOutbox := payments.NewMemoryOutbox()
+		outbox = memoryOutbox
+		outboxWorker = payments.NewOutboxWorker(memoryOutbox, eventsURL)
+		if outboxWorker.BaseDelay, err = envDuration("OUTBOX_RETRY_BASE_DELAY", outboxWorker.BaseDelay); err != nil {
+			log.Fatal(err)
+		}
+		if outboxWorker.MaxDelay, err = envDuration("OUTBOX_RETRY_MAX_DELAY", outboxWorker.MaxDelay); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// OPENAPI_VALIDATION=true включает проверку тел запросов
+	// по спецификации OpenAPI (payments/openapi.json) до обработчиков
+	validateRequests, err := envBool("OPENAPI_VALIDATION", false)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// DEBUG_BODIES=true пишет в лог тела запросов и ответов (до
+	// DEBUG_BODY_LIMIT байт, по умолчанию 4096)
 	//
-	// {"id":"pay_12345","amount":100.5,"currency":"RUB","status":"pending"}
-}
+	// REDACT_FIELDS ("email,description") — поля, значения которых
+	// заменяются на [REDACTED] во всех логах: телах запросов, строках
+	// лога и журнале аудита. Старое имя DEBUG_REDACT_FIELDS тоже работает
+	// Не задано = payments.DefaultRedactFields
+	debugBodies, err := envBool("DEBUG_BODIES", false)
+	if err != nil {
+		log.Fatal(err)
+	}
+	debugBodyLimit, err := envInt("DEBUG_BODY_LIMIT", 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+	redactFields := envList("REDACT_FIELDS")
+	if redactFields == nil {
+		redactFields = envList("DEBUG_REDACT_FIELDS")
+	}
 
-// handleGetPayment обрабатывает GET запрос для получения статуса платежа
-// В реальности здесь был бы ID в URL (например: GET /payments/pay_12345)
-// Пока возвращаем заглушку (mock data)
-func handleGetPayment(w http.ResponseWriter, r *http.Request) {
-	// Проверяем что это GET запрос
-	if r.Method != http.MethodGet {
-		http.Error(w, "Invalid method", http.StatusMethodNotAllowed)
-		return
+	// Ответы от COMPRESS_MIN_SIZE байт (по умолчанию 1024) сжимаются gzip
+	// для клиентов с Accept-Encoding: gzip; 0 = без сжатия
+	compressMinSize, err := envInt("COMPRESS_MIN_SIZE", 1024)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	// Создаем тестовый платеж (в реальности: запрос к БД)
-	// Короткая форма инициализации структуры
-	// Порядок полей не важен, можно указать только некоторые
-	payment := Payment{
-		ID:       "pay_12345",
-		Amount:   1000.50,
-		Currency: "RUB",
-		Status:   "succeeded", // Платеж успешно обработан
+	// Внедрение сбоев для проверки клиентов: CHAOS_RATE=0.1 — 10% запросов
+	// получают 500 или задержку до CHAOS_LATENCY (по умолчанию 2s)
+	// По умолчанию выключено; /health не затрагивается
+	chaosRate, err := envFloat("CHAOS_RATE", 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if chaosRate < 0 || chaosRate > 1 {
+		log.Fatalf("Invalid CHAOS_RATE %v: expected value between 0.0 and 1.0", chaosRate)
+	}
+	chaosLatency, err := envDuration("CHAOS_LATENCY", 2*time.Second)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if chaosRate > 0 {
+		log.Printf("WARNING: chaos mode is ON: %.0f%% of requests get injected failures or up to %s latency", chaosRate*100, chaosLatency)
 	}
 
-	// Отправляем JSON ответ
-	w.Header().Set("Content-Type", "application/json")
-	// Для GET используем статус 200 (OK) — это стандарт
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(payment)
-}
+	// Ключи API: API_KEYS="key1,key2" — каждый запрос должен нести
+	// X-API-Key с одним из них, и ключ видит только свои платежи
+	// ADMIN_API_KEYS — ключи, которым видны все платежи (?all=true в списке)
+	// Не задано = проверка ключей выключена
+	apiKeys := envList("API_KEYS")
+	adminAPIKeys := envList("ADMIN_API_KEYS")
+
+	// Только HTTPS: REQUIRE_HTTPS=true отклоняет запросы без TLS (кроме /health)
+	// За прокси, завершающим TLS, перечислите его адреса в TRUSTED_PROXIES
+	// ("10.0.0.0/8,192.168.1.10") — тогда учитывается X-Forwarded-Proto,
+	// а адрес клиента в логах берется из X-Forwarded-For
+	requireHTTPS, err := envBool("REQUIRE_HTTPS", false)
+	if err != nil {
+		log.Fatal(err)
+	}
+	trustedProxies, err := payments.ParseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES: ", err)
+	}
 
-// ===== ТОЧКА ВХОДА В ПРОГРАММУ =====
+	// Server получает все зависимости через конструктор
+	// Маршруты регистрируются внутри (см. payments/server.go)
+	server := payments.NewServer(store, fxProvider, gateway, payments.Config{
+		DefaultCurrency:     defaultCurrency,
+		SupportedCurrencies: currencies,
+		BlockedCurrencies:   blockedCurrencies,
+		DuplicateWindow:     duplicateWindow,
+		Rounding:            rounding,
+		ThousandsSeparators: thousandsSeparators,
+		MaxFloatAmountMinor: int64(maxFloatAmountMinor),
+		Fees:                fees,
+		MinAmounts:          minAmounts,
+		WarnAmounts:         warnAmounts,
+		FailureReasons:      failureReasons,
+		Outbox:              outbox,
+		AuditLog:            auditLog,
+		BasePath:            basePath,
+		MaxConcurrency:      maxConcurrency,
+		MaxURLLength:        maxURLLength,
+		MaxQueryLength:      maxQueryLength,
+		RequestTimeout:      requestTimeout,
+		Features:            &features,
+		MaxPaymentRetries:   maxPaymentRetries,
+		MaxRefunds:          maxRefunds,
+		AsyncGateway:        asyncGateway,
+		CompressMinSize:     compressMinSize,
+		IdempotencyTTL:      idempotencyTTL,
+		CaptureExpiry:       captureExpiry,
+		WebhookSecret:       os.Getenv("WEBHOOK_SECRET"),
+		WebhookReplayWindow: webhookReplayWindow,
+		ValidateRequests:    validateRequests,
+		ChaosRate:           chaosRate,
+		APIKeys:             apiKeys,
+		AdminAPIKeys:        adminAPIKeys,
+		RequireHTTPS:        requireHTTPS,
+		TrustedProxies:      trustedProxies,
+		ChaosLatency:        chaosLatency,
+		DebugBodies:         debugBodies,
+		DebugBodyLimit:      debugBodyLimit,
+		RedactFields:        redactFields,
+	})
+
+	// ===== ФОНОВЫЕ ЗАДАЧИ И ОСТАНОВКА =====
+
+	// signal.NotifyContext возвращает контекст, который отменяется,
+	// когда процесс получает Ctrl+C (SIGINT) или SIGTERM (так Docker
+	// и Kubernetes просят программу завершиться)
+	// Все фоновые горутины слушают этот контекст и выходят при отмене
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Уборщик хранилища в памяти (проверка каждые JANITOR_INTERVAL):
+	// всегда выбрасывает истекшие ключи идемпотентности (IDEMPOTENCY_TTL),
+	// а PAYMENT_RETENTION="24h" включает еще и удаление завершенных
+	// платежей старше 24 часов
+	// PAYMENT_RETENTION не задано = платежи хранятся, пока работает процесс
+	// В Redis ключи истекают сами (TTL), уборщик не нужен
+	retention, err := envDuration("PAYMENT_RETENTION", 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+	janitorInterval, err := envDuration("JANITOR_INTERVAL", time.Minute)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// В Redis данные общие для всех экземпляров — чистить их
+	// из каждого процесса нельзя
+	if retention > 0 && memoryStore == nil {
+		log.Fatal("PAYMENT_RETENTION is supported only with the in-memory store")
+	}
+	if memoryStore != nil {
+		if janitorInterval <= 0 {
+			log.Fatal("JANITOR_INTERVAL must be positive")
+		}
+		go memoryStore.RunJanitor(ctx, retention, janitorInterval)
+	}
 
-// main — специальная функция, выполняется при запуске программы
-// Это как if __name__ == "__main__" в Python
-// Должна быть в пакете main, иначе Go не запустится
-func main() {
-	// Выводим сообщение о запуске сервера
-	// Это не обязательно, но полезно для отладки
-	fmt.Println("Payment System API starting...")
+	if outboxWorker != nil {
+		pollInterval, err := envDuration("OUTBOX_POLL_INTERVAL", time.Second)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if pollInterval <= 0 {
+			log.Fatal("OUTBOX_POLL_INTERVAL must be positive")
+		}
+		go outboxWorker.Run(ctx, pollInterval)
+	}
 
-	// ===== РЕГИСТРАЦИЯ МАРШРУТОВ (ROUTING) =====
+	// Планировщик отложенных платежей (process_at): проверяет,
+	// не наступило ли время, каждые SCHEDULER_INTERVAL (по умолчанию 1s)
+	schedulerInterval, err := envDuration("SCHEDULER_INTERVAL", time.Second)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if schedulerInterval <= 0 {
+		log.Fatal("SCHEDULER_INTERVAL must be positive")
+	}
+	go server.RunScheduler(ctx, schedulerInterval)
 
-	// http.HandleFunc регистрирует обработчик для URL пути
-	// Параметры:
-	// 1. "/payments" = URL путь (pattern)
-	//    Запросы на http://localhost:8080/payments попадут сюда
-	// 2. handleCreatePayment = функция-обработчик
-	//    Передаем ФУНКЦИЮ (не вызываем её!)
-	//    Без скобок () — это важно!
-	http.HandleFunc("/payments", handleCreatePayment)
+	// Просроченные авторизации (старше CAPTURE_EXPIRY) аннулируются
+	// проверкой каждые CAPTURE_EXPIRY_INTERVAL (по умолчанию 1m)
+	captureExpiryInterval, err := envDuration("CAPTURE_EXPIRY_INTERVAL", time.Minute)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if captureExpiryInterval <= 0 {
+		log.Fatal("CAPTURE_EXPIRY_INTERVAL must be positive")
+	}
+	go server.RunCaptureExpiry(ctx, captureExpiryInterval)
+
+	// Сколько ждать завершения текущих запросов при остановке
+	shutdownTimeout, err := envDuration("SHUTDOWN_TIMEOUT", 10*time.Second)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Сколько "сливать" трафик до остановки: DRAIN_TIMEOUT=15s
+	// Все это время /ready отвечает 503, а запросы обслуживаются,
+	// пока балансировщик не выведет экземпляр из ротации
+	// 0 = останавливаться сразу (по умолчанию)
+	drainTimeout, err := envDuration("DRAIN_TIMEOUT", 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Таймауты соединений — защита от медленных клиентов (slow loris),
+	// которые открывают соединение и "по капле" шлют заголовки, занимая
+	// сервер. Нулевое значение в http.Server = без таймаута, поэтому
+	// задаем свои значения по умолчанию:
+	// - HTTP_READ_HEADER_TIMEOUT (5s) — на чтение заголовков запроса
+	// - HTTP_READ_TIMEOUT (15s) — на чтение всего запроса вместе с телом
+	// - HTTP_WRITE_TIMEOUT (30s) — на обработку и запись ответа
+	//   (поток событий /payments/{id}/stream снимает этот лимит для себя)
+	// - HTTP_IDLE_TIMEOUT (120s) — сколько держать keep-alive соединение
+	//   между запросами
+	readHeaderTimeout, err := envDuration("HTTP_READ_HEADER_TIMEOUT", 5*time.Second)
+	if err != nil {
+		log.Fatal(err)
+	}
+	readTimeout, err := envDuration("HTTP_READ_TIMEOUT", 15*time.Second)
+	if err != nil {
+		log.Fatal(err)
+	}
+	writeTimeout, err := envDuration("HTTP_WRITE_TIMEOUT", 30*time.Second)
+	if err != nil {
+		log.Fatal(err)
+	}
+	idleTimeout, err := envDuration("HTTP_IDLE_TIMEOUT", 120*time.Second)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	// Еще один маршрут для получения платежа
-	// В реальности путь был бы "/payments/{id}"
-	// Для простоты пока статичный путь
-	http.HandleFunc("/payments/status", handleGetPayment)
+	// TLS прямо на сервере (без прокси): TLS_CERT и TLS_KEY — пути
+	// к сертификату и ключу в PEM. Заданы оба — сервер работает по HTTPS
+	// (и заодно по HTTP/2), не заданы — по HTTP, как раньше
+	// Пару проверяем сразу: битый файл или чужой ключ — падаем при старте
+	tlsConfig, err := loadTLSConfig(os.Getenv("TLS_CERT"), os.Getenv("TLS_KEY"))
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	// ===== ЗАПУСК HTTP СЕРВЕРА =====
 
-	// http.ListenAndServe запускает HTTP сервер
-	// Параметры:
-	// 1. ":8080" = адрес и порт
+	// http.Server — явная структура сервера вместо http.ListenAndServe:
+	// у нее есть метод Shutdown для корректной остановки
+	// Поля:
+	// - Addr ":8080" = адрес и порт
 	//    : без IP = слушать на всех сетевых интерфейсах (0.0.0.0)
 	//    8080 = номер порта (можно любой от 1024 до 65535)
-	// 2. nil = использовать DefaultServeMux (роутер по умолчанию)
-	//    Все маршруты из HandleFunc идут туда
-	//
+	// - Handler = обработчик всех запросов (наш payments.Server)
+	// - *Timeout = таймауты соединений (см. выше)
+	// - TLSConfig = сертификат (nil = без TLS)
+	httpServer := &http.Server{
+		Addr:              ":8080",
+		Handler:           server,
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+	}
+
+	// КОРРЕКТНАЯ ОСТАНОВКА (graceful shutdown):
+	// Отдельная горутина ждет сигнала остановки, "сливает" трафик
+	// (drainTimeout, см. StartDraining) и вызывает Shutdown:
+	// сервер перестает принимать новые соединения и ждет завершения
+	// текущих запросов (не дольше shutdownTimeout)
+	go func() {
+		<-ctx.Done()
+		if drainTimeout > 0 {
+			log.Printf("Draining for %s before shutdown...", drainTimeout)
+			server.StartDraining()
+			// Без keep-alive клиенты открывают новые соединения —
+			// и балансировщик отправляет их уже на другие экземпляры
+			httpServer.SetKeepAlivesEnabled(false)
+			time.Sleep(drainTimeout)
+		}
+		log.Println("Shutting down server...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Server shutdown error: %v", err)
+		}
+	}()
+
 	// ЭТА ФУНКЦИЯ БЛОКИРУЮЩАЯ:
 	// После её вызова программа "зависает" и обрабатывает запросы
 	// Код после ListenAndServe выполнится только при ошибке или остановке
 	//
 	// ВОЗВРАЩАЕТ ERROR:
-	// Если сервер не смог запуститься (порт занят и т.д.)
-	log.Println("Server is running on http://localhost:8080")
-	err := http.ListenAndServe(":8080", nil)
+	// - http.ErrServerClosed = штатная остановка через Shutdown
+	// - любая другая ошибка = сервер не смог запуститься (порт занят и т.д.)
+	if tlsConfig != nil {
+		// Сертификат уже в TLSConfig — пути к файлам не нужны
+		// HTTP/2 net/http включает для TLS сам
+		log.Println("Server is running on https://localhost:8080")
+		err = httpServer.ListenAndServeTLS("", "")
+	} else {
+		log.Println("Server is running on http://localhost:8080")
+		err = httpServer.ListenAndServe()
+	}
 
-	// Если мы здесь — значит сервер упал
 	// log.Fatal логирует ошибку и вызывает os.Exit(1)
 	// Программа завершается с кодом ошибки 1
-	if err != nil {
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatal("Server failed to start:", err)
 	}
+	log.Println("Server stopped")
 }
 
 // ===== ЧТО ПРОИСХОДИТ ПРИ ЗАПУСКЕ =====
 //
 // 1. Go компилирует код в исполняемый файл (бинарник)
 // 2. Запускает функцию main()
-// 3. Регистрирует маршруты /payments и /payments/status
+// 3. Создает хранилище и payments.Server, который регистрирует маршруты
+//    /payments, /payments/status и /payments/{id}
 // 4. Запускает HTTP сервер на порту 8080
 // 5. Ждет входящих HTTP запросов
-// 6. При запросе на /payments вызывает handleCreatePayment
+// 6. При запросе на /payments вызывает handlePayments (создание/список)
 // 7. При запросе на /payments/status вызывает handleGetPayment
+// 8. При запросе на /payments/{id} вызывает handlePaymentByID
 //
 // ===== ПРИМЕР ИСПОЛЬЗОВАНИЯ =====
 //
@@ -324,10 +708,26 @@ func main() {
 //   -d '{"amount": 1000.50, "currency": "RUB"}'
 //
 // Ответ:
-// {"id":"pay_12345","amount":1000.5,"currency":"RUB","status":"pending"}
+// {"id":"pay_…","amount":1000.5,"currency":"RUB","status":"pending","created_at":"…"}
 //
 // Получение статуса:
 // curl http://localhost:8080/payments/status
 //
 // Ответ:
 // {"id":"pay_12345","amount":1000.5,"currency":"RUB","status":"succeeded"}
+//
+// Список платежей (включая удаленные):
+// curl "http://localhost:8080/payments?include_deleted=true"
+//
+// Мягкое удаление:
+// curl -X DELETE http://localhost:8080/payments/pay_…
+//
+// Ответ: 204 No Content (повторный вызов — тоже 204)
+//
+// Создание клиента и платежа от его имени:
+// curl -X POST http://localhost:8080/customers -d '{"email": "ivan@example.com", "name": "Иван"}'
+// curl -X POST http://localhost:8080/payments \
+//   -d '{"amount": 500, "currency": "RUB", "customer_id": "cus_…"}'
+//
+// Платежи клиента:
+// curl http://localhost:8080/customers/cus_…/payments
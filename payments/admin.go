@@ -0,0 +1,143 @@
+package payments
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// ===== СЛУЖЕБНЫЕ ЭНДПОИНТЫ ПОДДЕРЖКИ =====
+//
+// Иногда платеж "застревает" (шлюз ответил, а уведомление потерялось)
+// и его нужно исправить вручную. Обычный PATCH соблюдает правила
+// переходов, поэтому для поддержки есть отдельный эндпоинт, который
+// их обходит. Он:
+//   - выключен по умолчанию (Features.Admin, FEATURE_ADMIN=true)
+//   - доступен только с ключом администратора (Config.AdminAPIKeys)
+//   - требует причину, которая попадает в журнал аудита
+
+// maxReasonLength — максимальная длина причины исправления (в символах)
+const maxReasonLength = 500
+
+// forceStatusRequest — тело запроса POST /admin/payments/{id}/status
+type forceStatusRequest struct {
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
+// validateStatusReason проверяет пару "новый статус + причина"
+// служебных операций и собирает ВСЕ ошибки
+func validateStatusReason(status, reason string) []FieldError {
+	var errs []FieldError
+	switch {
+	case status == "":
+		errs = append(errs, FieldError{Field: "status", Code: FieldStatusRequired, Message: "Status is required"})
+	case !isKnownStatus(status):
+		errs = append(errs, FieldError{Field: "status", Code: FieldStatusUnknown,
+			Message: fmt.Sprintf("Unknown status %q", status)})
+	}
+	switch {
+	case strings.TrimSpace(reason) == "":
+		errs = append(errs, FieldError{Field: "reason", Code: FieldReasonRequired, Message: "Reason is required"})
+	case utf8.RuneCountInString(reason) > maxReasonLength:
+		errs = append(errs, FieldError{Field: "reason", Code: FieldReasonTooLong,
+			Message: fmt.Sprintf("Reason must be at most %d characters", maxReasonLength)})
+	}
+	return errs
+}
+
+// requireAdmin отвечает 403, если запрос сделан не ключом администратора
+// Без ключей в конфиге администратора нет вовсе — отказ всем
+func (s *Server) requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if s.authEnabled() {
+		if _, admin := s.caller(r); admin {
+			return true
+		}
+	}
+	writeError(w, r, http.StatusForbidden, CodeForbidden, "Admin API key required")
+	return false
+}
+
+// handleAdminSetStatus принудительно ставит платежу статус
+// POST /admin/payments/{id}/status {"status":"failed","reason":"…"}
+//
+// Правила переходов (allowedTransitions) не проверяются: можно
+// вернуть даже succeeded в pending. Поэтому каждое изменение пишется
+// в журнал аудита с действием force_status и причиной
+// If-Match работает как в PATCH
+//
+// Коды ответа:
+//   - 200 OK = статус изменен, в ответе платеж
+//   - 400 Bad Request = неверный ID или тело (все ошибки в fields)
+//   - 403 Forbidden = не ключ администратора
+//   - 404 Not Found = платежа нет
+//   - 412 Precondition Failed = If-Match не совпал с версией
+func (s *Server) handleAdminSetStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+	// Права проверяются до всего остального: не-администратор
+	// не должен узнать даже, корректен ли его запрос
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	id := r.PathValue("id")
+	if !isValidPaymentID(id) {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidID, "Invalid payment ID: must start with "+paymentIDPrefix)
+		return
+	}
+	defer s.paymentLocks.lock(id)()
+	expectedVersion, ok := parseIfMatch(r)
+	if !ok {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidIfMatch, "Invalid If-Match header")
+		return
+	}
+
+	var req forceStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+	if errs := validateStatusReason(req.Status, req.Reason); len(errs) > 0 {
+		writeFieldErrors(w, r, errs)
+		return
+	}
+
+	before, _ := s.store.Get(r.Context(), id)
+	var payment Payment
+	var err error
+	if req.Status == StatusFailed {
+		// failed — только вместе с причиной отказа; как и в массовой
+		// смене статуса, ею становится причина администратора
+		payment, err = s.store.FailPayment(r.Context(), id,
+			Failure{ExpectedVersion: expectedVersion, Reason: req.Reason, Force: true})
+	} else {
+		payment, err = s.store.ForceStatus(r.Context(), id, req.Status, expectedVersion)
+	}
+	switch {
+	case errors.Is(err, ErrPaymentNotFound):
+		writeError(w, r, http.StatusNotFound, CodePaymentNotFound, "Payment not found")
+		return
+	case errors.Is(err, ErrVersionMismatch):
+		w.Header().Set("ETag", paymentETag(payment))
+		writeError(w, r, http.StatusPreconditionFailed, CodeVersionMismatch, "Payment was modified by another request")
+		return
+	case err != nil:
+		log.Printf("Error forcing payment status: %v", err)
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+		return
+	}
+
+	log.Printf("Payment status forced: ID=%s, %s -> %s, Actor=%s, Reason=%s",
+		payment.ID, before.Status, payment.Status, auditActor(r), s.logValue("reason", req.Reason))
+	s.audit.recordReason(auditActor(r), AuditForce, payment.ID, before.Status, payment.Status, req.Reason)
+	s.publishStatus(r.Context(), payment)
+
+	w.Header().Set("ETag", paymentETag(payment))
+	writeJSON(w, r, http.StatusOK, payment)
+}
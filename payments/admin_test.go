@@ -0,0 +1,100 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+)
+
+// newAdminServer — сервер с включенными служебными эндпоинтами,
+// ключом клиента "alice", ключом администратора "admin" и успешным
+// платежом pay_stuck
+func newAdminServer(t *testing.T, audit *bytes.Buffer) (*Server, Store) {
+	t.Helper()
+	store := NewMemoryStore()
+	savePaymentT(t, store, Payment{ID: "pay_stuck", AmountMinor: 100, Currency: "RUB",
+		Status: StatusSucceeded, CreatedByKey: apiKeyID("alice"), Version: 1})
+	features := AllFeatures
+	features.Admin = true
+	return NewServer(store, nil, nil, Config{
+		APIKeys:      []string{"alice"},
+		AdminAPIKeys: []string{"admin"},
+		AuditLog:     audit,
+		Features:     &features,
+	}), store
+}
+
+// TestAdminSetStatusRequiresAdmin — без ключа администратора
+// статус не меняется, даже если тело запроса корректно
+func TestAdminSetStatusRequiresAdmin(t *testing.T) {
+	s, store := newAdminServer(t, nil)
+	body := `{"status": "pending", "reason": "gateway notification lost"}`
+
+	for _, tc := range []struct {
+		name   string
+		header map[string]string
+		want   int
+	}{
+		{"no key", nil, http.StatusUnauthorized},
+		{"unknown key", withKey("mallory"), http.StatusUnauthorized},
+		{"client key", withKey("alice"), http.StatusForbidden},
+	} {
+		rec := doJSON(t, s, http.MethodPost, "/admin/payments/pay_stuck/status", body, tc.header)
+		if rec.Code != tc.want {
+			t.Errorf("%s: status = %d, want %d", tc.name, rec.Code, tc.want)
+		}
+	}
+	if p, _ := store.Get(context.Background(), "pay_stuck"); p.Status != StatusSucceeded || p.Version != 1 {
+		t.Fatalf("payment changed without admin: %s v%d", p.Status, p.Version)
+	}
+}
+
+// TestAdminSetStatusAudited — администратор обходит правила переходов
+// (succeeded → pending), а причина попадает в журнал аудита
+func TestAdminSetStatusAudited(t *testing.T) {
+	var audit bytes.Buffer
+	s, _ := newAdminServer(t, &audit)
+
+	rec := doJSON(t, s, http.MethodPost, "/admin/payments/pay_stuck/status",
+		`{"status": "pending", "reason": "gateway notification lost"}`, withKey("admin"))
+	var got Payment
+	decodeBody(t, rec, &got)
+	if rec.Code != http.StatusOK || got.Status != StatusPending || got.Version != 2 {
+		t.Fatalf("force = %d %s v%d", rec.Code, got.Status, got.Version)
+	}
+
+	entries := auditEntries(t, &audit)
+	if len(entries) != 1 {
+		t.Fatalf("audit entries = %d, want 1", len(entries))
+	}
+	e := entries[0]
+	if e.Action != AuditForce || e.PaymentID != "pay_stuck" || e.Actor != apiKeyID("admin") ||
+		e.StatusBefore != StatusSucceeded || e.StatusAfter != StatusPending || e.Reason != "gateway notification lost" {
+		t.Fatalf("audit entry = %+v", e)
+	}
+}
+
+func TestAdminSetStatusValidation(t *testing.T) {
+	var audit bytes.Buffer
+	s, _ := newAdminServer(t, &audit)
+
+	rec := doJSON(t, s, http.MethodPost, "/admin/payments/pay_stuck/status", `{"status": "bogus"}`, withKey("admin"))
+	var resp ErrorResponse
+	decodeBody(t, rec, &resp)
+	codes := map[string]string{}
+	for _, f := range resp.Fields {
+		codes[f.Field] = f.Code
+	}
+	if rec.Code != http.StatusBadRequest || codes["status"] != FieldStatusUnknown || codes["reason"] != FieldReasonRequired {
+		t.Fatalf("invalid body: %d %+v", rec.Code, resp)
+	}
+
+	if rec := doJSON(t, s, http.MethodPost, "/admin/payments/pay_missing/status",
+		`{"status": "failed", "reason": "x"}`, withKey("admin")); rec.Code != http.StatusNotFound {
+		t.Fatalf("missing payment = %d", rec.Code)
+	}
+	if audit.Len() != 0 {
+		t.Fatalf("failed requests were audited: %s", audit.String())
+	}
+}
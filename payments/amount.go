@@ -0,0 +1,212 @@
+package payments
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ===== МИНОРНЫЕ ЕДИНИЦЫ =====
+
+// MaxAmountMinor — абсолютный потолок суммы платежа в минорных единицах
+// (копейках, центах), независимо от валюты: 1e12 = 10 млрд рублей
+//
+// Защищает от опечаток ("лишние нули"), злоупотреблений и переполнения
+// int64 при дальнейших вычислениях (суммы, комиссии, конвертация)
+const MaxAmountMinor int64 = 1_000_000_000_000
+
+// ErrAmountTooLarge — сумма превышает MaxAmountMinor
+var ErrAmountTooLarge = errors.New("amount exceeds system maximum")
+
+// RecommendedMaxFloatAmountMinor — рекомендуемый порог для
+// Config.MaxFloatAmountMinor (MAX_FLOAT_AMOUNT_MINOR): 1e10 = 100 млн
+// рублей, в 100 раз ниже MaxAmountMinor
+//
+// Клиенты (JavaScript и многие JSON библиотеки) держат числа во float64
+// Целые до 2^53 он хранит точно, но 2^53 больше MaxAmountMinor, и такой
+// порог не срабатывал бы никогда. Дробная сумма в основных единицах
+// ("123456789.99") теряет копейки раньше — при вычислениях на стороне
+// клиента, поэтому крупные суммы просим присылать строкой заранее
+//
+// По умолчанию порог выключен: числовые суммы всегда принимались
+// вплоть до MaxAmountMinor, и клиенты, присылающие крупные суммы
+// числом, получили бы 400 после обновления
+const RecommendedMaxFloatAmountMinor int64 = MaxAmountMinor / 100
+
+// ErrAmountNeedsString — числовая сумма больше Config.MaxFloatAmountMinor:
+// такую сумму клиент должен прислать строкой
+var ErrAmountNeedsString = errors.New("send this amount as a string")
+
+// toMinorUnits переводит сумму в минорные единицы валюты
+// Пример: 100.50 RUB → 10050 копеек, 1500 JPY → 1500 иен
+// Дробный остаток округляется по режиму mode
+func toMinorUnits(amount float64, currency string, mode RoundingMode) int64 {
+	scale := math.Pow10(decimalsFor(currency))
+	return int64(mode.round(amount * scale))
+}
+
+// amountToMinor переводит сумму платежа в минорные единицы
+// с проверкой системного потолка
+//
+// Сравнение делаем ДО приведения к int64: преобразование огромного
+// float64 (например, 1e300) в int64 дает непредсказуемый результат
+func amountToMinor(amount float64, currency string, mode RoundingMode) (int64, error) {
+	scaled := mode.round(amount * math.Pow10(decimalsFor(currency)))
+	if scaled > float64(MaxAmountMinor) {
+		return 0, ErrAmountTooLarge
+	}
+	return int64(scaled), nil
+}
+
+// parseAmountLiteral точно переводит строковую сумму в минорные единицы
+// Пример: "100.50" RUB → 10050, "1500" JPY → 1500
+//
+// Формат: цифры и необязательная дробная часть через точку, не длиннее
+// числа знаков валюты. Знак, пробелы, экспонента ("1e3") и лишние знаки
+// после точки ("100.505" RUB) — ошибка: округлять строку, которую клиент
+// прислал ради точности, было бы неожиданно
+func parseAmountLiteral(s, currency string) (int64, error) {
+	decimals := decimalsFor(currency)
+	whole, frac, hasPoint := strings.Cut(s, ".")
+	if whole == "" || (hasPoint && frac == "") || !isDigits(whole) || !isDigits(frac) {
+		return 0, fmt.Errorf("amount %q is not a decimal number like \"100.50\"", s)
+	}
+	if len(frac) > decimals {
+		return 0, fmt.Errorf("amount %q has more than %d decimal places for %s", s, decimals, currency)
+	}
+	// Дополняем дробную часть нулями до числа знаков валюты: "100.5" → 10050
+	digits := strings.TrimLeft(whole+frac+strings.Repeat("0", decimals-len(frac)), "0")
+
+	// Посимвольно собираем число, проверяя потолок на каждом шаге,
+	// чтобы огромная строка не переполнила int64
+	var minor int64
+	for _, c := range digits {
+		minor = minor*10 + int64(c-'0')
+		if minor > MaxAmountMinor {
+			return 0, ErrAmountTooLarge
+		}
+	}
+	return minor, nil
+}
+
+// stripThousandsSeparators убирает разделители разрядов из строковой
+// суммы: "1,000.50" → "1000.50"
+//
+// Разделитель — запятая, группы после первой — ровно по 3 цифры:
+// "1,000,000" подходит, а "1,00" или "10,0000" — нет (ok = false),
+// потому что это скорее десятичная запятая, чем разделитель разрядов
+// Строка без запятых возвращается как есть
+func stripThousandsSeparators(s string) (string, bool) {
+	whole, frac, hasPoint := strings.Cut(s, ".")
+	groups := strings.Split(whole, ",")
+	if len(groups) == 1 {
+		return s, true
+	}
+	if len(groups[0]) < 1 || len(groups[0]) > 3 {
+		return s, false
+	}
+	for _, g := range groups[1:] {
+		if len(g) != 3 {
+			return s, false
+		}
+	}
+	whole = strings.Join(groups, "")
+	if hasPoint {
+		return whole + "." + frac, true
+	}
+	return whole, true
+}
+
+// amountLiteral готовит строковую сумму из запроса к разбору
+// С Config.ThousandsSeparators убирает разделители разрядов
+// ("1,000.50"), без него строка остается как есть — и запятая
+// дает ошибку разбора (400): "1,000" могло означать и одну тысячу,
+// и одну целую по-европейски
+func (s *Server) amountLiteral(literal string) string {
+	if !s.thousandsSep {
+		return literal
+	}
+	if stripped, ok := stripThousandsSeparators(literal); ok {
+		return stripped
+	}
+	return literal
+}
+
+// isDigits сообщает, состоит ли строка только из цифр 0-9
+// Пустая строка подходит: это "нет дробной части"
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// numberToMinor переводит числовую сумму из JSON в минорные единицы
+// по ее исходной записи, без float64: 0.29 RUB → ровно 29 копеек
+// (через float64 0.29 * 100 = 28.999999999999996, и RoundFloor дал бы 28)
+//
+// Лишние знаки после точки ("100.505" RUB) округляются по режиму mode
+// целочисленно (см. divRound). Запись с экспонентой ("1e3"), знаком
+// или слишком длинная разбирается через float64, как раньше
+func numberToMinor(n json.Number, currency string, mode RoundingMode) (int64, error) {
+	s := n.String()
+	whole, frac, _ := strings.Cut(s, ".")
+	decimals := decimalsFor(currency)
+	// 18 цифр гарантированно помещаются в int64
+	if whole == "" || !isDigits(whole) || !isDigits(frac) || len(whole)+len(frac) > 18 {
+		f, err := n.Float64()
+		if err != nil {
+			return 0, err
+		}
+		return amountToMinor(f, currency, mode)
+	}
+	if len(frac) <= decimals {
+		return parseAmountLiteral(s, currency)
+	}
+
+	var digits int64
+	for _, c := range whole + frac {
+		digits = digits*10 + int64(c-'0')
+	}
+	// Отбрасываемые знаки: 100.505 RUB → 100505 / 10 = 10050.5 → по режиму
+	minor := mode.divRound(digits, int64(math.Pow10(len(frac)-decimals)))
+	if minor > MaxAmountMinor {
+		return 0, ErrAmountTooLarge
+	}
+	return minor, nil
+}
+
+// requestAmountMinor переводит сумму из запроса в минорные единицы
+// Строковая сумма (literal) разбирается точно и без округления,
+// числовая (number) — точно, с округлением лишних знаков по режиму mode
+// Без того и другого переводится amount (сумма, заданная не из JSON)
+//
+// Числовая сумма больше maxFloat минорных единиц (0 = без проверки) —
+// ErrAmountNeedsString: по дороге к нам она, скорее всего, уже побывала
+// во float64 клиента и могла потерять точность. Системный потолок
+// (ErrAmountTooLarge) проверяется раньше, поэтому maxFloat имеет смысл
+// только ниже MaxAmountMinor
+func requestAmountMinor(amount float64, literal string, number json.Number, currency string, mode RoundingMode, maxFloat int64) (int64, error) {
+	switch {
+	case literal != "":
+		return parseAmountLiteral(literal, currency)
+	case number != "":
+		minor, err := numberToMinor(number, currency, mode)
+		if err == nil && maxFloat > 0 && minor > maxFloat {
+			return 0, fmt.Errorf("amount %s is more than %d minor units and may lose precision as a JSON number: %w, e.g. \"amount\": \"%s\"",
+				number, maxFloat, ErrAmountNeedsString, formatAmountDecimal(minor, currency))
+		}
+		return minor, err
+	}
+	return amountToMinor(amount, currency, mode)
+}
+
+// minorToAmount переводит минорные единицы обратно в сумму валюты
+// Пример: 10050 RUB → 100.5
+func minorToAmount(minor int64, currency string) float64 {
+	return float64(minor) / math.Pow10(decimalsFor(currency))
+}
@@ -0,0 +1,228 @@
+package payments
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseAmountLiteral(t *testing.T) {
+	cases := []struct {
+		in, currency string
+		want         int64
+		ok           bool
+	}{
+		{"100.50", "RUB", 10050, true},
+		{"100.5", "RUB", 10050, true},
+		{"100", "RUB", 10000, true},
+		{"1500", "JPY", 1500, true},
+		{"100.505", "RUB", 0, false},
+		{"1e3", "RUB", 0, false},
+		{"-1", "RUB", 0, false},
+		{"100.", "RUB", 0, false},
+	}
+	for _, c := range cases {
+		got, err := parseAmountLiteral(c.in, c.currency)
+		if (err == nil) != c.ok || got != c.want {
+			t.Errorf("parseAmountLiteral(%q, %s) = %d, %v", c.in, c.currency, got, err)
+		}
+	}
+	if _, err := parseAmountLiteral("99999999999999999999999", "RUB"); !errors.Is(err, ErrAmountTooLarge) {
+		t.Errorf("huge literal: err = %v, want ErrAmountTooLarge", err)
+	}
+}
+
+// Рекомендуемый порог должен быть ниже системного потолка, иначе
+// проверка числовых сумм никогда не срабатывает
+func TestRecommendedMaxFloatBelowSystemMaximum(t *testing.T) {
+	if RecommendedMaxFloatAmountMinor >= MaxAmountMinor {
+		t.Fatalf("RecommendedMaxFloatAmountMinor = %d, must be below MaxAmountMinor = %d", RecommendedMaxFloatAmountMinor, MaxAmountMinor)
+	}
+}
+
+// TestRequestAmountMinorFloatBoundary — ровно на пороге число
+// принимается, на минорную единицу больше — ErrAmountNeedsString,
+// а та же сумма строкой проходит
+func TestRequestAmountMinorFloatBoundary(t *testing.T) {
+	const limit = RecommendedMaxFloatAmountMinor // 1e10 = "100000000.00" RUB
+
+	minor, err := requestAmountMinor(0, "", json.Number("100000000.00"), "RUB", RoundHalfUp, limit)
+	if err != nil || minor != limit {
+		t.Fatalf("at limit: %d, %v", minor, err)
+	}
+	if _, err := requestAmountMinor(0, "", json.Number("100000000.01"), "RUB", RoundHalfUp, limit); !errors.Is(err, ErrAmountNeedsString) {
+		t.Fatalf("above limit: err = %v, want ErrAmountNeedsString", err)
+	}
+	minor, err = requestAmountMinor(0, "100000000.01", "", "RUB", RoundHalfUp, limit)
+	if err != nil || minor != limit+1 {
+		t.Fatalf("string above limit: %d, %v", minor, err)
+	}
+	// 0 = без проверки
+	if _, err := requestAmountMinor(0, "", json.Number("100000000.01"), "RUB", RoundHalfUp, 0); err != nil {
+		t.Fatalf("no limit: %v", err)
+	}
+}
+
+func TestCreatePaymentFloatBoundary(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{MaxFloatAmountMinor: RecommendedMaxFloatAmountMinor})
+
+	createPaymentT(t, s, `{"amount": 100000000.00, "currency": "RUB"}`)
+
+	rec := doJSON(t, s, http.MethodPost, "/payments", `{"amount": 100000000.01, "currency": "RUB"}`, nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("above limit = %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp ErrorResponse
+	decodeBody(t, rec, &resp)
+	if len(resp.Fields) != 1 || resp.Fields[0].Code != FieldAmountNeedsString {
+		t.Fatalf("fields = %+v, want %s", resp.Fields, FieldAmountNeedsString)
+	}
+
+	rec = doJSON(t, s, http.MethodPost, "/payments", `{"amount": "100000000.01", "currency": "RUB"}`, nil)
+	var created struct {
+		AmountMinor int64 `json:"amount_minor"`
+	}
+	decodeBody(t, rec, &created)
+	if rec.Code != http.StatusCreated || created.AmountMinor != RecommendedMaxFloatAmountMinor+1 {
+		t.Fatalf("string above limit = %d, amount_minor = %d", rec.Code, created.AmountMinor)
+	}
+}
+
+// По умолчанию порог выключен: крупная сумма числом принимается,
+// как до появления MaxFloatAmountMinor
+func TestCreatePaymentFloatThresholdOffByDefault(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	rec := doJSON(t, s, http.MethodPost, "/payments", `{"amount": 100000000.01, "currency": "RUB"}`, nil)
+	var created struct {
+		AmountMinor int64 `json:"amount_minor"`
+	}
+	decodeBody(t, rec, &created)
+	if rec.Code != http.StatusCreated || created.AmountMinor != 10_000_000_001 {
+		t.Fatalf("numeric above recommended limit = %d, amount_minor = %d", rec.Code, created.AmountMinor)
+	}
+}
+
+// TestCreatePaymentSystemCeiling — ровно MaxAmountMinor принимается,
+// на минорную единицу больше — 400 "exceeds system maximum"
+func TestCreatePaymentSystemCeiling(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+
+	// Строкой: число такого размера упрется в порог числовых сумм,
+	// если он включен (Config.MaxFloatAmountMinor)
+	createPaymentT(t, s, `{"amount": "10000000000.00", "currency": "RUB"}`)
+
+	for _, body := range []string{
+		`{"amount": "10000000000.01", "currency": "RUB"}`,
+		`{"amount": 1e300, "currency": "RUB"}`,
+	} {
+		rec := doJSON(t, s, http.MethodPost, "/payments", body, nil)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s = %d", body, rec.Code)
+		}
+		var resp ErrorResponse
+		decodeBody(t, rec, &resp)
+		if len(resp.Fields) != 1 || resp.Fields[0].Code != FieldAmountTooLarge ||
+			!strings.Contains(resp.Message, "exceeds system maximum") {
+			t.Fatalf("%s: response = %+v", body, resp)
+		}
+	}
+}
+
+func TestAmountToMinorCeiling(t *testing.T) {
+	if minor, err := amountToMinor(float64(MaxAmountMinor)/100, "RUB", RoundHalfUp); err != nil || minor != MaxAmountMinor {
+		t.Fatalf("at ceiling: %d, %v", minor, err)
+	}
+	if _, err := amountToMinor(float64(MaxAmountMinor)/100+0.01, "RUB", RoundHalfUp); !errors.Is(err, ErrAmountTooLarge) {
+		t.Fatalf("above ceiling: %v", err)
+	}
+}
+
+// TestNumberAmountExact — числовая сумма переводится из json.Number
+// без промежуточного float64: 1.005 во float64 — это 1.00499999…,
+// и округление half_up дало бы 100 вместо 101
+func TestNumberAmountExact(t *testing.T) {
+	for _, tc := range []struct {
+		number string
+		want   int64
+	}{
+		{"1.005", 101},
+		{"0.285", 29},
+	} {
+		if viaFloat, _ := amountToMinor(mustFloat(t, tc.number), "RUB", RoundHalfUp); viaFloat == tc.want {
+			t.Fatalf("%s: float64 path is exact, the case proves nothing", tc.number)
+		}
+		got, err := numberToMinor(json.Number(tc.number), "RUB", RoundHalfUp)
+		if err != nil || got != tc.want {
+			t.Errorf("numberToMinor(%s) = %d, %v; want %d", tc.number, got, err, tc.want)
+		}
+	}
+
+	s := NewServer(NewMemoryStore(), nil, nil, Config{Rounding: Rounding{Default: RoundHalfUp}})
+	rec := doJSON(t, s, http.MethodPost, "/payments", `{"amount": 1.005, "currency": "RUB"}`, nil)
+	var created struct {
+		AmountMinor int64 `json:"amount_minor"`
+	}
+	decodeBody(t, rec, &created)
+	if rec.Code != http.StatusCreated || created.AmountMinor != 101 {
+		t.Fatalf("create 1.005 = %d, amount_minor = %d, want 101", rec.Code, created.AmountMinor)
+	}
+}
+
+func mustFloat(t *testing.T, s string) float64 {
+	t.Helper()
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestStripThousandsSeparators(t *testing.T) {
+	cases := []struct {
+		in, want string
+		ok       bool
+	}{
+		{"1,000.50", "1000.50", true},
+		{"1,000,000", "1000000", true},
+		{"100.50", "100.50", true},
+		{"1,00", "1,00", false},
+		{"10,0000", "10,0000", false},
+		{"1000,000", "1000,000", false},
+		{",000", ",000", false},
+	}
+	for _, c := range cases {
+		got, ok := stripThousandsSeparators(c.in)
+		if got != c.want || ok != c.ok {
+			t.Errorf("stripThousandsSeparators(%q) = %q, %t", c.in, got, ok)
+		}
+	}
+}
+
+// TestThousandsSeparatorsModes — по умолчанию "1,000.50" — ошибка 400,
+// с ThousandsSeparators — ровно 100050 минорных единиц
+func TestThousandsSeparatorsModes(t *testing.T) {
+	body := `{"amount": "1,000.50", "currency": "RUB"}`
+
+	strict := NewServer(NewMemoryStore(), nil, nil, Config{})
+	if rec := doJSON(t, strict, http.MethodPost, "/payments", body, nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("strict = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	tolerant := NewServer(NewMemoryStore(), nil, nil, Config{ThousandsSeparators: true})
+	rec := doJSON(t, tolerant, http.MethodPost, "/payments", body, nil)
+	var created struct {
+		AmountMinor int64 `json:"amount_minor"`
+	}
+	decodeBody(t, rec, &created)
+	if rec.Code != http.StatusCreated || created.AmountMinor != 100050 {
+		t.Fatalf("tolerant = %d, amount_minor = %d", rec.Code, created.AmountMinor)
+	}
+
+	// Неоднозначная запятая остается ошибкой и в терпимом режиме
+	if rec := doJSON(t, tolerant, http.MethodPost, "/payments", `{"amount": "1,00", "currency": "RUB"}`, nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("ambiguous comma = %d", rec.Code)
+	}
+}
@@ -0,0 +1,112 @@
+package payments
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+)
+
+// ===== КЛЮЧИ API =====
+//
+// Если в конфиге заданы ключи (Config.APIKeys, Config.AdminAPIKeys),
+// каждый запрос должен нести заголовок X-API-Key с одним из них
+// Платеж запоминает, каким ключом он создан (Payment.CreatedByKey),
+// и другие ключи его не видят: ни в списке, ни по ID (404, а не 403 —
+// чтобы не выдавать сам факт существования чужого платежа)
+// Ключ администратора видит любой платеж по ID, а в списке —
+// все платежи с ?all=true
+//
+// Без ключей в конфиге проверка выключена и все видят все
+
+// authExempt — пути, доступные без ключа API
+// /health и /ready опрашивает оркестратор, /webhooks/gateway защищен подписью,
+// /openapi.json и оглавление / — открытая документация
+var authExempt = map[string]bool{
+	"/":                 true,
+	"/health":           true,
+	"/ready":            true,
+	"/openapi.json":     true,
+	"/webhooks/gateway": true,
+}
+
+// apiKeyID — постоянный идентификатор ключа: начало его хеша
+// Сам ключ — секрет, поэтому ни в платеже, ни в журнале его нет
+func apiKeyID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "key_" + hex.EncodeToString(sum[:6])
+}
+
+// newAPIKeys строит таблицу ключей: хеш ключа → ключ администратора
+// Храним хеши, а не сами ключи: поиск в map по хешу не зависит
+// от того, сколько первых символов ключа угадано
+func newAPIKeys(keys, adminKeys []string) map[[sha256.Size]byte]bool {
+	table := make(map[[sha256.Size]byte]bool)
+	for _, key := range keys {
+		table[sha256.Sum256([]byte(key))] = false
+	}
+	for _, key := range adminKeys {
+		table[sha256.Sum256([]byte(key))] = true
+	}
+	return table
+}
+
+// authEnabled сообщает, включена ли проверка ключей
+func (s *Server) authEnabled() bool {
+	return len(s.apiKeys) > 0
+}
+
+// caller возвращает ID ключа автора запроса и признак администратора
+// Ключ уже проверен requireAPIKey
+func (s *Server) caller(r *http.Request) (keyID string, admin bool) {
+	key := r.Header.Get("X-API-Key")
+	return apiKeyID(key), s.apiKeys[sha256.Sum256([]byte(key))]
+}
+
+// requireAPIKey — middleware, которое отклоняет запросы без
+// действующего ключа API: 401 с кодом unauthorized
+func (s *Server) requireAPIKey(next http.Handler) http.Handler {
+	if !s.authEnabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authExempt[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if _, ok := s.apiKeys[sha256.Sum256([]byte(r.Header.Get("X-API-Key")))]; !ok {
+			log.Printf("Rejected request without valid API key: %s %s, IP=%s", r.Method, r.URL.Path, s.clientIP(r))
+			writeError(w, r, http.StatusUnauthorized, CodeUnauthorized, "Missing or invalid API key")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ownsPayment сообщает, может ли автор запроса обращаться к платежу по ID
+func (s *Server) ownsPayment(r *http.Request, p Payment) bool {
+	if !s.authEnabled() {
+		return true
+	}
+	keyID, admin := s.caller(r)
+	return admin || p.CreatedByKey == keyID
+}
+
+// scopePayments оставляет в списке только платежи автора запроса
+// (фильтрует "на месте"). Администратор с ?all=true видит все
+func (s *Server) scopePayments(r *http.Request, payments []Payment) []Payment {
+	if !s.authEnabled() {
+		return payments
+	}
+	keyID, admin := s.caller(r)
+	if admin && r.URL.Query().Get("all") == "true" {
+		return payments
+	}
+	result := payments[:0]
+	for _, p := range payments {
+		if p.CreatedByKey == keyID {
+			result = append(result, p)
+		}
+	}
+	return result
+}
@@ -0,0 +1,94 @@
+package payments
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func newAPIKeyServer(t *testing.T) (*Server, Store) {
+	t.Helper()
+	store := NewMemoryStore()
+	return NewServer(store, nil, nil, Config{APIKeys: []string{"alice", "bob"}, AdminAPIKeys: []string{"admin"}}), store
+}
+
+func withKey(key string) map[string]string {
+	return map[string]string{"X-API-Key": key}
+}
+
+// countListed — сколько платежей видит ключ в GET /payments{query}
+func countListed(t *testing.T, s *Server, key, query string) int {
+	t.Helper()
+	rec := doJSON(t, s, http.MethodGet, "/payments"+query, "", withKey(key))
+	var list []Payment
+	decodeBody(t, rec, &list)
+	return len(list)
+}
+
+// TestAPIKeyOwnerAccess — автор видит свой платеж по ID и в списке
+func TestAPIKeyOwnerAccess(t *testing.T) {
+	s, store := newAPIKeyServer(t)
+	rec := doJSON(t, s, http.MethodPost, "/payments", `{"amount": 10, "currency": "RUB"}`, withKey("alice"))
+	var p Payment
+	decodeBody(t, rec, &p)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create = %d", rec.Code)
+	}
+
+	if rec := doJSON(t, s, http.MethodGet, "/payments/"+p.ID, "", withKey("alice")); rec.Code != http.StatusOK {
+		t.Fatalf("owner GET = %d", rec.Code)
+	}
+	if n := countListed(t, s, "alice", ""); n != 1 {
+		t.Fatalf("owner list = %d, want 1", n)
+	}
+
+	// В платеже хранится ID ключа, а не сам ключ
+	stored, _ := store.Get(context.Background(), p.ID)
+	if stored.CreatedByKey != apiKeyID("alice") || stored.CreatedByKey == "alice" {
+		t.Fatalf("CreatedByKey = %q", stored.CreatedByKey)
+	}
+}
+
+// TestAPIKeyNonOwnerAccess — чужой ключ получает 404 и пустой список,
+// администратор видит все
+func TestAPIKeyNonOwnerAccess(t *testing.T) {
+	s, _ := newAPIKeyServer(t)
+	var p Payment
+	decodeBody(t, doJSON(t, s, http.MethodPost, "/payments", `{"amount": 10, "currency": "RUB"}`, withKey("alice")), &p)
+
+	if rec := doJSON(t, s, http.MethodGet, "/payments/"+p.ID, "", withKey("bob")); rec.Code != http.StatusNotFound {
+		t.Fatalf("non-owner GET = %d, want 404", rec.Code)
+	}
+	if rec := doJSON(t, s, http.MethodPatch, "/payments/"+p.ID, `{"status":"canceled"}`, withKey("bob")); rec.Code != http.StatusNotFound {
+		t.Fatalf("non-owner PATCH = %d, want 404", rec.Code)
+	}
+	if n := countListed(t, s, "bob", ""); n != 0 {
+		t.Fatalf("non-owner list = %d, want 0", n)
+	}
+	// ?all=true обычному ключу ничего не дает
+	if n := countListed(t, s, "bob", "?all=true"); n != 0 {
+		t.Fatalf("non-admin ?all=true list = %d, want 0", n)
+	}
+
+	if rec := doJSON(t, s, http.MethodGet, "/payments/"+p.ID, "", withKey("admin")); rec.Code != http.StatusOK {
+		t.Fatalf("admin GET = %d", rec.Code)
+	}
+	if n := countListed(t, s, "admin", ""); n != 0 {
+		t.Fatalf("admin list without all = %d, want 0", n)
+	}
+	if n := countListed(t, s, "admin", "?all=true"); n != 1 {
+		t.Fatalf("admin ?all=true list = %d, want 1", n)
+	}
+}
+
+func TestAPIKeyRequired(t *testing.T) {
+	s, _ := newAPIKeyServer(t)
+	for _, key := range []string{"", "mallory"} {
+		if rec := doJSON(t, s, http.MethodGet, "/payments", "", withKey(key)); rec.Code != http.StatusUnauthorized {
+			t.Errorf("key %q: status = %d, want 401", key, rec.Code)
+		}
+	}
+	if rec := doJSON(t, s, http.MethodGet, "/health", "", nil); rec.Code != http.StatusOK {
+		t.Errorf("/health without key = %d", rec.Code)
+	}
+}
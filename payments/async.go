@@ -0,0 +1,81 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"log"
+)
+
+// ===== АСИНХРОННОЕ СПИСАНИЕ =====
+//
+// Обычно POST /payments ждет ответа шлюза и сразу возвращает
+// succeeded или failed. С Config.AsyncGateway платеж сохраняется
+// в статусе processing, клиент сразу получает 201, а шлюз вызывается
+// в фоне. Когда он ответит, платеж переходит в succeeded или failed
+// Клиент, опрашивающий платеж в это время (GET /payments/{id},
+// в том числе с ?wait=), видит processing
+//
+// Двухшаговые платежи ("capture": false) и отложенные (process_at)
+// проводятся как раньше: асинхронным бывает только обычное списание
+
+// Completion — результат асинхронного списания, который хранилище
+// записывает в платеж атомарно (см. apply)
+//
+// ClaimedVersion — версия платежа в processing: если его успели
+// изменить (например, администратор через ForceStatus), результат
+// шлюза не записывается. FailureReason = "" значит успех
+type Completion struct {
+	ClaimedVersion int
+	FailureReason  string
+}
+
+// apply переводит платеж из processing в succeeded или failed
+// Вызывается хранилищем под блокировкой (или в транзакции)
+func (c Completion) apply(p Payment) (Payment, error) {
+	if p.Status != StatusProcessing {
+		return p, ErrInvalidTransition
+	}
+	if p.Version != c.ClaimedVersion {
+		return p, ErrVersionMismatch
+	}
+	p.Status = StatusSucceeded
+	if c.FailureReason != "" {
+		p.Status = StatusFailed
+	}
+	p.FailureReason = c.FailureReason
+	p.Version++
+	return p, nil
+}
+
+// chargeAsync списывает сохраненный платеж в processing и записывает
+// результат. Запускается в отдельной горутине из createPayment
+//
+// ctx — контекст запроса без отмены: клиент уже получил ответ,
+// а списание должно дойти до конца (значения вроде сквозного ID
+// при этом сохраняются)
+func (s *Server) chargeAsync(ctx context.Context, actor string, p Payment) {
+	reason := ""
+	if err := s.gateway.Charge(ctx, p); err != nil {
+		reason = s.failureReason(err)
+		log.Printf("Gateway charge failed: ID=%s, Reason=%s, Error=%v", p.ID, reason, err)
+	}
+
+	// Шлюз вызывается без блокировки (ответа можно ждать долго),
+	// а запись результата — под ней, как любое изменение платежа
+	defer s.paymentLocks.lock(p.ID)()
+	done, err := s.store.CompletePayment(ctx, p.ID, Completion{ClaimedVersion: p.Version, FailureReason: reason})
+	if errors.Is(err, ErrInvalidTransition) || errors.Is(err, ErrVersionMismatch) {
+		// Платеж изменили, пока шел запрос к шлюзу: оставляем как есть,
+		// результат шлюза — в логе
+		log.Printf("Async charge result for %s discarded, payment is now %s (gateway reason %q)", p.ID, done.Status, reason)
+		return
+	}
+	if err != nil {
+		log.Printf("Error completing async charge %s (gateway reason %q): %v", p.ID, reason, err)
+		return
+	}
+
+	log.Printf("Async charge completed: ID=%s, Status=%s", done.ID, done.Status)
+	s.audit.record(actor, AuditUpdate, done.ID, StatusProcessing, done.Status)
+	s.publishStatus(ctx, done)
+}
@@ -0,0 +1,98 @@
+package payments
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestAsyncChargeSequence — pending не виден: платеж создается в
+// processing, пока шлюз работает, GET показывает processing, затем
+// платеж сам переходит в succeeded
+func TestAsyncChargeSequence(t *testing.T) {
+	release := make(chan struct{})
+	s := NewServer(NewMemoryStore(), nil, gatewayFunc(func(context.Context, Payment) error {
+		<-release
+		return nil
+	}), Config{AsyncGateway: true})
+
+	p := createPaymentT(t, s, `{"amount": 100, "currency": "RUB"}`)
+	if p.Status != StatusProcessing {
+		t.Fatalf("created status = %s, want processing", p.Status)
+	}
+	var polled Payment
+	decodeBody(t, doJSON(t, s, http.MethodGet, "/payments/"+p.ID, "", nil), &polled)
+	if polled.Status != StatusProcessing {
+		t.Fatalf("polled status = %s, want processing", polled.Status)
+	}
+	// processing отменить нельзя: деньги уже в пути
+	if rec := doJSON(t, s, http.MethodPatch, "/payments/"+p.ID, `{"status":"canceled"}`, nil); rec.Code == http.StatusOK {
+		t.Fatal("processing payment was canceled")
+	}
+
+	close(release)
+	var done Payment
+	waitFor(t, func() bool {
+		decodeBody(t, doJSON(t, s, http.MethodGet, "/payments/"+p.ID, "", nil), &done)
+		return done.Status != StatusProcessing
+	})
+	if done.Status != StatusSucceeded {
+		t.Fatalf("final status = %s", done.Status)
+	}
+}
+
+func TestAsyncChargeDeclined(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, declineGateway("insufficient_funds"), Config{AsyncGateway: true})
+	p := createPaymentT(t, s, `{"amount": 100, "currency": "RUB"}`)
+
+	var got Payment
+	waitFor(t, func() bool {
+		got = Payment{}
+		decodeBody(t, doJSON(t, s, http.MethodGet, "/payments/"+p.ID, "", nil), &got)
+		return got.Status != StatusProcessing
+	})
+	if got.Status != StatusFailed || got.FailureReason != FailureInsufficientFunds {
+		t.Fatalf("declined: status = %s, reason = %q", got.Status, got.FailureReason)
+	}
+}
+
+// TestAsyncChargeDiscardedAfterForce — если платеж в processing
+// исправили вручную, ответ шлюза его не перезаписывает
+func TestAsyncChargeDiscardedAfterForce(t *testing.T) {
+	store := NewMemoryStore()
+	release, finished := make(chan struct{}), make(chan struct{})
+	s := NewServer(store, nil, gatewayFunc(func(context.Context, Payment) error {
+		defer close(finished)
+		<-release
+		return nil
+	}), Config{AsyncGateway: true})
+
+	p := createPaymentT(t, s, `{"amount": 100, "currency": "RUB"}`)
+	if _, err := store.ForceStatus(context.Background(), p.ID, StatusFailed, 0); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+	<-finished
+	// Запись результата идет сразу после шлюза — даем ей время
+	time.Sleep(20 * time.Millisecond)
+	if got, _ := store.Get(context.Background(), p.ID); got.Status != StatusFailed {
+		t.Fatalf("status = %s, forced failed was overwritten", got.Status)
+	}
+}
+
+func TestCompletionApply(t *testing.T) {
+	p := Payment{Status: StatusProcessing, Version: 2}
+	if got, err := (Completion{ClaimedVersion: 2}).apply(p); err != nil || got.Status != StatusSucceeded || got.Version != 3 {
+		t.Fatalf("success: %+v, %v", got, err)
+	}
+	if got, err := (Completion{ClaimedVersion: 2, FailureReason: "expired_card"}).apply(p); err != nil || got.Status != StatusFailed || got.FailureReason != "expired_card" {
+		t.Fatalf("failure: %+v, %v", got, err)
+	}
+	if _, err := (Completion{ClaimedVersion: 1}).apply(p); err != ErrVersionMismatch {
+		t.Fatalf("stale version: %v", err)
+	}
+	if _, err := (Completion{ClaimedVersion: 2}).apply(Payment{Status: StatusPending, Version: 2}); err != ErrInvalidTransition {
+		t.Fatalf("not processing: %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package payments
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ===== ЖУРНАЛ АУДИТА =====
+
+// Действия, которые попадают в журнал аудита
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRefund  = "refund"
+	AuditCapture = "capture"
+	AuditCancel  = "cancel"
+	AuditRetry   = "retry"
+	AuditSplit   = "split"
+	AuditVoid    = "void"
+	AuditForce   = "force_status"
+	AuditBulk    = "bulk_status"
+)
+
+// AuditEntry — одна запись журнала аудита (одна строка JSON)
+//
+// Hash = SHA-256 от PrevHash и содержимого записи. Каждая запись
+// ссылается на предыдущую, поэтому удаление или правка строки
+// в середине журнала ломает цепочку и обнаруживается проверкой
+type AuditEntry struct {
+	Time         time.Time `json:"time"`
+	Actor        string    `json:"actor"`
+	Action       string    `json:"action"`
+	PaymentID    string    `json:"payment_id"`
+	StatusBefore string    `json:"status_before,omitempty"`
+	StatusAfter  string    `json:"status_after,omitempty"`
+	Reason       string    `json:"reason,omitempty"`
+	PrevHash     string    `json:"prev_hash"`
+	Hash         string    `json:"hash"`
+}
+
+// auditLog пишет записи аудита в отдельный поток (файл или stdout),
+// независимо от обычного лога запросов
+// nil-указатель — рабочее значение: аудит выключен
+type auditLog struct {
+	mu       sync.Mutex
+	w        io.Writer
+	redact   map[string]bool
+	lastHash string
+}
+
+// newAuditLog создает журнал; w == nil = аудит выключен
+// redact — поля записи, значения которых скрываются (см. redact.go)
+func newAuditLog(w io.Writer, redact map[string]bool) *auditLog {
+	if w == nil {
+		return nil
+	}
+	return &auditLog{w: w, redact: redact}
+}
+
+// record дописывает запись в журнал
+// Ошибка записи не должна ломать запрос клиента — только логируем ее
+func (a *auditLog) record(actor, action, paymentID, before, after string) {
+	a.recordReason(actor, action, paymentID, before, after, "")
+}
+
+// recordReason дописывает запись с причиной изменения
+// (например, ручное исправление статуса поддержкой)
+func (a *auditLog) recordReason(actor, action, paymentID, before, after, reason string) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// Поля скрываются ДО подсчета хеша: цепочка проверяется по тому,
+	// что реально записано в журнал
+	entry := AuditEntry{
+		Time:         time.Now().UTC(),
+		Actor:        redactString(a.redact, "actor", actor),
+		Action:       action,
+		PaymentID:    redactString(a.redact, "payment_id", paymentID),
+		StatusBefore: redactString(a.redact, "status_before", before),
+		StatusAfter:  redactString(a.redact, "status_after", after),
+		Reason:       redactString(a.redact, "reason", reason),
+		PrevHash:     a.lastHash,
+	}
+	// Хеш считаем от записи с пустым полем Hash
+	body, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("Error encoding audit entry: %v", err)
+		return
+	}
+	sum := sha256.Sum256(body)
+	entry.Hash = hex.EncodeToString(sum[:])
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("Error encoding audit entry: %v", err)
+		return
+	}
+	if _, err := a.w.Write(append(line, '\n')); err != nil {
+		log.Printf("Error writing audit entry: %v", err)
+		return
+	}
+	a.lastHash = entry.Hash
+}
+
+// auditActor определяет, кто выполняет запрос
+// Сам ключ в журнал не пишем (это секрет) — только начало его хеша
+func auditActor(r *http.Request) string {
+	key := r.Header.Get("X-API-Key")
+	if key == "" {
+		return "anonymous"
+	}
+	return apiKeyID(key)
+}
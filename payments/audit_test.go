@@ -0,0 +1,82 @@
+package payments
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// auditEntries разбирает журнал аудита построчно
+func auditEntries(t *testing.T, buf *bytes.Buffer) []AuditEntry {
+	t.Helper()
+	var entries []AuditEntry
+	sc := bufio.NewScanner(bytes.NewReader(buf.Bytes()))
+	for sc.Scan() {
+		var e AuditEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatalf("audit line %q: %v", sc.Text(), err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestAuditLogRecordsCreateAndUpdate(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewServer(NewMemoryStore(), nil, nil, Config{AuditLog: &buf})
+	p := createPaymentT(t, s, `{"amount": 100, "currency": "RUB"}`)
+	doJSON(t, s, http.MethodPatch, "/payments/"+p.ID, `{"status":"canceled"}`, nil)
+
+	entries := auditEntries(t, &buf)
+	if len(entries) != 2 {
+		t.Fatalf("entries = %d, want 2", len(entries))
+	}
+	create, update := entries[0], entries[1]
+	if create.Action != AuditCreate || create.PaymentID != p.ID || create.Actor != "anonymous" || create.StatusAfter != StatusPending {
+		t.Fatalf("create entry = %+v", create)
+	}
+	if update.Action != AuditUpdate || update.StatusBefore != StatusPending || update.StatusAfter != StatusCanceled {
+		t.Fatalf("update entry = %+v", update)
+	}
+}
+
+// Каждая запись ссылается на хеш предыдущей, а хеш считается
+// от записи с пустым полем Hash
+func TestAuditLogHashChain(t *testing.T) {
+	var buf bytes.Buffer
+	a := newAuditLog(&buf, nil)
+	a.record("tester", AuditCreate, "pay_1", "", StatusPending)
+	a.record("tester", AuditUpdate, "pay_1", StatusPending, StatusSucceeded)
+
+	entries := auditEntries(t, &buf)
+	if entries[0].PrevHash != "" || entries[1].PrevHash != entries[0].Hash {
+		t.Fatalf("chain broken: %q -> %q", entries[0].Hash, entries[1].PrevHash)
+	}
+	for _, e := range entries {
+		hash := e.Hash
+		e.Hash = ""
+		body, _ := json.Marshal(e)
+		sum := sha256.Sum256(body)
+		if hex.EncodeToString(sum[:]) != hash {
+			t.Fatalf("hash mismatch for %+v", e)
+		}
+	}
+}
+
+func TestAuditActorHidesKey(t *testing.T) {
+	r, _ := http.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-API-Key", "super-secret")
+	if actor := auditActor(r); actor == "super-secret" || actor == "" {
+		t.Fatalf("actor = %q", actor)
+	}
+}
+
+// nil-журнал — рабочее значение (аудит выключен)
+func TestAuditLogNilIsNoop(t *testing.T) {
+	var a *auditLog
+	a.record("tester", AuditCreate, "pay_1", "", StatusPending)
+}
@@ -0,0 +1,130 @@
+package payments
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+)
+
+// ===== ОТЛАДОЧНЫЙ ЖУРНАЛ ТЕЛ ЗАПРОСОВ =====
+
+// defaultBodyLogLimit — сколько байт тела запроса и ответа журналировать
+const defaultBodyLogLimit = 4096
+
+// logBodies — middleware, записывающее в лог тела запроса и ответа
+// (включается Config.DebugBodies для разбора проблем интеграции)
+//
+// Тело запроса НЕ читается заранее: io.TeeReader копирует в буфер то,
+// что прочитал обработчик, поэтому обработчик получает тело нетронутым.
+// Ответ копируется так же — через обертку над ResponseWriter
+//
+// Буферы ограничены limit байтами. Значения полей из redact
+// (на любой глубине JSON, см. redact.go) заменяются на "[REDACTED]"; тело, которое
+// нельзя разобрать как JSON (обрезано лимитом, CSV), в лог не пишется
+// вовсе — иначе скрыть в нем чувствительные поля было бы нечем
+//
+// enabled = false возвращает next как есть: в рабочем режиме
+// накладных расходов нет
+func logBodies(enabled bool, limit int, fields map[string]bool, next http.Handler) http.Handler {
+	if !enabled {
+		return next
+	}
+	if limit <= 0 {
+		limit = defaultBodyLogLimit
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqBuf := &cappedBuffer{limit: limit}
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = readCloser{io.TeeReader(r.Body, reqBuf), r.Body}
+		}
+		rw := &bodyRecorder{ResponseWriter: w, body: cappedBuffer{limit: limit}}
+
+		next.ServeHTTP(rw, r)
+
+		status := rw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		log.Printf("Debug body: %s %s request=%s response(%d)=%s",
+			r.Method, r.URL.Path, redactBody(reqBuf, fields), status, redactBody(&rw.body, fields))
+	})
+}
+
+// redactBody возвращает тело для лога со скрытыми полями
+func redactBody(b *cappedBuffer, fields map[string]bool) string {
+	if b.total == 0 {
+		return "<empty>"
+	}
+	if b.truncated() {
+		return "<omitted: exceeds log limit>"
+	}
+	// UseNumber сохраняет числа как есть ("100.50", а не 100.5)
+	dec := json.NewDecoder(bytes.NewReader(b.buf.Bytes()))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return "<omitted: not JSON>"
+	}
+	out, err := json.Marshal(redactValue(v, fields))
+	if err != nil {
+		return "<omitted: not JSON>"
+	}
+	return string(out)
+}
+
+// cappedBuffer — буфер, хранящий не больше limit байт
+// Запись никогда не возвращает ошибку: лишние байты только считаются
+type cappedBuffer struct {
+	buf   bytes.Buffer
+	limit int
+	total int
+}
+
+// Write реализует io.Writer
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	b.total += len(p)
+	if room := b.limit - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(room, len(p))])
+	}
+	return len(p), nil
+}
+
+// truncated сообщает, что в буфер попало не все
+func (b *cappedBuffer) truncated() bool {
+	return b.total > b.buf.Len()
+}
+
+// readCloser объединяет Reader (TeeReader) и Closer исходного тела
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// bodyRecorder — statusRecorder, который еще и копирует тело ответа
+type bodyRecorder struct {
+	http.ResponseWriter
+	status int
+	body   cappedBuffer
+}
+
+// WriteHeader реализует http.ResponseWriter
+func (rw *bodyRecorder) WriteHeader(status int) {
+	if rw.status == 0 {
+		rw.status = status
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+// Write реализует http.ResponseWriter
+func (rw *bodyRecorder) Write(b []byte) (int, error) {
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
+// Unwrap возвращает исходный ResponseWriter (см. statusRecorder.Unwrap)
+func (rw *bodyRecorder) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,76 @@
+package payments
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// captureLog перенаправляет стандартный log в буфер до конца теста
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := log.Writer()
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(prev) })
+	return &buf
+}
+
+// echoHandler возвращает тело запроса как есть
+var echoHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	w.WriteHeader(http.StatusCreated)
+	w.Write(body)
+})
+
+// TestLogBodiesRedacts — обработчик получает тело нетронутым,
+// а в лог поля из списка попадают скрытыми на любой глубине
+func TestLogBodiesRedacts(t *testing.T) {
+	logs := captureLog(t)
+	h := logBodies(true, 0, map[string]bool{"card_number": true}, echoHandler)
+
+	body := `{"amount":"100.50","card":{"card_number":"4111111111111111"}}`
+	rec := doJSON(t, h, http.MethodPost, "/payments", body, nil)
+	if rec.Body.String() != body {
+		t.Fatalf("handler saw %q, want untouched body", rec.Body.String())
+	}
+
+	out := logs.String()
+	if strings.Contains(out, "4111111111111111") {
+		t.Fatalf("card number leaked into log: %s", out)
+	}
+	if !strings.Contains(out, `"card_number":"[REDACTED]"`) || !strings.Contains(out, `"amount":"100.50"`) {
+		t.Fatalf("unexpected log: %s", out)
+	}
+	if !strings.Contains(out, "response(201)") {
+		t.Fatalf("status missing from log: %s", out)
+	}
+}
+
+// TestLogBodiesSizeCap — тело больше лимита в лог не попадает,
+// но обработчик получает его целиком
+func TestLogBodiesSizeCap(t *testing.T) {
+	logs := captureLog(t)
+	h := logBodies(true, 16, nil, echoHandler)
+
+	body := `{"description":"` + strings.Repeat("x", 64) + `"}`
+	rec := doJSON(t, h, http.MethodPost, "/payments", body, nil)
+	if rec.Body.String() != body {
+		t.Fatal("handler body truncated by the log limit")
+	}
+	if strings.Contains(logs.String(), "xxxx") || !strings.Contains(logs.String(), "<omitted: exceeds log limit>") {
+		t.Fatalf("unexpected log: %s", logs.String())
+	}
+}
+
+func TestLogBodiesDisabled(t *testing.T) {
+	logs := captureLog(t)
+	h := logBodies(false, 0, nil, echoHandler)
+	doJSON(t, h, http.MethodPost, "/payments", `{"amount":1}`, nil)
+	if logs.Len() != 0 {
+		t.Fatalf("disabled middleware logged: %s", logs.String())
+	}
+}
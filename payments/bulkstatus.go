@@ -0,0 +1,148 @@
+package payments
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// ===== МАССОВАЯ СМЕНА СТАТУСА =====
+//
+// После сбоя шлюза эксплуатации бывает нужно перевести сразу много
+// платежей (например, зависшие pending — в failed). В отличие от
+// POST /admin/payments/{id}/status правила переходов здесь соблюдаются:
+// платеж, которому переход недоступен, пропускается, а не ломается
+
+// maxBulkStatusIDs — сколько платежей можно изменить за один запрос
+const maxBulkStatusIDs = 500
+
+// Результаты для отдельного платежа (bulkStatusResult.Result)
+const (
+	BulkUpdated           = "updated"
+	BulkInvalidTransition = "skipped_invalid_transition"
+	BulkNotFound          = "not_found"
+	BulkError             = "error"
+)
+
+// bulkStatusRequest — тело POST /payments/bulk-status
+// Reason пишется в журнал аудита, а при Status = failed становится
+// и причиной отказа платежа (FailureReason)
+type bulkStatusRequest struct {
+	IDs    []string `json:"ids"`
+	Status string   `json:"status"`
+	Reason string   `json:"reason"`
+}
+
+// bulkStatusResult — итог по одному платежу
+// Status — статус платежа после операции (пусто, если платежа нет)
+type bulkStatusResult struct {
+	ID     string `json:"id"`
+	Result string `json:"result"`
+	Status string `json:"status,omitempty"`
+}
+
+// bulkStatusResponse — ответ POST /payments/bulk-status:
+// результаты в порядке ids запроса и счетчики по ним
+type bulkStatusResponse struct {
+	Results  []bulkStatusResult `json:"results"`
+	Updated  int                `json:"updated"`
+	Skipped  int                `json:"skipped"`
+	NotFound int                `json:"not_found"`
+	Failed   int                `json:"failed"`
+}
+
+// validate проверяет запрос и собирает ВСЕ ошибки
+// Поле ошибки ID — его позиция: "ids[3]"
+func (req bulkStatusRequest) validate() []FieldError {
+	var errs []FieldError
+	switch {
+	case len(req.IDs) == 0:
+		errs = append(errs, FieldError{Field: "ids", Code: FieldIDsRequired, Message: "At least one payment ID is required"})
+	case len(req.IDs) > maxBulkStatusIDs:
+		errs = append(errs, FieldError{Field: "ids", Code: FieldIDsTooMany,
+			Message: fmt.Sprintf("At most %d payment IDs per request", maxBulkStatusIDs)})
+	default:
+		seen := make(map[string]bool, len(req.IDs))
+		for i, id := range req.IDs {
+			field := fmt.Sprintf("ids[%d]", i)
+			switch {
+			case !isValidPaymentID(id):
+				errs = append(errs, FieldError{Field: field, Code: FieldIDInvalid,
+					Message: "Invalid payment ID: must start with " + paymentIDPrefix})
+			case seen[id]:
+				errs = append(errs, FieldError{Field: field, Code: FieldIDDuplicate,
+					Message: "Duplicate payment ID " + id})
+			}
+			seen[id] = true
+		}
+	}
+	return append(errs, validateStatusReason(req.Status, req.Reason)...)
+}
+
+// handleBulkStatus меняет статус нескольких платежей
+// POST /payments/bulk-status {"ids":["pay_…"],"status":"failed","reason":"…"}
+//
+// Только с ключом администратора. Каждый платеж меняется отдельно
+// (без общей транзакции): один неподходящий платеж не мешает остальным,
+// а итог по каждому — в results. Каждое изменение пишется в журнал
+// аудита с причиной
+//
+// Коды ответа:
+//   - 200 OK = запрос обработан (итоги по платежам — в теле)
+//   - 400 Bad Request = некорректный запрос (все ошибки в fields)
+//   - 403 Forbidden = не ключ администратора
+func (s *Server) handleBulkStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+	if !s.requireAdmin(w, r) {
+		return
+	}
+
+	var req bulkStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+	if errs := req.validate(); len(errs) > 0 {
+		writeFieldErrors(w, r, errs)
+		return
+	}
+
+	actor := auditActor(r)
+	resp := bulkStatusResponse{Results: make([]bulkStatusResult, 0, len(req.IDs))}
+	for _, id := range req.IDs {
+		result := bulkStatusResult{ID: id}
+		// Блокируем каждый платеж отдельно: весь список целиком
+		// надолго задержал бы обычные запросы к этим платежам
+		unlock := s.paymentLocks.lock(id)
+		before, _ := s.store.Get(r.Context(), id)
+		payment, err := s.updateStatus(r.Context(), id, req.Status, 0, req.Reason)
+		switch {
+		case errors.Is(err, ErrPaymentNotFound):
+			result.Result = BulkNotFound
+			resp.NotFound++
+		case errors.Is(err, ErrInvalidTransition):
+			result.Result, result.Status = BulkInvalidTransition, payment.Status
+			resp.Skipped++
+		case err != nil:
+			log.Printf("Error updating payment %s in bulk: %v", id, err)
+			result.Result = BulkError
+			resp.Failed++
+		default:
+			result.Result, result.Status = BulkUpdated, payment.Status
+			resp.Updated++
+			s.audit.recordReason(actor, AuditBulk, payment.ID, before.Status, payment.Status, req.Reason)
+			s.publishStatus(r.Context(), payment)
+		}
+		unlock()
+		resp.Results = append(resp.Results, result)
+	}
+
+	log.Printf("Bulk status update to %s: updated=%d, skipped=%d, not_found=%d, failed=%d, Actor=%s, Reason=%s",
+		req.Status, resp.Updated, resp.Skipped, resp.NotFound, resp.Failed, actor, s.logValue("reason", req.Reason))
+	writeJSON(w, r, http.StatusOK, resp)
+}
@@ -0,0 +1,110 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// newBulkServer — ключ администратора "admin", клиента "alice"
+// и платежи: pay_p1, pay_p2 (pending), pay_done (succeeded)
+func newBulkServer(t *testing.T, audit *bytes.Buffer) (*Server, Store) {
+	t.Helper()
+	store := NewMemoryStore()
+	for _, p := range []Payment{
+		{ID: "pay_p1", Status: StatusPending},
+		{ID: "pay_p2", Status: StatusPending},
+		{ID: "pay_done", Status: StatusSucceeded},
+	} {
+		p.AmountMinor, p.Currency, p.Version = 100, "RUB", 1
+		savePaymentT(t, store, p)
+	}
+	return NewServer(store, nil, nil, Config{APIKeys: []string{"alice"}, AdminAPIKeys: []string{"admin"}, AuditLog: audit}), store
+}
+
+// TestBulkStatusMixed — допустимые переходы применяются, недопустимые
+// и несуществующие платежи пропускаются, результат — по каждому ID
+func TestBulkStatusMixed(t *testing.T) {
+	var audit bytes.Buffer
+	s, store := newBulkServer(t, &audit)
+
+	rec := doJSON(t, s, http.MethodPost, "/payments/bulk-status",
+		`{"ids":["pay_p1","pay_done","pay_missing","pay_p2"],"status":"failed","reason":"gateway outage"}`, withKey("admin"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("bulk = %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp bulkStatusResponse
+	decodeBody(t, rec, &resp)
+
+	want := []bulkStatusResult{
+		{ID: "pay_p1", Result: BulkUpdated, Status: StatusFailed},
+		{ID: "pay_done", Result: BulkInvalidTransition, Status: StatusSucceeded},
+		{ID: "pay_missing", Result: BulkNotFound},
+		{ID: "pay_p2", Result: BulkUpdated, Status: StatusFailed},
+	}
+	if fmt.Sprint(resp.Results) != fmt.Sprint(want) {
+		t.Fatalf("results = %+v, want %+v", resp.Results, want)
+	}
+	if resp.Updated != 2 || resp.Skipped != 1 || resp.NotFound != 1 || resp.Failed != 0 {
+		t.Fatalf("counters = %+v", resp)
+	}
+
+	if p, _ := store.Get(context.Background(), "pay_done"); p.Status != StatusSucceeded {
+		t.Fatalf("pay_done changed to %s", p.Status)
+	}
+	// В аудите — только примененные переходы, с причиной
+	entries := auditEntries(t, &audit)
+	if len(entries) != 2 {
+		t.Fatalf("audit entries = %d, want 2", len(entries))
+	}
+	for _, e := range entries {
+		if e.Reason != "gateway outage" || e.StatusAfter != StatusFailed || e.Actor != apiKeyID("admin") {
+			t.Errorf("audit entry = %+v", e)
+		}
+	}
+}
+
+func TestBulkStatusRequiresAdmin(t *testing.T) {
+	s, store := newBulkServer(t, nil)
+	rec := doJSON(t, s, http.MethodPost, "/payments/bulk-status",
+		`{"ids":["pay_p1"],"status":"failed","reason":"x"}`, withKey("alice"))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("client key = %d, want 403", rec.Code)
+	}
+	if p, _ := store.Get(context.Background(), "pay_p1"); p.Status != StatusPending {
+		t.Fatalf("pay_p1 changed to %s", p.Status)
+	}
+}
+
+// TestBulkStatusValidation — размер пачки ограничен, ID проверяются
+// до любых изменений
+func TestBulkStatusValidation(t *testing.T) {
+	s, _ := newBulkServer(t, nil)
+
+	ids := make([]string, maxBulkStatusIDs+1)
+	for i := range ids {
+		ids[i] = fmt.Sprintf(`"pay_%d"`, i)
+	}
+	cases := map[string]string{
+		`{"ids":[],"status":"failed","reason":"x"}`:                               FieldIDsRequired,
+		`{"ids":[` + strings.Join(ids, ",") + `],"status":"failed","reason":"x"}`: FieldIDsTooMany,
+		`{"ids":["pay_p1","pay_p1"],"status":"failed","reason":"x"}`:              FieldIDDuplicate,
+		`{"ids":["nope"],"status":"failed","reason":"x"}`:                         FieldIDInvalid,
+		`{"ids":["pay_p1"],"status":"failed"}`:                                    FieldReasonRequired,
+	}
+	for body, code := range cases {
+		rec := doJSON(t, s, http.MethodPost, "/payments/bulk-status", body, withKey("admin"))
+		var resp ErrorResponse
+		decodeBody(t, rec, &resp)
+		found := false
+		for _, f := range resp.Fields {
+			found = found || f.Code == code
+		}
+		if rec.Code != http.StatusBadRequest || !found {
+			t.Errorf("%.60s: %d %+v, want %s", body, rec.Code, resp, code)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package payments
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// ===== АВТОРИЗАЦИЯ И СПИСАНИЕ =====
+//
+// ДВУХШАГОВЫЙ ПЛАТЕЖ (auth/capture), как у карточных платежей:
+// 1. POST /payments {"amount": 100, "capture": false} — шлюз проверяет
+//    карту и блокирует сумму, платеж в статусе authorized
+// 2. POST /payments/{id}/capture — списание всей суммы или ее части
+//    (например, в заказе не оказалось одного товара), статус succeeded
+//
+// Передумали — POST /payments/{id}/cancel снимает блокировку (canceled)
+// POST /payments/{id}/void — то же со стороны бухгалтерии: аннулирование
+// авторизации (voided). Это не возврат: деньги не списывались,
+// поэтому и возвращать нечего
+
+// Capture — результат списания, который хранилище записывает в платеж
+// Суммы считает обработчик (комиссия и курс зависят от настроек сервера),
+// а проверку статуса и лимита хранилище делает атомарно (см. apply)
+//
+// Now — время списания: с ним сравнивается срок авторизации
+// (Payment.CaptureExpiresAt)
+type Capture struct {
+	AmountMinor           int64
+	FeeMinor              int64
+	SettlementAmountMinor int64
+	Now                   time.Time
+}
+
+// apply проверяет, что платеж можно списать, и возвращает его
+// в статусе succeeded со списанной суммой
+// Вызывается хранилищем под блокировкой (или в транзакции)
+func (c Capture) apply(p Payment) (Payment, error) {
+	if p.Status != StatusAuthorized {
+		return p, ErrNotCapturable
+	}
+	if p.CaptureExpiresAt != nil && !c.Now.Before(*p.CaptureExpiresAt) {
+		return p, ErrAuthorizationExpired
+	}
+	if c.AmountMinor > p.AmountAuthorizedMinor {
+		return p, ErrCaptureExceedsAuthorized
+	}
+	p.Status = StatusSucceeded
+	p.AmountMinor = c.AmountMinor
+	p.Amount = minorToAmount(c.AmountMinor, p.Currency)
+	p.FeeMinor = c.FeeMinor
+	p.NetAmountMinor = c.AmountMinor - c.FeeMinor
+	p.AmountRefundableMinor = c.AmountMinor
+	p.SettlementAmountMinor = c.SettlementAmountMinor
+	p.Version++
+	return p, nil
+}
+
+// captureRequest — тело запроса на списание
+// Amount — число или строка, как у платежа; без суммы (или без тела)
+// списывается вся заблокированная сумма
+type captureRequest struct {
+	Amount json.RawMessage `json:"amount"`
+}
+
+// handleCapturePayment списывает авторизованный платеж
+// POST /payments/{id}/capture {"amount": "80.00"}
+//
+// Коды ответа:
+//   - 200 OK = списано, в ответе платеж
+//   - 404 Not Found = платежа нет
+//   - 422 Unprocessable Entity = платеж не authorized, срок авторизации
+//     истек, сумма больше заблокированной или меньше минимума валюты
+//     (Config.MinAmounts)
+func (s *Server) handleCapturePayment(w http.ResponseWriter, r *http.Request) {
+	if !isValidPaymentID(r.PathValue("id")) {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidID, "Invalid payment ID: must start with "+paymentIDPrefix)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	// Тело необязательно: пустой запрос = списать все
+	var req captureRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeDecodeError(w, r, err)
+		return
+	}
+	amount, literal, number, err := decodeAmount(req.Amount)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidJSON, "Invalid JSON")
+		return
+	}
+
+	id := r.PathValue("id")
+	payment, err := s.store.Get(r.Context(), id)
+	if errors.Is(err, ErrPaymentNotFound) || (err == nil && payment.Deleted) {
+		writeError(w, r, http.StatusNotFound, CodePaymentNotFound, "Payment not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Error loading payment: %v", err)
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+		return
+	}
+
+	mode := s.rounding.modeFor(payment.Currency)
+	capture := Capture{AmountMinor: payment.AmountAuthorizedMinor, Now: time.Now().UTC()}
+	// Частичное списание — если сумма передана; явный 0 не означает
+	// "списать все", его отклонит проверка "сумма > 0"
+	if number != "" || literal != "" {
+		capture.AmountMinor, err = requestAmountMinor(amount, s.amountLiteral(literal), number, payment.Currency, mode, s.maxFloatMinor)
+		if errors.Is(err, ErrAmountTooLarge) {
+			writeError(w, r, http.StatusUnprocessableEntity, CodeCaptureExceedsAuthorized,
+				fmt.Sprintf("Capture exceeds authorized amount of %d minor units", payment.AmountAuthorizedMinor))
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, CodeInvalidAmount, err.Error())
+			return
+		}
+		if capture.AmountMinor <= 0 {
+			writeError(w, r, http.StatusBadRequest, CodeInvalidAmount, "Amount must be positive")
+			return
+		}
+	}
+
+	// Частичное списание не может быть меньше минимума валюты
+	// (того же, что при создании платежа): иначе комиссия шлюза
+	// съест микросписание целиком
+	if minimum, below := s.belowMinimum(capture.AmountMinor, payment.Currency); below {
+		writeError(w, r, http.StatusUnprocessableEntity, CodeAmountTooSmall, minimumMessage(minimum, payment.Currency))
+		return
+	}
+
+	// Комиссия и сумма расчета — от списанной суммы, а не заблокированной
+	// Курс берем зафиксированный при авторизации
+	capture.FeeMinor = s.fees[payment.Currency].calculate(capture.AmountMinor, mode)
+	if payment.SettlementCurrency != "" {
+		capture.SettlementAmountMinor = toMinorUnits(
+			minorToAmount(capture.AmountMinor, payment.Currency)*payment.FXRate,
+			payment.SettlementCurrency, s.rounding.modeFor(payment.SettlementCurrency))
+	}
+
+	before := payment.Status
+	payment, err = s.store.CapturePayment(r.Context(), id, capture)
+	switch {
+	case errors.Is(err, ErrPaymentNotFound):
+		writeError(w, r, http.StatusNotFound, CodePaymentNotFound, "Payment not found")
+		return
+	case errors.Is(err, ErrNotCapturable):
+		writeError(w, r, http.StatusUnprocessableEntity, CodeNotCapturable,
+			fmt.Sprintf("Only authorized payments can be captured, payment is %s", payment.Status))
+		return
+	case errors.Is(err, ErrCaptureExceedsAuthorized):
+		writeError(w, r, http.StatusUnprocessableEntity, CodeCaptureExceedsAuthorized,
+			fmt.Sprintf("Capture exceeds authorized amount of %d minor units", payment.AmountAuthorizedMinor))
+		return
+	case errors.Is(err, ErrAuthorizationExpired):
+		writeError(w, r, http.StatusUnprocessableEntity, CodeAuthorizationExpired,
+			fmt.Sprintf("Authorization expired at %s", payment.CaptureExpiresAt.Format(time.RFC3339)))
+		return
+	case err != nil:
+		log.Printf("Error capturing payment: %v", err)
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+		return
+	}
+
+	log.Printf("Payment captured: ID=%s, AmountMinor=%d, Authorized=%d",
+		payment.ID, payment.AmountMinor, payment.AmountAuthorizedMinor)
+	s.audit.record(auditActor(r), AuditCapture, payment.ID, before, payment.Status)
+	s.publishStatus(r.Context(), payment)
+
+	w.Header().Set("ETag", paymentETag(payment))
+	writeJSON(w, r, http.StatusOK, payment)
+}
+
+// handleCancelPayment отменяет платеж до списания денег
+// POST /payments/{id}/cancel
+//
+// Отменить можно pending (еще не обработан) и authorized
+// (блокировка суммы снимается). Списанный платеж не отменяется —
+// для него есть возврат (POST /payments/{id}/refunds)
+//
+// Коды ответа:
+// - 200 OK = отменен, в ответе платеж
+// - 404 Not Found = платежа нет
+// - 409 Conflict = платеж уже в конечном статусе
+// - 412 Precondition Failed = If-Match не совпал с версией
+func (s *Server) handleCancelPayment(w http.ResponseWriter, r *http.Request) {
+	if !isValidPaymentID(r.PathValue("id")) {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidID, "Invalid payment ID: must start with "+paymentIDPrefix)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+	expectedVersion, ok := parseIfMatch(r)
+	if !ok {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidIfMatch, "Invalid If-Match header")
+		return
+	}
+
+	id := r.PathValue("id")
+	// Статус "до" для журнала аудита (как в handleUpdatePaymentStatus)
+	before, _ := s.store.Get(r.Context(), id)
+
+	payment, err := s.store.UpdateStatus(r.Context(), id, StatusCanceled, expectedVersion)
+	switch {
+	case errors.Is(err, ErrPaymentNotFound):
+		writeError(w, r, http.StatusNotFound, CodePaymentNotFound, "Payment not found")
+		return
+	case errors.Is(err, ErrVersionMismatch):
+		w.Header().Set("ETag", paymentETag(payment))
+		writeError(w, r, http.StatusPreconditionFailed, CodeVersionMismatch, "Payment was modified by another request")
+		return
+	case errors.Is(err, ErrInvalidTransition):
+		writeError(w, r, http.StatusConflict, CodeInvalidTransition,
+			fmt.Sprintf("Cannot cancel payment in status %s", payment.Status))
+		return
+	case err != nil:
+		log.Printf("Error canceling payment: %v", err)
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+		return
+	}
+
+	log.Printf("Payment canceled: ID=%s, Version=%d", payment.ID, payment.Version)
+	s.audit.record(auditActor(r), AuditCancel, payment.ID, before.Status, payment.Status)
+	s.publishStatus(r.Context(), payment)
+
+	w.Header().Set("ETag", paymentETag(payment))
+	writeJSON(w, r, http.StatusOK, payment)
+}
+
+// handleVoidPayment аннулирует авторизацию: блокировка суммы снимается
+// без списания
+// POST /payments/{id}/void
+//
+// В отличие от отмены (cancel) работает только для authorized: void —
+// операция над блокировкой, а не над платежом вообще. Для списанного
+// платежа нужен возврат, и ответ прямо говорит об этом
+//
+// Коды ответа:
+//   - 200 OK = авторизация аннулирована, в ответе платеж
+//   - 404 Not Found = платежа нет
+//   - 409 Conflict = платеж уже списан (payment_already_captured)
+//     или не авторизован (invalid_transition)
+//   - 412 Precondition Failed = If-Match не совпал с версией
+func (s *Server) handleVoidPayment(w http.ResponseWriter, r *http.Request) {
+	if !isValidPaymentID(r.PathValue("id")) {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidID, "Invalid payment ID: must start with "+paymentIDPrefix)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+	expectedVersion, ok := parseIfMatch(r)
+	if !ok {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidIfMatch, "Invalid If-Match header")
+		return
+	}
+
+	payment, err := s.store.UpdateStatus(r.Context(), r.PathValue("id"), StatusVoided, expectedVersion)
+	switch {
+	case errors.Is(err, ErrPaymentNotFound):
+		writeError(w, r, http.StatusNotFound, CodePaymentNotFound, "Payment not found")
+		return
+	case errors.Is(err, ErrVersionMismatch):
+		w.Header().Set("ETag", paymentETag(payment))
+		writeError(w, r, http.StatusPreconditionFailed, CodeVersionMismatch, "Payment was modified by another request")
+		return
+	case errors.Is(err, ErrInvalidTransition) && (payment.Status == StatusSucceeded || payment.Status == StatusRefunded):
+		writeError(w, r, http.StatusConflict, CodeAlreadyCaptured,
+			"Payment is already captured: use a refund (POST /payments/{id}/refunds) instead")
+		return
+	case errors.Is(err, ErrInvalidTransition):
+		writeError(w, r, http.StatusConflict, CodeInvalidTransition,
+			fmt.Sprintf("Only authorized payments can be voided, payment is %s", payment.Status))
+		return
+	case err != nil:
+		log.Printf("Error voiding payment: %v", err)
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+		return
+	}
+
+	log.Printf("Payment voided: ID=%s, Version=%d", payment.ID, payment.Version)
+	s.audit.record(auditActor(r), AuditVoid, payment.ID, StatusAuthorized, payment.Status)
+	s.publishStatus(r.Context(), payment)
+
+	w.Header().Set("ETag", paymentETag(payment))
+	writeJSON(w, r, http.StatusOK, payment)
+}
@@ -0,0 +1,161 @@
+package payments
+
+import (
+	"net/http"
+	"testing"
+)
+
+// authorizeT создает платеж с "capture": false (статус authorized)
+func authorizeT(t *testing.T, s *Server) Payment {
+	t.Helper()
+	p := createPaymentT(t, s, `{"amount": 100, "currency": "RUB", "capture": false}`)
+	if p.Status != StatusAuthorized {
+		t.Fatalf("status = %s, want authorized", p.Status)
+	}
+	return p
+}
+
+func TestAuthorizeThenCapture(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+
+	p := authorizeT(t, s)
+	rec := doJSON(t, s, http.MethodPost, "/payments/"+p.ID+"/capture", "", nil)
+	var full struct {
+		Status      string `json:"status"`
+		AmountMinor int64  `json:"amount_minor"`
+	}
+	decodeBody(t, rec, &full)
+	if rec.Code != http.StatusOK || full.Status != StatusSucceeded || full.AmountMinor != 10000 {
+		t.Fatalf("full capture: %d %+v", rec.Code, full)
+	}
+
+	// Частичное списание: заблокировано 100, списываем 80
+	p = authorizeT(t, s)
+	rec = doJSON(t, s, http.MethodPost, "/payments/"+p.ID+"/capture", `{"amount": "80.00"}`, nil)
+	var partial struct {
+		AmountMinor int64 `json:"amount_minor"`
+	}
+	decodeBody(t, rec, &partial)
+	if rec.Code != http.StatusOK || partial.AmountMinor != 8000 {
+		t.Fatalf("partial capture: %d %+v", rec.Code, partial)
+	}
+
+	// Повторно списать уже списанный платеж нельзя
+	rec = doJSON(t, s, http.MethodPost, "/payments/"+p.ID+"/capture", "", nil)
+	var resp ErrorResponse
+	decodeBody(t, rec, &resp)
+	if rec.Code != http.StatusUnprocessableEntity || resp.Code != CodeNotCapturable {
+		t.Fatalf("second capture: %d %+v", rec.Code, resp)
+	}
+}
+
+func TestCaptureExceedsAuthorized(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	p := authorizeT(t, s)
+	rec := doJSON(t, s, http.MethodPost, "/payments/"+p.ID+"/capture", `{"amount": "100.01"}`, nil)
+	var resp ErrorResponse
+	decodeBody(t, rec, &resp)
+	if rec.Code != http.StatusUnprocessableEntity || resp.Code != CodeCaptureExceedsAuthorized {
+		t.Fatalf("over-capture: %d %+v", rec.Code, resp)
+	}
+}
+
+// Явный 0 — ошибка суммы, а не списание всей заблокированной суммы
+func TestCaptureZeroAmount(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	p := authorizeT(t, s)
+	for _, body := range []string{`{"amount": 0}`, `{"amount": "0"}`} {
+		rec := doJSON(t, s, http.MethodPost, "/payments/"+p.ID+"/capture", body, nil)
+		var resp ErrorResponse
+		decodeBody(t, rec, &resp)
+		if rec.Code != http.StatusBadRequest || resp.Code != CodeInvalidAmount {
+			t.Errorf("%s = %d %s, want 400 %s", body, rec.Code, resp.Code, CodeInvalidAmount)
+		}
+	}
+
+	var got Payment
+	decodeBody(t, doJSON(t, s, http.MethodGet, "/payments/"+p.ID, "", nil), &got)
+	if got.Status != StatusAuthorized {
+		t.Fatalf("status after zero capture = %s, want authorized", got.Status)
+	}
+}
+
+func TestAuthorizeThenVoid(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	for path, want := range map[string]string{"/void": StatusVoided, "/cancel": StatusCanceled} {
+		p := authorizeT(t, s)
+		rec := doJSON(t, s, http.MethodPost, "/payments/"+p.ID+path, "", nil)
+		var got Payment
+		decodeBody(t, rec, &got)
+		if rec.Code != http.StatusOK || got.Status != want {
+			t.Fatalf("%s: %d status = %s, want %s", path, rec.Code, got.Status, want)
+		}
+		// После аннулирования списать нельзя
+		if rec := doJSON(t, s, http.MethodPost, "/payments/"+p.ID+"/capture", "", nil); rec.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("capture after %s = %d", path, rec.Code)
+		}
+	}
+
+	// Списанный платеж аннулировать нельзя: для него есть возврат
+	p := authorizeT(t, s)
+	doJSON(t, s, http.MethodPost, "/payments/"+p.ID+"/capture", "", nil)
+	rec := doJSON(t, s, http.MethodPost, "/payments/"+p.ID+"/void", "", nil)
+	var resp ErrorResponse
+	decodeBody(t, rec, &resp)
+	if rec.Code != http.StatusConflict || resp.Code != CodeAlreadyCaptured {
+		t.Fatalf("void after capture: %d %+v", rec.Code, resp)
+	}
+}
+
+// TestVoidRequiresAuthorized — void снимает только блокировку:
+// неавторизованный, уже аннулированный и несуществующий платеж
+// получают отказ, а не возврат
+func TestVoidRequiresAuthorized(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+
+	pending := createPaymentT(t, s, `{"amount": 100, "currency": "RUB"}`)
+	rec := doJSON(t, s, http.MethodPost, "/payments/"+pending.ID+"/void", "", nil)
+	var resp ErrorResponse
+	decodeBody(t, rec, &resp)
+	if rec.Code != http.StatusConflict || resp.Code != CodeInvalidTransition {
+		t.Fatalf("void pending: %d %+v", rec.Code, resp)
+	}
+
+	p := authorizeT(t, s)
+	if rec := doJSON(t, s, http.MethodPost, "/payments/"+p.ID+"/void", "", nil); rec.Code != http.StatusOK {
+		t.Fatalf("void = %d", rec.Code)
+	}
+	rec = doJSON(t, s, http.MethodPost, "/payments/"+p.ID+"/void", "", nil)
+	decodeBody(t, rec, &resp)
+	if rec.Code != http.StatusConflict || resp.Code != CodeInvalidTransition {
+		t.Fatalf("second void: %d %+v", rec.Code, resp)
+	}
+
+	if rec := doJSON(t, s, http.MethodPost, "/payments/pay_missing/void", "", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("void missing = %d", rec.Code)
+	}
+}
+
+// TestCaptureMinimumAmount — частичное списание меньше минимума валюты
+// отклоняется с 422, ровно минимум проходит
+func TestCaptureMinimumAmount(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{MinAmounts: map[string]int64{"RUB": 1000}})
+
+	p := authorizeT(t, s)
+	rec := doJSON(t, s, http.MethodPost, "/payments/"+p.ID+"/capture", `{"amount": "9.99"}`, nil)
+	var resp ErrorResponse
+	decodeBody(t, rec, &resp)
+	if rec.Code != http.StatusUnprocessableEntity || resp.Code != CodeAmountTooSmall {
+		t.Fatalf("below minimum: %d %+v", rec.Code, resp)
+	}
+	// Отказ не меняет платеж: его можно списать корректной суммой
+	rec = doJSON(t, s, http.MethodPost, "/payments/"+p.ID+"/capture", `{"amount": "10.00"}`, nil)
+	var got struct {
+		Status      string `json:"status"`
+		AmountMinor int64  `json:"amount_minor"`
+	}
+	decodeBody(t, rec, &got)
+	if rec.Code != http.StatusOK || got.Status != StatusSucceeded || got.AmountMinor != 1000 {
+		t.Fatalf("at minimum: %d %+v", rec.Code, got)
+	}
+}
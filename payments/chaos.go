@@ -0,0 +1,77 @@
+package payments
+
+import (
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ===== ВНЕДРЕНИЕ СБОЕВ (CHAOS TESTING) =====
+
+// chaosExempt — пути, которые никогда не получают внедренных сбоев
+// Оркестратор (Kubernetes) по /health решает, жив ли процесс:
+// случайный 500 там привел бы к перезапуску здорового сервера,
+// а на /ready — к выводу его из балансировки
+var chaosExempt = map[string]bool{
+	"/health": true,
+	"/ready":  true,
+}
+
+// injectChaos — middleware, которое для доли rate запросов (0.0–1.0)
+// имитирует сбой: половина таких запросов получает 500,
+// половина — задержку до maxLatency перед обычной обработкой
+//
+// Нужно, чтобы проверить устойчивость клиентов: повторы, таймауты,
+// идемпотентность. Включается только явно (Config.ChaosRate)
+//
+// rng — источник случайности; тест передает генератор с фиксированным
+// seed, чтобы сбои выпадали одинаково при каждом запуске
+//
+// rate <= 0 = выключено
+func injectChaos(rate float64, maxLatency time.Duration, rng *rand.Rand, next http.Handler) http.Handler {
+	if rate <= 0 {
+		return next
+	}
+	// rand.Rand не потокобезопасен, а запросы обрабатываются параллельно
+	var mu sync.Mutex
+	roll := func() (fault bool, fail bool, delay time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		if rng.Float64() >= rate {
+			return false, false, 0
+		}
+		if rng.IntN(2) == 0 {
+			return true, true, 0
+		}
+		if maxLatency > 0 {
+			delay = time.Duration(rng.Int64N(int64(maxLatency)))
+		}
+		return true, false, delay
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if chaosExempt[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		fault, fail, delay := roll()
+		switch {
+		case !fault:
+		case fail:
+			log.Printf("Chaos: injected 500 for %s %s", r.Method, r.URL.Path)
+			writeError(w, r, http.StatusInternalServerError, CodeInternal, "Injected failure (chaos mode)")
+			return
+		default:
+			log.Printf("Chaos: injected %s latency for %s %s", delay, r.Method, r.URL.Path)
+			// Ожидание прерывается, если клиент отключился
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,63 @@
+package payments
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"testing"
+	"time"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+// TestInjectChaosDeterministic — с фиксированным seed сбои выпадают
+// ровно там, где их предсказывает такой же генератор
+func TestInjectChaosDeterministic(t *testing.T) {
+	const rate = 0.3
+	h := injectChaos(rate, time.Millisecond, rand.New(rand.NewPCG(1, 2)), okHandler)
+
+	// Тот же порядок вызовов, что у injectChaos
+	oracle := rand.New(rand.NewPCG(1, 2))
+	failures := 0
+	for i := range 200 {
+		want := http.StatusOK
+		if oracle.Float64() < rate {
+			if oracle.IntN(2) == 0 {
+				want = http.StatusInternalServerError
+				failures++
+			} else {
+				oracle.Int64N(int64(time.Millisecond))
+			}
+		}
+		if rec := doJSON(t, h, http.MethodGet, "/payments", "", nil); rec.Code != want {
+			t.Fatalf("request %d: status = %d, want %d", i, rec.Code, want)
+		}
+	}
+	if failures == 0 {
+		t.Fatal("seed produced no failures; test checks nothing")
+	}
+}
+
+// TestInjectChaosExemptsHealth — /health и /ready не получают сбоев
+// даже при rate = 1
+func TestInjectChaosExemptsHealth(t *testing.T) {
+	h := injectChaos(1, 0, rand.New(rand.NewPCG(1, 2)), okHandler)
+	for range 20 {
+		for _, path := range []string{"/health", "/ready"} {
+			if rec := doJSON(t, h, http.MethodGet, path, "", nil); rec.Code != http.StatusOK {
+				t.Fatalf("%s = %d", path, rec.Code)
+			}
+		}
+	}
+}
+
+// TestInjectChaosDisabledByDefault — без ChaosRate сервер не сбоит
+func TestInjectChaosDisabledByDefault(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	for range 50 {
+		if rec := doJSON(t, s, http.MethodGet, "/payments", "", nil); rec.Code != http.StatusOK {
+			t.Fatalf("status = %d with chaos off", rec.Code)
+		}
+	}
+}
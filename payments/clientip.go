@@ -0,0 +1,57 @@
+package payments
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ===== АДРЕС КЛИЕНТА =====
+//
+// За балансировщиком r.RemoteAddr — адрес балансировщика, а настоящий
+// адрес клиента прокси дописывает в X-Forwarded-For:
+//
+//	X-Forwarded-For: 203.0.113.7, 10.0.0.5
+//
+// Каждый прокси добавляет в конец адрес, от которого пришел запрос.
+// Начало списка присылает сам клиент и может написать туда что угодно,
+// поэтому верить можно только адресам, дописанным доверенными прокси
+// (Config.TrustedProxies) — список читается с конца
+
+// clientIP возвращает адрес клиента для логов и лимитов
+//
+//   - соединение не от доверенного прокси — его адрес (RemoteAddr),
+//     X-Forwarded-For не учитывается: подделать его может кто угодно
+//   - от доверенного прокси — первый с конца адрес X-Forwarded-For,
+//     не входящий в доверенные (его дописал последний наш прокси)
+//
+// Если разобрать адрес нельзя, возвращается RemoteAddr без порта
+func (s *Server) clientIP(r *http.Request) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	if !fromTrustedProxy(r.RemoteAddr, s.trustedProxies) {
+		return remote
+	}
+
+	// Заголовков X-Forwarded-For может быть несколько — это один список
+	var hops []string
+	for _, h := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(h, ",")...)
+	}
+	client := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// Мусор в цепочке: дальше (левее) верить нечему
+			break
+		}
+		client = addr.Unmap().String()
+		if !isTrustedAddr(addr, s.trustedProxies) {
+			break
+		}
+	}
+	return client
+}
@@ -0,0 +1,57 @@
+package payments
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies("10.0.0.0/8, 192.168.1.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(NewMemoryStore(), nil, nil, Config{TrustedProxies: proxies})
+
+	cases := []struct {
+		name   string
+		remote string
+		xff    []string
+		want   string
+	}{
+		// Не доверенный отправитель: заголовок игнорируется
+		{"direct", "203.0.113.7:5000", nil, "203.0.113.7"},
+		{"untrusted spoof", "203.0.113.7:5000", []string{"1.2.3.4"}, "203.0.113.7"},
+		// Доверенный прокси: первый с конца недоверенный адрес
+		{"trusted", "10.0.0.5:443", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"chain", "10.0.0.5:443", []string{"1.2.3.4, 198.51.100.1, 192.168.1.10"}, "198.51.100.1"},
+		{"split headers", "10.0.0.5:443", []string{"1.2.3.4", "198.51.100.1"}, "198.51.100.1"},
+		{"ipv4-mapped", "10.0.0.5:443", []string{"::ffff:198.51.100.1"}, "198.51.100.1"},
+		{"garbage stops", "10.0.0.5:443", []string{"1.2.3.4, junk, 10.0.0.6"}, "10.0.0.6"},
+		{"no header", "10.0.0.5:443", nil, "10.0.0.5"},
+		{"only proxies", "10.0.0.5:443", []string{"10.0.0.7"}, "10.0.0.7"},
+		{"ipv6 remote", "[2001:db8::1]:443", []string{"1.2.3.4"}, "2001:db8::1"},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/payments", nil)
+		r.RemoteAddr = c.remote
+		for _, h := range c.xff {
+			r.Header.Add("X-Forwarded-For", h)
+		}
+		if got := s.clientIP(r); got != c.want {
+			t.Errorf("%s: clientIP = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+// TestClientIPNoTrustedProxies — без доверенных прокси
+// X-Forwarded-For не учитывается никогда
+func TestClientIPNoTrustedProxies(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	r := httptest.NewRequest(http.MethodGet, "/payments", nil)
+	r.RemoteAddr = "10.0.0.5:443"
+	r.Header.Set("X-Forwarded-For", "1.2.3.4")
+	if got := s.clientIP(r); got != "10.0.0.5" {
+		t.Fatalf("clientIP = %q", got)
+	}
+}
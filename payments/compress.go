@@ -0,0 +1,182 @@
+package payments
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ===== СЖАТИЕ ОТВЕТОВ (GZIP) =====
+
+// gzipWriters — пул gzip-компрессоров: создавать компрессор на каждый
+// ответ дорого (он выделяет сотни килобайт буферов)
+var gzipWriters = sync.Pool{
+	New: func() any { return gzip.NewWriter(nil) },
+}
+
+// compressResponses — middleware, сжимающее ответы gzip
+//
+// Сжимается ответ, если:
+//   - клиент прислал Accept-Encoding с gzip (и без gzip;q=0)
+//   - тело не меньше minSize байт: маленький ответ от сжатия не выигрывает,
+//     а процессор тратится
+//
+// Пока тело не набрало minSize байт, оно копится в буфере — так решение
+// "сжимать или нет" принимается до отправки заголовков. Flush (поток
+// событий /payments/{id}/stream) отправляет накопленное без сжатия:
+// поток должен доходить до клиента сразу, а не блоками
+//
+// minSize <= 0 = сжатие выключено
+func compressResponses(minSize int, next http.Handler) http.Handler {
+	if minSize <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Vary сообщает кэшам (CDN, прокси), что ответ зависит
+		// от Accept-Encoding: сжатый ответ нельзя отдать клиенту без gzip
+		w.Header().Add("Vary", "Accept-Encoding")
+		if r.Method == http.MethodHead || !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cw := &gzipResponseWriter{ResponseWriter: w, minSize: minSize}
+		defer cw.finish()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// acceptsGzip разбирает Accept-Encoding: "gzip, deflate;q=0.5"
+// Подходит явный gzip или "*" с ненулевым весом; gzip;q=0 = запрет
+func acceptsGzip(header string) bool {
+	accepted := false
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		q := 1.0
+		if name, v, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(name) == "q" {
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				q = parsed
+			}
+		}
+		if coding == "gzip" {
+			// Явное указание gzip важнее "*"
+			return q > 0
+		}
+		accepted = q > 0
+	}
+	return accepted
+}
+
+// gzipResponseWriter копит начало ответа и решает, сжимать ли его
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	minSize int
+
+	status  int          // код ответа, отложенный до решения
+	buf     bytes.Buffer // начало тела, пока решение не принято
+	decided bool         // заголовки уже отправлены
+	gz      *gzip.Writer // nil = ответ идет без сжатия
+}
+
+// WriteHeader реализует http.ResponseWriter
+// Код запоминается: отправить его можно только вместе с решением
+// о Content-Encoding
+func (cw *gzipResponseWriter) WriteHeader(status int) {
+	if cw.status == 0 {
+		cw.status = status
+	}
+}
+
+// Write реализует http.ResponseWriter
+func (cw *gzipResponseWriter) Write(b []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if !cw.decided {
+		cw.buf.Write(b)
+		if cw.buf.Len() < cw.minSize {
+			return len(b), nil
+		}
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if cw.gz != nil {
+		return cw.gz.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// decide отправляет заголовки и накопленное тело
+// compress = тело достаточно большое для сжатия
+func (cw *gzipResponseWriter) decide(compress bool) error {
+	cw.decided = true
+	h := cw.Header()
+	// Уже сжатый ответ и ответы без тела не трогаем
+	if h.Get("Content-Encoding") != "" || cw.status == http.StatusNoContent || cw.status == http.StatusNotModified {
+		compress = false
+	}
+	if compress {
+		h.Set("Content-Encoding", "gzip")
+		// Длина сжатого тела заранее неизвестна
+		h.Del("Content-Length")
+		cw.gz = gzipWriters.Get().(*gzip.Writer)
+		cw.gz.Reset(cw.ResponseWriter)
+	}
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if cw.buf.Len() == 0 {
+		return nil
+	}
+	var err error
+	if cw.gz != nil {
+		_, err = cw.gz.Write(cw.buf.Bytes())
+	} else {
+		_, err = cw.ResponseWriter.Write(cw.buf.Bytes())
+	}
+	cw.buf.Reset()
+	return err
+}
+
+// Flush отправляет накопленное клиенту (см. http.Flusher)
+// Если решение еще не принято, ответ идет без сжатия
+func (cw *gzipResponseWriter) Flush() {
+	if !cw.decided {
+		cw.decide(false)
+	}
+	if cw.gz != nil {
+		cw.gz.Flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// finish завершает ответ: маленькое тело отправляется как есть,
+// сжатый поток закрывается (gzip дописывает контрольную сумму)
+func (cw *gzipResponseWriter) finish() {
+	if !cw.decided {
+		if cw.status == 0 {
+			// Обработчик ничего не отправил — net/http сам ответит 200
+			return
+		}
+		cw.decide(false)
+	}
+	if cw.gz != nil {
+		cw.gz.Close()
+		gzipWriters.Put(cw.gz)
+		cw.gz = nil
+	}
+}
+
+// Unwrap возвращает исходный ResponseWriter (см. statusRecorder.Unwrap)
+func (cw *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
@@ -0,0 +1,80 @@
+package payments
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// textHandler отвечает телом body
+func textHandler(body string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, body)
+	})
+}
+
+// TestCompressLargeResponse — большой ответ сжимается, если клиент
+// прислал Accept-Encoding: gzip, и распаковывается в исходное тело
+func TestCompressLargeResponse(t *testing.T) {
+	body := `[` + strings.Repeat(`{"id":"pay_x","amount":100},`, 200) + `{}]`
+	h := compressResponses(1024, textHandler(body))
+
+	rec := doJSON(t, h, http.MethodGet, "/payments", "", map[string]string{"Accept-Encoding": "gzip, deflate"})
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", rec.Header().Get("Content-Encoding"))
+	}
+	if !strings.Contains(rec.Header().Get("Vary"), "Accept-Encoding") {
+		t.Error("Vary: Accept-Encoding missing")
+	}
+	if rec.Body.Len() >= len(body) {
+		t.Errorf("compressed size %d >= original %d", rec.Body.Len(), len(body))
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(zr)
+	if err != nil || string(got) != body {
+		t.Fatalf("decompressed body differs (err = %v)", err)
+	}
+}
+
+// TestCompressSkipped — маленький ответ и клиент без gzip не затронуты
+func TestCompressSkipped(t *testing.T) {
+	large := strings.Repeat("x", 4096)
+	for name, tc := range map[string]struct {
+		body, accept string
+	}{
+		"small":       {`{"ok":true}`, "gzip"},
+		"no gzip":     {large, "deflate"},
+		"gzip;q=0":    {large, "gzip;q=0, *"},
+		"no encoding": {large, ""},
+	} {
+		h := compressResponses(1024, textHandler(tc.body))
+		rec := doJSON(t, h, http.MethodGet, "/payments", "", map[string]string{"Accept-Encoding": tc.accept})
+		if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != tc.body {
+			t.Errorf("%s: Content-Encoding = %q, body changed = %v",
+				name, rec.Header().Get("Content-Encoding"), rec.Body.String() != tc.body)
+		}
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	for header, want := range map[string]bool{
+		"gzip":            true,
+		"GZIP;q=0.5":      true,
+		"*":               true,
+		"gzip;q=0":        false,
+		"*, gzip;q=0":     false,
+		"gzip;q=0, *;q=1": false,
+		"br, deflate":     false,
+		"":                false,
+	} {
+		if got := acceptsGzip(header); got != want {
+			t.Errorf("acceptsGzip(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package payments
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// ===== СКВОЗНОЙ ID ОПЕРАЦИЙ (CORRELATION ID) =====
+//
+// Клиент может передать при создании платежа заголовок
+// X-Correlation-ID (например, ID заказа в своей системе трассировки)
+// Он сохраняется в платеже (Payment.CorrelationID), и каждый следующий
+// запрос к этому платежу — capture, refund, cancel, GET — возвращает
+// его в том же заголовке и пишет в лог. Так по одному ID в логах
+// находится вся история платежа, а не отдельный запрос
+
+// correlationHeader — заголовок со сквозным ID
+const correlationHeader = "X-Correlation-ID"
+
+// maxCorrelationIDLength — максимальная длина сквозного ID
+const maxCorrelationIDLength = 128
+
+// parseCorrelationID читает сквозной ID из запроса ("" = не передан)
+// Допускаются только видимые ASCII символы: значение попадает в логи
+// и заголовки ответов, переводы строк там недопустимы
+func parseCorrelationID(r *http.Request) (string, error) {
+	id := r.Header.Get(correlationHeader)
+	if len(id) > maxCorrelationIDLength {
+		return "", fmt.Errorf("%s must be at most %d characters", correlationHeader, maxCorrelationIDLength)
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return "", fmt.Errorf("%s must contain only printable ASCII characters", correlationHeader)
+		}
+	}
+	return id, nil
+}
+
+// paymentRoute оборачивает обработчик маршрута /payments/{id}/…:
+//   - чужой платеж (при включенных ключах API, см. apikey.go)
+//     для него не существует — 404
+//   - сквозной ID платежа возвращается в X-Correlation-ID и пишется в лог
+//   - изменяющие запросы к одному платежу идут по очереди (см. lock.go)
+//
+// Некорректный или несуществующий ID пропускаем — на него
+// ответит сам обработчик
+func (s *Server) paymentRoute(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if !isValidPaymentID(id) {
+			next(w, r)
+			return
+		}
+		// Блокировка — до чтения платежа: проверка владельца
+		// и обработчик видят одно и то же состояние
+		if isMutation(r) {
+			defer s.paymentLocks.lock(id)()
+		}
+		p, err := s.store.Get(r.Context(), id)
+		if err != nil {
+			next(w, r)
+			return
+		}
+		if !s.ownsPayment(r, p) {
+			writeError(w, r, http.StatusNotFound, CodePaymentNotFound, "Payment not found")
+			return
+		}
+		if p.CorrelationID != "" {
+			w.Header().Set(correlationHeader, p.CorrelationID)
+			log.Printf("Correlation %s: %s %s", p.CorrelationID, r.Method, r.URL.Path)
+		}
+		next(w, r)
+	}
+}
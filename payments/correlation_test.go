@@ -0,0 +1,67 @@
+package payments
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestCorrelationIDAcrossOperations — сквозной ID, переданный при
+// создании, возвращается и пишется в лог на capture, refund и GET
+func TestCorrelationIDAcrossOperations(t *testing.T) {
+	const corr = "trace-order-42"
+	logs := captureLog(t)
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+
+	rec := doJSON(t, s, http.MethodPost, "/payments", `{"amount": 100, "currency": "RUB", "capture": false}`,
+		map[string]string{correlationHeader: corr})
+	var p Payment
+	decodeBody(t, rec, &p)
+	if rec.Code != http.StatusCreated || p.CorrelationID != corr || rec.Header().Get(correlationHeader) != corr {
+		t.Fatalf("create: %d correlation_id = %q, header = %q", rec.Code, p.CorrelationID, rec.Header().Get(correlationHeader))
+	}
+
+	// Последующие запросы заголовок не передают — ID берется из платежа
+	for _, op := range []struct{ method, path, body string }{
+		{http.MethodPost, "/payments/" + p.ID + "/capture", ""},
+		{http.MethodPost, "/payments/" + p.ID + "/refunds", `{"amount": 10}`},
+		{http.MethodGet, "/payments/" + p.ID, ""},
+	} {
+		logs.Reset()
+		rec := doJSON(t, s, op.method, op.path, op.body, nil)
+		if rec.Code >= 300 {
+			t.Fatalf("%s %s = %d: %s", op.method, op.path, rec.Code, rec.Body.String())
+		}
+		if got := rec.Header().Get(correlationHeader); got != corr {
+			t.Errorf("%s %s: header = %q, want %q", op.method, op.path, got, corr)
+		}
+		if !strings.Contains(logs.String(), "Correlation "+corr) {
+			t.Errorf("%s %s: correlation not logged: %s", op.method, op.path, logs.String())
+		}
+	}
+
+	var got Payment
+	decodeBody(t, doJSON(t, s, http.MethodGet, "/payments/"+p.ID, "", nil), &got)
+	if got.Status != StatusSucceeded || got.CorrelationID != corr {
+		t.Fatalf("after operations: status = %s, correlation_id = %q", got.Status, got.CorrelationID)
+	}
+}
+
+func TestCorrelationIDInvalid(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	for _, id := range []string{"with space", strings.Repeat("x", maxCorrelationIDLength+1)} {
+		rec := doJSON(t, s, http.MethodPost, "/payments", `{"amount": 100, "currency": "RUB"}`,
+			map[string]string{correlationHeader: id})
+		var resp ErrorResponse
+		decodeBody(t, rec, &resp)
+		if rec.Code != http.StatusBadRequest || resp.Code != CodeInvalidCorrelationID {
+			t.Errorf("%q: %d %+v", id, rec.Code, resp)
+		}
+	}
+
+	// Без заголовка платеж создается без сквозного ID
+	rec := doJSON(t, s, http.MethodPost, "/payments", `{"amount": 100, "currency": "RUB"}`, nil)
+	if rec.Header().Get(correlationHeader) != "" {
+		t.Fatalf("unexpected header %q", rec.Header().Get(correlationHeader))
+	}
+}
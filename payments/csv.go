@@ -0,0 +1,44 @@
+package payments
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ===== ЭКСПОРТ В CSV =====
+
+// csvHeader — заголовок CSV таблицы платежей
+var csvHeader = []string{"id", "amount", "currency", "status", "created_at"}
+
+// writePaymentsCSV отправляет платежи в формате CSV
+//
+// encoding/csv сам экранирует запятые и кавычки в значениях
+// csv.Writer пишет прямо в ResponseWriter построчно (поток),
+// не собирая весь файл в памяти
+func writePaymentsCSV(w http.ResponseWriter, payments []Payment) error {
+	w.Header().Set("Content-Type", mediaCSV+"; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, p := range payments {
+		record := []string{
+			p.ID,
+			// 'f' = без экспоненты, -1 = минимум знаков без потери точности
+			strconv.FormatFloat(p.Amount, 'f', -1, 64),
+			p.Currency,
+			p.Status,
+			p.CreatedAt.Format(time.RFC3339),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	// Writer буферизует данные — Flush дописывает остаток
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,125 @@
+package payments
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// ===== ПОДДЕРЖИВАЕМЫЕ ВАЛЮТЫ =====
+
+// DefaultSupportedCurrencies — валюты (ISO 4217), которые система
+// принимает, если список не задан в конфигурации (SUPPORTED_CURRENCIES)
+var DefaultSupportedCurrencies = []string{"RUB", "USD", "EUR", "GBP", "CNY", "JPY", "KRW"}
+
+// ParseCurrencies разбирает список валют из строки конфигурации
+//
+// Формат: "RUB,USD,EUR" (пробелы вокруг кодов допускаются)
+// Каждый код — ровно 3 латинские буквы, регистр приводится к верхнему
+// Пустой элемент или неверный код = ошибка (лучше упасть при старте,
+// чем молча не принимать платежи в нужной валюте)
+func ParseCurrencies(s string) ([]string, error) {
+	var codes []string
+	for _, entry := range strings.Split(s, ",") {
+		code := strings.ToUpper(strings.TrimSpace(entry))
+		if !isCurrencyCode(code) {
+			return nil, fmt.Errorf("invalid currency code %q: expected 3-letter ISO 4217 code", entry)
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// isCurrencyCode проверяет формат кода валюты: 3 заглавные латинские буквы
+func isCurrencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// newCurrencySet строит множество валют для быстрой проверки
+// map[string]bool используется как множество: set["RUB"] == true
+func newCurrencySet(codes []string) map[string]bool {
+	set := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		set[code] = true
+	}
+	return set
+}
+
+// currencyDecimals — количество знаков после запятой для валюты
+// Минорная единица = копейка для RUB, цент для USD
+// У некоторых валют копеек нет (JPY, KRW) — 0 знаков
+var currencyDecimals = map[string]int{
+	"JPY": 0,
+	"KRW": 0,
+}
+
+// decimalsFor возвращает число десятичных знаков валюты (по умолчанию 2)
+func decimalsFor(currency string) int {
+	if d, ok := currencyDecimals[currency]; ok {
+		return d
+	}
+	return 2
+}
+
+// CurrencyDecimals возвращает число десятичных знаков валюты
+// (2 для RUB и USD, 0 для JPY и KRW)
+func CurrencyDecimals(code string) int {
+	return decimalsFor(code)
+}
+
+// currencyInfo — валюта в ответе GET /currencies
+//   - Decimals — знаков после запятой (minor = amount × 10^decimals)
+//   - MinMinor — минимальная сумма платежа в минорных единицах
+//     (Config.MinAmounts, без минимума — 1)
+//   - MaxMinor — максимальная сумма (MaxAmountMinor)
+type currencyInfo struct {
+	Code     string `json:"code"`
+	Decimals int    `json:"decimals"`
+	MinMinor int64  `json:"min_minor"`
+	MaxMinor int64  `json:"max_minor"`
+}
+
+// handleCurrencies возвращает валюты, в которых можно создать платеж,
+// и их ограничения — чтобы формы клиента не хранили их у себя
+// GET /currencies → [{"code":"RUB","decimals":2,"min_minor":1,"max_minor":…}]
+//
+// Временно запрещенные валюты (Config.BlockedCurrencies) не входят:
+// платеж в них сейчас не создать. Порядок — по коду валюты
+func (s *Server) handleCurrencies(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	// Пустой срез, а не nil: в JSON [] вместо null
+	result := []currencyInfo{}
+	for code := range s.currencies {
+		if s.blocked[code] {
+			continue
+		}
+		minimum, ok := s.minAmounts[code]
+		if !ok || minimum < 1 {
+			minimum = 1
+		}
+		result = append(result, currencyInfo{
+			Code:     code,
+			Decimals: decimalsFor(code),
+			MinMinor: minimum,
+			MaxMinor: MaxAmountMinor,
+		})
+	}
+	slices.SortFunc(result, func(a, b currencyInfo) int {
+		return strings.Compare(a.Code, b.Code)
+	})
+
+	writeJSON(w, r, http.StatusOK, result)
+}
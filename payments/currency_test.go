@@ -0,0 +1,74 @@
+package payments
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+// TestBlockedCurrency — поддерживаемая, но запрещенная валюта дает 403,
+// остальные валюты работают как прежде
+func TestBlockedCurrency(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{BlockedCurrencies: []string{"USD"}})
+
+	rec := doJSON(t, s, http.MethodPost, "/payments", `{"amount": 10, "currency": "USD"}`, nil)
+	var resp ErrorResponse
+	decodeBody(t, rec, &resp)
+	if rec.Code != http.StatusForbidden || resp.Code != CodeCurrencyBlocked || resp.Message != "Payments in USD are temporarily blocked" {
+		t.Fatalf("blocked: %d %+v", rec.Code, resp)
+	}
+
+	if rec := doJSON(t, s, http.MethodPost, "/payments", `{"amount": 10, "currency": "RUB"}`, nil); rec.Code != http.StatusCreated {
+		t.Fatalf("RUB = %d", rec.Code)
+	}
+
+	// Без запрета USD принимается — запрет не путается с неподдерживаемой валютой
+	open := NewServer(NewMemoryStore(), nil, nil, Config{})
+	if rec := doJSON(t, open, http.MethodPost, "/payments", `{"amount": 10, "currency": "USD"}`, nil); rec.Code != http.StatusCreated {
+		t.Fatalf("USD without denylist = %d", rec.Code)
+	}
+}
+
+// TestCurrenciesHidesBlocked — /currencies не предлагает запрещенные валюты
+func TestCurrenciesHidesBlocked(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{BlockedCurrencies: []string{"USD"}})
+	var list []currencyInfo
+	decodeBody(t, doJSON(t, s, http.MethodGet, "/currencies", "", nil), &list)
+	sawRUB := false
+	for _, c := range list {
+		if c.Code == "USD" {
+			t.Fatal("blocked USD listed")
+		}
+		sawRUB = sawRUB || c.Code == "RUB"
+	}
+	if !sawRUB {
+		t.Fatalf("currencies = %+v, want RUB", list)
+	}
+}
+
+// TestCurrenciesAttributes — в ответе ровно настроенные валюты
+// (по коду), с их знаками после запятой и границами сумм
+func TestCurrenciesAttributes(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{
+		SupportedCurrencies: []string{"USD", "JPY", "RUB"},
+		MinAmounts:          map[string]int64{"RUB": 1000},
+	})
+	rec := doJSON(t, s, http.MethodGet, "/currencies", "", nil)
+	var list []currencyInfo
+	decodeBody(t, rec, &list)
+
+	want := []currencyInfo{
+		{Code: "JPY", Decimals: 0, MinMinor: 1, MaxMinor: MaxAmountMinor},
+		{Code: "RUB", Decimals: 2, MinMinor: 1000, MaxMinor: MaxAmountMinor},
+		{Code: "USD", Decimals: 2, MinMinor: 1, MaxMinor: MaxAmountMinor},
+	}
+	if rec.Code != http.StatusOK || !slices.Equal(list, want) {
+		t.Fatalf("currencies = %d %+v, want %+v", rec.Code, list, want)
+	}
+
+	// Пустой список — [], а не null
+	none := NewServer(NewMemoryStore(), nil, nil, Config{SupportedCurrencies: []string{"USD"}, BlockedCurrencies: []string{"USD"}})
+	if body := doJSON(t, none, http.MethodGet, "/currencies", "", nil).Body.String(); body != "[]\n" {
+		t.Fatalf("empty body = %q", body)
+	}
+}
@@ -0,0 +1,122 @@
+package payments
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// ===== КЛИЕНТЫ (CUSTOMERS) =====
+
+// Customer — клиент, которому принадлежат платежи
+type Customer struct {
+	ID        string    `json:"id"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ErrCustomerNotFound — клиента с таким ID нет
+var ErrCustomerNotFound = errors.New("customer not found")
+
+// newCustomerID генерирует ID клиента вида "cus_<uuid v4>"
+func newCustomerID() string {
+	return newID("cus_")
+}
+
+// handleCustomers обрабатывает запросы к /customers
+// Пока поддерживается только создание (POST)
+func (s *Server) handleCustomers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	var customer Customer
+	if err := json.NewDecoder(r.Body).Decode(&customer); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	// Email обязателен и должен быть корректным адресом
+	// mail.ParseAddress разбирает адрес по RFC 5322
+	customer.Email = strings.TrimSpace(customer.Email)
+	if customer.Email == "" {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidEmail, "Email is required")
+		return
+	}
+	if _, err := mail.ParseAddress(customer.Email); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidEmail, "Invalid email")
+		return
+	}
+
+	// ID и время создания назначает сервер, а не клиент
+	customer.ID = newCustomerID()
+	customer.CreatedAt = time.Now().UTC()
+
+	if err := s.store.SaveCustomer(r.Context(), customer); err != nil {
+		log.Printf("Error saving customer: %v", err)
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+		return
+	}
+
+	log.Printf("Customer created: ID=%s", customer.ID)
+
+	writeJSON(w, r, http.StatusCreated, customer)
+}
+
+// handleCustomerPayments возвращает платежи клиента
+// GET /customers/{id}/payments
+// Удаленные платежи скрыты, как и в общем списке
+func (s *Server) handleCustomerPayments(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	display, err := parseDisplayRequest(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidQuery, err.Error())
+		return
+	}
+
+	customerID := r.PathValue("id")
+	_, err = s.store.GetCustomer(r.Context(), customerID)
+	if errors.Is(err, ErrCustomerNotFound) {
+		writeError(w, r, http.StatusNotFound, CodeCustomerNotFound, "Customer not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Error loading customer: %v", err)
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+		return
+	}
+
+	all, err := s.store.List(r.Context(), false)
+	if err != nil {
+		log.Printf("Error listing payments: %v", err)
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+		return
+	}
+	all = s.scopePayments(r, all)
+
+	// Фильтруем "на месте": result использует тот же массив, что и all
+	result := all[:0]
+	for _, p := range all {
+		if p.CustomerID == customerID {
+			result = append(result, p)
+		}
+	}
+	if includeDisplay(r) {
+		withDisplay(result)
+	}
+	if err := s.withDisplayAmounts(result, display); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeUnsupportedCurrencyPair, err.Error())
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, result)
+}
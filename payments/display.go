@@ -0,0 +1,150 @@
+package payments
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ===== ФОРМАТИРОВАНИЕ СУММ ДЛЯ ПОКАЗА =====
+
+// displayFormat — разделители при показе суммы
+type displayFormat struct {
+	group   string // разделитель тысяч: "1,000"
+	decimal string // разделитель дробной части: "0.50"
+}
+
+// defaultDisplayFormat — формат по умолчанию: "1,000.50"
+var defaultDisplayFormat = displayFormat{group: ",", decimal: "."}
+
+// displayFormats — валюты, для которых принято писать суммы иначе
+// Число знаков после разделителя берется из decimalsFor
+var displayFormats = map[string]displayFormat{
+	"EUR": {group: ".", decimal: ","}, // 1.000,50 EUR
+}
+
+// formatAmountDisplay форматирует сумму для показа человеку
+// Пример: 100050 RUB → "1,000.50 RUB", 1500 JPY → "1,500 JPY"
+// Считаем из минорных единиц, а не из float64 — без ошибок округления
+func formatAmountDisplay(minor int64, currency string) string {
+	f, ok := displayFormats[currency]
+	if !ok {
+		f = defaultDisplayFormat
+	}
+
+	whole, frac := minorDigits(minor, currency)
+
+	// Группы по 3 цифры справа налево
+	var b strings.Builder
+	for i, c := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteString(f.group)
+		}
+		b.WriteRune(c)
+	}
+	if frac != "" {
+		b.WriteString(f.decimal)
+		b.WriteString(frac)
+	}
+	b.WriteString(" ")
+	b.WriteString(currency)
+	return b.String()
+}
+
+// formatAmountDecimal записывает сумму десятичной строкой без
+// разделителей разрядов: 10050 RUB → "100.50", 1500 JPY → "1500"
+// Число знаков — ровно decimalsFor(currency), как в amount_minor
+func formatAmountDecimal(minor int64, currency string) string {
+	sign := ""
+	if minor < 0 {
+		sign, minor = "-", -minor
+	}
+	whole, frac := minorDigits(minor, currency)
+	if frac == "" {
+		return sign + whole
+	}
+	return sign + whole + "." + frac
+}
+
+// minorDigits делит неотрицательную сумму в минорных единицах
+// на цифры целой и дробной части: 10050 RUB → "100", "50"
+func minorDigits(minor int64, currency string) (whole, frac string) {
+	digits := strconv.FormatInt(minor, 10)
+	decimals := decimalsFor(currency)
+	// Дополняем нулями слева, чтобы была хотя бы одна цифра до запятой:
+	// 5 копеек → "005" → "0.05"
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+	return digits[:len(digits)-decimals], digits[len(digits)-decimals:]
+}
+
+// includeDisplay сообщает, просил ли клиент amount_display
+// (GET /payments?include_display=true)
+func includeDisplay(r *http.Request) bool {
+	return r.URL.Query().Get("include_display") == "true"
+}
+
+// withDisplay заполняет AmountDisplay у платежей (на месте)
+func withDisplay(payments []Payment) {
+	for i := range payments {
+		payments[i].AmountDisplay = formatAmountDisplay(payments[i].AmountMinor, payments[i].Currency)
+	}
+}
+
+// ===== СУММЫ В ДРУГИХ ВАЛЮТАХ =====
+//
+// GET /payments/{id}?display_currencies=USD,EUR добавляет в ответ
+//
+//	"display_amounts": {"USD": 1.09, "EUR": 1.01}
+//
+// — сумму платежа, пересчитанную по текущему курсу (FXProvider).
+// Только для показа: amount и currency платежа не меняются
+// Валюту без курса молча пропускаем, а с &strict_display=true
+// отвечаем 400 — для дашборда, которому нужны все колонки
+
+// displayRequest — какие валюты показать и строго ли
+type displayRequest struct {
+	currencies []string
+	strict     bool
+}
+
+// parseDisplayRequest читает display_currencies и strict_display
+// Без display_currencies возвращает пустой запрос (ничего не добавлять)
+func parseDisplayRequest(r *http.Request) (displayRequest, error) {
+	query := r.URL.Query()
+	raw := query.Get("display_currencies")
+	if raw == "" {
+		return displayRequest{}, nil
+	}
+	currencies, err := ParseCurrencies(raw)
+	if err != nil {
+		return displayRequest{}, fmt.Errorf("display_currencies: %w", err)
+	}
+	return displayRequest{currencies: currencies, strict: query.Get("strict_display") == "true"}, nil
+}
+
+// withDisplayAmounts заполняет DisplayAmounts у платежей (на месте)
+// Ошибка — только в строгом режиме: нет курса для какой-то пары
+func (s *Server) withDisplayAmounts(payments []Payment, req displayRequest) error {
+	if len(req.currencies) == 0 {
+		return nil
+	}
+	for i := range payments {
+		p := &payments[i]
+		p.DisplayAmounts = make(map[string]float64, len(req.currencies))
+		for _, currency := range req.currencies {
+			minor, _, err := convertToMinor(s.fx, minorToAmount(p.AmountMinor, p.Currency), p.Currency, currency,
+				s.rounding.modeFor(currency))
+			if err != nil {
+				if req.strict {
+					return fmt.Errorf("no exchange rate from %s to %s", p.Currency, currency)
+				}
+				continue
+			}
+			p.DisplayAmounts[currency] = minorToAmount(minor, currency)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package payments
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestFormatAmountDisplay(t *testing.T) {
+	cases := []struct {
+		minor    int64
+		currency string
+		want     string
+	}{
+		{100050, "RUB", "1,000.50 RUB"},
+		{5, "RUB", "0.05 RUB"},
+		{123456789, "USD", "1,234,567.89 USD"},
+		{100050, "EUR", "1.000,50 EUR"},
+		{1500, "JPY", "1,500 JPY"},
+	}
+	for _, c := range cases {
+		if got := formatAmountDisplay(c.minor, c.currency); got != c.want {
+			t.Errorf("formatAmountDisplay(%d, %s) = %q, want %q", c.minor, c.currency, got, c.want)
+		}
+	}
+}
+
+// newDisplayServer — платеж pay_disp на 1000.00 RUB и курсы к USD и EUR
+func newDisplayServer(t *testing.T) *Server {
+	t.Helper()
+	store := NewMemoryStore()
+	savePaymentT(t, store, Payment{ID: "pay_disp", AmountMinor: 100000, Currency: "RUB", Status: StatusPending, Version: 1})
+	fx := NewStaticFXProvider(map[string]float64{"USD/RUB": 80, "EUR/RUB": 100})
+	return NewServer(store, fx, nil, Config{})
+}
+
+// TestDisplayAmounts — суммы в других валютах добавляются по курсу,
+// а amount и currency платежа не меняются
+func TestDisplayAmounts(t *testing.T) {
+	s := newDisplayServer(t)
+
+	rec := doJSON(t, s, http.MethodGet, "/payments/pay_disp?display_currencies=USD,EUR", "", nil)
+	var got struct {
+		AmountMinor    int64              `json:"amount_minor"`
+		Currency       string             `json:"currency"`
+		DisplayAmounts map[string]float64 `json:"display_amounts"`
+	}
+	decodeBody(t, rec, &got)
+	if rec.Code != http.StatusOK || got.AmountMinor != 100000 || got.Currency != "RUB" {
+		t.Fatalf("GET = %d %+v", rec.Code, got)
+	}
+	if got.DisplayAmounts["USD"] != 12.5 || got.DisplayAmounts["EUR"] != 10 {
+		t.Fatalf("display_amounts = %v", got.DisplayAmounts)
+	}
+
+	// В списке — то же
+	rec = doJSON(t, s, http.MethodGet, "/payments?display_currencies=EUR", "", nil)
+	var list []struct {
+		DisplayAmounts map[string]float64 `json:"display_amounts"`
+	}
+	decodeBody(t, rec, &list)
+	if len(list) != 1 || list[0].DisplayAmounts["EUR"] != 10 {
+		t.Fatalf("list = %+v", list)
+	}
+
+	// Без параметра поля нет
+	rec = doJSON(t, s, http.MethodGet, "/payments/pay_disp", "", nil)
+	var plain map[string]any
+	decodeBody(t, rec, &plain)
+	if _, ok := plain["display_amounts"]; ok {
+		t.Fatalf("display_amounts without request: %v", plain)
+	}
+}
+
+// TestDisplayAmountsUnsupported — валюта без курса пропускается,
+// а в строгом режиме дает 400
+func TestDisplayAmountsUnsupported(t *testing.T) {
+	s := newDisplayServer(t)
+
+	rec := doJSON(t, s, http.MethodGet, "/payments/pay_disp?display_currencies=USD,GBP", "", nil)
+	var got struct {
+		DisplayAmounts map[string]float64 `json:"display_amounts"`
+	}
+	decodeBody(t, rec, &got)
+	if _, ok := got.DisplayAmounts["GBP"]; rec.Code != http.StatusOK || ok || got.DisplayAmounts["USD"] != 12.5 {
+		t.Fatalf("lenient = %d %v", rec.Code, got.DisplayAmounts)
+	}
+
+	rec = doJSON(t, s, http.MethodGet, "/payments/pay_disp?display_currencies=USD,GBP&strict_display=true", "", nil)
+	var resp ErrorResponse
+	decodeBody(t, rec, &resp)
+	if rec.Code != http.StatusBadRequest || resp.Code != CodeUnsupportedCurrencyPair {
+		t.Fatalf("strict = %d %+v", rec.Code, resp)
+	}
+
+	if rec := doJSON(t, s, http.MethodGet, "/payments/pay_disp?display_currencies=XX", "", nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid currency = %d", rec.Code)
+	}
+}
+
+func TestFormatAmountDecimal(t *testing.T) {
+	cases := []struct {
+		minor    int64
+		currency string
+		want     string
+	}{
+		{10050, "RUB", "100.50"},
+		{5, "RUB", "0.05"},
+		{100000, "USD", "1000.00"},
+		{1500, "JPY", "1500"},
+		{-10050, "RUB", "-100.50"},
+		{MaxAmountMinor, "RUB", "10000000000.00"},
+	}
+	for _, c := range cases {
+		if got := formatAmountDecimal(c.minor, c.currency); got != c.want {
+			t.Errorf("formatAmountDecimal(%d, %s) = %q, want %q", c.minor, c.currency, got, c.want)
+		}
+	}
+}
+
+// TestPaymentJSONAmountForms — в ответе рядом с amount есть точные
+// amount_minor и amount_decimal
+func TestPaymentJSONAmountForms(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	rec := doJSON(t, s, http.MethodPost, "/payments", `{"amount": "100.50", "currency": "RUB"}`, nil)
+	var got map[string]any
+	decodeBody(t, rec, &got)
+	if got["amount"] != 100.5 || got["amount_minor"] != float64(10050) || got["amount_decimal"] != "100.50" {
+		t.Fatalf("amount fields = %v %v %v", got["amount"], got["amount_minor"], got["amount_decimal"])
+	}
+}
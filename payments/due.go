@@ -0,0 +1,56 @@
+package payments
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// ===== ИНДЕКС СРОКОВ =====
+//
+// Планировщик (schedule.go) и срок авторизации (expiry.go) на каждом
+// тике ищут платежи, срок которых наступил. Полный List читал бы все
+// платежи, в том числе давно завершенные, хотя срок есть лишь у малой
+// их части. Поэтому хранилище ведет индекс сроков: в нем только
+// платежи scheduled (срок — ProcessAt) и authorized (CaptureExpiresAt),
+// и он обновляется при каждой записи платежа (см. Store.ListDue)
+
+// dueStatuses — статусы, у платежей в которых есть срок
+var dueStatuses = []string{StatusScheduled, StatusAuthorized}
+
+// dueAt возвращает срок платежа для индекса: ProcessAt для scheduled,
+// CaptureExpiresAt для authorized. ok = false — платеж в индекс
+// не входит (другой статус, срок не задан или платеж удален)
+func dueAt(p Payment) (at time.Time, ok bool) {
+	if p.Deleted {
+		return time.Time{}, false
+	}
+	var t *time.Time
+	switch p.Status {
+	case StatusScheduled:
+		t = p.ProcessAt
+	case StatusAuthorized:
+		t = p.CaptureExpiresAt
+	}
+	if t == nil {
+		return time.Time{}, false
+	}
+	return *t, true
+}
+
+// isDue сообщает, что платеж в статусе status и его срок наступил к now
+// Хранилища перепроверяют так прочитанное из индекса: платеж мог
+// измениться после того, как индекс его выдал
+func isDue(p Payment, status string, now time.Time) bool {
+	at, ok := dueAt(p)
+	return ok && p.Status == status && !at.After(now)
+}
+
+// sortByDue упорядочивает платежи по сроку: раньше срок — раньше в списке
+func sortByDue(payments []Payment) {
+	slices.SortFunc(payments, func(a, b Payment) int {
+		at, _ := dueAt(a)
+		bt, _ := dueAt(b)
+		return cmp.Or(at.Compare(bt), cmp.Compare(a.ID, b.ID))
+	})
+}
@@ -0,0 +1,92 @@
+package payments
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestListDue(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+		past, later := now.Add(-time.Minute), now.Add(time.Hour)
+
+		savePaymentT(t, store, Payment{ID: "pay_due", Status: StatusScheduled, ProcessAt: &past, Version: 1})
+		savePaymentT(t, store, Payment{ID: "pay_due_first", Status: StatusScheduled, ProcessAt: ptr(past.Add(-time.Minute)), Version: 1})
+		savePaymentT(t, store, Payment{ID: "pay_later", Status: StatusScheduled, ProcessAt: &later, Version: 1})
+		savePaymentT(t, store, Payment{ID: "pay_auth", Status: StatusAuthorized, CaptureExpiresAt: &past, Version: 1})
+		savePaymentT(t, store, Payment{ID: "pay_plain", Status: StatusPending, Version: 1})
+
+		due, err := store.ListDue(ctx, StatusScheduled, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := paymentIDs(due); len(got) != 2 || got[0] != "pay_due_first" || got[1] != "pay_due" {
+			t.Fatalf("scheduled due = %v", got)
+		}
+		expired, _ := store.ListDue(ctx, StatusAuthorized, now)
+		if got := paymentIDs(expired); len(got) != 1 || got[0] != "pay_auth" {
+			t.Fatalf("authorized due = %v", got)
+		}
+
+		// Смена статуса убирает платеж из индекса
+		if _, err := store.UpdateStatus(ctx, "pay_due", StatusPending, 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.MarkDeleted(ctx, "pay_due_first"); err != nil {
+			t.Fatal(err)
+		}
+		if due, _ := store.ListDue(ctx, StatusScheduled, now); len(due) != 0 {
+			t.Fatalf("index kept changed payments: %v", paymentIDs(due))
+		}
+		// Срок наступает — платеж появляется
+		if due, _ := store.ListDue(ctx, StatusScheduled, later); len(due) != 1 || due[0].ID != "pay_later" {
+			t.Fatalf("due at later = %v", paymentIDs(due))
+		}
+	})
+}
+
+// TestSchedulerUsesDueIndex — планировщик проводит наступивший платеж
+// и не трогает будущий
+func TestSchedulerUsesDueIndex(t *testing.T) {
+	store := NewMemoryStore()
+	s := NewServer(store, nil, nil, Config{})
+	ctx := context.Background()
+	now := time.Now()
+	past, later := now.Add(-time.Second), now.Add(time.Hour)
+	savePaymentT(t, store, Payment{ID: "pay_now", AmountMinor: 100, Currency: "RUB", Status: StatusScheduled, ProcessAt: &past, Version: 1})
+	savePaymentT(t, store, Payment{ID: "pay_later", AmountMinor: 100, Currency: "RUB", Status: StatusScheduled, ProcessAt: &later, Version: 1})
+
+	if n := s.ProcessDueScheduled(ctx, now); n != 1 {
+		t.Fatalf("processed = %d, want 1", n)
+	}
+	if p, _ := store.Get(ctx, "pay_later"); p.Status != StatusScheduled {
+		t.Fatalf("future payment status = %s", p.Status)
+	}
+	if n := s.ProcessDueScheduled(ctx, now); n != 0 {
+		t.Fatalf("second tick processed %d", n)
+	}
+}
+
+func TestVoidExpiredAuthorizations(t *testing.T) {
+	store := NewMemoryStore()
+	s := NewServer(store, nil, nil, Config{})
+	ctx := context.Background()
+	now := time.Now()
+	past, later := now.Add(-time.Second), now.Add(time.Hour)
+	savePaymentT(t, store, Payment{ID: "pay_old", AmountMinor: 100, Currency: "RUB", Status: StatusAuthorized, CaptureExpiresAt: &past, Version: 1})
+	savePaymentT(t, store, Payment{ID: "pay_fresh", AmountMinor: 100, Currency: "RUB", Status: StatusAuthorized, CaptureExpiresAt: &later, Version: 1})
+
+	if n := s.VoidExpiredAuthorizations(ctx, now); n != 1 {
+		t.Fatalf("voided = %d, want 1", n)
+	}
+	if p, _ := store.Get(ctx, "pay_old"); p.Status != StatusVoided {
+		t.Fatalf("expired status = %s", p.Status)
+	}
+	if p, _ := store.Get(ctx, "pay_fresh"); p.Status != StatusAuthorized {
+		t.Fatalf("fresh status = %s", p.Status)
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
@@ -0,0 +1,87 @@
+package payments
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ===== ЗАЩИТА ОТ СЛУЧАЙНЫХ ДУБЛЕЙ =====
+
+// duplicateGuard запоминает недавно созданные платежи, чтобы поймать
+// случайный повтор: тот же клиент, та же сумма и валюта за короткое время
+// (например, пользователь дважды нажал "Оплатить")
+//
+// В отличие от ключей идемпотентности, клиенту ничего не нужно передавать —
+// совпадение ищется по содержимому платежа
+type duplicateGuard struct {
+	window time.Duration
+
+	mu     sync.Mutex
+	recent map[string]recentPayment
+}
+
+// recentPayment — недавно созданный платеж с заданным "отпечатком"
+type recentPayment struct {
+	id        string
+	createdAt time.Time
+}
+
+// newDuplicateGuard создает защиту с окном window
+// window <= 0 = защита выключена (check всегда пропускает)
+func newDuplicateGuard(window time.Duration) *duplicateGuard {
+	return &duplicateGuard{window: window, recent: make(map[string]recentPayment)}
+}
+
+// fingerprint — "отпечаток" платежа для поиска дублей
+// Сумма — в минорных единицах (точно, в отличие от float64 Amount),
+// ключ API — чтобы совпадение у другого клиента не выдавало ему
+// ID чужого платежа (см. apikey.go)
+func fingerprint(p Payment) string {
+	return fmt.Sprintf("%s|%s|%s|%d", p.CreatedByKey, p.CustomerID, p.Currency, p.AmountMinor)
+}
+
+// checkAndRemember проверяет платеж и запоминает его
+//
+// Возвращает ID похожего платежа, созданного в пределах окна,
+// или "" если дубля нет. force = true пропускает проверку,
+// но платеж все равно запоминается
+// Проверка и запись под одной блокировкой: два одновременных
+// одинаковых запроса не пройдут оба
+//
+// Платеж без customer_id не проверяется: без клиента "тот же
+// платеж" нельзя отличить от чужого с той же суммой
+//
+// forget отменяет запись: ее нужно вызвать, если платеж в итоге
+// не сохранен (ошибка хранилища, отказ обогащения) — иначе
+// исправленный повтор получил бы 409 из-за платежа, которого нет
+func (g *duplicateGuard) checkAndRemember(p Payment, force bool) (dupID string, forget func()) {
+	if g.window <= 0 || p.CustomerID == "" {
+		return "", func() {}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := p.CreatedAt
+	// Заодно выбрасываем устаревшие записи, чтобы map не рос бесконечно
+	for key, rp := range g.recent {
+		if now.Sub(rp.createdAt) > g.window {
+			delete(g.recent, key)
+		}
+	}
+
+	key := fingerprint(p)
+	if rp, ok := g.recent[key]; ok && !force {
+		return rp.id, func() {}
+	}
+	g.recent[key] = recentPayment{id: p.ID, createdAt: now}
+	return "", func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		// Запись могли уже заменить (X-Force-Create) — чужую не трогаем
+		if g.recent[key].id == p.ID {
+			delete(g.recent, key)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package payments
+
+import (
+	"context"
+	"fmt"
+)
+
+// ===== ОБОГАЩЕНИЕ ПЛАТЕЖА =====
+//
+// Точка расширения для конкретной инсталляции: перед списанием
+// и сохранением новый платеж проходит через обогатителей
+// (Config.Enrichers), которые дописывают в него свои данные —
+// оценку риска, страну по IP и т.п. (обычно в Metadata)
+//
+//	cfg.Enrichers = []payments.PaymentEnricher{
+//		payments.EnricherFunc(func(ctx context.Context, p *payments.Payment) error {
+//			if p.Metadata == nil {
+//				p.Metadata = map[string]string{}
+//			}
+//			p.Metadata["risk_score"] = "low"
+//			return nil
+//		}),
+//	}
+
+// PaymentEnricher дополняет новый платеж перед сохранением
+//
+// Enrich вызывается, когда платеж уже проверен и получил ID, сумму
+// и комиссию, но еще не списан и не сохранен. Ошибка отменяет
+// создание: клиент получает 422 enrichment_failed
+type PaymentEnricher interface {
+	Enrich(ctx context.Context, p *Payment) error
+}
+
+// EnricherFunc позволяет передать обычную функцию как PaymentEnricher
+// (как http.HandlerFunc для http.Handler)
+type EnricherFunc func(ctx context.Context, p *Payment) error
+
+// Enrich реализует PaymentEnricher
+func (f EnricherFunc) Enrich(ctx context.Context, p *Payment) error {
+	return f(ctx, p)
+}
+
+// enrichPayment прогоняет платеж через обогатителей по порядку
+// Первая ошибка останавливает цепочку
+// Без обогатителей (Config.Enrichers == nil) ничего не делает
+func (s *Server) enrichPayment(ctx context.Context, p *Payment) error {
+	for i, e := range s.enrichers {
+		if err := e.Enrich(ctx, p); err != nil {
+			return fmt.Errorf("enricher %d: %w", i, err)
+		}
+	}
+	return nil
+}
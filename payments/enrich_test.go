@@ -0,0 +1,68 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+// setMetadata — обогатитель, дописывающий key=value в Metadata
+func setMetadata(key, value string) EnricherFunc {
+	return func(_ context.Context, p *Payment) error {
+		if p.Metadata == nil {
+			p.Metadata = map[string]string{}
+		}
+		p.Metadata[key] = value
+		return nil
+	}
+}
+
+// TestEnrichersRunInOrder — данные обогатителей сохраняются в платеже,
+// а следующий видит то, что записал предыдущий
+func TestEnrichersRunInOrder(t *testing.T) {
+	store := NewMemoryStore()
+	s := NewServer(store, nil, nil, Config{Enrichers: []PaymentEnricher{
+		setMetadata("risk_score", "low"),
+		EnricherFunc(func(_ context.Context, p *Payment) error {
+			if p.ID == "" || p.AmountMinor != 10000 {
+				return errors.New("enricher called before validation")
+			}
+			p.Metadata["risk_checked"] = p.Metadata["risk_score"]
+			return nil
+		}),
+	}})
+
+	p := createPaymentT(t, s, `{"amount": 100, "currency": "RUB"}`)
+	stored, err := store.Get(context.Background(), p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Metadata["risk_score"] != "low" || stored.Metadata["risk_checked"] != "low" {
+		t.Fatalf("metadata = %v", stored.Metadata)
+	}
+}
+
+// TestEnricherErrorFailsCreation — ошибка обогатителя дает 422, платеж
+// не списывается и не сохраняется, следующие обогатители не вызываются
+func TestEnricherErrorFailsCreation(t *testing.T) {
+	store := NewMemoryStore()
+	charged, later := false, false
+	s := NewServer(store, nil, gatewayFunc(func(context.Context, Payment) error {
+		charged = true
+		return nil
+	}), Config{Enrichers: []PaymentEnricher{
+		EnricherFunc(func(context.Context, *Payment) error { return errors.New("geo service down") }),
+		EnricherFunc(func(context.Context, *Payment) error { later = true; return nil }),
+	}})
+
+	rec := doJSON(t, s, http.MethodPost, "/payments", `{"amount": 100, "currency": "RUB"}`, nil)
+	var resp ErrorResponse
+	decodeBody(t, rec, &resp)
+	if rec.Code != http.StatusUnprocessableEntity || resp.Code != CodeEnrichmentFailed {
+		t.Fatalf("create = %d %+v", rec.Code, resp)
+	}
+	if list, _ := store.List(context.Background(), true); len(list) != 0 || charged || later {
+		t.Fatalf("stored = %d, charged = %t, later enricher ran = %t", len(list), charged, later)
+	}
+}
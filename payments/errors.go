@@ -0,0 +1,235 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// ===== ОШИБКИ API =====
+
+// Коды ошибок API
+//
+// Код — стабильная машиночитаемая строка: клиент проверяет код,
+// а не текст сообщения (текст может меняться и переводиться)
+const (
+	CodeMethodNotAllowed         = "method_not_allowed"
+	CodeUnauthorized             = "unauthorized"
+	CodeForbidden                = "forbidden"
+	CodeInvalidJSON              = "invalid_json"
+	CodeBodyRequired             = "body_required"
+	CodeInvalidAmount            = "invalid_amount"
+	CodeAmountTooLarge           = "amount_too_large"
+	CodeAmountTooSmall           = "amount_too_small"
+	CodeCurrencyRequired         = "currency_required"
+	CodeUnsupportedCurrency      = "unsupported_currency"
+	CodeCurrencyBlocked          = "currency_blocked"
+	CodeUnsupportedCurrencyPair  = "unsupported_currency_pair"
+	CodeInvalidID                = "invalid_id"
+	CodePaymentNotFound          = "payment_not_found"
+	CodePaymentExists            = "payment_exists"
+	CodeCustomerNotFound         = "customer_not_found"
+	CodeInvalidMetadata          = "invalid_metadata"
+	CodeInvalidDescription       = "invalid_description"
+	CodeInvalidExternalID        = "invalid_external_id"
+	CodeExternalIDExists         = "external_id_exists"
+	CodeValidationFailed         = "validation_failed"
+	CodeInvalidEmail             = "invalid_email"
+	CodeSchemaViolation          = "schema_violation"
+	CodeInvalidIfMatch           = "invalid_if_match"
+	CodeStatusRequired           = "status_required"
+	CodeVersionMismatch          = "version_mismatch"
+	CodeInvalidTransition        = "invalid_transition"
+	CodePossibleDuplicate        = "possible_duplicate"
+	CodeInvalidIdempotencyKey    = "invalid_idempotency_key"
+	CodeIdempotencyInProgress    = "idempotency_in_progress"
+	CodeNotRefundable            = "payment_not_refundable"
+	CodeRefundExceedsBalance     = "refund_exceeds_balance"
+	CodeRefundNotFound           = "refund_not_found"
+	CodeRefundLimitReached       = "refund_limit_reached"
+	CodeNotCapturable            = "payment_not_capturable"
+	CodeCaptureExceedsAuthorized = "capture_exceeds_authorized"
+	CodeAuthorizationExpired     = "authorization_expired"
+	CodeInvalidCorrelationID     = "invalid_correlation_id"
+	CodeNotRetryable             = "payment_not_retryable"
+	CodeRetriesExhausted         = "retries_exhausted"
+	CodeNotSplittable            = "payment_not_splittable"
+	CodeAlreadyCaptured          = "payment_already_captured"
+	CodeNoReceipt                = "receipt_unavailable"
+	CodeNotFound                 = "not_found"
+	CodeNotAcceptable            = "not_acceptable"
+	CodeInvalidQuery             = "invalid_query"
+	CodeTotalOverflow            = "total_overflow"
+	CodeLineTooLong              = "line_too_long"
+	CodeEnrichmentFailed         = "enrichment_failed"
+	CodeInvalidSignature         = "invalid_signature"
+	CodeInvalidEvent             = "invalid_event"
+	CodeOverloaded               = "overloaded"
+	CodeDraining                 = "draining"
+	CodeURITooLong               = "uri_too_long"
+	CodeStoreFull                = "store_full"
+	CodeRequestTimeout           = "request_timeout"
+	CodeHTTPSRequired            = "https_required"
+	CodeInternal                 = "internal_error"
+)
+
+// ErrorResponse — тело ответа с ошибкой
+// Пример: {"code":"payment_not_found","message":"Payment not found"}
+//
+// Fields — ошибки отдельных полей (только для ошибок проверки запроса)
+type ErrorResponse struct {
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Fields  []FieldError `json:"fields,omitempty"`
+}
+
+// ProblemDetails — тело ошибки в формате RFC 7807 (application/problem+json)
+// Пример:
+//
+//	{"type":"urn:payments:error:payment_not_found","title":"Not Found",
+//	 "status":404,"detail":"Payment not found","code":"payment_not_found"}
+//
+// Code — расширение формата: тот же машиночитаемый код, что в ErrorResponse
+type ProblemDetails struct {
+	Type   string       `json:"type"`
+	Title  string       `json:"title"`
+	Status int          `json:"status"`
+	Detail string       `json:"detail"`
+	Code   string       `json:"code"`
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+// problemTypePrefix — префикс URI типа ошибки в ProblemDetails.Type
+const problemTypePrefix = "urn:payments:error:"
+
+// writeError отправляет ошибку в формате JSON
+//
+// Все обработчики сообщают об ошибках только через эту функцию,
+// поэтому формат ошибок одинаковый во всем API
+//
+// Формат выбирается по заголовку Accept запроса:
+// - application/problem+json = ProblemDetails (RFC 7807)
+// - иначе = ErrorResponse (формат по умолчанию)
+func writeError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	// Внутренняя ошибка из-за истекшего дедлайна запроса (Config.RequestTimeout)
+	// — не сбой сервера: клиенту честнее ответить 503 и предложить повтор
+	if status == http.StatusInternalServerError && errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+		status, code, message = http.StatusServiceUnavailable, CodeRequestTimeout, "Request exceeded the server deadline"
+	}
+	writeErrorResponse(w, r, status, ErrorResponse{Code: code, Message: message})
+}
+
+// writeMethodNotAllowed отвечает 405 с заголовком Allow
+//
+// По RFC 9110 ответ 405 ОБЯЗАН перечислить разрешенные методы
+// в заголовке Allow, например "Allow: POST, GET" — клиент узнает,
+// как правильно обратиться к ресурсу
+func writeMethodNotAllowed(w http.ResponseWriter, r *http.Request, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, r, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Invalid method")
+}
+
+// writeFieldErrors отвечает 400 со списком ошибок полей
+//
+// Одна ошибка — ее текст становится текстом ответа, а код — прежним
+// кодом ответа для этой ошибки (см. fieldErrorCodes), как до появления
+// списка; несколько — общий код validation_failed
+// Коды самих полей ("amount.too_small") всегда в fields
+func writeFieldErrors(w http.ResponseWriter, r *http.Request, fields []FieldError) {
+	resp := ErrorResponse{
+		Code:    CodeValidationFailed,
+		Message: fmt.Sprintf("Request has %d invalid fields", len(fields)),
+		Fields:  fields,
+	}
+	if len(fields) == 1 {
+		resp.Message = fields[0].Message
+		if code, ok := fieldErrorCodes[fields[0].Code]; ok {
+			resp.Code = code
+		}
+	}
+	writeErrorResponse(w, r, http.StatusBadRequest, resp)
+}
+
+// writeDecodeError отвечает 400 на ошибку разбора JSON тела запроса
+//
+// Пустое тело (Decode вернул io.EOF) — отдельная ошибка body_required:
+// "Invalid JSON" на пустой запрос сбивает с толку, ведь JSON там просто нет
+func writeDecodeError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, io.EOF) {
+		writeError(w, r, http.StatusBadRequest, CodeBodyRequired, "request body is required")
+		return
+	}
+	log.Printf("Error decoding JSON: %v", err)
+	writeError(w, r, http.StatusBadRequest, CodeInvalidJSON, "Invalid JSON")
+}
+
+// writeErrorResponse отправляет ошибку в формате, выбранном по Accept
+func writeErrorResponse(w http.ResponseWriter, r *http.Request, status int, resp ErrorResponse) {
+	// nosniff запрещает браузеру "угадывать" тип содержимого
+	// (http.Error ставит этот заголовок по той же причине)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if negotiate(r.Header.Get("Accept"), mediaJSON, mediaProblem) == mediaProblem {
+		w.Header().Set("Content-Type", mediaProblem)
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(ProblemDetails{
+			Type:   problemTypePrefix + resp.Code,
+			Title:  http.StatusText(status),
+			Status: status,
+			Detail: resp.Message,
+			Code:   resp.Code,
+			Fields: resp.Fields,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ===== ПРОВЕРКА ID =====
+
+// Обязательные префиксы ID: по префиксу сразу видно тип объекта
+const (
+	paymentIDPrefix = "pay_"
+	refundIDPrefix  = "re_"
+)
+
+// maxIDLength — максимальная длина ID (защита от мусора в URL)
+const maxIDLength = 64
+
+// isValidPaymentID проверяет формат ID платежа
+//
+// Корректный ID: префикс "pay_" + от 1 символа из [A-Za-z0-9_-]
+// Проверка формата позволяет отличить "ID кривой" (400 invalid_id)
+// от "ID правильный, но платежа нет" (404 payment_not_found)
+func isValidPaymentID(id string) bool {
+	return isValidID(id, paymentIDPrefix)
+}
+
+// isValidRefundID проверяет формат ID возврата ("re_…")
+func isValidRefundID(id string) bool {
+	return isValidID(id, refundIDPrefix)
+}
+
+// isValidID проверяет ID вида "<prefix><[A-Za-z0-9_-]+>"
+func isValidID(id, prefix string) bool {
+	rest, ok := strings.CutPrefix(id, prefix)
+	if !ok || rest == "" || len(id) > maxIDLength {
+		return false
+	}
+	for _, c := range rest {
+		isLetter := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		isDigit := c >= '0' && c <= '9'
+		if !isLetter && !isDigit && c != '-' && c != '_' {
+			return false
+		}
+	}
+	return true
+}
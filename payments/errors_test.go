@@ -0,0 +1,110 @@
+package payments
+
+import (
+	"net/http"
+	"testing"
+)
+
+// ===== ФОРМАТ ОШИБОК =====
+
+func TestErrorDefaultFormat(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	rec := doJSON(t, s, http.MethodGet, "/payments/pay_missing", "", nil)
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("Content-Type = %s", ct)
+	}
+	var resp ErrorResponse
+	decodeBody(t, rec, &resp)
+	if rec.Code != http.StatusNotFound || resp.Code != CodePaymentNotFound || resp.Message == "" {
+		t.Fatalf("%d %+v", rec.Code, resp)
+	}
+}
+
+func TestErrorProblemDetails(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	rec := doJSON(t, s, http.MethodGet, "/payments/pay_missing", "", map[string]string{"Accept": mediaProblem})
+	if ct := rec.Header().Get("Content-Type"); ct != mediaProblem {
+		t.Fatalf("Content-Type = %s", ct)
+	}
+	var problem ProblemDetails
+	decodeBody(t, rec, &problem)
+	want := ProblemDetails{
+		Type:   problemTypePrefix + CodePaymentNotFound,
+		Title:  "Not Found",
+		Status: http.StatusNotFound,
+		Detail: "Payment not found",
+		Code:   CodePaymentNotFound,
+	}
+	if problem.Type != want.Type || problem.Title != want.Title || problem.Status != want.Status ||
+		problem.Detail != want.Detail || problem.Code != want.Code {
+		t.Fatalf("problem = %+v, want %+v", problem, want)
+	}
+
+	// Ошибки полей тоже переносятся
+	rec = doJSON(t, s, http.MethodPost, "/payments", `{"amount": -1, "currency": "XXX"}`, map[string]string{"Accept": mediaProblem})
+	decodeBody(t, rec, &problem)
+	if problem.Status != http.StatusBadRequest || len(problem.Fields) != 2 {
+		t.Fatalf("validation problem = %+v", problem)
+	}
+}
+
+// TestEmptyBodyVsMalformedJSON — пустое тело и битый JSON различимы
+func TestEmptyBodyVsMalformedJSON(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	for _, tc := range []struct {
+		name, body, code, message string
+	}{
+		{"empty", "", CodeBodyRequired, "request body is required"},
+		{"whitespace", " \n", CodeBodyRequired, "request body is required"},
+		{"malformed", `{"amount": `, CodeInvalidJSON, "Invalid JSON"},
+	} {
+		rec := doJSON(t, s, http.MethodPost, "/payments", tc.body, nil)
+		var resp ErrorResponse
+		decodeBody(t, rec, &resp)
+		if rec.Code != http.StatusBadRequest || resp.Code != tc.code || resp.Message != tc.message {
+			t.Errorf("%s: %d %+v", tc.name, rec.Code, resp)
+		}
+	}
+}
+
+// TestMethodNotAllowedAllowHeader — 405 перечисляет разрешенные методы маршрута
+func TestMethodNotAllowedAllowHeader(t *testing.T) {
+	features := AllFeatures
+	features.Admin = true
+	s := NewServer(NewMemoryStore(), nil, nil, Config{Features: &features})
+	for path, allow := range map[string]string{
+		"/payments":                    "POST, GET",
+		"/payments/pay_x":              "GET, PUT, PATCH, DELETE",
+		"/payments/pay_x/capture":      "POST",
+		"/payments/pay_x/cancel":       "POST",
+		"/payments/pay_x/void":         "POST",
+		"/payments/pay_x/retry":        "POST",
+		"/payments/pay_x/split":        "POST",
+		"/payments/pay_x/refunds":      "GET, POST",
+		"/payments/pay_x/refunds/re_x": "GET",
+		"/payments/pay_x/receipt":      "GET",
+		"/payments/summary":            "GET",
+		"/payments/export":             "GET",
+		"/payments/import":             "POST",
+		"/payments/reconcile":          "POST",
+		"/payments/bulk-status":        "POST",
+		"/webhooks/gateway":            "POST",
+		"/currencies":                  "GET",
+		"/health":                      "GET",
+		"/ready":                       "GET",
+		"/metrics":                     "GET",
+		"/openapi.json":                "GET",
+		"/customers":                   "POST",
+		"/customers/cus_x/payments":    "GET",
+		"/admin/payments/pay_x/status": "POST",
+	} {
+		rec := doJSON(t, s, "TRACE", path, "", nil)
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("%s: status = %d, want 405", path, rec.Code)
+			continue
+		}
+		if got := rec.Header().Get("Allow"); got != allow {
+			t.Errorf("%s: Allow = %q, want %q", path, got, allow)
+		}
+	}
+}
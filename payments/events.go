@@ -0,0 +1,247 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ===== ПОДПИСКА НА ИЗМЕНЕНИЯ СТАТУСА =====
+
+// statusBroker рассылает изменения статусов платежей подписчикам
+//
+// Для каждого ID платежа хранится множество каналов-подписчиков
+// Код, меняющий статус, вызывает publish, и каждый подписчик
+// получает новую версию платежа в свой канал
+type statusBroker struct {
+	mu   sync.Mutex
+	subs map[string]map[chan Payment]struct{}
+}
+
+// subscriberBuffer — размер буфера канала подписчика
+// Буфер нужен, чтобы publish не ждал медленного клиента
+const subscriberBuffer = 16
+
+func newStatusBroker() *statusBroker {
+	return &statusBroker{subs: make(map[string]map[chan Payment]struct{})}
+}
+
+// subscribe подписывается на изменения платежа id
+// Возвращает канал событий и функцию отписки
+// Отписку ОБЯЗАТЕЛЬНО вызвать (обычно через defer), иначе канал
+// останется в брокере навсегда (утечка памяти)
+func (b *statusBroker) subscribe(id string) (<-chan Payment, func()) {
+	ch := make(chan Payment, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subs[id] == nil {
+		b.subs[id] = make(map[chan Payment]struct{})
+	}
+	b.subs[id][ch] = struct{}{}
+	b.mu.Unlock()
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs[id], ch)
+		if len(b.subs[id]) == 0 {
+			delete(b.subs, id)
+		}
+	}
+	return ch, unsubscribe
+}
+
+// publish отправляет новую версию платежа всем его подписчикам
+// Отправка неблокирующая: если буфер подписчика полон,
+// событие для него пропускается (клиент все равно получит
+// следующее изменение с актуальным состоянием)
+func (b *statusBroker) publish(p Payment) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs[p.ID] {
+		// select с default = "отправить, если можно, иначе не ждать"
+		select {
+		case ch <- p:
+		default:
+			log.Printf("Dropping status event for slow subscriber: ID=%s", p.ID)
+		}
+	}
+}
+
+// publishStatus сообщает об изменении платежа всем заинтересованным:
+// подписчикам потока /payments/{id}/stream и (если включен) outbox
+// для доставки уведомления по вебхуку
+func (s *Server) publishStatus(ctx context.Context, p Payment) {
+	s.events.publish(p)
+	if s.outbox == nil {
+		return
+	}
+	event := OutboxEvent{
+		ID:        newEventID(),
+		Type:      EventPaymentStatus,
+		CreatedAt: time.Now().UTC(),
+		Payment:   p,
+	}
+	// Контекст запроса может отмениться сразу после ответа клиенту —
+	// событие все равно должно попасть в outbox
+	if err := s.outbox.Enqueue(context.WithoutCancel(ctx), event); err != nil {
+		log.Printf("Error enqueuing outbox event: ID=%s, Error=%v", p.ID, err)
+	}
+}
+
+// isTerminalStatus — статус конечный, дальше меняться не будет
+func isTerminalStatus(status string) bool {
+	return len(allowedTransitions[status]) == 0
+}
+
+// ===== LONG POLLING =====
+
+// maxLongPollWait — наибольшее ожидание в GET /payments/{id}?wait=
+// Дольше держать соединение бессмысленно: прокси и балансировщики
+// обычно обрывают простаивающие запросы через 60 секунд
+const maxLongPollWait = 60 * time.Second
+
+// longPollWriteSlack — запас дедлайна записи сверх ожидания
+// (на чтение платежа и отправку ответа)
+const longPollWriteSlack = 10 * time.Second
+
+// parseWait читает параметр ?wait=30s (формат time.ParseDuration)
+// 0 = не ждать (обычный GET)
+func parseWait(query url.Values) (time.Duration, error) {
+	raw := query.Get("wait")
+	if raw == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < 0 || d > maxLongPollWait {
+		return 0, fmt.Errorf("wait must be a duration between 0s and %s, like 30s", maxLongPollWait)
+	}
+	return d, nil
+}
+
+// awaitStatusChange ждет, пока статус платежа станет отличным от p.Status,
+// но не дольше timeout. Возвращает последнее известное состояние платежа
+//
+// events — канал подписки statusBroker (тот же механизм, что у SSE)
+// Ошибка — только если ctx отменен (клиент отключился)
+func awaitStatusChange(ctx context.Context, events <-chan Payment, p Payment, timeout time.Duration) (Payment, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return p, ctx.Err()
+		case <-timer.C:
+			return p, nil
+		case next := <-events:
+			// Старые версии пропускаем (см. handlePaymentStream)
+			if next.Version <= p.Version {
+				continue
+			}
+			changed := next.Status != p.Status
+			p = next
+			if changed {
+				return p, nil
+			}
+		}
+	}
+}
+
+// ===== SERVER-SENT EVENTS =====
+
+// handlePaymentStream отдает изменения статуса платежа потоком SSE
+// GET /payments/{id}/stream
+//
+// SERVER-SENT EVENTS (SSE):
+// Соединение не закрывается после ответа — сервер дописывает в него
+// события по мере появления. Формат события:
+//
+//	event: status
+//	data: {"id":"pay_…","status":"succeeded",…}
+//	(пустая строка = конец события)
+//
+// Первое событие — текущее состояние платежа. Поток закрывается,
+// когда платеж доходит до конечного статуса или клиент отключается
+func (s *Server) handlePaymentStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	id := r.PathValue("id")
+	if !isValidPaymentID(id) {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidID, "Invalid payment ID: must start with "+paymentIDPrefix)
+		return
+	}
+
+	// Подписываемся ДО чтения текущего состояния: иначе изменение,
+	// случившееся между чтением и подпиской, потерялось бы
+	events, unsubscribe := s.events.subscribe(id)
+	defer unsubscribe()
+
+	payment, err := s.store.Get(r.Context(), id)
+	if errors.Is(err, ErrPaymentNotFound) {
+		writeError(w, r, http.StatusNotFound, CodePaymentNotFound, "Payment not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Error loading payment: %v", err)
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+		return
+	}
+
+	// ResponseController дает доступ к Flush: без него данные
+	// копились бы в буфере и не доходили до клиента сразу
+	rc := http.NewResponseController(w)
+
+	// Поток живет дольше WriteTimeout сервера — снимаем дедлайн записи
+	// для этого соединения (нулевое время = без дедлайна)
+	// Ошибку игнорируем: не все ResponseWriter поддерживают дедлайны
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	if err := writeStatusEvent(w, rc, payment); err != nil || isTerminalStatus(payment.Status) {
+		return
+	}
+
+	for {
+		select {
+		// Клиент отключился (или сервер останавливается) —
+		// выходим, defer отпишет канал, горутина не утечет
+		case <-r.Context().Done():
+			return
+		case p := <-events:
+			// События могут прийти не по порядку версий, если клиент
+			// уже получил более свежее состояние — старое пропускаем
+			if p.Version <= payment.Version {
+				continue
+			}
+			payment = p
+			if err := writeStatusEvent(w, rc, payment); err != nil || isTerminalStatus(payment.Status) {
+				return
+			}
+		}
+	}
+}
+
+// writeStatusEvent пишет одно SSE событие и сразу отправляет его клиенту
+func writeStatusEvent(w http.ResponseWriter, rc *http.ResponseController, p Payment) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	// id: = номер версии, клиент может узнать, какое событие видел последним
+	if _, err := fmt.Fprintf(w, "id: %d\nevent: status\ndata: %s\n\n", p.Version, data); err != nil {
+		return err
+	}
+	return rc.Flush()
+}
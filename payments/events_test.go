@@ -0,0 +1,213 @@
+package payments
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ===== SSE =====
+
+// readSSEEvent читает одно событие потока и разбирает его data
+func readSSEEvent(t *testing.T, br *bufio.Reader) Payment {
+	t.Helper()
+	var data string
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading event: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		if line == "" {
+			break
+		}
+		if v, ok := strings.CutPrefix(line, "data: "); ok {
+			data = v
+		}
+	}
+	var p Payment
+	if err := json.Unmarshal([]byte(data), &p); err != nil {
+		t.Fatalf("event data %q: %v", data, err)
+	}
+	return p
+}
+
+func TestPaymentStreamDeliversTransition(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	p := createPaymentT(t, s, `{"amount": 100, "currency": "RUB"}`)
+
+	resp, err := http.Get(ts.URL + "/payments/" + p.ID + "/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %s", ct)
+	}
+	br := bufio.NewReader(resp.Body)
+
+	if first := readSSEEvent(t, br); first.Status != StatusPending {
+		t.Fatalf("first event status = %s", first.Status)
+	}
+
+	rec := doJSON(t, s, http.MethodPatch, "/payments/"+p.ID, `{"status":"succeeded"}`, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PATCH = %d", rec.Code)
+	}
+	if next := readSSEEvent(t, br); next.Status != StatusSucceeded {
+		t.Fatalf("next event status = %s", next.Status)
+	}
+	// Конечный статус закрывает поток
+	if rest, _ := io.ReadAll(br); len(rest) != 0 {
+		t.Fatalf("stream continued after terminal status: %q", rest)
+	}
+}
+
+// Отключение клиента отписывает поток: горутина и канал не утекают
+func TestPaymentStreamUnsubscribesOnDisconnect(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	p := createPaymentT(t, s, `{"amount": 100, "currency": "RUB"}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/payments/"+p.ID+"/stream", nil).WithContext(ctx)
+	done := make(chan struct{})
+	go func() {
+		s.ServeHTTP(httptest.NewRecorder(), req)
+		close(done)
+	}()
+
+	waitFor(t, func() bool { return subscriberCount(s, p.ID) == 1 })
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("stream handler did not return after disconnect")
+	}
+	if n := subscriberCount(s, p.ID); n != 0 {
+		t.Fatalf("subscribers left: %d", n)
+	}
+}
+
+func TestStatusBrokerDropsForSlowSubscriber(t *testing.T) {
+	b := newStatusBroker()
+	events, unsubscribe := b.subscribe("pay_slow")
+	defer unsubscribe()
+
+	// publish не должен блокироваться, даже если никто не читает
+	for i := range subscriberBuffer + 5 {
+		b.publish(Payment{ID: "pay_slow", Version: i + 1})
+	}
+	if len(events) != subscriberBuffer {
+		t.Fatalf("buffered = %d, want %d", len(events), subscriberBuffer)
+	}
+}
+
+func subscriberCount(s *Server, id string) int {
+	s.events.mu.Lock()
+	defer s.events.mu.Unlock()
+	return len(s.events.subs[id])
+}
+
+// waitFor ждет, пока cond станет true (не дольше секунды)
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not reached in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// ===== LONG POLLING =====
+
+// TestLongPollUnblocksOnStatusChange — параллельная смена статуса
+// завершает ожидающий GET ?wait= сразу, не дожидаясь таймаута
+func TestLongPollUnblocksOnStatusChange(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	p := createPaymentT(t, s, `{"amount": 100, "currency": "RUB"}`)
+
+	type result struct {
+		code    int
+		payment Payment
+	}
+	done := make(chan result, 1)
+	start := time.Now()
+	go func() {
+		rec := doJSON(t, s, http.MethodGet, "/payments/"+p.ID+"?wait=10s", "", nil)
+		var got Payment
+		json.Unmarshal(rec.Body.Bytes(), &got)
+		done <- result{rec.Code, got}
+	}()
+
+	waitFor(t, func() bool { return subscriberCount(s, p.ID) == 1 })
+	if rec := doJSON(t, s, http.MethodPatch, "/payments/"+p.ID, `{"status":"succeeded"}`, nil); rec.Code != http.StatusOK {
+		t.Fatalf("PATCH = %d", rec.Code)
+	}
+
+	select {
+	case res := <-done:
+		if res.code != http.StatusOK || res.payment.Status != StatusSucceeded {
+			t.Fatalf("long poll = %d %s", res.code, res.payment.Status)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Fatalf("long poll waited %s", elapsed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("long poll not unblocked by status change")
+	}
+	if n := subscriberCount(s, p.ID); n != 0 {
+		t.Fatalf("subscribers left: %d", n)
+	}
+}
+
+// TestLongPollTimeout — без изменений по истечении wait приходит
+// текущее состояние
+func TestLongPollTimeout(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	p := createPaymentT(t, s, `{"amount": 100, "currency": "RUB"}`)
+
+	start := time.Now()
+	rec := doJSON(t, s, http.MethodGet, "/payments/"+p.ID+"?wait=50ms", "", nil)
+	var got Payment
+	decodeBody(t, rec, &got)
+	if rec.Code != http.StatusOK || got.Status != StatusPending {
+		t.Fatalf("timeout = %d %s", rec.Code, got.Status)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("returned after %s, before wait elapsed", elapsed)
+	}
+}
+
+// TestLongPollTerminalReturnsImmediately — конечный статус больше
+// не изменится, ждать нечего
+func TestLongPollTerminalReturnsImmediately(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	p := createPaymentT(t, s, `{"amount": 100, "currency": "RUB"}`)
+	doJSON(t, s, http.MethodPatch, "/payments/"+p.ID, `{"status":"canceled"}`, nil)
+
+	start := time.Now()
+	rec := doJSON(t, s, http.MethodGet, "/payments/"+p.ID+"?wait=10s", "", nil)
+	if rec.Code != http.StatusOK || time.Since(start) > time.Second {
+		t.Fatalf("terminal long poll = %d after %s", rec.Code, time.Since(start))
+	}
+}
+
+func TestLongPollInvalidWait(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	p := createPaymentT(t, s, `{"amount": 100, "currency": "RUB"}`)
+	for _, wait := range []string{"abc", "-1s", "1h"} {
+		if rec := doJSON(t, s, http.MethodGet, "/payments/"+p.ID+"?wait="+wait, "", nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("wait=%s: status = %d, want 400", wait, rec.Code)
+		}
+	}
+}
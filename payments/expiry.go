@@ -0,0 +1,72 @@
+package payments
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// ===== СРОК АВТОРИЗАЦИИ =====
+//
+// Авторизация двухшагового платежа ("capture": false) блокирует деньги
+// клиента, и держать их вечно нельзя. У платежа есть срок списания
+// (CaptureExpiresAt = создание + Config.CaptureExpiry): после него
+// списание отклоняется, а RunCaptureExpiry аннулирует платеж —
+// authorized → voided, как ручной POST /payments/{id}/void
+
+// expiryActor — "кто" аннулирует платеж в журнале аудита
+const expiryActor = "capture_expiry"
+
+// captureExpiredReason — причина аннулирования в журнале аудита
+const captureExpiredReason = "authorization expired without capture"
+
+// RunCaptureExpiry аннулирует просроченные авторизации каждые interval
+//
+// Функция блокирующая: запускайте в отдельной горутине
+//
+//	go server.RunCaptureExpiry(ctx, time.Minute)
+//
+// Работает, пока не отменен ctx (например, при остановке сервера)
+func (s *Server) RunCaptureExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if n := s.VoidExpiredAuthorizations(ctx, now); n > 0 {
+				log.Printf("Capture expiry voided %d payments", n)
+			}
+		}
+	}
+}
+
+// VoidExpiredAuthorizations аннулирует авторизованные платежи
+// с CaptureExpiresAt <= now. Возвращает, сколько платежей аннулировано
+// now передается параметром, чтобы тест мог "перевести часы"
+func (s *Server) VoidExpiredAuthorizations(ctx context.Context, now time.Time) int {
+	// Индекс сроков хранилища, а не полный список (см. due.go)
+	payments, err := s.store.ListDue(ctx, StatusAuthorized, now)
+	if err != nil {
+		log.Printf("Error listing authorized payments: %v", err)
+		return 0
+	}
+	n := 0
+	for _, p := range payments {
+		// Версия проверяется: если платеж успели списать или отменить
+		// после чтения списка, UpdateStatus вернет ошибку и мы его пропустим
+		unlock := s.paymentLocks.lock(p.ID)
+		voided, err := s.store.UpdateStatus(ctx, p.ID, StatusVoided, p.Version)
+		unlock()
+		if err != nil {
+			continue
+		}
+		log.Printf("Authorization expired: ID=%s, ExpiresAt=%s", p.ID, p.CaptureExpiresAt.Format(time.RFC3339))
+		s.audit.recordReason(expiryActor, AuditVoid, p.ID, p.Status, voided.Status, captureExpiredReason)
+		s.publishStatus(ctx, voided)
+		n++
+	}
+	return n
+}
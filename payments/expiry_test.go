@@ -0,0 +1,69 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestCaptureExpiryVoidsAfterDeadline — до срока авторизация живет,
+// после "перевода часов" за CaptureExpiresAt уборщик ее аннулирует
+func TestCaptureExpiryVoidsAfterDeadline(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		var audit bytes.Buffer
+		s := NewServer(store, nil, nil, Config{CaptureExpiry: time.Hour, AuditLog: &audit})
+		p := authorizeT(t, s)
+		if p.CaptureExpiresAt == nil {
+			t.Fatal("authorized payment has no capture_expires_at")
+		}
+		if d := p.CaptureExpiresAt.Sub(p.CreatedAt); d != time.Hour {
+			t.Fatalf("capture window = %s, want 1h", d)
+		}
+		// Обычный платеж срока списания не получает
+		if plain := createPaymentT(t, s, `{"amount": 100, "currency": "RUB"}`); plain.CaptureExpiresAt != nil {
+			t.Fatalf("pending payment has capture_expires_at %s", plain.CaptureExpiresAt)
+		}
+
+		ctx := context.Background()
+		if n := s.VoidExpiredAuthorizations(ctx, p.CaptureExpiresAt.Add(-time.Second)); n != 0 {
+			t.Fatalf("voided %d before expiry", n)
+		}
+		if n := s.VoidExpiredAuthorizations(ctx, p.CaptureExpiresAt.Add(time.Minute)); n != 1 {
+			t.Fatalf("voided %d after expiry, want 1", n)
+		}
+		got, err := store.Get(ctx, p.ID)
+		if err != nil || got.Status != StatusVoided {
+			t.Fatalf("status = %s, %v", got.Status, err)
+		}
+
+		entries := auditEntries(t, &audit)
+		last := entries[len(entries)-1]
+		if last.Action != AuditVoid || last.Actor != expiryActor || last.Reason != captureExpiredReason {
+			t.Fatalf("audit entry = %+v", last)
+		}
+
+		// Повторный проход ничего не трогает
+		if n := s.VoidExpiredAuthorizations(ctx, p.CaptureExpiresAt.Add(time.Hour)); n != 0 {
+			t.Fatalf("second pass voided %d", n)
+		}
+	})
+}
+
+// TestCaptureAfterExpiry — просроченную авторизацию, которую уборщик
+// еще не успел аннулировать, списать все равно нельзя
+func TestCaptureAfterExpiry(t *testing.T) {
+	store := NewMemoryStore()
+	s := NewServer(store, nil, nil, Config{})
+	expired := time.Now().Add(-time.Minute)
+	savePaymentT(t, store, Payment{ID: "pay_expired", AmountMinor: 100, AmountAuthorizedMinor: 100, Currency: "RUB",
+		Status: StatusAuthorized, CaptureExpiresAt: &expired, Version: 1})
+
+	rec := doJSON(t, s, http.MethodPost, "/payments/pay_expired/capture", "", nil)
+	var resp ErrorResponse
+	decodeBody(t, rec, &resp)
+	if rec.Code != http.StatusUnprocessableEntity || resp.Code != CodeAuthorizationExpired {
+		t.Fatalf("capture expired: %d %+v", rec.Code, resp)
+	}
+}
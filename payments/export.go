@@ -0,0 +1,87 @@
+package payments
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// ===== ВЫГРУЗКА В NDJSON =====
+//
+// NDJSON (newline-delimited JSON) — по одному JSON объекту на строку:
+//
+//	{"id":"pay_…","amount":100,…}
+//	{"id":"pay_…","amount":250,…}
+//
+// Потребитель (ETL, скрипт на jq) читает строку за строкой и не держит
+// в памяти весь массив, как пришлось бы с обычным JSON
+
+// exportFlushEvery — через сколько строк отправлять накопленное клиенту
+// Сбрасывать каждую строку дорого, а копить все — теряется смысл потока
+const exportFlushEvery = 100
+
+// handleExportPayments выгружает платежи потоком NDJSON
+// GET /payments/export
+//
+// Фильтры те же, что у списка (см. listFilter):
+// GET /payments/export?status=succeeded&from=2024-01-01T00:00:00Z&to=2024-02-01T00:00:00Z
+// Порядок — по времени создания, старые первыми: новые платежи
+// дописываются в конец, и выгрузку удобно продолжать с последней даты
+//
+// Коды ответа:
+// - 200 OK = поток платежей (пустой, если ничего не нашлось)
+// - 400 Bad Request = некорректный фильтр
+func (s *Server) handleExportPayments(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	query := r.URL.Query()
+	filter, err := parseListFilter(query)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidQuery, err.Error())
+		return
+	}
+
+	payments, err := s.store.List(r.Context(), query.Get("include_deleted") == "true")
+	if err != nil {
+		log.Printf("Error listing payments: %v", err)
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+		return
+	}
+	// С ключами API выгружаются только платежи ключа запроса
+	payments = filter.apply(s.scopePayments(r, payments))
+	listSort{key: sortCreatedAt}.apply(payments)
+
+	if err := writePaymentsNDJSON(w, r, payments); err != nil {
+		// Заголовки уже отправлены — остается только записать в лог
+		log.Printf("Error writing export: %v", err)
+	}
+}
+
+// writePaymentsNDJSON пишет платежи прямо в ResponseWriter, строка
+// за строкой, и периодически отправляет их клиенту (Flush)
+// json.Encoder сам добавляет "\n" после каждого объекта
+func writePaymentsNDJSON(w http.ResponseWriter, r *http.Request, payments []Payment) error {
+	w.Header().Set("Content-Type", mediaNDJSON)
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	enc := json.NewEncoder(w)
+	for i, p := range payments {
+		// Клиент отключился — дальше писать некому
+		if err := r.Context().Err(); err != nil {
+			return err
+		}
+		if err := enc.Encode(p); err != nil {
+			return err
+		}
+		if (i+1)%exportFlushEvery == 0 {
+			if err := rc.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return rc.Flush()
+}
@@ -0,0 +1,61 @@
+package payments
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// exportIDs читает поток NDJSON построчно: каждая строка — отдельный платеж
+func exportIDs(t *testing.T, rec *httptest.ResponseRecorder) []string {
+	t.Helper()
+	var ids []string
+	sc := bufio.NewScanner(rec.Body)
+	for sc.Scan() {
+		var p Payment
+		if err := json.Unmarshal(sc.Bytes(), &p); err != nil {
+			t.Fatalf("line %q is not JSON: %v", sc.Text(), err)
+		}
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
+func TestExportNDJSON(t *testing.T) {
+	s := newListServer(t)
+	rec := doJSON(t, s, http.MethodGet, "/payments/export", "", nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != mediaNDJSON {
+		t.Fatalf("export = %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !rec.Flushed {
+		t.Error("export was not flushed")
+	}
+	if n := strings.Count(rec.Body.String(), "\n"); n != 3 {
+		t.Fatalf("lines = %d, want 3", n)
+	}
+	// Старые первыми
+	if got := exportIDs(t, rec); !slices.Equal(got, []string{"pay_a", "pay_b", "pay_c"}) {
+		t.Fatalf("ids = %v", got)
+	}
+}
+
+func TestExportFilters(t *testing.T) {
+	s := newListServer(t)
+	rec := doJSON(t, s, http.MethodGet, "/payments/export?from=2024-01-01T01:00:00Z", "", nil)
+	if got := exportIDs(t, rec); !slices.Equal(got, []string{"pay_b", "pay_c"}) {
+		t.Fatalf("from filter = %v", got)
+	}
+
+	rec = doJSON(t, s, http.MethodGet, "/payments/export?status=succeeded", "", nil)
+	if rec.Code != http.StatusOK || rec.Body.Len() != 0 {
+		t.Fatalf("no matches = %d %q", rec.Code, rec.Body.String())
+	}
+
+	if rec := doJSON(t, s, http.MethodGet, "/payments/export?from=yesterday", "", nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad filter = %d", rec.Code)
+	}
+}
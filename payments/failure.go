@@ -0,0 +1,110 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+)
+
+// ===== ПРИЧИНЫ ОТКАЗА =====
+//
+// У каждого шлюза свои коды ошибок ("do_not_honor", "51", …), а клиенту
+// нужна стабильная причина, по которой можно показать понятный текст:
+// "недостаточно средств", "истек срок карты". Таблица кодов шлюза
+// в причины настраивается (Config.FailureReasons), код без записи
+// дает FailureProcessingError
+
+// Причины отказа (Payment.FailureReason)
+const (
+	FailureCardDeclined      = "card_declined"
+	FailureInsufficientFunds = "insufficient_funds"
+	FailureCardExpired       = "card_expired"
+	FailureProcessingError   = "processing_error"
+)
+
+// DefaultFailureReasons — коды ошибок шлюза (GatewayError.Code)
+// и соответствующие им причины отказа
+var DefaultFailureReasons = map[string]string{
+	"card_declined":       FailureCardDeclined,
+	"insufficient_funds":  FailureInsufficientFunds,
+	"expired_card":        FailureCardExpired,
+	"gateway_unavailable": FailureProcessingError,
+}
+
+// ParseFailureReasons разбирает дополнения к DefaultFailureReasons
+// Формат: "do_not_honor:card_declined,51:insufficient_funds"
+// (код шлюза:причина). Записи добавляются к таблице по умолчанию
+// или заменяют ее записи с тем же кодом
+func ParseFailureReasons(s string) (map[string]string, error) {
+	reasons := maps.Clone(DefaultFailureReasons)
+	if strings.TrimSpace(s) == "" {
+		return reasons, nil
+	}
+	for _, entry := range strings.Split(s, ",") {
+		code, reason, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		code, reason = strings.TrimSpace(code), strings.TrimSpace(reason)
+		if !ok || code == "" || reason == "" {
+			return nil, fmt.Errorf("invalid failure reason %q: expected gateway_code:reason", entry)
+		}
+		reasons[code] = reason
+	}
+	return reasons, nil
+}
+
+// failureReason переводит ошибку списания в причину отказа
+// Ошибка не от шлюза (например, отмена запроса) — processing_error
+func (s *Server) failureReason(err error) string {
+	var gwErr *GatewayError
+	if errors.As(err, &gwErr) {
+		return s.failureReasonForCode(gwErr.Code)
+	}
+	return FailureProcessingError
+}
+
+// failureReasonForCode переводит код ошибки шлюза в причину отказа
+// Неизвестный или пустой код — processing_error
+func (s *Server) failureReasonForCode(code string) string {
+	if reason, ok := s.failureReasons[code]; ok {
+		return reason
+	}
+	return FailureProcessingError
+}
+
+// Failure — перевод платежа в failed вместе с причиной отказа,
+// который хранилище записывает атомарно (см. apply)
+//
+// Так платеж не бывает failed без FailureReason, даже на мгновение:
+// UpdateStatus меняет только статус
+// ExpectedVersion = 0 = не проверять версию
+// Force = без проверки перехода (администратор, см. ForceStatus)
+type Failure struct {
+	ExpectedVersion int
+	Reason          string
+	Force           bool
+}
+
+// apply переводит платеж в failed с причиной f.Reason
+// Вызывается хранилищем под блокировкой (или в транзакции)
+func (f Failure) apply(p Payment) (Payment, error) {
+	if f.ExpectedVersion != 0 && p.Version != f.ExpectedVersion {
+		return p, ErrVersionMismatch
+	}
+	if !f.Force && !canTransition(p.Status, StatusFailed) {
+		return p, ErrInvalidTransition
+	}
+	p.Status = StatusFailed
+	p.FailureReason = f.Reason
+	p.Version++
+	return p, nil
+}
+
+// updateStatus меняет статус платежа; в failed — вместе с причиной
+// reason (для остальных статусов она не нужна и не записывается)
+func (s *Server) updateStatus(ctx context.Context, id, status string, expectedVersion int, reason string) (Payment, error) {
+	if status == StatusFailed {
+		return s.store.FailPayment(ctx, id, Failure{ExpectedVersion: expectedVersion, Reason: reason})
+	}
+	return s.store.UpdateStatus(ctx, id, status, expectedVersion)
+}
@@ -0,0 +1,134 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestFailureReasonForCode(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	cases := map[string]string{
+		"insufficient_funds": FailureInsufficientFunds,
+		"expired_card":       FailureCardExpired,
+		"no_such_code":       FailureProcessingError,
+		"":                   FailureProcessingError,
+	}
+	for code, want := range cases {
+		if got := s.failureReasonForCode(code); got != want {
+			t.Errorf("failureReasonForCode(%q) = %q, want %q", code, got, want)
+		}
+	}
+}
+
+func TestParseFailureReasons(t *testing.T) {
+	reasons, err := ParseFailureReasons("do_not_honor:card_declined, 51:insufficient_funds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reasons["do_not_honor"] != FailureCardDeclined || reasons["51"] != FailureInsufficientFunds {
+		t.Fatalf("custom entries missing: %v", reasons)
+	}
+	if reasons["expired_card"] != FailureCardExpired {
+		t.Fatal("default entries must stay")
+	}
+	if _, err := ParseFailureReasons("broken"); err == nil {
+		t.Fatal("entry without colon must be rejected")
+	}
+}
+
+// TestScheduledFailureHasReason — отклоненный отложенный платеж
+// получает причину отказа по таблице кодов шлюза
+func TestScheduledFailureHasReason(t *testing.T) {
+	store := NewMemoryStore()
+	s := NewServer(store, nil, declineGateway("insufficient_funds"), Config{})
+	past := time.Now().Add(-time.Minute)
+	savePaymentT(t, store, Payment{ID: "pay_sched", AmountMinor: 100, Currency: "RUB", Status: StatusScheduled, ProcessAt: &past, Version: 1})
+
+	if n := s.ProcessDueScheduled(context.Background(), time.Now()); n != 1 {
+		t.Fatalf("processed = %d, want 1", n)
+	}
+	got, _ := store.Get(context.Background(), "pay_sched")
+	if got.Status != StatusFailed || got.FailureReason != FailureInsufficientFunds {
+		t.Fatalf("status = %s, reason = %q", got.Status, got.FailureReason)
+	}
+}
+
+// TestWebhookFailureHasReason — payment.failed с кодом шлюза
+func TestWebhookFailureHasReason(t *testing.T) {
+	store := NewMemoryStore()
+	s := NewServer(store, nil, nil, Config{WebhookSecret: "secret"})
+	savePaymentT(t, store, Payment{ID: "pay_hook", AmountMinor: 100, Currency: "RUB", Status: StatusPending, Version: 1})
+
+	body := `{"id":"evt_1","type":"payment.failed","payment_id":"pay_hook","failure_code":"expired_card"}`
+	rec := doJSON(t, s, http.MethodPost, "/webhooks/gateway", body, signWebhook("secret", body))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("webhook = %d: %s", rec.Code, rec.Body.String())
+	}
+	got, _ := store.Get(context.Background(), "pay_hook")
+	if got.Status != StatusFailed || got.FailureReason != FailureCardExpired {
+		t.Fatalf("status = %s, reason = %q", got.Status, got.FailureReason)
+	}
+}
+
+// TestBulkFailureHasReason — причина массового перевода в failed
+// становится причиной отказа каждого платежа
+func TestBulkFailureHasReason(t *testing.T) {
+	store := NewMemoryStore()
+	s := NewServer(store, nil, nil, Config{AdminAPIKeys: []string{"admin-key"}})
+	savePaymentT(t, store, Payment{ID: "pay_bulk", AmountMinor: 100, Currency: "RUB", Status: StatusPending, Version: 1})
+
+	rec := doJSON(t, s, http.MethodPost, "/payments/bulk-status",
+		`{"ids":["pay_bulk"],"status":"failed","reason":"fraud_review"}`,
+		map[string]string{"X-API-Key": "admin-key"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("bulk-status = %d: %s", rec.Code, rec.Body.String())
+	}
+	got, _ := store.Get(context.Background(), "pay_bulk")
+	if got.Status != StatusFailed || got.FailureReason != "fraud_review" {
+		t.Fatalf("status = %s, reason = %q", got.Status, got.FailureReason)
+	}
+}
+
+// TestPatchFailedHasReason — ручной перевод в failed через PATCH
+// получает общую причину отказа: failed без причины не бывает
+func TestPatchFailedHasReason(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		s := NewServer(store, nil, nil, Config{})
+		savePaymentT(t, store, Payment{ID: "pay_patch", AmountMinor: 100, Currency: "RUB", Status: StatusPending, Version: 1})
+
+		rec := doJSON(t, s, http.MethodPatch, "/payments/pay_patch", `{"status": "failed"}`, nil)
+		var got Payment
+		decodeBody(t, rec, &got)
+		if rec.Code != http.StatusOK || got.Status != StatusFailed || got.FailureReason != FailureProcessingError {
+			t.Fatalf("PATCH = %d %s, reason = %q", rec.Code, got.Status, got.FailureReason)
+		}
+	})
+}
+
+// TestAdminForceFailedHasReason — администратор переводит в failed
+// в обход правил переходов, причина администратора становится
+// причиной отказа; уход из failed причину стирает
+func TestAdminForceFailedHasReason(t *testing.T) {
+	var audit bytes.Buffer
+	s, store := newAdminServer(t, &audit)
+
+	rec := doJSON(t, s, http.MethodPost, "/admin/payments/pay_stuck/status",
+		`{"status": "failed", "reason": "chargeback"}`, withKey("admin"))
+	var got Payment
+	decodeBody(t, rec, &got)
+	if rec.Code != http.StatusOK || got.Status != StatusFailed || got.FailureReason != "chargeback" {
+		t.Fatalf("force failed = %d %s, reason = %q", rec.Code, got.Status, got.FailureReason)
+	}
+
+	rec = doJSON(t, s, http.MethodPost, "/admin/payments/pay_stuck/status",
+		`{"status": "pending", "reason": "chargeback reversed"}`, withKey("admin"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("force pending = %d: %s", rec.Code, rec.Body.String())
+	}
+	if p, _ := store.Get(context.Background(), "pay_stuck"); p.Status != StatusPending || p.FailureReason != "" {
+		t.Fatalf("after force pending: %s, reason = %q", p.Status, p.FailureReason)
+	}
+}
@@ -0,0 +1,48 @@
+package payments
+
+// ===== ФЛАГИ ВОЗМОЖНОСТЕЙ =====
+//
+// Группы эндпоинтов можно выключить для конкретной инсталляции
+// (Config.Features): выключенный маршрут просто не регистрируется
+// и отвечает 404, как будто его нет. Так новую возможность можно
+// выкатить "в темную" и включить позже без перекомпиляции
+//
+// Основные операции с платежами (создание, чтение, статус, отмена)
+// выключить нельзя — без них API не имеет смысла
+
+// Features — какие группы эндпоинтов включены
+type Features struct {
+	Refunds   bool // /payments/{id}/refunds…
+	Capture   bool // /payments/{id}/capture и /void (двухшаговые платежи)
+	Receipts  bool // /payments/{id}/receipt
+	Stream    bool // /payments/{id}/stream
+	Retry     bool // /payments/{id}/retry
+	Split     bool // /payments/{id}/split
+	Export    bool // /payments/export
+	Import    bool // /payments/import
+	Reconcile bool // /payments/reconcile
+	Customers bool // /customers…
+	Webhooks  bool // /webhooks/gateway
+	Index     bool // GET / — оглавление API (см. index.go)
+
+	// Admin — служебные эндпоинты поддержки (/admin/…), только
+	// с ключом администратора. Выключены, пока их не включат явно
+	Admin bool
+}
+
+// AllFeatures — все обычные группы включены (Config.Features == nil)
+// Служебные эндпоинты (Admin) в "все" не входят
+var AllFeatures = Features{
+	Refunds:   true,
+	Capture:   true,
+	Receipts:  true,
+	Stream:    true,
+	Retry:     true,
+	Split:     true,
+	Export:    true,
+	Import:    true,
+	Reconcile: true,
+	Customers: true,
+	Webhooks:  true,
+	Index:     true,
+}
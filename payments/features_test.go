@@ -0,0 +1,47 @@
+package payments
+
+import (
+	"net/http"
+	"testing"
+)
+
+// TestDisabledFeatureReturns404 — выключенная группа отвечает 404
+// not_found, как несуществующий адрес, а не 400 от /payments/{id}
+func TestDisabledFeatureReturns404(t *testing.T) {
+	features := AllFeatures
+	features.Refunds = false
+	features.Export = false
+	s := NewServer(NewMemoryStore(), nil, nil, Config{Features: &features})
+	p := createPaymentT(t, s, `{"amount": 100, "currency": "RUB"}`)
+
+	for _, path := range []string{"/payments/" + p.ID + "/refunds", "/payments/export"} {
+		rec := doJSON(t, s, http.MethodGet, path, "", nil)
+		var resp ErrorResponse
+		decodeBody(t, rec, &resp)
+		if rec.Code != http.StatusNotFound || resp.Code != CodeNotFound {
+			t.Errorf("%s: %d %+v, want 404 not_found", path, rec.Code, resp)
+		}
+	}
+
+	// Остальные группы и основные операции на месте (чек для pending
+	// дает 409, но не 404)
+	if rec := doJSON(t, s, http.MethodGet, "/payments/"+p.ID+"/receipt", "", nil); rec.Code == http.StatusNotFound {
+		t.Errorf("receipt = %d", rec.Code)
+	}
+	if rec := doJSON(t, s, http.MethodGet, "/payments/"+p.ID, "", nil); rec.Code != http.StatusOK {
+		t.Errorf("get = %d", rec.Code)
+	}
+}
+
+// TestFeaturesDefault — без Config.Features все обычные группы
+// включены, а служебные (Admin) — нет
+func TestFeaturesDefault(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	p := createPaymentT(t, s, `{"amount": 100, "currency": "RUB"}`)
+	if rec := doJSON(t, s, http.MethodGet, "/payments/"+p.ID+"/refunds", "", nil); rec.Code != http.StatusOK {
+		t.Errorf("refunds = %d", rec.Code)
+	}
+	if rec := doJSON(t, s, http.MethodPost, "/admin/payments/"+p.ID+"/status", `{"status":"failed"}`, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("admin = %d, want 404", rec.Code)
+	}
+}
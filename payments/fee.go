@@ -0,0 +1,59 @@
+package payments
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ===== КОМИССИИ =====
+
+// Fee — комиссия процессинга для валюты: процент от суммы плюс фикс
+// Пример: 2.9% + 30 центов = Fee{BasisPoints: 290, FixedMinor: 30}
+//
+// Процент хранится в базисных пунктах (1 б.п. = 0.01%), чтобы
+// считать комиссию в целых числах без ошибок округления float64
+type Fee struct {
+	BasisPoints int64
+	FixedMinor  int64
+}
+
+// calculate возвращает комиссию с суммы amountMinor
+// Комиссия не может быть больше самой суммы (иначе "к зачислению"
+// получилось бы отрицательным)
+func (f Fee) calculate(amountMinor int64, mode RoundingMode) int64 {
+	fee := mode.divRound(amountMinor*f.BasisPoints, 10_000) + f.FixedMinor
+	return min(fee, amountMinor)
+}
+
+// ParseFees разбирает комиссии из строки конфигурации
+// Формат: "RUB:2.9%+30,USD:2.9%+30,JPY:3.6%" (фикс в минорных единицах,
+// необязателен)
+func ParseFees(s string) (map[string]Fee, error) {
+	fees := make(map[string]Fee)
+	if strings.TrimSpace(s) == "" {
+		return fees, nil
+	}
+	for _, entry := range strings.Split(s, ",") {
+		code, rule, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if !ok || !isCurrencyCode(code) {
+			return nil, fmt.Errorf("invalid fee entry %q: expected CUR:percent%%+fixed", entry)
+		}
+		percent, fixed, hasFixed := strings.Cut(rule, "+")
+		p, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(percent), "%"), 64)
+		if err != nil || p < 0 || p > 100 {
+			return nil, fmt.Errorf("invalid fee percent in %q: expected 0-100", entry)
+		}
+		fee := Fee{BasisPoints: int64(math.Round(p * 100))}
+		if hasFixed {
+			fee.FixedMinor, err = strconv.ParseInt(strings.TrimSpace(fixed), 10, 64)
+			if err != nil || fee.FixedMinor < 0 {
+				return nil, fmt.Errorf("invalid fixed fee in %q: expected non-negative minor units", entry)
+			}
+		}
+		fees[code] = fee
+	}
+	return fees, nil
+}
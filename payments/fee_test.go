@@ -0,0 +1,62 @@
+package payments
+
+import (
+	"net/http"
+	"testing"
+)
+
+// TestFeeCalculate — 2.9% + 30 с 10000 = 290 + 30 = 320
+func TestFeeCalculate(t *testing.T) {
+	fee := Fee{BasisPoints: 290, FixedMinor: 30}
+	for _, tc := range []struct {
+		amount int64
+		mode   RoundingMode
+		want   int64
+	}{
+		{10000, RoundHalfEven, 320},
+		{2500, RoundHalfEven, 102}, // 72.5 → 72, + 30
+		{2500, RoundHalfUp, 103},   // 72.5 → 73, + 30
+		{10, RoundHalfEven, 10},    // комиссия не больше суммы
+	} {
+		if got := fee.calculate(tc.amount, tc.mode); got != tc.want {
+			t.Errorf("calculate(%d, %s) = %d, want %d", tc.amount, tc.mode, got, tc.want)
+		}
+	}
+	if got := (Fee{}).calculate(10000, RoundHalfEven); got != 0 {
+		t.Errorf("zero fee = %d", got)
+	}
+}
+
+func TestParseFees(t *testing.T) {
+	fees, err := ParseFees("rub:2.9%+30, JPY:3.6%")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fees["RUB"] != (Fee{BasisPoints: 290, FixedMinor: 30}) || fees["JPY"] != (Fee{BasisPoints: 360}) {
+		t.Fatalf("fees = %+v", fees)
+	}
+	for _, bad := range []string{"RUB", "RUB:abc", "RUB:101%", "RUB:1%+-5", "RUBL:1%"} {
+		if _, err := ParseFees(bad); err == nil {
+			t.Errorf("ParseFees(%q) succeeded", bad)
+		}
+	}
+}
+
+// TestCreatePaymentFee — комиссия и сумма к зачислению в ответе
+func TestCreatePaymentFee(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{Fees: map[string]Fee{"USD": {BasisPoints: 290, FixedMinor: 30}}})
+	var got struct {
+		FeeMinor       int64 `json:"fee_minor"`
+		NetAmountMinor int64 `json:"net_amount_minor"`
+	}
+	decodeBody(t, doJSON(t, s, http.MethodPost, "/payments", `{"amount": 100, "currency": "USD"}`, nil), &got)
+	if got.FeeMinor != 320 || got.NetAmountMinor != 9680 {
+		t.Fatalf("fee = %d, net = %d, want 320 / 9680", got.FeeMinor, got.NetAmountMinor)
+	}
+
+	// Валюта без настроенной комиссии — без комиссии
+	decodeBody(t, doJSON(t, s, http.MethodPost, "/payments", `{"amount": 100, "currency": "RUB"}`, nil), &got)
+	if got.FeeMinor != 0 || got.NetAmountMinor != 10000 {
+		t.Fatalf("RUB fee = %d, net = %d", got.FeeMinor, got.NetAmountMinor)
+	}
+}
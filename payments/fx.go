@@ -0,0 +1,106 @@
+package payments
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ===== КОНВЕРТАЦИЯ ВАЛЮТ (FX) =====
+
+// FXProvider — источник курсов валют
+//
+// ЧТО ТАКОЕ ИНТЕРФЕЙС:
+// Интерфейс описывает ЧТО умеет тип, но не КАК
+// Любой тип с методом Rate(from, to string) (float64, error)
+// автоматически реализует FXProvider (явно "implements" писать не нужно)
+//
+// Сейчас есть статическая реализация из конфига,
+// в продакшене можно подключить API банка или биржи, не меняя обработчики
+type FXProvider interface {
+	// Rate возвращает курс: сколько единиц валюты to дают за 1 единицу from
+	Rate(from, to string) (float64, error)
+}
+
+// ErrUnsupportedPair — курс для пары валют неизвестен
+// Ошибки-"сигналы" принято объявлять как переменные с префиксом Err,
+// чтобы вызывающий код мог проверить их через errors.Is
+var ErrUnsupportedPair = errors.New("unsupported currency pair")
+
+// StaticFXProvider — провайдер с фиксированными курсами из конфигурации
+// Ключ map = "FROM/TO", например "USD/RUB"
+type StaticFXProvider struct {
+	rates map[string]float64
+}
+
+// NewStaticFXProvider создает провайдер из набора курсов
+// Обратный курс (RUB/USD) вычисляется автоматически, если не задан явно
+func NewStaticFXProvider(rates map[string]float64) *StaticFXProvider {
+	all := make(map[string]float64, len(rates)*2)
+	for pair, rate := range rates {
+		all[pair] = rate
+	}
+	for pair, rate := range rates {
+		from, to, _ := strings.Cut(pair, "/")
+		inverse := to + "/" + from
+		if _, ok := all[inverse]; !ok {
+			all[inverse] = 1 / rate
+		}
+	}
+	return &StaticFXProvider{rates: all}
+}
+
+// Rate реализует интерфейс FXProvider
+func (p *StaticFXProvider) Rate(from, to string) (float64, error) {
+	// Конвертация в ту же валюту — курс всегда 1
+	if from == to {
+		return 1, nil
+	}
+	rate, ok := p.rates[from+"/"+to]
+	if !ok {
+		// %w "заворачивает" ошибку: errors.Is(err, ErrUnsupportedPair) == true
+		return 0, fmt.Errorf("%w: %s/%s", ErrUnsupportedPair, from, to)
+	}
+	return rate, nil
+}
+
+// ParseFXRates разбирает курсы из строки конфигурации
+//
+// Формат: "USD/RUB=92.5,EUR/RUB=100.1"
+// Пустая строка = нет курсов (любая конвертация кроме X→X вернет ошибку)
+func ParseFXRates(s string) (map[string]float64, error) {
+	rates := make(map[string]float64)
+	if strings.TrimSpace(s) == "" {
+		return rates, nil
+	}
+
+	for _, entry := range strings.Split(s, ",") {
+		pair, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid FX rate entry %q: expected FROM/TO=RATE", entry)
+		}
+		from, to, ok := strings.Cut(pair, "/")
+		if !ok || len(from) != 3 || len(to) != 3 {
+			return nil, fmt.Errorf("invalid FX pair %q: expected FROM/TO", pair)
+		}
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate <= 0 || math.IsInf(rate, 0) {
+			return nil, fmt.Errorf("invalid FX rate %q for %s", value, pair)
+		}
+		rates[strings.ToUpper(from)+"/"+strings.ToUpper(to)] = rate
+	}
+	return rates, nil
+}
+
+// convertToMinor конвертирует сумму из одной валюты в минорные единицы другой
+// Возвращает также примененный курс (сохраняется в платеже для аудита)
+// mode = режим округления для валюты to
+func convertToMinor(fx FXProvider, amount float64, from, to string, mode RoundingMode) (int64, float64, error) {
+	rate, err := fx.Rate(from, to)
+	if err != nil {
+		return 0, 0, err
+	}
+	return toMinorUnits(amount*rate, to, mode), rate, nil
+}
@@ -0,0 +1,168 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// ===== ПЛАТЕЖНЫЙ ШЛЮЗ =====
+
+// PaymentGateway — внешний платежный шлюз (Stripe, CloudPayments и т.д.)
+//
+// Charge списывает деньги по платежу
+// nil = списание прошло успешно, ошибка = платеж не прошел
+// context.Context передает дедлайн и отмену запроса: если клиент
+// отключился, шлюзу не нужно продолжать работу
+type PaymentGateway interface {
+	Charge(ctx context.Context, p Payment) error
+}
+
+// GatewayError — ошибка, которую вернул шлюз
+//
+// Retryable показывает, есть ли смысл повторять запрос:
+// - сбой сети или таймаут шлюза — временная ошибка, повтор может помочь
+// - карта отклонена — повтор даст тот же результат
+type GatewayError struct {
+	Code      string // машиночитаемый код шлюза, например "card_declined"
+	Message   string
+	Retryable bool
+}
+
+// Error реализует интерфейс error
+func (e *GatewayError) Error() string {
+	return fmt.Sprintf("gateway error %s: %s", e.Code, e.Message)
+}
+
+// Типовые ошибки шлюза
+var (
+	ErrCardDeclined       = &GatewayError{Code: "card_declined", Message: "card was declined"}
+	ErrInsufficientFunds  = &GatewayError{Code: "insufficient_funds", Message: "insufficient funds"}
+	ErrCardExpired        = &GatewayError{Code: "expired_card", Message: "card has expired"}
+	ErrGatewayUnavailable = &GatewayError{Code: "gateway_unavailable", Message: "gateway is temporarily unavailable", Retryable: true}
+)
+
+// IsRetryable сообщает, стоит ли повторить запрос к шлюзу
+// errors.As ищет в цепочке ошибок значение нужного типа
+func IsRetryable(err error) bool {
+	var gwErr *GatewayError
+	if errors.As(err, &gwErr) {
+		return gwErr.Retryable
+	}
+	return false
+}
+
+// ===== ТЕСТОВЫЙ ШЛЮЗ =====
+
+// MockGateway — заглушка шлюза для разработки
+// Никуда не ходит по сети; отклоняет платежи дороже DeclineAbove,
+// чтобы можно было проверить путь "платеж не прошел"
+type MockGateway struct {
+	// DeclineAbove — суммы строго больше этой отклоняются (0 = без лимита)
+	DeclineAbove float64
+
+	// TestMode включает управление исходом через метаданные платежа:
+	// metadata.test_outcome = "fail" — платеж отклоняется при любой сумме,
+	// "insufficient_funds" и "expired_card" — отклоняется с этим кодом
+	// (см. testOutcomes). Без TestMode подсказка игнорируется (клиент не должен влиять
+	// на результат списания в рабочем режиме)
+	TestMode bool
+}
+
+// testOutcomeKey — ключ метаданных с желаемым исходом (только TestMode)
+const testOutcomeKey = "test_outcome"
+
+// testOutcomes — исходы, которые можно заказать через test_outcome
+var testOutcomes = map[string]error{
+	"fail":               ErrCardDeclined,
+	"insufficient_funds": ErrInsufficientFunds,
+	"expired_card":       ErrCardExpired,
+}
+
+// Charge реализует PaymentGateway
+func (g *MockGateway) Charge(ctx context.Context, p Payment) error {
+	// Уважаем отмену запроса даже в заглушке
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err, ok := testOutcomes[p.Metadata[testOutcomeKey]]; g.TestMode && ok {
+		return err
+	}
+	if g.DeclineAbove > 0 && p.Amount > g.DeclineAbove {
+		return ErrCardDeclined
+	}
+	return nil
+}
+
+// ===== ПОВТОРЫ (RETRY) =====
+
+// RetryingGateway — обертка (декоратор), повторяющая Charge
+// при временных ошибках шлюза
+//
+// ПАТТЕРН "ДЕКОРАТОР":
+// RetryingGateway сам реализует PaymentGateway и внутри вызывает
+// другой PaymentGateway. Обработчики не знают о повторах —
+// им передают обертку вместо "голого" шлюза
+//
+// ЭКСПОНЕНЦИАЛЬНАЯ ЗАДЕРЖКА С ДЖИТТЕРОМ:
+// Перед повтором N ждем BaseDelay * 2^N (100ms, 200ms, 400ms...),
+// причем случайную часть этого времени (jitter), чтобы тысячи клиентов
+// после сбоя не ударили по шлюзу одновременно
+type RetryingGateway struct {
+	gateway    PaymentGateway
+	maxRetries int
+	baseDelay  time.Duration
+}
+
+// NewRetryingGateway оборачивает шлюз повторами
+// maxRetries = сколько раз повторить ПОСЛЕ первой попытки (0 = без повторов)
+func NewRetryingGateway(gateway PaymentGateway, maxRetries int, baseDelay time.Duration) *RetryingGateway {
+	return &RetryingGateway{
+		gateway:    gateway,
+		maxRetries: maxRetries,
+		baseDelay:  baseDelay,
+	}
+}
+
+// Charge реализует PaymentGateway
+func (g *RetryingGateway) Charge(ctx context.Context, p Payment) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = g.gateway.Charge(ctx, p)
+		// Успех или "окончательная" ошибка (например, карта отклонена) —
+		// повторять бессмысленно
+		if err == nil || !IsRetryable(err) || attempt >= g.maxRetries {
+			return err
+		}
+
+		delay := g.backoff(attempt)
+
+		// Если дедлайн запроса наступит раньше, чем закончится ожидание,
+		// повтор все равно не успеет — возвращаем последнюю ошибку сразу
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return err
+		}
+
+		// select ждет первое из событий: таймер или отмену контекста
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff вычисляет задержку перед повтором номер attempt (с нуля)
+// Результат — случайное значение в [d/2, d], где d = baseDelay * 2^attempt
+func (g *RetryingGateway) backoff(attempt int) time.Duration {
+	d := g.baseDelay << attempt // << = умножение на 2^attempt
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + rand.N(half+1)
+}
@@ -0,0 +1,126 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// flakyGateway отвечает ошибками из errs по очереди, потом успехом
+// и считает вызовы
+type flakyGateway struct {
+	errs  []error
+	calls int
+}
+
+func (g *flakyGateway) Charge(context.Context, Payment) error {
+	g.calls++
+	if g.calls <= len(g.errs) {
+		return g.errs[g.calls-1]
+	}
+	return nil
+}
+
+func TestRetryingGatewayRetriesTemporaryErrors(t *testing.T) {
+	flaky := &flakyGateway{errs: []error{ErrGatewayUnavailable, ErrGatewayUnavailable}}
+	g := NewRetryingGateway(flaky, 3, time.Millisecond)
+
+	if err := g.Charge(context.Background(), Payment{}); err != nil {
+		t.Fatalf("Charge = %v, want success after retries", err)
+	}
+	if flaky.calls != 3 {
+		t.Fatalf("calls = %d, want 3", flaky.calls)
+	}
+}
+
+func TestRetryingGatewayGivesUp(t *testing.T) {
+	flaky := &flakyGateway{errs: []error{ErrGatewayUnavailable, ErrGatewayUnavailable, ErrGatewayUnavailable}}
+	g := NewRetryingGateway(flaky, 1, time.Millisecond)
+
+	if err := g.Charge(context.Background(), Payment{}); !errors.Is(err, ErrGatewayUnavailable) {
+		t.Fatalf("Charge = %v, want last gateway error", err)
+	}
+	if flaky.calls != 2 {
+		t.Fatalf("calls = %d, want 1 attempt + 1 retry", flaky.calls)
+	}
+}
+
+func TestRetryingGatewaySkipsPermanentErrors(t *testing.T) {
+	flaky := &flakyGateway{errs: []error{ErrCardDeclined}}
+	g := NewRetryingGateway(flaky, 5, time.Millisecond)
+
+	if err := g.Charge(context.Background(), Payment{}); !errors.Is(err, ErrCardDeclined) {
+		t.Fatalf("Charge = %v", err)
+	}
+	if flaky.calls != 1 {
+		t.Fatalf("declined card retried: calls = %d", flaky.calls)
+	}
+}
+
+// Дедлайн ближе задержки — повтор не успеет, ждать его незачем
+func TestRetryingGatewayRespectsDeadline(t *testing.T) {
+	flaky := &flakyGateway{errs: []error{ErrGatewayUnavailable, ErrGatewayUnavailable}}
+	g := NewRetryingGateway(flaky, 3, time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := g.Charge(ctx, Payment{}); !errors.Is(err, ErrGatewayUnavailable) {
+		t.Fatalf("Charge = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second || flaky.calls != 1 {
+		t.Fatalf("waited %s, calls = %d", elapsed, flaky.calls)
+	}
+}
+
+func TestRetryingGatewayBackoffBounds(t *testing.T) {
+	g := NewRetryingGateway(nil, 3, 100*time.Millisecond)
+	for attempt, full := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond} {
+		for range 50 {
+			if d := g.backoff(attempt); d < full/2 || d > full {
+				t.Fatalf("backoff(%d) = %s, want within [%s, %s]", attempt, d, full/2, full)
+			}
+		}
+	}
+}
+
+// TestMockGatewayTestOutcome — test_outcome управляет исходом только в TestMode
+func TestMockGatewayTestOutcome(t *testing.T) {
+	ctx := context.Background()
+	p := Payment{Amount: 1, Currency: "RUB", Metadata: map[string]string{testOutcomeKey: "fail"}}
+
+	if err := (&MockGateway{TestMode: true}).Charge(ctx, p); !errors.Is(err, ErrCardDeclined) {
+		t.Fatalf("test mode: err = %v, want card declined", err)
+	}
+	p.Metadata[testOutcomeKey] = "insufficient_funds"
+	if err := (&MockGateway{TestMode: true}).Charge(ctx, p); !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("test mode: err = %v, want insufficient funds", err)
+	}
+	p.Metadata[testOutcomeKey] = "fail"
+	if err := (&MockGateway{}).Charge(ctx, p); err != nil {
+		t.Fatalf("production mode must ignore the hint: err = %v", err)
+	}
+	p.Metadata[testOutcomeKey] = "something-else"
+	if err := (&MockGateway{TestMode: true}).Charge(ctx, p); err != nil {
+		t.Fatalf("unknown outcome must be ignored: err = %v", err)
+	}
+}
+
+// TestCreatePaymentTestOutcome — тот же сценарий через API
+func TestCreatePaymentTestOutcome(t *testing.T) {
+	body := `{"amount": 10, "currency": "RUB", "metadata": {"test_outcome": "fail"}}`
+	for _, tc := range []struct {
+		testMode bool
+		want     string
+	}{
+		{true, StatusFailed},
+		{false, StatusSucceeded},
+	} {
+		s := NewServer(NewMemoryStore(), nil, &MockGateway{TestMode: tc.testMode}, Config{})
+		p := createPaymentT(t, s, body)
+		if p.Status != tc.want {
+			t.Errorf("TestMode=%v: status = %s, want %s", tc.testMode, p.Status, tc.want)
+		}
+	}
+}
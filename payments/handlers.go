@@ -0,0 +1,729 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ===== HTTP ОБРАБОТЧИКИ (HANDLERS) =====
+
+// handleCreatePayment обрабатывает POST запрос для создания платежа
+//
+// СИГНАТУРА МЕТОДА:
+// func = ключевое слово объявления функции
+// (s *Server) = получатель: метод "принадлежит" Server и видит его поля
+// handleCreatePayment = имя метода (с маленькой буквы = приватный)
+// (w http.ResponseWriter, r *http.Request) = параметры:
+//   - w = куда писать ответ (response)
+//   - r = откуда читать запрос (request), * = указатель (объясню ниже)
+//
+// УКАЗАТЕЛЬ (*):
+// В Go данные передаются ПО ЗНАЧЕНИЮ (копируются)
+// Звездочка * означает "передать ссылку, а не копию"
+// Зачем: http.Request большой объект, копировать его дорого
+func (s *Server) handleCreatePayment(w http.ResponseWriter, r *http.Request) {
+	// Проверяем HTTP метод
+	// r.Method = строка с методом запроса ("GET", "POST", "PUT" и т.д.)
+	// != означает "не равно"
+	if r.Method != http.MethodPost {
+		// writeMethodNotAllowed отправляет HTTP ответ 405 с ошибкой в JSON
+		// Параметры:
+		// 1. w = куда писать
+		// 2. r = запрос (по заголовку Accept выбирается формат ошибки)
+		// 3. http.MethodPost = разрешенные методы для заголовка Allow
+		//    (405 = правильный код для "метод не поддерживается")
+		writeMethodNotAllowed(w, r, http.MethodPost)
+
+		// return = прекратить выполнение функции
+		// Без return код ниже выполнился бы (это ошибка!)
+		return
+	}
+
+	// "" = ID сгенерирует сервер
+	s.createPayment(w, r, "")
+}
+
+// createPayment — общая логика создания платежа для POST и PUT
+//
+// id = ID, выбранный клиентом (PUT /payments/{id}),
+// или "" — тогда сервер сгенерирует новый (POST /payments)
+func (s *Server) createPayment(w http.ResponseWriter, r *http.Request, id string) {
+	// Создаем переменную для хранения распарсенных данных
+	// var = полная форма объявления переменной
+	// payment = имя переменной
+	// Payment = тип (наша структура выше)
+	// Значение по умолчанию: пустая структура {ID:"", Amount:0, Currency:"", Status:""}
+	var payment Payment
+
+	// Декодируем JSON из тела запроса в структуру
+	//
+	// json.NewDecoder(r.Body) = создает декодер, читающий из тела запроса
+	// r.Body = io.Reader, поток данных (как файл)
+	//
+	// .Decode(&payment) = декодировать JSON → структуру
+	// &payment = АДРЕС переменной payment (не копия, а именно она!)
+	// Зачем &: Decode должен ИЗМЕНИТЬ payment, поэтому нужна ссылка
+	//
+	// ВАЖНО: Decode возвращает error
+	// В Go НЕТ исключений (exceptions), вместо них — ошибки (error)
+	// Если JSON невалидный, err будет содержать описание проблемы
+	err := json.NewDecoder(r.Body).Decode(&payment)
+
+	// := это "короткая форма объявления"
+	// Эквивалентно: var err error = json.NewDecoder(...).Decode(...)
+	// Go сам определяет тип (здесь error)
+
+	// Проверяем, была ли ошибка при декодировании
+	// nil = "ничего", "null", "нет значения"
+	// err != nil означает "ошибка произошла"
+	if err != nil {
+		// Отправляем HTTP 400 (Bad Request) клиенту
+		// writeDecodeError логирует ошибку (для разработчика/администратора)
+		// и отличает пустое тело ("request body is required")
+		// от невалидного JSON ("Invalid JSON")
+		// НЕ отправляем детали err клиенту (это детали реализации)
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	// ===== ВАЛИДАЦИЯ ДАННЫХ =====
+	// КРИТИЧЕСКИ ВАЖНО ДЛЯ ФИНТЕХА!
+
+	// Проверяем все поля сразу: сумму, валюту, описание, метаданные
+	// Ответ 400 перечисляет ВСЕ ошибки в массиве fields
+	if errs := s.validatePayment(&payment); len(errs) > 0 {
+		writeFieldErrors(w, r, errs)
+		return
+	}
+
+	// Валюта поддерживается, но временно запрещена (Config.BlockedCurrencies)
+	// 403 Forbidden = запрос корректный, но сервер отказывается его выполнять
+	if s.blocked[payment.Currency] {
+		writeError(w, r, http.StatusForbidden, CodeCurrencyBlocked,
+			fmt.Sprintf("Payments in %s are temporarily blocked", payment.Currency))
+		return
+	}
+
+	// Если указан владелец платежа — он должен существовать
+	// 422 Unprocessable Entity = JSON корректный, но ссылается
+	// на несуществующую сущность (в отличие от 400 = "запрос кривой")
+	if payment.CustomerID != "" {
+		_, err := s.store.GetCustomer(r.Context(), payment.CustomerID)
+		if errors.Is(err, ErrCustomerNotFound) {
+			writeError(w, r, http.StatusUnprocessableEntity, CodeCustomerNotFound, "Customer not found")
+			return
+		}
+		if err != nil {
+			log.Printf("Error loading customer: %v", err)
+			writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+			return
+		}
+	}
+
+	// ===== КОНВЕРТАЦИЯ В ВАЛЮТУ РАСЧЕТА =====
+
+	// Поля результата вычисляет сервер — значения клиента игнорируем
+	payment.SettlementAmountMinor = 0
+	payment.FXRate = 0
+	if payment.SettlementCurrency != "" {
+		minor, rate, err := convertToMinor(s.fx, payment.Amount, payment.Currency, payment.SettlementCurrency,
+			s.rounding.modeFor(payment.SettlementCurrency))
+		if err != nil {
+			// Неизвестная пара валют — ошибка клиента (400), а не сервера
+			writeError(w, r, http.StatusBadRequest, CodeUnsupportedCurrencyPair, "Unsupported currency pair for settlement")
+			return
+		}
+		payment.SettlementAmountMinor = minor
+		payment.FXRate = rate
+	}
+
+	// ===== РЕЖИМ "ТОЛЬКО ПРОВЕРКА" (DRY RUN) =====
+
+	// POST /payments?validate_only=true — все проверки выше уже пройдены,
+	// но платеж НЕ создается: нет ID, нет записи в хранилище.
+	// Удобно для проверки формы в UI без побочных эффектов
+	// Предупреждения не мешают созданию, но клиент их увидит
+	warnings := s.paymentWarnings(payment)
+	if r.URL.Query().Get("validate_only") == "true" {
+		// map[string]any{"valid": true} → {"valid":true}
+		resp := map[string]any{"valid": true}
+		if len(warnings) > 0 {
+			resp["warnings"] = warnings
+		}
+		writeJSON(w, r, http.StatusOK, resp)
+		return
+	}
+
+	// ===== БИЗНЕС-ЛОГИКА =====
+
+	// Генерируем уникальный ID платежа (pay_ + UUID v4),
+	// если клиент не выбрал его сам. "id" из тела запроса игнорируется
+	payment.ID = id
+	if payment.ID == "" {
+		payment.ID = newPaymentID()
+	}
+	payment.CreatedAt = time.Now().UTC()
+
+	// Клиент не может создать сразу удаленный платеж
+	payment.Deleted = false
+	payment.DeletedAt = nil
+	// Предупреждения и суммы для показа — только ответ сервера,
+	// в хранилище не попадают
+	payment.Warnings = nil
+	payment.DisplayAmounts = nil
+
+	// Ссылку на исходный платеж ставит только разбиение (см. split.go)
+	payment.ParentID = ""
+
+	// Сквозной ID — из заголовка, а не из тела (см. correlation.go)
+	payment.CorrelationID, err = parseCorrelationID(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidCorrelationID, err.Error())
+		return
+	}
+
+	// Владелец — ключ API запроса, а не значение из тела
+	payment.CreatedByKey = ""
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		payment.CreatedByKey = apiKeyID(key)
+	}
+
+	// ИДЕМПОТЕНТНОСТЬ:
+	// Клиент может передать заголовок Idempotency-Key (например, UUID).
+	// Повтор запроса с тем же ключом (таймаут сети, ретрай клиента)
+	// не создаст второй платеж, а вернет уже созданный
+	// Ключ хранится в Store — при нескольких экземплярах API с общим
+	// хранилищем (Redis) повтор распознается на любом из них
+	// Ключи разных ключей API не пересекаются (см. idempotencyStoreKey)
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+	storeKey := idempotencyStoreKey(r, idempotencyKey)
+	if idempotencyKey != "" {
+		if len(idempotencyKey) > maxIdempotencyKeyLength {
+			writeError(w, r, http.StatusBadRequest, CodeInvalidIdempotencyKey,
+				fmt.Sprintf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength))
+			return
+		}
+		existingID, err := s.store.ClaimIdempotencyKey(r.Context(), storeKey, payment.ID, s.idempotencyTTL)
+		if err != nil {
+			log.Printf("Error claiming idempotency key: %v", err)
+			writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+			return
+		}
+		if existingID != "" {
+			s.replayIdempotent(w, r, existingID)
+			return
+		}
+	}
+	// Если платеж создать не удастся, ключ нужно освободить,
+	// иначе повтор запроса получал бы "запрос еще выполняется"
+	created := false
+	defer func() {
+		if idempotencyKey != "" && !created {
+			// Контекст запроса может быть уже отменен — освобождаем без него
+			if err := s.store.ReleaseIdempotencyKey(context.WithoutCancel(r.Context()), storeKey); err != nil {
+				log.Printf("Error releasing idempotency key: %v", err)
+			}
+		}
+	}()
+
+	// Защита от случайных дублей (включается через Config.DuplicateWindow)
+	// Заголовок X-Force-Create: true = "я знаю, что делаю, создавай"
+	force := r.Header.Get("X-Force-Create") == "true"
+	dupID, forgetDuplicate := s.duplicates.checkAndRemember(payment, force)
+	if dupID != "" {
+		writeError(w, r, http.StatusConflict, CodePossibleDuplicate,
+			fmt.Sprintf("Possible duplicate of %s created within the last %s; retry with X-Force-Create: true to create anyway", dupID, s.duplicates.window))
+		return
+	}
+	// Дублем считается только сохраненный и не отклоненный платеж:
+	// после отказа шлюза клиент вправе сразу повторить оплату
+	defer func() {
+		if !created || payment.Status == StatusFailed {
+			forgetDuplicate()
+		}
+	}()
+
+	// external_id уникален: проверяем ДО списания денег через шлюз
+	// (окончательно уникальность гарантирует store.Create ниже)
+	if payment.ExternalID != "" {
+		existing, err := s.store.GetByExternalID(r.Context(), payment.ExternalID)
+		if err == nil {
+			s.writeExternalIDExists(w, r, existing.ID)
+			return
+		}
+		if !errors.Is(err, ErrPaymentNotFound) {
+			log.Printf("Error looking up external_id: %v", err)
+			writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+			return
+		}
+	}
+
+	// Устанавливаем начальный статус
+	payment.Status = StatusPending
+	payment.FailureReason = ""
+	payment.RetryCount = 0
+	payment.Version = 1
+
+	// Порядковый номер выдает хранилище — он уникален даже при нескольких
+	// экземплярах API. Если сохранение ниже не удастся, номер пропадет:
+	// пропуски допустимы, повторы нет
+	payment.SequenceNumber, err = s.store.NextSequenceNumber(r.Context())
+	if err != nil {
+		log.Printf("Error allocating sequence number: %v", err)
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+		return
+	}
+
+	// Комиссия процессинга и сумма к зачислению
+	// Обращение к отсутствующему ключу map дает нулевую Fee = без комиссии
+	payment.FeeMinor = s.fees[payment.Currency].calculate(payment.AmountMinor, s.rounding.modeFor(payment.Currency))
+	payment.NetAmountMinor = payment.AmountMinor - payment.FeeMinor
+
+	// Возвратов еще не было: вернуть можно всю сумму
+	payment.AmountRefundedMinor = 0
+	payment.AmountRefundableMinor = payment.AmountMinor
+
+	// process_at в будущем — платеж ждет планировщика (см. schedule.go),
+	// в шлюз пока не идем. Время в прошлом = провести сейчас
+	scheduled := payment.ProcessAt != nil && payment.ProcessAt.After(payment.CreatedAt)
+	if scheduled {
+		processAt := payment.ProcessAt.UTC()
+		payment.ProcessAt = &processAt
+		payment.Status = StatusScheduled
+	} else {
+		payment.ProcessAt = nil
+	}
+
+	// Данные инсталляции (оценка риска и т.п., см. enrich.go) —
+	// до списания, чтобы шлюз уже видел их
+	// 422: запрос корректный, но платеж не прошел обогащение
+	if err := s.enrichPayment(r.Context(), &payment); err != nil {
+		log.Printf("Payment enrichment failed: ID=%s, Error=%v", payment.ID, err)
+		writeError(w, r, http.StatusUnprocessableEntity, CodeEnrichmentFailed, "Payment enrichment failed")
+		return
+	}
+
+	// Если подключен платежный шлюз — сразу списываем деньги
+	// Без шлюза платеж остается pending (статус меняют через PATCH)
+	// r.Context() отменяется, если клиент разорвал соединение
+	// В асинхронном режиме платеж сохраняется в processing, а шлюз
+	// вызывается после ответа клиенту (см. async.go)
+	async := s.gateway != nil && s.asyncGateway && !scheduled && !payment.manualCapture
+	if async {
+		payment.Status = StatusProcessing
+	} else if s.gateway != nil && !scheduled {
+		if err := s.gateway.Charge(r.Context(), payment); err != nil {
+			log.Printf("Gateway charge failed: ID=%s, Error=%v", payment.ID, err)
+			payment.Status = StatusFailed
+			payment.FailureReason = s.failureReason(err)
+		} else {
+			payment.Status = StatusSucceeded
+		}
+	}
+
+	// "capture": false — двухшаговый платеж (см. capture.go): шлюз
+	// только проверил карту, деньги спишет POST /payments/{id}/capture
+	// Пока не списано, возвращать нечего
+	payment.AmountAuthorizedMinor = 0
+	payment.CaptureExpiresAt = nil
+	if payment.manualCapture && payment.Status != StatusFailed {
+		payment.Status = StatusAuthorized
+		payment.AmountAuthorizedMinor = payment.AmountMinor
+		payment.AmountRefundableMinor = 0
+		// Блокировка не вечна: после срока платеж аннулируется (expiry.go)
+		expiresAt := payment.CreatedAt.Add(s.captureExpiry)
+		payment.CaptureExpiresAt = &expiresAt
+	}
+
+	// Сохраняем платеж, чтобы его можно было получить по ID
+	// Create не перезаписывает существующий платеж: если ID уже занят
+	// (два PUT с одним ID одновременно), второй получит 409
+	err = s.store.Create(r.Context(), payment)
+	if errors.Is(err, ErrPaymentExists) {
+		writeError(w, r, http.StatusConflict, CodePaymentExists, "Payment with this ID already exists")
+		return
+	}
+	if errors.Is(err, ErrExternalIDExists) {
+		// Параллельный запрос с тем же external_id успел первым
+		existing, lookupErr := s.store.GetByExternalID(r.Context(), payment.ExternalID)
+		if lookupErr == nil {
+			s.writeExternalIDExists(w, r, existing.ID)
+			return
+		}
+		err = lookupErr
+	}
+	if errors.Is(err, ErrStoreFull) {
+		// Не ошибка сервера: место освободится, когда уборщик
+		// удалит старые завершенные платежи
+		log.Printf("Payment store is full, rejecting %s", payment.ID)
+		writeError(w, r, http.StatusServiceUnavailable, CodeStoreFull, "Payment storage is at capacity; retry later")
+		return
+	}
+	if err != nil {
+		log.Printf("Error saving payment: %v", err)
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+		return
+	}
+	created = true
+	s.publishStatus(r.Context(), payment)
+
+	// Логируем успешное создание (для мониторинга)
+	// %+v = подробный вывод структуры со всеми полями
+	// Вывод: {ID:pay_12345 Amount:100.5 Currency:RUB Status:pending}
+	//log.Printf("Payment created: %+v", payment)
+	// Замени на:
+	// Логируем создание платежа с детальной информацией
+	// %s = строка (string)
+	// %f = число с плавающей точкой (float)
+	// %q = строка в кавычках (удобно для текстовых полей)
+	log.Printf("Payment created: ID=%s, Amount=%.2f %s, Status=%s, Description=%q, Correlation=%q",
+		payment.ID,       // ID платежа
+		payment.Amount,   // Сумма (%.2f = 2 знака после запятой)
+		payment.Currency, // Валюта
+		payment.Status,   // Статус
+		s.logValue("description", payment.Description), // Описание (может быть скрыто)
+		payment.CorrelationID)                          // Сквозной ID (может быть пустым)
+	s.audit.record(auditActor(r), AuditCreate, payment.ID, "", payment.Status)
+	if async {
+		// Запускаем после записи о создании: в журнале она будет первой
+		go s.chargeAsync(context.WithoutCancel(r.Context()), auditActor(r), payment)
+	}
+
+	// ===== ОТПРАВКА ОТВЕТА =====
+
+	// ETag = текущая версия платежа (нужна для If-Match при изменении)
+	// w.Header() = map с HTTP заголовками (как dict в Python)
+	// .Set("ключ", "значение") = установить заголовок
+	w.Header().Set("ETag", paymentETag(payment))
+
+	// Location = адрес созданного ресурса (стандарт для 201 Created)
+	w.Header().Set("Location", s.url("/payments/"+payment.ID))
+	if payment.CorrelationID != "" {
+		w.Header().Set(correlationHeader, payment.CorrelationID)
+	}
+
+	// Платеж уже сохранен — предупреждения добавляем только в ответ
+	payment.Warnings = warnings
+
+	// Отправляем платеж в JSON со статусом 201 (Created)
+	// 201 = "ресурс успешно создан" (правильный код для POST)
+	// НЕ 200, потому что 200 = "ok, но ничего не создано"
+	// writeJSON сам выставит Content-Type: application/json
+	writeJSON(w, r, http.StatusCreated, payment)
+
+	// Что увидит клиент:
+	// HTTP/1.1 201 Created
+	// Content-Type: application/json
+	//
+	// {"id":"pay_12345","amount":100.5,"currency":"RUB","status":"pending"}
+}
+
+// writeExternalIDExists отвечает 409 на повтор external_id
+// Location и текст ошибки указывают на уже существующий платеж,
+// чтобы клиент мог сразу его запросить
+func (s *Server) writeExternalIDExists(w http.ResponseWriter, r *http.Request, existingID string) {
+	location := s.url("/payments/" + existingID)
+	w.Header().Set("Location", location)
+	writeError(w, r, http.StatusConflict, CodeExternalIDExists,
+		fmt.Sprintf("Payment with this external_id already exists: %s", location))
+}
+
+// handleGetPayment обрабатывает GET запрос для получения статуса платежа
+// В реальности здесь был бы ID в URL (например: GET /payments/pay_12345)
+// Пока возвращаем заглушку (mock data)
+func (s *Server) handleGetPayment(w http.ResponseWriter, r *http.Request) {
+	// Проверяем что это GET запрос
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	// Создаем тестовый платеж (в реальности: запрос к БД)
+	// Короткая форма инициализации структуры
+	// Порядок полей не важен, можно указать только некоторые
+	payment := Payment{
+		ID:       "pay_12345",
+		Amount:   1000.50,
+		Currency: "RUB",
+		Status:   StatusSucceeded, // Платеж успешно обработан
+	}
+
+	// Отправляем JSON ответ
+	// Для GET используем статус 200 (OK) — это стандарт
+	writeJSON(w, r, http.StatusOK, payment)
+}
+
+// handlePayments маршрутизирует запросы к /payments по HTTP методу
+// POST = создать платеж, GET = получить список платежей
+func (s *Server) handlePayments(w http.ResponseWriter, r *http.Request) {
+	// switch — аналог цепочки if/else if, но читается проще
+	switch r.Method {
+	case http.MethodPost:
+		s.handleCreatePayment(w, r)
+	case http.MethodGet:
+		s.handleListPayments(w, r)
+	default:
+		writeMethodNotAllowed(w, r, http.MethodPost, http.MethodGet)
+	}
+}
+
+// handlePaymentByID маршрутизирует запросы к /payments/{id}
+// GET = получить платеж, PUT = создать платеж с заданным ID,
+// PATCH = сменить статус, DELETE = мягко удалить платеж
+func (s *Server) handlePaymentByID(w http.ResponseWriter, r *http.Request) {
+	// Сначала проверяем формат ID: "pay_x" без платежа = 404,
+	// а "abc" — это вообще не ID платежа = 400
+	if !isValidPaymentID(r.PathValue("id")) {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidID, "Invalid payment ID: must start with "+paymentIDPrefix)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.handleGetPaymentByID(w, r)
+	case http.MethodPut:
+		s.handlePutPayment(w, r)
+	case http.MethodPatch:
+		s.handleUpdatePaymentStatus(w, r)
+	case http.MethodDelete:
+		s.handleDeletePayment(w, r)
+	default:
+		writeMethodNotAllowed(w, r, http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete)
+	}
+}
+
+// handleGetPaymentByID возвращает платеж по ID из URL
+// Удаленный платеж тоже возвращается (с deleted: true) — для аудита
+func (s *Server) handleGetPaymentByID(w http.ResponseWriter, r *http.Request) {
+	wait, err := parseWait(r.URL.Query())
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidQuery, err.Error())
+		return
+	}
+	display, err := parseDisplayRequest(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidQuery, err.Error())
+		return
+	}
+
+	// r.PathValue("id") достает часть пути, совпавшую с {id} в шаблоне
+	// (поддерживается роутером стандартной библиотеки начиная с Go 1.22)
+	id := r.PathValue("id")
+
+	// Для ?wait= подписываемся ДО чтения (как в handlePaymentStream),
+	// чтобы не пропустить изменение между чтением и подпиской
+	var events <-chan Payment
+	if wait > 0 {
+		var unsubscribe func()
+		events, unsubscribe = s.events.subscribe(id)
+		defer unsubscribe()
+	}
+
+	payment, err := s.store.Get(r.Context(), id)
+	if errors.Is(err, ErrPaymentNotFound) {
+		writeError(w, r, http.StatusNotFound, CodePaymentNotFound, "Payment not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Error loading payment: %v", err)
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+		return
+	}
+
+	// LONG POLLING: держим запрос, пока статус не изменится
+	// или не истечет wait; в ответе в любом случае текущее состояние
+	if wait > 0 && !isTerminalStatus(payment.Status) {
+		// Ожидание может быть дольше WriteTimeout сервера — продлеваем
+		// дедлайн записи для этого ответа
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(wait + longPollWriteSlack))
+		payment, err = awaitStatusChange(r.Context(), events, payment, wait)
+		if errors.Is(err, context.Canceled) {
+			// Клиент отключился — отвечать некому
+			return
+		}
+		// Истек дедлайн запроса — отвечаем последним известным
+		// состоянием, как по истечении wait (пустой ответ хуже)
+	}
+
+	if includeDisplay(r) {
+		payment.AmountDisplay = formatAmountDisplay(payment.AmountMinor, payment.Currency)
+	}
+	one := []Payment{payment}
+	if err := s.withDisplayAmounts(one, display); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeUnsupportedCurrencyPair, err.Error())
+		return
+	}
+	payment = one[0]
+
+	w.Header().Set("ETag", paymentETag(payment))
+	writeJSON(w, r, http.StatusOK, payment)
+}
+
+// handlePutPayment создает платеж с ID, который выбрал клиент
+// PUT /payments/{id}
+//
+// Нужен интеграциям, которые сверяют платежи по своим ID
+// Тело запроса проверяется так же, как в POST /payments
+//
+// Коды ответа:
+// - 201 Created = платеж создан
+// - 409 Conflict = платеж с таким ID уже есть (суммы НЕ перезаписываются)
+// Формат ID проверен раньше, в handlePaymentByID
+func (s *Server) handlePutPayment(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	// Быстрая проверка до разбора тела и вызова шлюза
+	// (окончательную атомарную проверку делает store.Create)
+	_, err := s.store.Get(r.Context(), id)
+	if err == nil {
+		writeError(w, r, http.StatusConflict, CodePaymentExists, "Payment with this ID already exists")
+		return
+	}
+	if !errors.Is(err, ErrPaymentNotFound) {
+		log.Printf("Error loading payment: %v", err)
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+		return
+	}
+
+	s.createPayment(w, r, id)
+}
+
+// paymentETag формирует ETag платежа из его версии
+// По стандарту HTTP значение ETag заключается в двойные кавычки: "3"
+func paymentETag(p Payment) string {
+	return fmt.Sprintf("%q", strconv.Itoa(p.Version))
+}
+
+// parseIfMatch извлекает ожидаемую версию из заголовка If-Match
+//
+// Возвращает:
+// - 0, true  = заголовка нет или он равен "*" (подойдет любая версия)
+// - N, true  = клиент ожидает версию N
+// - 0, false = заголовок некорректный
+func parseIfMatch(r *http.Request) (int, bool) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" || value == "*" {
+		return 0, true
+	}
+	// Допускаем и "3", и 3, и слабый ETag W/"3"
+	value = strings.TrimPrefix(value, "W/")
+	value = strings.Trim(value, `"`)
+	version, err := strconv.Atoi(value)
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+	return version, true
+}
+
+// updateStatusRequest — тело запроса PATCH /payments/{id}
+type updateStatusRequest struct {
+	Status string `json:"status"`
+}
+
+// handleUpdatePaymentStatus меняет статус платежа
+//
+// ОПТИМИСТИЧНАЯ БЛОКИРОВКА:
+// Клиент читает платеж (GET) и получает ETag с версией.
+// При изменении он отправляет эту версию в If-Match.
+// Если кто-то успел изменить платеж раньше, версия уже другая —
+// отвечаем 412 Precondition Failed вместо тихой перезаписи.
+//
+// Коды ответа:
+// - 200 OK = статус изменен
+// - 400 Bad Request = некорректный JSON или If-Match
+// - 404 Not Found = платежа нет (или он удален)
+// - 409 Conflict = недопустимый переход статуса
+// - 412 Precondition Failed = версия не совпала
+func (s *Server) handleUpdatePaymentStatus(w http.ResponseWriter, r *http.Request) {
+	expectedVersion, ok := parseIfMatch(r)
+	if !ok {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidIfMatch, "Invalid If-Match header")
+		return
+	}
+
+	var req updateStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+	if req.Status == "" {
+		writeError(w, r, http.StatusBadRequest, CodeStatusRequired, "Status is required")
+		return
+	}
+
+	id := r.PathValue("id")
+
+	// Статус "до" для журнала аудита. Из конечных статусов переходов нет,
+	// поэтому при успешном обновлении прочитанный статус еще актуален
+	// (ошибку чтения не проверяем: ее вернет UpdateStatus)
+	before, _ := s.store.Get(r.Context(), id)
+
+	// Ручной перевод в failed — без ответа шлюза, поэтому причина
+	// отказа общая (processing_error): failed без причины не бывает
+	payment, err := s.updateStatus(r.Context(), id, req.Status, expectedVersion, FailureProcessingError)
+	switch {
+	case errors.Is(err, ErrPaymentNotFound):
+		writeError(w, r, http.StatusNotFound, CodePaymentNotFound, "Payment not found")
+		return
+	case errors.Is(err, ErrVersionMismatch):
+		// Сообщаем актуальный ETag, чтобы клиент мог перечитать платеж
+		w.Header().Set("ETag", paymentETag(payment))
+		writeError(w, r, http.StatusPreconditionFailed, CodeVersionMismatch, "Payment was modified by another request")
+		return
+	case errors.Is(err, ErrInvalidTransition):
+		writeError(w, r, http.StatusConflict, CodeInvalidTransition, fmt.Sprintf("Cannot change status from %s to %s", payment.Status, req.Status))
+		return
+	case err != nil:
+		log.Printf("Error updating payment: %v", err)
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+		return
+	}
+
+	log.Printf("Payment status updated: ID=%s, Status=%s, Version=%d", payment.ID, payment.Status, payment.Version)
+	s.audit.record(auditActor(r), AuditUpdate, payment.ID, before.Status, payment.Status)
+
+	// Сообщаем подписчикам потока /payments/{id}/stream
+	s.publishStatus(r.Context(), payment)
+
+	w.Header().Set("ETag", paymentETag(payment))
+	writeJSON(w, r, http.StatusOK, payment)
+}
+
+// handleDeletePayment мягко удаляет платеж
+//
+// ИДЕМПОТЕНТНОСТЬ:
+// Повторный DELETE того же платежа дает тот же результат (204),
+// поэтому клиент может безопасно повторять запрос при сетевых сбоях
+//
+// Коды ответа:
+// - 204 No Content = платеж удален (или уже был удален)
+// - 404 Not Found = платежа не существует
+func (s *Server) handleDeletePayment(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	// Текущий статус — для журнала аудита (удаление его не меняет)
+	before, _ := s.store.Get(r.Context(), id)
+
+	err := s.store.MarkDeleted(r.Context(), id)
+	if errors.Is(err, ErrPaymentNotFound) {
+		writeError(w, r, http.StatusNotFound, CodePaymentNotFound, "Payment not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Error deleting payment: %v", err)
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+		return
+	}
+
+	log.Printf("Payment deleted: ID=%s", id)
+	s.audit.record(auditActor(r), AuditDelete, id, before.Status, before.Status)
+
+	// 204 = успех без тела ответа
+	w.WriteHeader(http.StatusNoContent)
+}
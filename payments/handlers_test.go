@@ -0,0 +1,173 @@
+package payments
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// ===== If-Match =====
+
+func TestUpdateStatusStaleIfMatch(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	p := createPaymentT(t, s, `{"amount": 100, "currency": "RUB"}`)
+
+	rec := doJSON(t, s, http.MethodPatch, "/payments/"+p.ID, `{"status":"canceled"}`,
+		map[string]string{"If-Match": `"` + "99" + `"`})
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale If-Match = %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("ETag"); got != paymentETag(p) {
+		t.Fatalf("ETag = %s, want current %s", got, paymentETag(p))
+	}
+
+	rec = doJSON(t, s, http.MethodPatch, "/payments/"+p.ID, `{"status":"canceled"}`,
+		map[string]string{"If-Match": paymentETag(p)})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("current If-Match = %d: %s", rec.Code, rec.Body.String())
+	}
+	var updated Payment
+	decodeBody(t, rec, &updated)
+	if updated.Version != p.Version+1 || updated.Status != StatusCanceled {
+		t.Fatalf("updated = version %d, status %s", updated.Version, updated.Status)
+	}
+
+	// Прежний ETag после изменения устарел
+	rec = doJSON(t, s, http.MethodPatch, "/payments/"+p.ID, `{"status":"pending"}`,
+		map[string]string{"If-Match": paymentETag(p)})
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("reused ETag = %d", rec.Code)
+	}
+}
+
+func TestParseIfMatch(t *testing.T) {
+	cases := []struct {
+		header  string
+		version int
+		ok      bool
+	}{
+		{"", 0, true},
+		{"*", 0, true},
+		{`"3"`, 3, true},
+		{"3", 3, true},
+		{`W/"3"`, 3, true},
+		{`"abc"`, 0, false},
+		{`"0"`, 0, false},
+	}
+	for _, c := range cases {
+		r, _ := http.NewRequest(http.MethodPatch, "/", nil)
+		r.Header.Set("If-Match", c.header)
+		version, ok := parseIfMatch(r)
+		if version != c.version || ok != c.ok {
+			t.Errorf("parseIfMatch(%q) = %d, %t", c.header, version, ok)
+		}
+	}
+
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	p := createPaymentT(t, s, `{"amount": 100, "currency": "RUB"}`)
+	rec := doJSON(t, s, http.MethodPatch, "/payments/"+p.ID, `{"status":"canceled"}`, map[string]string{"If-Match": "nope"})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid If-Match = %d", rec.Code)
+	}
+}
+
+// ===== validate_only =====
+
+func TestValidateOnlyStoresNothing(t *testing.T) {
+	store := NewMemoryStore()
+	charged := false
+	s := NewServer(store, nil, gatewayFunc(func(context.Context, Payment) error {
+		charged = true
+		return nil
+	}), Config{})
+
+	rec := doJSON(t, s, http.MethodPost, "/payments?validate_only=true", `{"amount": 100, "currency": "RUB"}`, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("valid dry run = %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Valid bool `json:"valid"`
+	}
+	decodeBody(t, rec, &resp)
+	if !resp.Valid {
+		t.Fatalf("body = %s", rec.Body.String())
+	}
+
+	rec = doJSON(t, s, http.MethodPost, "/payments?validate_only=true", `{"amount": -1, "currency": "RUB"}`, nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid dry run = %d", rec.Code)
+	}
+
+	if list, _ := store.List(context.Background(), true); len(list) != 0 || charged {
+		t.Fatalf("dry run stored %d payments, charged = %t", len(list), charged)
+	}
+}
+
+// ===== external_id =====
+
+// TestCreateDuplicateExternalID — повтор external_id получает 409
+// со ссылкой на уже созданный платеж
+func TestCreateDuplicateExternalID(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	body := `{"amount": 100, "currency": "RUB", "external_id": "order-1"}`
+	first := createPaymentT(t, s, body)
+
+	rec := doJSON(t, s, http.MethodPost, "/payments", body, nil)
+	var resp ErrorResponse
+	decodeBody(t, rec, &resp)
+	if rec.Code != http.StatusConflict || resp.Code != CodeExternalIDExists {
+		t.Fatalf("duplicate: %d %+v", rec.Code, resp)
+	}
+	if loc := rec.Header().Get("Location"); !strings.HasSuffix(loc, "/payments/"+first.ID) {
+		t.Fatalf("Location = %q, want link to %s", loc, first.ID)
+	}
+	if !strings.Contains(resp.Message, first.ID) {
+		t.Fatalf("message %q does not mention %s", resp.Message, first.ID)
+	}
+}
+
+// TestCreateWithoutExternalID — external_id необязателен: платежи без
+// него не конфликтуют друг с другом
+func TestCreateWithoutExternalID(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	a := createPaymentT(t, s, `{"amount": 100, "currency": "RUB"}`)
+	b := createPaymentT(t, s, `{"amount": 100, "currency": "RUB"}`)
+	if a.ID == b.ID {
+		t.Fatal("payments without external_id share an ID")
+	}
+}
+
+// TestCreateExternalIDConcurrent — из параллельных запросов с одним
+// external_id проходит ровно один
+func TestCreateExternalIDConcurrent(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	const n = 10
+	codes := make(chan int, n)
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- doJSON(t, s, http.MethodPost, "/payments",
+				`{"amount": 100, "currency": "RUB", "external_id": "order-race"}`, nil).Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	created := 0
+	for code := range codes {
+		switch code {
+		case http.StatusCreated:
+			created++
+		case http.StatusConflict:
+		default:
+			t.Errorf("unexpected status %d", code)
+		}
+	}
+	if created != 1 {
+		t.Fatalf("created = %d, want exactly 1", created)
+	}
+}
@@ -0,0 +1,111 @@
+package payments
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ===== ТОЛЬКО HTTPS =====
+
+// httpsExempt — пути, доступные и без TLS
+// Оркестратор проверяет /health и /ready напрямую по HTTP, минуя прокси
+var httpsExempt = map[string]bool{
+	"/health": true,
+	"/ready":  true,
+}
+
+// ParseTrustedProxies разбирает список доверенных прокси
+// Формат: "10.0.0.0/8,192.168.1.10" — подсети CIDR или отдельные адреса
+func ParseTrustedProxies(s string) ([]netip.Prefix, error) {
+	var proxies []netip.Prefix
+	if strings.TrimSpace(s) == "" {
+		return proxies, nil
+	}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			proxies = append(proxies, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %q: expected IP address or CIDR", entry)
+		}
+		proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return proxies, nil
+}
+
+// requireHTTPS — middleware, которое отклоняет запросы, пришедшие
+// без TLS: 400 с кодом https_required
+//
+// Запрос считается защищенным, если:
+//   - TLS завершен на самом сервере (r.TLS != nil)
+//   - или его прислал доверенный прокси (адрес из trusted) с заголовком
+//     X-Forwarded-Proto: https — TLS завершен на прокси
+//
+// Заголовку от остальных клиентов верить нельзя: подделать его может
+// кто угодно. Пустой trusted = заголовок не учитывается вовсе
+//
+// Это самый внешний слой после recoverPanic: тело запроса без TLS
+// (в нем могут быть данные карты) не должно дойти даже до журнала
+// тел (logBodies). Поэтому путь здесь еще с префиксом basePath —
+// для сравнения с httpsExempt он отрезается
+//
+// enabled = false — запросы пропускаются как есть (локальная разработка)
+func requireHTTPS(enabled bool, trusted []netip.Prefix, basePath string, next http.Handler) http.Handler {
+	if !enabled {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, _ := strings.CutPrefix(r.URL.Path, basePath)
+		if httpsExempt[route] || isSecureRequest(r, trusted) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		writeError(w, r, http.StatusBadRequest, CodeHTTPSRequired, "HTTPS is required")
+	})
+}
+
+// isSecureRequest сообщает, пришел ли запрос по TLS
+func isSecureRequest(r *http.Request, trusted []netip.Prefix) bool {
+	if r.TLS != nil {
+		return true
+	}
+	if !fromTrustedProxy(r.RemoteAddr, trusted) {
+		return false
+	}
+	// Через цепочку прокси: "https, http" — первое значение выставил
+	// прокси, принявший соединение клиента
+	proto, _, _ := strings.Cut(r.Header.Get("X-Forwarded-Proto"), ",")
+	return strings.EqualFold(strings.TrimSpace(proto), "https")
+}
+
+// fromTrustedProxy проверяет, что адрес соединения ("ip:port")
+// входит в одну из доверенных подсетей
+func fromTrustedProxy(remoteAddr string, trusted []netip.Prefix) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return false
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	return isTrustedAddr(addr, trusted)
+}
+
+// isTrustedAddr проверяет, что адрес входит в одну из доверенных подсетей
+func isTrustedAddr(addr netip.Addr, trusted []netip.Prefix) bool {
+	// IPv4 через IPv6-сокет приходит как ::ffff:10.0.0.1
+	addr = addr.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
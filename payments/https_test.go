@@ -0,0 +1,82 @@
+package payments
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+// requestFrom — GET path с адреса remote и заголовком X-Forwarded-Proto
+func requestFrom(remote, path, proto string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = remote
+	if proto != "" {
+		req.Header.Set("X-Forwarded-Proto", proto)
+	}
+	return req
+}
+
+func TestRequireHTTPS(t *testing.T) {
+	proxies, err := ParseTrustedProxies("10.0.0.0/8, 192.168.1.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(NewMemoryStore(), nil, nil, Config{RequireHTTPS: true, TrustedProxies: proxies, BasePath: "/api/v1"})
+
+	tlsReq := requestFrom("203.0.113.5:1234", "/api/v1/payments", "")
+	tlsReq.TLS = &tls.ConnectionState{}
+
+	for name, tc := range map[string]struct {
+		req  *http.Request
+		want int
+	}{
+		"direct TLS":                {tlsReq, http.StatusOK},
+		"trusted proxy https":       {requestFrom("10.1.2.3:5000", "/api/v1/payments", "https"), http.StatusOK},
+		"trusted proxy chain":       {requestFrom("192.168.1.10:5000", "/api/v1/payments", "HTTPS, http"), http.StatusOK},
+		"trusted proxy IPv4-mapped": {requestFrom("[::ffff:10.0.0.1]:5000", "/api/v1/payments", "https"), http.StatusOK},
+		"trusted proxy http":        {requestFrom("10.1.2.3:5000", "/api/v1/payments", "http"), http.StatusBadRequest},
+		"untrusted forged header":   {requestFrom("203.0.113.5:1234", "/api/v1/payments", "https"), http.StatusBadRequest},
+		"plaintext":                 {requestFrom("203.0.113.5:1234", "/api/v1/payments", ""), http.StatusBadRequest},
+		"health exempt":             {requestFrom("203.0.113.5:1234", "/api/v1/health", ""), http.StatusOK},
+	} {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, tc.req)
+		if rec.Code != tc.want {
+			t.Errorf("%s: status = %d, want %d", name, rec.Code, tc.want)
+			continue
+		}
+		if tc.want == http.StatusBadRequest {
+			var resp ErrorResponse
+			decodeBody(t, rec, &resp)
+			if resp.Code != CodeHTTPSRequired {
+				t.Errorf("%s: code = %s", name, resp.Code)
+			}
+		}
+	}
+}
+
+// TestRequireHTTPSDisabled — без флага локальная разработка по HTTP работает
+func TestRequireHTTPSDisabled(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, requestFrom("127.0.0.1:1234", "/payments", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d with REQUIRE_HTTPS off", rec.Code)
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	got, err := ParseTrustedProxies("10.0.0.1/8, 2001:db8::1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("2001:db8::1/128")}
+	if len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("proxies = %v, want %v", got, want)
+	}
+	if _, err := ParseTrustedProxies("10.0.0.0/8,not-an-ip"); err == nil {
+		t.Fatal("invalid proxy accepted")
+	}
+}
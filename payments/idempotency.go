@@ -0,0 +1,51 @@
+package payments
+
+import (
+	"errors"
+	"log"
+	"net/http"
+)
+
+// ===== КЛЮЧИ ИДЕМПОТЕНТНОСТИ =====
+
+// maxIdempotencyKeyLength — максимальная длина заголовка Idempotency-Key
+const maxIdempotencyKeyLength = 255
+
+// idempotencyStoreKey — ключ идемпотентности в хранилище
+//
+// Ключ выбирает клиент, и два клиента легко выберут одинаковый
+// ("order-1"). Поэтому в хранилище он привязан к ключу API запроса:
+// "key_ab12…:order-1" — у каждого ключа API свое пространство ключей,
+// и повтор чужого запроса не вернет чужой платеж
+// Без ключа API (проверка выключена) — общий ключ, как есть
+func idempotencyStoreKey(r *http.Request, key string) string {
+	if apiKey := r.Header.Get("X-API-Key"); apiKey != "" {
+		return apiKeyID(apiKey) + ":" + key
+	}
+	return key
+}
+
+// replayIdempotent отвечает на повтор запроса с уже использованным
+// ключом идемпотентности: возвращает платеж, созданный первым запросом
+//
+// Заголовок Idempotent-Replayed: true сообщает клиенту, что это повтор
+// Если первый запрос еще выполняется (платеж не сохранен), отвечаем 409 —
+// клиент повторит позже
+func (s *Server) replayIdempotent(w http.ResponseWriter, r *http.Request, paymentID string) {
+	payment, err := s.store.Get(r.Context(), paymentID)
+	if errors.Is(err, ErrPaymentNotFound) {
+		writeError(w, r, http.StatusConflict, CodeIdempotencyInProgress,
+			"A request with this Idempotency-Key is still in progress; retry later")
+		return
+	}
+	if err != nil {
+		log.Printf("Error loading payment: %v", err)
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+		return
+	}
+
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.Header().Set("ETag", paymentETag(payment))
+	w.Header().Set("Location", s.url("/payments/"+payment.ID))
+	writeJSON(w, r, http.StatusOK, payment)
+}
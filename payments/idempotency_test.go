@@ -0,0 +1,49 @@
+package payments
+
+import (
+	"net/http"
+	"testing"
+)
+
+// createWithKey создает платеж от имени apiKey с Idempotency-Key
+func createWithKey(t *testing.T, s *Server, apiKey, idempotencyKey string) Payment {
+	t.Helper()
+	rec := doJSON(t, s, http.MethodPost, "/payments", `{"amount": 100, "currency": "RUB"}`,
+		map[string]string{"X-API-Key": apiKey, "Idempotency-Key": idempotencyKey})
+	if rec.Code != http.StatusCreated && rec.Code != http.StatusOK {
+		t.Fatalf("POST as %s = %d: %s", apiKey, rec.Code, rec.Body.String())
+	}
+	var p Payment
+	decodeBody(t, rec, &p)
+	return p
+}
+
+// TestIdempotencyKeyScopedByAPIKey — одинаковый Idempotency-Key от
+// разных ключей API создает разные платежи, а повтор от того же
+// ключа возвращает уже созданный
+func TestIdempotencyKeyScopedByAPIKey(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{APIKeys: []string{"alice", "bob"}})
+
+	alice := createWithKey(t, s, "alice", "order-1")
+	bob := createWithKey(t, s, "bob", "order-1")
+	if alice.ID == bob.ID {
+		t.Fatalf("tenants share payment %s for the same idempotency key", alice.ID)
+	}
+	if again := createWithKey(t, s, "alice", "order-1"); again.ID != alice.ID {
+		t.Fatalf("replay = %s, want %s", again.ID, alice.ID)
+	}
+	if again := createWithKey(t, s, "bob", "order-1"); again.ID != bob.ID {
+		t.Fatalf("replay = %s, want %s", again.ID, bob.ID)
+	}
+}
+
+func TestIdempotencyStoreKey(t *testing.T) {
+	r, _ := http.NewRequest(http.MethodPost, "/payments", nil)
+	if got := idempotencyStoreKey(r, "order-1"); got != "order-1" {
+		t.Fatalf("without API key = %q", got)
+	}
+	r.Header.Set("X-API-Key", "alice")
+	if got := idempotencyStoreKey(r, "order-1"); got != apiKeyID("alice")+":order-1" {
+		t.Fatalf("with API key = %q", got)
+	}
+}
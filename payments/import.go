@@ -0,0 +1,197 @@
+package payments
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+)
+
+// ===== ЗАГРУЗКА ИЗ NDJSON =====
+//
+// POST /payments/import принимает поток NDJSON — по платежу на строку,
+// в том же виде, что тело POST /payments:
+//
+//	{"amount": 100, "currency": "RUB"}
+//	{"amount": "250.00", "currency": "USD", "external_id": "order-42"}
+//
+// Строки обрабатываются по мере чтения, а результат каждой сразу
+// уходит клиенту тоже строкой NDJSON. Ни запрос, ни ответ не собираются
+// в памяти целиком, поэтому размер загрузки не ограничен
+// Ошибка в строке (невалидный JSON, неверная сумма) попадает в ее
+// результат и не прерывает загрузку
+
+// maxImportLineBytes — наибольшая длина строки загрузки
+// Длинная строка пропускается с ошибкой line_too_long: читать ее
+// целиком в память — ровно то, от чего спасает построчная загрузка
+const maxImportLineBytes = 64 << 10
+
+// importFlushEvery — через сколько результатов отправлять их клиенту
+const importFlushEvery = 100
+
+// importResult — результат одной строки загрузки
+// Line — номер строки с 1 (пустые строки пропускаются, но считаются),
+// Status — HTTP код, который получил бы POST /payments с этой строкой
+// Успех — Payment (созданный платеж), иначе Error
+type importResult struct {
+	Line    int             `json:"line"`
+	Status  int             `json:"status"`
+	Payment json.RawMessage `json:"payment,omitempty"`
+	Error   *ErrorResponse  `json:"error,omitempty"`
+}
+
+// handleImportPayments создает платежи из потока NDJSON
+// POST /payments/import
+//
+// Каждая строка проходит тот же путь, что POST /payments: проверки,
+// защита от дублей, шлюз, журнал аудита. Idempotency-Key к строкам
+// не применяется (он один на весь запрос), X-Force-Create — применяется
+// ?validate_only=true проверяет строки, ничего не создавая
+//
+// Коды ответа:
+// - 200 OK = поток результатов, по одному на непустую строку
+//
+// Ошибки отдельных строк — в их результатах, а не в коде ответа:
+// заголовки уходят клиенту раньше, чем прочитана вся загрузка
+func (s *Server) handleImportPayments(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// HTTP/1.1 по умолчанию не дает читать тело после начала ответа,
+	// а мы отвечаем, не дочитав загрузку. HTTP/2 умеет это и так
+	// (тогда ErrNotSupported — не ошибка)
+	if err := rc.EnableFullDuplex(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Printf("Error enabling full duplex for import: %v", err)
+	}
+	w.Header().Set("Content-Type", mediaNDJSON)
+	w.WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(w)
+	br := bufio.NewReader(r.Body)
+	created, failed := 0, 0
+	for lineNo := 1; ; lineNo++ {
+		line, tooLong, readErr := readImportLine(br)
+		line = bytes.TrimSpace(line)
+
+		if tooLong || len(line) > 0 {
+			var result importResult
+			if tooLong {
+				result = importResult{Line: lineNo, Status: http.StatusRequestEntityTooLarge, Error: &ErrorResponse{
+					Code:    CodeLineTooLong,
+					Message: fmt.Sprintf("Line is longer than %d bytes", maxImportLineBytes),
+				}}
+			} else {
+				result = s.importLine(r, lineNo, line)
+			}
+			if result.Error != nil {
+				failed++
+			} else {
+				created++
+			}
+			if err := enc.Encode(result); err != nil {
+				// Клиент отключился — дальше писать некому
+				log.Printf("Error writing import result: %v", err)
+				return
+			}
+			if (created+failed)%importFlushEvery == 0 {
+				if err := rc.Flush(); err != nil {
+					log.Printf("Error flushing import results: %v", err)
+					return
+				}
+			}
+		}
+
+		if readErr != nil {
+			if !errors.Is(readErr, io.EOF) {
+				log.Printf("Error reading import body at line %d: %v", lineNo, readErr)
+			}
+			break
+		}
+	}
+
+	log.Printf("Import finished: created=%d, failed=%d", created, failed)
+	if err := rc.Flush(); err != nil {
+		log.Printf("Error flushing import results: %v", err)
+	}
+}
+
+// importLine создает платеж из одной строки загрузки
+//
+// Строка отправляется в createPayment как тело отдельного запроса
+// (копия исходного: те же ключ API, сквозной ID и параметры),
+// а ответ перехватывается importRecorder и становится результатом
+func (s *Server) importLine(r *http.Request, lineNo int, line []byte) importResult {
+	lineReq := r.Clone(r.Context())
+	lineReq.Body = io.NopCloser(bytes.NewReader(line))
+	lineReq.ContentLength = int64(len(line))
+	// Один ключ идемпотентности на все строки превратил бы
+	// загрузку в один платеж
+	lineReq.Header.Del("Idempotency-Key")
+	// Ошибку строки кладем в результат обычным ErrorResponse,
+	// а не RFC 7807 или JSON:API
+	lineReq.Header.Set("Accept", mediaJSON)
+
+	rec := &importRecorder{header: make(http.Header)}
+	s.createPayment(rec, lineReq, "")
+
+	result := importResult{Line: lineNo, Status: rec.status}
+	if rec.status < http.StatusBadRequest {
+		result.Payment = json.RawMessage(bytes.TrimSpace(rec.body.Bytes()))
+		return result
+	}
+	var errResp ErrorResponse
+	if err := json.Unmarshal(rec.body.Bytes(), &errResp); err != nil {
+		errResp = ErrorResponse{Code: CodeInternal, Message: "Internal error"}
+	}
+	result.Error = &errResp
+	return result
+}
+
+// readImportLine читает строку до "\n" (или до конца потока),
+// не держа в памяти больше maxImportLineBytes: остаток длинной
+// строки дочитывается и отбрасывается, tooLong = true
+// err = io.EOF вместе с последней строкой без "\n"
+func readImportLine(br *bufio.Reader) (line []byte, tooLong bool, err error) {
+	for {
+		chunk, err := br.ReadSlice('\n')
+		if !tooLong {
+			if len(line)+len(chunk) > maxImportLineBytes {
+				tooLong, line = true, nil
+			} else {
+				line = append(line, chunk...)
+			}
+		}
+		// ErrBufferFull — строка длиннее буфера bufio, читаем дальше
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return line, tooLong, err
+		}
+	}
+}
+
+// importRecorder — http.ResponseWriter в памяти для ответа на одну
+// строку загрузки (как httptest.ResponseRecorder, но без пакета для тестов)
+type importRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *importRecorder) Header() http.Header { return rec.header }
+
+func (rec *importRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+func (rec *importRecorder) Write(p []byte) (int, error) {
+	rec.WriteHeader(http.StatusOK)
+	return rec.body.Write(p)
+}
@@ -0,0 +1,100 @@
+package payments
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// importT отправляет тело в POST /payments/import и разбирает
+// результаты построчно
+func importT(t *testing.T, s *Server, query, body string) []importResult {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/payments/import"+query, strings.NewReader(body))
+	req.Header.Set("Content-Type", mediaNDJSON)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != mediaNDJSON {
+		t.Fatalf("import = %d %s: %s", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+	var results []importResult
+	sc := bufio.NewScanner(bytes.NewReader(rec.Body.Bytes()))
+	for sc.Scan() {
+		var res importResult
+		if err := json.Unmarshal(sc.Bytes(), &res); err != nil {
+			t.Fatalf("result line %q: %v", sc.Text(), err)
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+// TestImportMixedLines — ошибочные строки получают ошибку со своим
+// номером и не прерывают загрузку; пустые строки считаются, но
+// результата не дают
+func TestImportMixedLines(t *testing.T) {
+	store := NewMemoryStore()
+	s := NewServer(store, nil, nil, Config{})
+	body := strings.Join([]string{
+		`{"amount": 100, "currency": "RUB"}`,
+		`{"amount": 100, "currency": `,
+		``,
+		`{"amount": -5, "currency": "RUB"}`,
+		`{"amount": "1.00", "currency": "RUB", "description": "` + strings.Repeat("x", maxImportLineBytes) + `"}`,
+		`{"amount": "250.00", "currency": "USD", "external_id": "order-42"}`,
+	}, "\n") // последняя строка без "\n"
+
+	results := importT(t, s, "", body)
+	want := []struct {
+		line, status int
+		code         string
+	}{
+		{1, http.StatusCreated, ""},
+		{2, http.StatusBadRequest, CodeInvalidJSON},
+		{4, http.StatusBadRequest, CodeInvalidAmount},
+		{5, http.StatusRequestEntityTooLarge, CodeLineTooLong},
+		{6, http.StatusCreated, ""},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("results = %+v, want %d", results, len(want))
+	}
+	for i, w := range want {
+		res := results[i]
+		code := ""
+		if res.Error != nil {
+			code = res.Error.Code
+		}
+		if res.Line != w.line || res.Status != w.status || code != w.code {
+			t.Errorf("result %d = line %d, status %d, code %q; want %+v", i, res.Line, res.Status, code, w)
+		}
+		if (res.Error == nil) != (len(res.Payment) > 0) {
+			t.Errorf("line %d: exactly one of payment/error expected", res.Line)
+		}
+	}
+
+	var created Payment
+	if err := json.Unmarshal(results[4].Payment, &created); err != nil || created.ExternalID != "order-42" {
+		t.Fatalf("line 6 payment = %s, %v", results[4].Payment, err)
+	}
+	if list, _ := store.List(context.Background(), true); len(list) != 2 {
+		t.Fatalf("stored = %d, want 2", len(list))
+	}
+}
+
+// TestImportValidateOnly — строки проверяются, но ничего не создается
+func TestImportValidateOnly(t *testing.T) {
+	store := NewMemoryStore()
+	s := NewServer(store, nil, nil, Config{})
+	results := importT(t, s, "?validate_only=true", "{\"amount\": 100, \"currency\": \"RUB\"}\n{\"amount\": 0, \"currency\": \"RUB\"}\n")
+	if len(results) != 2 || results[0].Error != nil || results[1].Error == nil {
+		t.Fatalf("results = %+v", results)
+	}
+	if list, _ := store.List(context.Background(), true); len(list) != 0 {
+		t.Fatalf("validate_only stored %d payments", len(list))
+	}
+}
@@ -0,0 +1,66 @@
+package payments
+
+import "net/http"
+
+// ===== ОГЛАВЛЕНИЕ API =====
+//
+// GET / отвечает кратким оглавлением: какие эндпоинты есть в этой
+// инсталляции и где служебные адреса (проверки, спецификация, метрики)
+// Удобно, чтобы "осмотреться" curl'ом без документации
+// Выключенные группы (Config.Features) в оглавление не попадают;
+// само оглавление выключается Features.Index — тогда GET / снова 404
+
+// indexResponse — тело ответа GET /
+type indexResponse struct {
+	Service   string            `json:"service"`
+	Endpoints []string          `json:"endpoints"`
+	Links     map[string]string `json:"links"`
+}
+
+// handleIndex отдает оглавление API
+// GET / → 200 {"service":"payment-system","endpoints":[…],"links":{…}}
+//
+// Пути — с учетом Config.BasePath, как в заголовке Location
+// Описание каждого эндпоинта — в спецификации (links.openapi)
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	var endpoints []string
+	add := func(enabled bool, paths ...string) {
+		if !enabled {
+			return
+		}
+		for _, p := range paths {
+			endpoints = append(endpoints, s.url(p))
+		}
+	}
+	f := s.features
+	add(true, "/payments", "/payments/{id}", "/payments/{id}/cancel", "/payments/summary", "/payments/bulk-status")
+	add(f.Export, "/payments/export")
+	add(f.Import, "/payments/import")
+	add(f.Reconcile, "/payments/reconcile")
+	add(f.Capture, "/payments/{id}/capture", "/payments/{id}/void")
+	add(f.Retry, "/payments/{id}/retry")
+	add(f.Split, "/payments/{id}/split")
+	add(f.Refunds, "/payments/{id}/refunds", "/payments/{id}/refunds/{refundId}")
+	add(f.Receipts, "/payments/{id}/receipt")
+	add(f.Stream, "/payments/{id}/stream")
+	add(f.Customers, "/customers", "/customers/{id}/payments")
+	add(f.Webhooks, "/webhooks/gateway")
+	add(f.Admin, "/admin/payments/{id}/status")
+	add(true, "/currencies")
+
+	writeJSON(w, r, http.StatusOK, indexResponse{
+		Service:   "payment-system",
+		Endpoints: endpoints,
+		Links: map[string]string{
+			"health":  s.url("/health"),
+			"ready":   s.url("/ready"),
+			"openapi": s.url("/openapi.json"),
+			"metrics": s.url("/metrics"),
+		},
+	})
+}
@@ -0,0 +1,66 @@
+package payments
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestIndexListsEndpoints(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	rec := doJSON(t, s, http.MethodGet, "/", "", nil)
+	var idx indexResponse
+	decodeBody(t, rec, &idx)
+	if rec.Code != http.StatusOK || idx.Service != "payment-system" {
+		t.Fatalf("GET / = %d %+v", rec.Code, idx)
+	}
+	for _, path := range []string{"/payments", "/payments/{id}", "/payments/{id}/refunds", "/payments/export", "/currencies"} {
+		if !slices.Contains(idx.Endpoints, path) {
+			t.Errorf("endpoints missing %s: %v", path, idx.Endpoints)
+		}
+	}
+	// Служебные эндпоинты по умолчанию выключены — и в оглавлении их нет
+	if slices.Contains(idx.Endpoints, "/admin/payments/{id}/status") {
+		t.Errorf("admin endpoint listed while disabled")
+	}
+	for name, want := range map[string]string{"health": "/health", "ready": "/ready", "openapi": "/openapi.json", "metrics": "/metrics"} {
+		if idx.Links[name] != want {
+			t.Errorf("links[%s] = %q, want %q", name, idx.Links[name], want)
+		}
+	}
+
+	// Оглавление — только для самого корня
+	if rec := doJSON(t, s, http.MethodGet, "/nope", "", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("GET /nope = %d", rec.Code)
+	}
+}
+
+// TestIndexFollowsFeatures — выключенные группы не попадают
+// в оглавление, а Features.Index выключает и его самого
+func TestIndexFollowsFeatures(t *testing.T) {
+	features := AllFeatures
+	features.Refunds = false
+	s := NewServer(NewMemoryStore(), nil, nil, Config{Features: &features})
+	var idx indexResponse
+	decodeBody(t, doJSON(t, s, http.MethodGet, "/", "", nil), &idx)
+	if slices.Contains(idx.Endpoints, "/payments/{id}/refunds") {
+		t.Fatalf("disabled refunds listed: %v", idx.Endpoints)
+	}
+
+	features.Index = false
+	off := NewServer(NewMemoryStore(), nil, nil, Config{Features: &features})
+	if rec := doJSON(t, off, http.MethodGet, "/", "", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("disabled index = %d", rec.Code)
+	}
+}
+
+// TestIndexBasePath — пути оглавления с префиксом, как в Location
+func TestIndexBasePath(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{BasePath: "/api/v1"})
+	rec := doJSON(t, s, http.MethodGet, "/api/v1", "", nil)
+	var idx indexResponse
+	decodeBody(t, rec, &idx)
+	if rec.Code != http.StatusOK || !slices.Contains(idx.Endpoints, "/api/v1/payments") || idx.Links["health"] != "/api/v1/health" {
+		t.Fatalf("GET /api/v1 = %d %+v", rec.Code, idx)
+	}
+}
@@ -0,0 +1,107 @@
+package payments
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+)
+
+// ===== ФОРМАТ JSON:API =====
+//
+// Клиенты на JSON:API (https://jsonapi.org) присылают
+// Accept: application/vnd.api+json и ждут ответ в "конверте":
+//
+//	{"data":{"type":"payment","id":"pay_…","attributes":{"amount":100,…}}}
+//
+// Список — массив таких объектов в "data". Обработчики об этом
+// не знают: конверт собирает writeJSON (см. jsonAPIEnvelope)
+
+// mediaJSONAPI — тип содержимого JSON:API
+const mediaJSONAPI = "application/vnd.api+json"
+
+// jsonAPIResource — сущность, которую можно отдать ресурсом JSON:API
+type jsonAPIResource interface {
+	jsonAPIType() string
+	jsonAPIID() string
+}
+
+func (p Payment) jsonAPIType() string  { return "payment" }
+func (p Payment) jsonAPIID() string    { return p.ID }
+func (r Refund) jsonAPIType() string   { return "refund" }
+func (r Refund) jsonAPIID() string     { return r.ID }
+func (c Customer) jsonAPIType() string { return "customer" }
+func (c Customer) jsonAPIID() string   { return c.ID }
+
+// jsonAPIObject — ресурс JSON:API: тип, ID и остальные поля
+type jsonAPIObject struct {
+	Type       string         `json:"type"`
+	ID         string         `json:"id"`
+	Attributes map[string]any `json:"attributes"`
+}
+
+// jsonAPIDocument — тело ответа JSON:API
+// Data — один jsonAPIObject или их массив
+type jsonAPIDocument struct {
+	Data any            `json:"data"`
+	Meta map[string]any `json:"meta,omitempty"`
+}
+
+// wantsJSONAPI сообщает, что клиент предпочитает JSON:API обычному JSON
+// Accept: */* или без заголовка — обычный JSON
+func wantsJSONAPI(r *http.Request) bool {
+	return negotiate(r.Header.Get("Accept"), mediaJSON, mediaJSONAPI) == mediaJSONAPI
+}
+
+// jsonAPIEnvelope заворачивает ответ обработчика в документ JSON:API
+// ok = false — у значения нет представления ресурсом (например,
+// {"status":"ok"}), оно отправляется обычным JSON
+func jsonAPIEnvelope(v any) (doc jsonAPIDocument, ok bool, err error) {
+	switch v := v.(type) {
+	case jsonAPIResource:
+		obj, err := newJSONAPIObject(v)
+		return jsonAPIDocument{Data: obj}, err == nil, err
+	case []Payment:
+		objs, err := newJSONAPIObjects(v)
+		return jsonAPIDocument{Data: objs}, err == nil, err
+	case []Refund:
+		objs, err := newJSONAPIObjects(v)
+		return jsonAPIDocument{Data: objs}, err == nil, err
+	case bulkPaymentsResponse:
+		// Ненайденные ID — не ресурсы, а сведения о запросе: в "meta"
+		objs, err := newJSONAPIObjects(v.Payments)
+		return jsonAPIDocument{Data: objs, Meta: map[string]any{"missing": v.Missing}}, err == nil, err
+	}
+	return jsonAPIDocument{}, false, nil
+}
+
+// newJSONAPIObjects собирает массив ресурсов (пустой список = [], не null)
+func newJSONAPIObjects[T jsonAPIResource](items []T) ([]jsonAPIObject, error) {
+	objs := make([]jsonAPIObject, 0, len(items))
+	for _, item := range items {
+		obj, err := newJSONAPIObject(item)
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
+
+// newJSONAPIObject собирает ресурс: attributes — те же поля, что
+// в обычном JSON ответе, кроме "id" (он уже на уровне ресурса)
+func newJSONAPIObject(res jsonAPIResource) (jsonAPIObject, error) {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return jsonAPIObject{}, err
+	}
+	// UseNumber: суммы в минорных единицах остаются точными, а не
+	// проходят через float64
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var attrs map[string]any
+	if err := dec.Decode(&attrs); err != nil {
+		return jsonAPIObject{}, err
+	}
+	delete(attrs, "id")
+	return jsonAPIObject{Type: res.jsonAPIType(), ID: res.jsonAPIID(), Attributes: attrs}, nil
+}
@@ -0,0 +1,82 @@
+package payments
+
+import (
+	"net/http"
+	"testing"
+)
+
+var acceptJSONAPI = map[string]string{"Accept": mediaJSONAPI}
+
+// TestJSONAPISingle — один платеж в "data" ресурсом с type, id, attributes
+func TestJSONAPISingle(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	p := createPaymentT(t, s, `{"amount": 100.5, "currency": "RUB", "description": "Order"}`)
+
+	rec := doJSON(t, s, http.MethodGet, "/payments/"+p.ID, "", acceptJSONAPI)
+	if ct := rec.Header().Get("Content-Type"); ct != mediaJSONAPI {
+		t.Fatalf("Content-Type = %s", ct)
+	}
+	var doc struct {
+		Data jsonAPIObject `json:"data"`
+	}
+	decodeBody(t, rec, &doc)
+	if doc.Data.Type != "payment" || doc.Data.ID != p.ID {
+		t.Fatalf("data = %+v", doc.Data)
+	}
+	attrs := doc.Data.Attributes
+	if _, ok := attrs["id"]; ok {
+		t.Error("id duplicated in attributes")
+	}
+	if attrs["currency"] != "RUB" || attrs["description"] != "Order" || attrs["amount_minor"] != float64(10050) {
+		t.Fatalf("attributes = %v", attrs)
+	}
+}
+
+// TestJSONAPICollection — список — массив ресурсов; пустой — [], не null
+func TestJSONAPICollection(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+
+	rec := doJSON(t, s, http.MethodGet, "/payments", "", acceptJSONAPI)
+	if body := rec.Body.String(); body != "{\"data\":[]}\n" {
+		t.Fatalf("empty list = %q", body)
+	}
+
+	a := createPaymentT(t, s, `{"amount": 1, "currency": "RUB"}`)
+	b := createPaymentT(t, s, `{"amount": 2, "currency": "RUB"}`)
+	var doc struct {
+		Data []jsonAPIObject `json:"data"`
+	}
+	decodeBody(t, doJSON(t, s, http.MethodGet, "/payments", "", acceptJSONAPI), &doc)
+	if len(doc.Data) != 2 {
+		t.Fatalf("data = %+v", doc.Data)
+	}
+	ids := map[string]bool{}
+	for _, obj := range doc.Data {
+		if obj.Type != "payment" {
+			t.Errorf("type = %s", obj.Type)
+		}
+		ids[obj.ID] = true
+	}
+	if !ids[a.ID] || !ids[b.ID] {
+		t.Fatalf("ids = %v, want %s and %s", ids, a.ID, b.ID)
+	}
+}
+
+// TestJSONAPIDefaultUnchanged — без Accept ответ обычный JSON, а
+// значения без представления ресурсом не заворачиваются
+func TestJSONAPIDefaultUnchanged(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	p := createPaymentT(t, s, `{"amount": 1, "currency": "RUB"}`)
+
+	rec := doJSON(t, s, http.MethodGet, "/payments/"+p.ID, "", nil)
+	var plain Payment
+	decodeBody(t, rec, &plain)
+	if rec.Header().Get("Content-Type") != mediaJSON || plain.ID != p.ID {
+		t.Fatalf("default = %s %+v", rec.Header().Get("Content-Type"), plain)
+	}
+
+	rec = doJSON(t, s, http.MethodGet, "/health", "", acceptJSONAPI)
+	if rec.Header().Get("Content-Type") != mediaJSON {
+		t.Fatalf("health Content-Type = %s, want plain JSON", rec.Header().Get("Content-Type"))
+	}
+}
@@ -0,0 +1,394 @@
+package payments
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ===== СПИСОК ПЛАТЕЖЕЙ =====
+
+// maxBulkIDs — сколько ID можно запросить за раз через ?ids=
+// Ограничение защищает от огромных запросов
+const maxBulkIDs = 100
+
+// bulkPaymentsResponse — ответ на ?ids=…&report_missing=true
+type bulkPaymentsResponse struct {
+	Payments []Payment `json:"payments"`
+	Missing  []string  `json:"missing"`
+}
+
+// handleListPayments возвращает список платежей
+// По умолчанию удаленные платежи скрыты
+// GET /payments?include_deleted=true — показать и удаленные
+//
+// Фильтры (комбинируются через "И", см. listFilter):
+// GET /payments?status=succeeded&currency=RUB&min_amount=1000&max_amount=5000
+// GET /payments?from=2024-01-01T00:00:00Z&to=2024-02-01T00:00:00Z
+// GET /payments?metadata[order_id]=1234 — по меткам интеграции
+//
+// Сортировка (см. listSort), по умолчанию новые платежи первыми:
+// GET /payments?sort=amount&order=asc
+//
+// GET /payments?include_display=true — добавить amount_display ("1,000.50 RUB")
+// GET /payments?display_currencies=USD,EUR — добавить display_amounts
+// (сумма по текущему курсу, см. withDisplayAmounts)
+//
+// Выборка по списку ID (вместо отдельного GET на каждый платеж):
+// GET /payments?ids=pay_a,pay_b,pay_c — только эти платежи в порядке ids
+// (если sort и order не заданы явно),
+// несуществующие ID молча пропускаются. С &report_missing=true ответ —
+// объект {"payments":[…],"missing":["pay_c"]}
+//
+// С ключами API (Config.APIKeys) список содержит только платежи ключа
+// запроса; ключ администратора с ?all=true видит все (см. apikey.go)
+//
+// Формат ответа выбирается по заголовку Accept:
+// - application/json (по умолчанию) = JSON массив
+// - application/vnd.api+json = документ JSON:API (см. jsonapi.go)
+// - text/csv = CSV таблица (удобно открыть в Excel)
+// - другое = 406 Not Acceptable
+func (s *Server) handleListPayments(w http.ResponseWriter, r *http.Request) {
+	format := negotiate(r.Header.Get("Accept"), mediaJSON, mediaJSONAPI, mediaCSV)
+	if format == "" {
+		writeError(w, r, http.StatusNotAcceptable, CodeNotAcceptable, "Supported formats: application/json, application/vnd.api+json, text/csv")
+		return
+	}
+
+	// r.URL.Query() = разобранные параметры строки запроса (?a=1&b=2)
+	// .Get("ключ") возвращает "" если параметра нет
+	query := r.URL.Query()
+	includeDeleted := query.Get("include_deleted") == "true"
+	filter, err := parseListFilter(query)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidQuery, err.Error())
+		return
+	}
+	order, err := parseListSort(query)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidQuery, err.Error())
+		return
+	}
+	display, err := parseDisplayRequest(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidQuery, err.Error())
+		return
+	}
+
+	var (
+		payments []Payment
+		missing  = []string{} // [] а не null в JSON
+	)
+	rawIDs := query.Get("ids")
+	if rawIDs != "" {
+		ids, parseErr := parseIDList(rawIDs)
+		if parseErr != nil {
+			writeError(w, r, http.StatusBadRequest, CodeInvalidID, parseErr.Error())
+			return
+		}
+		payments, missing, err = s.lookupPayments(r, ids, includeDeleted)
+	} else {
+		payments, err = s.store.List(r.Context(), includeDeleted)
+		// С ключами API каждый видит только свои платежи
+		payments = s.scopePayments(r, payments)
+	}
+	if err != nil {
+		log.Printf("Error listing payments: %v", err)
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+		return
+	}
+	payments = filter.apply(payments)
+	// Клиент, перечисливший ID, ждет их в своем порядке; сортировка
+	// по умолчанию этот порядок сломала бы
+	if rawIDs == "" || query.Has("sort") || query.Has("order") {
+		order.apply(payments)
+	}
+	if includeDisplay(r) {
+		withDisplay(payments)
+	}
+	if err := s.withDisplayAmounts(payments, display); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeUnsupportedCurrencyPair, err.Error())
+		return
+	}
+
+	if format == mediaCSV {
+		if err := writePaymentsCSV(w, payments); err != nil {
+			// Заголовки уже отправлены — остается только записать в лог
+			log.Printf("Error writing CSV: %v", err)
+		}
+		return
+	}
+	if query.Get("report_missing") == "true" {
+		writeJSON(w, r, http.StatusOK, bulkPaymentsResponse{Payments: payments, Missing: missing})
+		return
+	}
+	writeJSON(w, r, http.StatusOK, payments)
+}
+
+// listFilter — условия отбора платежей в списке
+// Пустое поле = условие не задано
+type listFilter struct {
+	status   string
+	currency string
+
+	// Границы суммы в минорных единицах (копейках), включительно
+	// nil = граница не задана
+	minAmount *int64
+	maxAmount *int64
+
+	// Период создания: from включительно, to НЕ включительно
+	// (from=2024-01-01, to=2024-02-01 = ровно январь)
+	// Нулевое время = граница не задана
+	from time.Time
+	to   time.Time
+
+	// Метки платежа: все пары ключ=значение должны совпасть
+	// (?metadata[order_id]=1234&metadata[shop]=main), nil = не задано
+	metadata map[string]string
+}
+
+// maxMetadataFilters — сколько условий metadata[…] можно задать за раз
+const maxMetadataFilters = 5
+
+// parseListFilter читает фильтры из строки запроса
+func parseListFilter(query url.Values) (listFilter, error) {
+	f := listFilter{
+		status:   query.Get("status"),
+		currency: query.Get("currency"),
+	}
+	if f.status != "" && !isKnownStatus(f.status) {
+		return f, fmt.Errorf("unknown status %q", f.status)
+	}
+
+	var err error
+	if f.minAmount, err = parseAmountBound(query, "min_amount"); err != nil {
+		return f, err
+	}
+	if f.maxAmount, err = parseAmountBound(query, "max_amount"); err != nil {
+		return f, err
+	}
+	if f.minAmount != nil && f.maxAmount != nil && *f.minAmount > *f.maxAmount {
+		return f, errors.New("min_amount must not be greater than max_amount")
+	}
+
+	if f.from, err = parseTimeBound(query, "from"); err != nil {
+		return f, err
+	}
+	if f.to, err = parseTimeBound(query, "to"); err != nil {
+		return f, err
+	}
+	if !f.from.IsZero() && !f.to.IsZero() && !f.from.Before(f.to) {
+		return f, errors.New("from must be earlier than to")
+	}
+
+	if f.metadata, err = parseMetadataFilter(query); err != nil {
+		return f, err
+	}
+	return f, nil
+}
+
+// parseMetadataFilter читает условия вида metadata[key]=value
+// Квадратные скобки в URL кодируются: metadata%5Border_id%5D=1234,
+// но url.Values уже содержит раскодированные имена
+func parseMetadataFilter(query url.Values) (map[string]string, error) {
+	var metadata map[string]string
+	for name, values := range query {
+		key, ok := strings.CutPrefix(name, "metadata[")
+		if !ok {
+			continue
+		}
+		key, ok = strings.CutSuffix(key, "]")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid metadata filter %q: expected metadata[key]=value", name)
+		}
+		if len(values) > 1 {
+			return nil, fmt.Errorf("metadata filter %q is given more than once", name)
+		}
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+		metadata[key] = values[0]
+	}
+	if len(metadata) > maxMetadataFilters {
+		return nil, fmt.Errorf("at most %d metadata filters are allowed", maxMetadataFilters)
+	}
+	return metadata, nil
+}
+
+// parseTimeBound читает границу периода в формате RFC 3339
+// Пример: 2024-01-01T00:00:00Z
+func parseTimeBound(query url.Values, name string) (time.Time, error) {
+	raw := query.Get(name)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 timestamp like 2024-01-01T00:00:00Z", name)
+	}
+	return t, nil
+}
+
+// parseAmountBound читает границу суммы (целое число минорных единиц)
+func parseAmountBound(query url.Values, name string) (*int64, error) {
+	raw := query.Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("%s must be a non-negative integer in minor units", name)
+	}
+	return &n, nil
+}
+
+// match сообщает, проходит ли платеж все условия фильтра
+func (f listFilter) match(p Payment) bool {
+	if f.status != "" && p.Status != f.status {
+		return false
+	}
+	if f.currency != "" && p.Currency != f.currency {
+		return false
+	}
+	if f.minAmount != nil && p.AmountMinor < *f.minAmount {
+		return false
+	}
+	if f.maxAmount != nil && p.AmountMinor > *f.maxAmount {
+		return false
+	}
+	if !f.from.IsZero() && p.CreatedAt.Before(f.from) {
+		return false
+	}
+	if !f.to.IsZero() && !p.CreatedAt.Before(f.to) {
+		return false
+	}
+	for key, value := range f.metadata {
+		// Отсутствующий ключ не совпадает даже с пустым значением
+		if v, ok := p.Metadata[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// apply возвращает платежи, прошедшие фильтр (порядок сохраняется)
+func (f listFilter) apply(payments []Payment) []Payment {
+	result := make([]Payment, 0, len(payments))
+	for _, p := range payments {
+		if f.match(p) {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// Ключи сортировки списка
+const (
+	sortCreatedAt = "created_at"
+	sortAmount    = "amount"
+	sortStatus    = "status"
+)
+
+// listSort — порядок платежей в списке
+type listSort struct {
+	key  string
+	desc bool
+}
+
+// parseListSort читает параметры sort и order
+// По умолчанию: created_at, desc (новые платежи первыми)
+func parseListSort(query url.Values) (listSort, error) {
+	o := listSort{key: sortCreatedAt, desc: true}
+	switch key := query.Get("sort"); key {
+	case "":
+	case sortCreatedAt, sortAmount, sortStatus:
+		o.key = key
+	default:
+		return o, fmt.Errorf("unknown sort key %q: expected created_at, amount or status", key)
+	}
+	switch order := query.Get("order"); order {
+	case "", "desc":
+	case "asc":
+		o.desc = false
+	default:
+		return o, fmt.Errorf("unknown order %q: expected asc or desc", order)
+	}
+	return o, nil
+}
+
+// apply сортирует платежи на месте
+//
+// При равных ключах порядок задают время создания и ID, поэтому
+// для одних и тех же данных результат всегда одинаковый
+func (o listSort) apply(payments []Payment) {
+	slices.SortFunc(payments, func(a, b Payment) int {
+		var c int
+		switch o.key {
+		case sortAmount:
+			c = cmp.Compare(a.AmountMinor, b.AmountMinor)
+		case sortStatus:
+			c = strings.Compare(a.Status, b.Status)
+		}
+		if c == 0 {
+			c = a.CreatedAt.Compare(b.CreatedAt)
+		}
+		if c == 0 {
+			c = strings.Compare(a.ID, b.ID)
+		}
+		if o.desc {
+			return -c
+		}
+		return c
+	})
+}
+
+// parseIDList разбирает список ID через запятую
+// Повторы убираются (порядок первых вхождений сохраняется),
+// некорректный ID или слишком длинный список = ошибка
+func parseIDList(raw string) ([]string, error) {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, id := range strings.Split(raw, ",") {
+		id = strings.TrimSpace(id)
+		if !isValidPaymentID(id) {
+			return nil, fmt.Errorf("invalid payment ID %q: must start with %s", id, paymentIDPrefix)
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	if len(ids) > maxBulkIDs {
+		return nil, fmt.Errorf("too many ids: at most %d allowed", maxBulkIDs)
+	}
+	return ids, nil
+}
+
+// lookupPayments загружает платежи по списку ID
+// Возвращает найденные платежи (в порядке ids) и ID, которых нет
+// Удаленные платежи без includeDeleted и чужие платежи (см. ownsPayment)
+// считаются отсутствующими
+func (s *Server) lookupPayments(r *http.Request, ids []string, includeDeleted bool) ([]Payment, []string, error) {
+	payments := make([]Payment, 0, len(ids))
+	missing := []string{}
+	for _, id := range ids {
+		p, err := s.store.Get(r.Context(), id)
+		if errors.Is(err, ErrPaymentNotFound) || (err == nil && ((p.Deleted && !includeDeleted) || !s.ownsPayment(r, p))) {
+			missing = append(missing, id)
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		payments = append(payments, p)
+	}
+	return payments, missing, nil
+}
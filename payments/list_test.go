@@ -0,0 +1,178 @@
+package payments
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+// listIDs запрашивает список и возвращает ID платежей в порядке ответа
+func listIDs(t *testing.T, s *Server, query string) []string {
+	t.Helper()
+	rec := doJSON(t, s, http.MethodGet, "/payments"+query, "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /payments%s = %d: %s", query, rec.Code, rec.Body.String())
+	}
+	var list []Payment
+	decodeBody(t, rec, &list)
+	ids := make([]string, len(list))
+	for i, p := range list {
+		ids[i] = p.ID
+	}
+	return ids
+}
+
+func newListServer(t *testing.T) *Server {
+	t.Helper()
+	store := NewMemoryStore()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, id := range []string{"pay_a", "pay_b", "pay_c"} {
+		savePaymentT(t, store, Payment{ID: id, AmountMinor: int64(300 - i*100), Currency: "RUB",
+			Status: StatusPending, CreatedAt: base.Add(time.Duration(i) * time.Hour), Version: 1})
+	}
+	return NewServer(store, nil, nil, Config{})
+}
+
+func TestListDefaultOrderNewestFirst(t *testing.T) {
+	s := newListServer(t)
+	if got := listIDs(t, s, ""); !slices.Equal(got, []string{"pay_c", "pay_b", "pay_a"}) {
+		t.Fatalf("order = %v", got)
+	}
+	if got := listIDs(t, s, "?sort=amount&order=asc"); !slices.Equal(got, []string{"pay_c", "pay_b", "pay_a"}) {
+		t.Fatalf("amount asc = %v", got)
+	}
+}
+
+// TestListByIDsKeepsRequestOrder — ?ids= отдает платежи в порядке
+// запроса, пока сортировка не задана явно
+func TestListByIDsKeepsRequestOrder(t *testing.T) {
+	s := newListServer(t)
+	if got := listIDs(t, s, "?ids=pay_a,pay_c,pay_b"); !slices.Equal(got, []string{"pay_a", "pay_c", "pay_b"}) {
+		t.Fatalf("ids order = %v", got)
+	}
+	if got := listIDs(t, s, "?ids=pay_a,pay_c,pay_b&sort=created_at"); !slices.Equal(got, []string{"pay_c", "pay_b", "pay_a"}) {
+		t.Fatalf("explicit sort = %v", got)
+	}
+}
+
+func TestListByIDsReportsMissing(t *testing.T) {
+	s := newListServer(t)
+	rec := doJSON(t, s, http.MethodGet, "/payments?ids=pay_b,pay_zzz&report_missing=true", "", nil)
+	var resp struct {
+		Payments []Payment `json:"payments"`
+		Missing  []string  `json:"missing"`
+	}
+	decodeBody(t, rec, &resp)
+	if len(resp.Payments) != 1 || resp.Payments[0].ID != "pay_b" || !slices.Equal(resp.Missing, []string{"pay_zzz"}) {
+		t.Fatalf("response = %+v", resp)
+	}
+}
+
+func TestListAmountRange(t *testing.T) {
+	s := newListServer(t)
+	if got := listIDs(t, s, "?min_amount=150&max_amount=300"); !slices.Equal(got, []string{"pay_b", "pay_a"}) {
+		t.Fatalf("range = %v", got)
+	}
+	// Границы включаются
+	if got := listIDs(t, s, "?min_amount=100&max_amount=100"); !slices.Equal(got, []string{"pay_c"}) {
+		t.Fatalf("exact bound = %v", got)
+	}
+	// Сочетается с другими фильтрами
+	if got := listIDs(t, s, "?min_amount=100&status=succeeded"); len(got) != 0 {
+		t.Fatalf("with status = %v", got)
+	}
+
+	for _, query := range []string{"?min_amount=500&max_amount=100", "?min_amount=abc", "?max_amount=-1"} {
+		if rec := doJSON(t, s, http.MethodGet, "/payments"+query, "", nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s = %d, want 400", query, rec.Code)
+		}
+	}
+}
+
+func TestListSorting(t *testing.T) {
+	s := newListServer(t)
+	cases := map[string][]string{
+		"?sort=amount":               {"pay_a", "pay_b", "pay_c"},
+		"?sort=created_at&order=asc": {"pay_a", "pay_b", "pay_c"},
+		// Равные статусы упорядочиваются по времени создания
+		"?sort=status&order=asc": {"pay_a", "pay_b", "pay_c"},
+	}
+	for query, want := range cases {
+		// Повторный запрос дает тот же порядок
+		for range 3 {
+			if got := listIDs(t, s, query); !slices.Equal(got, want) {
+				t.Fatalf("%s = %v, want %v", query, got, want)
+			}
+		}
+	}
+	for _, query := range []string{"?sort=fee", "?order=up"} {
+		if rec := doJSON(t, s, http.MethodGet, "/payments"+query, "", nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s = %d, want 400", query, rec.Code)
+		}
+	}
+}
+
+// TestListCreatedRange — from включается, to — нет
+// newListServer: pay_a 00:00, pay_b 01:00, pay_c 02:00 (1 января 2024)
+func TestListCreatedRange(t *testing.T) {
+	s := newListServer(t)
+	cases := map[string][]string{
+		"?from=2024-01-01T01:00:00Z":                                 {"pay_c", "pay_b"},
+		"?to=2024-01-01T01:00:00Z":                                   {"pay_a"},
+		"?from=2024-01-01T01:00:00Z&to=2024-01-01T02:00:00Z":         {"pay_b"},
+		"?from=2024-01-01T00:59:59.999Z&to=2024-01-01T01:00:00.001Z": {"pay_b"},
+		"?from=2024-01-01T03:00:00%2B03:00":                          {"pay_c", "pay_b", "pay_a"},
+	}
+	for query, want := range cases {
+		if got := listIDs(t, s, query); !slices.Equal(got, want) {
+			t.Errorf("%s = %v, want %v", query, got, want)
+		}
+	}
+	for _, query := range []string{"?from=2024-01-01", "?to=yesterday"} {
+		if rec := doJSON(t, s, http.MethodGet, "/payments"+query, "", nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s = %d, want 400", query, rec.Code)
+		}
+	}
+}
+
+// TestListByMetadata — платеж подходит, только если совпали все пары
+func TestListByMetadata(t *testing.T) {
+	store := NewMemoryStore()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, meta := range []map[string]string{
+		{"order_id": "1234", "shop": "main"},
+		{"order_id": "1234", "shop": "outlet"},
+		{"order_id": "9999"},
+	} {
+		savePaymentT(t, store, Payment{ID: []string{"pay_a", "pay_b", "pay_c"}[i], AmountMinor: 100, Currency: "RUB",
+			Status: StatusPending, CreatedAt: base.Add(time.Duration(i) * time.Hour), Metadata: meta, Version: 1})
+	}
+	s := NewServer(store, nil, nil, Config{})
+
+	for query, want := range map[string][]string{
+		"?metadata[order_id]=1234":                     {"pay_b", "pay_a"},
+		"?metadata%5Border_id%5D=1234":                 {"pay_b", "pay_a"},
+		"?metadata[order_id]=1234&metadata[shop]=main": {"pay_a"},
+		"?metadata[order_id]=0000":                     {},
+		"?metadata[missing]=x":                         {},
+	} {
+		if got := listIDs(t, s, query); !slices.Equal(got, want) {
+			t.Errorf("%s = %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestListByMetadataInvalid(t *testing.T) {
+	s := newListServer(t)
+	for _, query := range []string{
+		"?metadata[]=x",
+		"?metadata[order_id=x",
+		"?metadata[a]=1&metadata[a]=2",
+		"?metadata[a]=1&metadata[b]=1&metadata[c]=1&metadata[d]=1&metadata[e]=1&metadata[f]=1",
+	} {
+		if rec := doJSON(t, s, http.MethodGet, "/payments"+query, "", nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s = %d, want 400", query, rec.Code)
+		}
+	}
+}
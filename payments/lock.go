@@ -0,0 +1,76 @@
+package payments
+
+import (
+	"net/http"
+	"sync"
+)
+
+// ===== БЛОКИРОВКА ПЛАТЕЖА =====
+//
+// Каждая операция хранилища атомарна, но обработчик часто делает
+// несколько шагов: прочитать платеж, проверить, вызвать шлюз, записать
+// Два параллельных запроса к одному платежу (PATCH и отмена, два
+// возврата) переплетали бы эти шаги, и один из них получал бы
+// version_mismatch или, хуже, работал со старыми данными
+//
+// Поэтому изменяющие запросы к одному платежу выполняются по очереди
+// (paymentLocks), а к разным — параллельно, как и раньше
+// Блокировка действует внутри одного процесса: между экземплярами API
+// с общим Redis по-прежнему защищает проверка версии
+
+// keyedMutex — набор мьютексов по ключу (ID платежа)
+//
+// Мьютекс создается при первом lock и удаляется, когда его больше
+// никто не держит и не ждет (refs = 0): иначе map росла бы с каждым
+// платежом, к которому хоть раз обращались
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+// keyedLock — мьютекс ключа и число его владельцев и ожидающих
+type keyedLock struct {
+	sync.Mutex
+	refs int
+}
+
+func newKeyedMutex() *keyedMutex {
+	return &keyedMutex{locks: make(map[string]*keyedLock)}
+}
+
+// lock захватывает мьютекс ключа и возвращает функцию освобождения:
+//
+//	defer s.paymentLocks.lock(id)()
+func (km *keyedMutex) lock(key string) (unlock func()) {
+	km.mu.Lock()
+	l, ok := km.locks[key]
+	if !ok {
+		l = &keyedLock{}
+		km.locks[key] = l
+	}
+	l.refs++
+	km.mu.Unlock()
+
+	// Ждем уже без общего мьютекса: другие ключи не блокируются
+	l.Lock()
+	return func() {
+		l.Unlock()
+		km.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(km.locks, key)
+		}
+		km.mu.Unlock()
+	}
+}
+
+// isMutation сообщает, меняет ли запрос данные (все, кроме чтения)
+// Чтение не ждет блокировку: GET и долгий поток /stream не должны
+// стоять в очереди за изменениями
+func isMutation(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
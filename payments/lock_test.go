@@ -0,0 +1,134 @@
+package payments
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestKeyedMutexSerializesSameKey — под одним ключом счетчик без
+// атомиков не теряет инкременты (go test -race заметил бы гонку),
+// а после всех unlock мьютексы удалены из map
+func TestKeyedMutexSerializesSameKey(t *testing.T) {
+	km := newKeyedMutex()
+	const workers, rounds = 16, 200
+
+	counter := 0
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range rounds {
+				unlock := km.lock("pay_1")
+				counter++
+				unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if counter != workers*rounds {
+		t.Fatalf("counter = %d, want %d", counter, workers*rounds)
+	}
+	if len(km.locks) != 0 {
+		t.Fatalf("locks left in map: %d", len(km.locks))
+	}
+}
+
+// TestKeyedMutexOtherKeysNotBlocked — занятый ключ не держит другие
+func TestKeyedMutexOtherKeysNotBlocked(t *testing.T) {
+	km := newKeyedMutex()
+	unlock := km.lock("pay_1")
+	defer unlock()
+
+	done := make(chan struct{})
+	go func() {
+		km.lock("pay_2")()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("lock of another key waited for pay_1")
+	}
+}
+
+// TestSchedulerWaitsForPaymentLock — планировщик не трогает платеж,
+// пока его держит изменяющий запрос
+func TestSchedulerWaitsForPaymentLock(t *testing.T) {
+	store := NewMemoryStore()
+	s := NewServer(store, nil, nil, Config{})
+	ctx := context.Background()
+
+	past := time.Now().Add(-time.Minute)
+	p := Payment{ID: "pay_locked", AmountMinor: 100, Currency: "RUB", Status: StatusScheduled, ProcessAt: &past, Version: 1}
+	if err := store.Save(ctx, p); err != nil {
+		t.Fatal(err)
+	}
+
+	unlock := s.paymentLocks.lock(p.ID)
+	processed := make(chan int)
+	go func() { processed <- s.ProcessDueScheduled(ctx, time.Now()) }()
+
+	select {
+	case <-processed:
+		unlock()
+		t.Fatal("scheduler processed a locked payment")
+	case <-time.After(50 * time.Millisecond):
+	}
+	got, _ := store.Get(ctx, p.ID)
+	if got.Status != StatusScheduled {
+		t.Fatalf("status changed under lock: %s", got.Status)
+	}
+
+	unlock()
+	if n := <-processed; n != 1 {
+		t.Fatalf("processed = %d, want 1", n)
+	}
+	got, _ = store.Get(ctx, p.ID)
+	if got.Status != StatusPending {
+		t.Fatalf("status = %s, want %s", got.Status, StatusPending)
+	}
+}
+
+// TestConcurrentRefundsDoNotOverdraw — параллельные полные возвраты
+// одного платежа: проходит ровно один, остаток не уходит в минус
+func TestConcurrentRefundsDoNotOverdraw(t *testing.T) {
+	store := NewMemoryStore()
+	s := NewServer(store, nil, nil, Config{})
+	ctx := context.Background()
+
+	p := Payment{ID: "pay_refund", AmountMinor: 10000, AmountRefundableMinor: 10000, Currency: "RUB", Status: StatusSucceeded, Version: 1}
+	if err := store.Save(ctx, p); err != nil {
+		t.Fatal(err)
+	}
+
+	const workers = 20
+	codes := make(chan int, workers)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- doJSON(t, s, "POST", "/payments/pay_refund/refunds", `{}`, nil).Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	created := 0
+	for code := range codes {
+		if code == 201 {
+			created++
+		}
+	}
+	if created != 1 {
+		t.Fatalf("created refunds = %d, want 1", created)
+	}
+	got, _ := store.Get(ctx, p.ID)
+	if got.AmountRefundableMinor != 0 || got.AmountRefundedMinor != 10000 {
+		t.Fatalf("refundable = %d, refunded = %d", got.AmountRefundableMinor, got.AmountRefundedMinor)
+	}
+}
@@ -0,0 +1,36 @@
+package payments
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// ===== МЕТАДАННЫЕ ПЛАТЕЖА =====
+
+// Ограничения на Payment.Metadata
+const (
+	maxMetadataKeys        = 20
+	maxMetadataKeyLength   = 40
+	maxMetadataValueLength = 500
+)
+
+// validateMetadata проверяет количество ключей и длину ключей и значений
+// Длина считается в символах (рунах), а не в байтах: "заказ" = 5
+func validateMetadata(metadata map[string]string) error {
+	if len(metadata) > maxMetadataKeys {
+		return fmt.Errorf("metadata may contain at most %d keys", maxMetadataKeys)
+	}
+	for key, value := range metadata {
+		if key == "" {
+			return errors.New("metadata keys must not be empty")
+		}
+		if utf8.RuneCountInString(key) > maxMetadataKeyLength {
+			return fmt.Errorf("metadata key %q is longer than %d characters", key, maxMetadataKeyLength)
+		}
+		if utf8.RuneCountInString(value) > maxMetadataValueLength {
+			return fmt.Errorf("metadata value for key %q is longer than %d characters", key, maxMetadataValueLength)
+		}
+	}
+	return nil
+}
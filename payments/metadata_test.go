@@ -0,0 +1,51 @@
+package payments
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMetadataRoundTrip(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	p := createPaymentT(t, s, `{"amount": 100, "currency": "RUB", "metadata": {"order_id": "1234", "заказ": "№5"}}`)
+
+	var got Payment
+	decodeBody(t, doJSON(t, s, http.MethodGet, "/payments/"+p.ID, "", nil), &got)
+	want := map[string]string{"order_id": "1234", "заказ": "№5"}
+	if !maps.Equal(got.Metadata, want) {
+		t.Fatalf("metadata = %v, want %v", got.Metadata, want)
+	}
+}
+
+func TestMetadataLimits(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+
+	tooMany := make([]string, maxMetadataKeys+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf(`"k%d": "v"`, i)
+	}
+	cases := map[string]string{
+		"too many keys": `{` + strings.Join(tooMany, ",") + `}`,
+		"long key":      `{"` + strings.Repeat("k", maxMetadataKeyLength+1) + `": "v"}`,
+		"long value":    `{"k": "` + strings.Repeat("в", maxMetadataValueLength+1) + `"}`,
+		"empty key":     `{"": "v"}`,
+	}
+	for name, metadata := range cases {
+		rec := doJSON(t, s, http.MethodPost, "/payments", `{"amount": 100, "currency": "RUB", "metadata": `+metadata+`}`, nil)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d", name, rec.Code)
+			continue
+		}
+		var resp ErrorResponse
+		decodeBody(t, rec, &resp)
+		if resp.Code != CodeInvalidMetadata {
+			t.Errorf("%s: code = %s", name, resp.Code)
+		}
+	}
+
+	// Ровно на пределе — можно (длина в символах, а не байтах)
+	createPaymentT(t, s, `{"amount": 100, "currency": "RUB", "metadata": {"k": "`+strings.Repeat("в", maxMetadataValueLength)+`"}}`)
+}
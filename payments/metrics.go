@@ -0,0 +1,209 @@
+package payments
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ===== МЕТРИКИ =====
+
+// handleMetrics отдает метрики в текстовом формате Prometheus
+// GET /metrics
+//
+// Формат: строки "имя значение" с комментариями # HELP и # TYPE
+// Prometheus периодически забирает эту страницу и строит графики
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	s.metrics.write(w)
+
+	// Хранилища без уборщика (Redis) счетчика не ведут
+	if counter, ok := s.store.(timedStore).Store.(evictionCounter); ok {
+		fmt.Fprintln(w, "# HELP payments_evicted_total Terminal payments removed by the retention janitor.")
+		fmt.Fprintln(w, "# TYPE payments_evicted_total counter")
+		fmt.Fprintf(w, "payments_evicted_total %d\n", counter.Evicted())
+	}
+
+	if s.outbox != nil {
+		depth, err := s.outbox.Depth(r.Context())
+		if err != nil {
+			log.Printf("Error reading outbox depth: %v", err)
+		} else {
+			fmt.Fprintln(w, "# HELP payments_outbox_depth Events waiting for webhook delivery.")
+			fmt.Fprintln(w, "# TYPE payments_outbox_depth gauge")
+			fmt.Fprintf(w, "payments_outbox_depth %d\n", depth)
+		}
+	}
+}
+
+// ===== МЕТРИКИ ЗАПРОСОВ =====
+//
+// Каждый запрос считается с метками:
+//   - route — ШАБЛОН маршрута ("/payments/{id}"), а не путь запроса:
+//     с путем каждый ID платежа давал бы свой временной ряд, и число
+//     рядов в Prometheus росло бы без предела
+//   - method — метод HTTP (неизвестные методы — "OTHER", по той же причине)
+//   - status — класс кода ответа: "2xx", "4xx", "5xx"
+//
+// Запрос, не дошедший до роутера (например, 414 или неканонический
+// путь), получает route="unmatched"
+
+// durationBuckets — верхние границы корзин гистограммы времени
+// ответа в секундах (как корзины по умолчанию клиента Prometheus)
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// unmatchedRoute — метка route для запросов мимо роутера
+const unmatchedRoute = "unmatched"
+
+// requestLabels — метки одного временного ряда
+type requestLabels struct {
+	route  string
+	method string
+	status string
+}
+
+// requestSeries — счетчик и гистограмма одного набора меток
+// buckets[i] — число запросов не дольше durationBuckets[i]
+// (накопительно, как требует формат Prometheus)
+type requestSeries struct {
+	count   uint64
+	sum     float64
+	buckets []uint64
+}
+
+// requestMetrics накапливает метрики запросов
+// Обновляется из всех обработчиков одновременно — под мьютексом
+type requestMetrics struct {
+	mu     sync.Mutex
+	series map[requestLabels]*requestSeries
+}
+
+func newRequestMetrics() *requestMetrics {
+	return &requestMetrics{series: make(map[requestLabels]*requestSeries)}
+}
+
+// observe учитывает один запрос
+func (m *requestMetrics) observe(labels requestLabels, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.series[labels]
+	if !ok {
+		s = &requestSeries{buckets: make([]uint64, len(durationBuckets))}
+		m.series[labels] = s
+	}
+	seconds := d.Seconds()
+	s.count++
+	s.sum += seconds
+	for i, le := range durationBuckets {
+		if seconds <= le {
+			s.buckets[i]++
+		}
+	}
+}
+
+// write выводит метрики в формате Prometheus
+// Ряды сортируются, чтобы вывод не "прыгал" между запросами
+func (m *requestMetrics) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]requestLabels, 0, len(m.series))
+	for k := range m.series {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b requestLabels) int {
+		return cmp.Or(cmp.Compare(a.route, b.route), cmp.Compare(a.method, b.method), cmp.Compare(a.status, b.status))
+	})
+
+	fmt.Fprintln(w, "# HELP payments_http_requests_total HTTP requests by route template, method and status class.")
+	fmt.Fprintln(w, "# TYPE payments_http_requests_total counter")
+	for _, k := range keys {
+		fmt.Fprintf(w, "payments_http_requests_total{%s} %d\n", k.format(), m.series[k].count)
+	}
+
+	fmt.Fprintln(w, "# HELP payments_http_request_duration_seconds HTTP request duration by route template, method and status class.")
+	fmt.Fprintln(w, "# TYPE payments_http_request_duration_seconds histogram")
+	for _, k := range keys {
+		s, labels := m.series[k], k.format()
+		for i, le := range durationBuckets {
+			fmt.Fprintf(w, "payments_http_request_duration_seconds_bucket{%s,le=\"%g\"} %d\n", labels, le, s.buckets[i])
+		}
+		fmt.Fprintf(w, "payments_http_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, s.count)
+		fmt.Fprintf(w, "payments_http_request_duration_seconds_sum{%s} %g\n", labels, s.sum)
+		fmt.Fprintf(w, "payments_http_request_duration_seconds_count{%s} %d\n", labels, s.count)
+	}
+}
+
+// format собирает метки в виде route="…",method="…",status="…"
+// %q экранирует кавычки и обратную косую черту, как требует формат
+func (l requestLabels) format() string {
+	return fmt.Sprintf("route=%q,method=%q,status=%q", l.route, l.method, l.status)
+}
+
+// routeHolder — шаблон маршрута запроса, который узнает dispatch
+// Лежит в контексте: middleware снаружи роутера видит тот же указатель
+type routeHolder struct {
+	pattern string
+}
+
+// routeHolderKey — ключ routeHolder в контексте запроса
+type routeHolderKey struct{}
+
+// setRoute запоминает шаблон маршрута для метрик запроса
+// Вне measureRequests ничего не делает
+func setRoute(ctx context.Context, pattern string) {
+	if h, ok := ctx.Value(routeHolderKey{}).(*routeHolder); ok {
+		h.pattern = pattern
+	}
+}
+
+// metricMethods — методы, которые попадают в метку method как есть
+// Метод запроса выбирает клиент: без списка любой "метод" стал бы
+// новым временным рядом
+var metricMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+// measureRequests — middleware, которое считает запросы и время
+// ответа (см. requestMetrics); шаблон маршрута сообщает dispatch
+func (m *requestMetrics) measureRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		route := &routeHolder{pattern: unmatchedRoute}
+		rw := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), routeHolderKey{}, route)))
+
+		method := r.Method
+		if !metricMethods[method] {
+			method = "OTHER"
+		}
+		status := rw.status
+		if status == 0 {
+			// Обработчик ничего не отправил — net/http ответит 200
+			status = http.StatusOK
+		}
+		m.observe(requestLabels{
+			route:  route.pattern,
+			method: method,
+			status: fmt.Sprintf("%dxx", status/100),
+		}, time.Since(start))
+	})
+}
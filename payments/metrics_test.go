@@ -0,0 +1,77 @@
+package payments
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// metricLine ищет строку счетчика запросов с метками labels
+func metricLine(t *testing.T, s *Server, labels string) string {
+	t.Helper()
+	body := doJSON(t, s, http.MethodGet, "/metrics", "", nil).Body.String()
+	prefix := "payments_http_requests_total{" + labels + "} "
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(line, prefix) {
+			return line
+		}
+	}
+	t.Fatalf("no series %s in:\n%s", labels, body)
+	return ""
+}
+
+// TestRequestMetricsLabels — запросы к разным платежам попадают в один
+// ряд с шаблоном маршрута, методом и классом кода ответа
+func TestRequestMetricsLabels(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	a := createPaymentT(t, s, `{"amount": 100, "currency": "RUB"}`)
+	b := createPaymentT(t, s, `{"amount": 100, "currency": "RUB"}`)
+	doJSON(t, s, http.MethodGet, "/payments/"+a.ID, "", nil)
+	doJSON(t, s, http.MethodGet, "/payments/"+b.ID, "", nil)
+	doJSON(t, s, http.MethodGet, "/payments/pay_missing", "", nil)
+
+	cases := map[string]string{
+		`route="/payments",method="POST",status="2xx"`:     "2",
+		`route="/payments/{id}",method="GET",status="2xx"`: "2",
+		`route="/payments/{id}",method="GET",status="4xx"`: "1",
+	}
+	for labels, want := range cases {
+		if line := metricLine(t, s, labels); !strings.HasSuffix(line, " "+want) {
+			t.Errorf("%s: %q, want count %s", labels, line, want)
+		}
+	}
+
+	body := doJSON(t, s, http.MethodGet, "/metrics", "", nil).Body.String()
+	if strings.Contains(body, a.ID) {
+		t.Fatalf("raw payment ID leaked into labels:\n%s", body)
+	}
+	if !strings.Contains(body, `payments_http_request_duration_seconds_bucket{route="/payments/{id}",method="GET",status="2xx",le="+Inf"} 2`) {
+		t.Fatalf("histogram +Inf bucket missing:\n%s", body)
+	}
+}
+
+// TestRequestMetricsUnknownMethod — нестандартный метод не заводит
+// новый ряд на каждое значение
+func TestRequestMetricsUnknownMethod(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	doJSON(t, s, "BREW", "/payments", "", nil)
+	doJSON(t, s, "PURGE", "/payments", "", nil)
+	if line := metricLine(t, s, `route="/payments",method="OTHER",status="4xx"`); !strings.HasSuffix(line, " 2") {
+		t.Fatalf("OTHER = %q", line)
+	}
+}
+
+// TestEvictedMetric — /metrics отдает счетчик уборщика MemoryStore
+func TestEvictedMetric(t *testing.T) {
+	store := NewMemoryStore()
+	s := NewServer(store, nil, nil, Config{})
+	savePaymentT(t, store, Payment{ID: "pay_old", AmountMinor: 100, Currency: "RUB", Status: StatusCanceled,
+		CreatedAt: time.Now().Add(-time.Hour), Version: 1})
+	store.EvictExpired(time.Now())
+
+	body := doJSON(t, s, http.MethodGet, "/metrics", "", nil).Body.String()
+	if !strings.Contains(body, "# TYPE payments_evicted_total counter\npayments_evicted_total 1\n") {
+		t.Fatalf("no evicted counter in:\n%s", body)
+	}
+}
@@ -0,0 +1,153 @@
+package payments
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+)
+
+// ===== ПРОМЕЖУТОЧНЫЕ ОБРАБОТЧИКИ (MIDDLEWARE) =====
+//
+// Middleware — функция, которая принимает http.Handler и возвращает
+// новый http.Handler, добавляя поведение "вокруг" обработчика:
+//
+//	handler = recoverPanic(handler)
+//
+// Так общая логика (восстановление после паники, лимиты и т.д.)
+// пишется один раз, а не в каждом обработчике
+
+// requestIDHeader — ID запроса, который ставит прокси или балансировщик
+// перед API; пишется в лог паники, чтобы связать ее с логами прокси
+const requestIDHeader = "X-Request-ID"
+
+// recoverPanic перехватывает панику в обработчике и отвечает 500 JSON
+//
+// Без него net/http тоже переживет панику, но клиент получит
+// оборванное соединение вместо понятной ошибки
+//
+// Паника пишется в лог через slog отдельными полями (метод, путь,
+// ID запроса, стек): по ним сборщик логов находит и группирует
+// паники, не разбирая многострочный текст. Клиент стека не видит
+func recoverPanic(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &statusRecorder{ResponseWriter: w}
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			// http.ErrAbortHandler — "штатная" паника для обрыва ответа,
+			// ее нужно пробросить дальше в net/http
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+			// Стек вызовов — чтобы найти место паники
+			slog.Error("panic serving request",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("request_id", r.Header.Get(requestIDHeader)),
+				slog.String("correlation_id", r.Header.Get(correlationHeader)),
+				slog.Any("panic", err),
+				slog.String("stack", string(debug.Stack())))
+
+			// Если ответ уже начали отправлять, заменить его нельзя
+			if rw.status != 0 {
+				return
+			}
+			writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+		}()
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// statusRecorder запоминает код ответа, отправленный обработчиком
+// 0 = обработчик еще ничего не отправил
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader реализует http.ResponseWriter
+func (rw *statusRecorder) WriteHeader(status int) {
+	if rw.status == 0 {
+		rw.status = status
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+// Write реализует http.ResponseWriter
+// Запись тела без WriteHeader означает код 200
+func (rw *statusRecorder) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	return rw.ResponseWriter.Write(b)
+}
+
+// Unwrap возвращает исходный ResponseWriter
+// Через него http.ResponseController находит Flush и дедлайны
+// (нужны потоку событий /payments/{id}/stream)
+func (rw *statusRecorder) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// limitConcurrency ограничивает число одновременно выполняемых запросов
+//
+// СЕМАФОР НА БУФЕРИЗОВАННОМ КАНАЛЕ:
+// Канал емкостью max — это max "жетонов". Запрос кладет жетон в канал
+// и забирает его по завершении. Если канал полон, свободных мест нет:
+// отвечаем 503 сразу, а не ставим запрос в бесконечную очередь
+// (очередь только копила бы память и таймауты у клиентов)
+//
+// Открытый поток /payments/{id}/stream тоже занимает место,
+// пока клиент не отключится
+//
+// max <= 0 = без ограничения
+func limitConcurrency(max int, next http.Handler) http.Handler {
+	if max <= 0 {
+		return next
+	}
+	slots := make(chan struct{}, max)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// select с default не блокируется: либо место есть, либо сразу отказ
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+			next.ServeHTTP(w, r)
+		default:
+			// Retry-After — через сколько секунд имеет смысл повторить
+			w.Header().Set("Retry-After", "1")
+			writeError(w, r, http.StatusServiceUnavailable, CodeOverloaded, "Too many concurrent requests, retry later")
+		}
+	})
+}
+
+// limitURLLength отклоняет запросы со слишком длинным адресом: 414
+//
+// Длинная строка запроса (например, ?ids= на тысячи ID) заставляет
+// сервер разбирать и фильтровать все это до ответа; проще отказать
+// сразу, до любой другой обработки
+//   - maxURL — длина всего адреса запроса (путь + строка запроса)
+//   - maxQuery — длина строки запроса (после "?")
+//
+// Значение <= 0 = без ограничения
+func limitURLLength(maxURL, maxQuery int, next http.Handler) http.Handler {
+	if maxURL <= 0 && maxQuery <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// RequestURI — адрес ровно в том виде, в каком он пришел
+		// (r.URL.String() собрал бы его заново и мог бы изменить длину)
+		switch {
+		case maxURL > 0 && len(r.RequestURI) > maxURL:
+			writeError(w, r, http.StatusRequestURITooLong, CodeURITooLong,
+				fmt.Sprintf("Request URL must be at most %d bytes", maxURL))
+		case maxQuery > 0 && len(r.URL.RawQuery) > maxQuery:
+			writeError(w, r, http.StatusRequestURITooLong, CodeURITooLong,
+				fmt.Sprintf("Query string must be at most %d bytes", maxQuery))
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package payments
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestRecoverPanicReturnsCleanError — паника обработчика дает 500
+// с общим JSON телом, а в лог уходят метод, путь, ID запроса и стек
+func TestRecoverPanicReturnsCleanError(t *testing.T) {
+	var logs bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+	defer slog.SetDefault(prev)
+
+	h := recoverPanic(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("secret internal state")
+	}))
+	rec := doJSON(t, h, http.MethodGet, "/boom", "", map[string]string{requestIDHeader: "req-42"})
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", rec.Code)
+	}
+	var resp ErrorResponse
+	decodeBody(t, rec, &resp)
+	if resp.Code != CodeInternal || strings.Contains(rec.Body.String(), "secret") {
+		t.Fatalf("body leaks internals or has wrong code: %s", rec.Body.String())
+	}
+
+	var entry map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("log is not one JSON record: %q", logs.String())
+	}
+	if entry["method"] != "GET" || entry["path"] != "/boom" || entry["request_id"] != "req-42" {
+		t.Fatalf("log fields = %v", entry)
+	}
+	if stack, _ := entry["stack"].(string); !strings.Contains(stack, "recoverPanic") {
+		t.Fatalf("stack missing: %v", entry["stack"])
+	}
+}
+
+// Ответ уже начат — заменить его на 500 нельзя
+func TestRecoverPanicAfterWriteKeepsStatus(t *testing.T) {
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)))
+	defer slog.SetDefault(prev)
+
+	h := recoverPanic(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("late")
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202", rec.Code)
+	}
+}
+
+func TestRecoverPanicPropagatesAbort(t *testing.T) {
+	h := recoverPanic(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	defer func() {
+		if err, _ := recover().(error); !errors.Is(err, http.ErrAbortHandler) {
+			t.Fatalf("recovered %v, want http.ErrAbortHandler", err)
+		}
+	}()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	t.Fatal("ErrAbortHandler must not be swallowed")
+}
+
+// TestLimitConcurrencySaturated — пока все места заняты, новый запрос
+// сразу получает 503 с Retry-After; после освобождения снова проходит
+func TestLimitConcurrencySaturated(t *testing.T) {
+	const limit = 2
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	h := limitConcurrency(limit, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/block" {
+			entered <- struct{}{}
+			<-release
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	var wg sync.WaitGroup
+	for range limit {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/block", nil))
+			if rec.Code != http.StatusNoContent {
+				t.Errorf("blocked request status = %d", rec.Code)
+			}
+		}()
+		<-entered
+	}
+
+	rec := doJSON(t, h, http.MethodGet, "/fast", "", nil)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("saturated status = %d, want 503", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("Retry-After missing")
+	}
+	var resp ErrorResponse
+	decodeBody(t, rec, &resp)
+	if resp.Code != CodeOverloaded {
+		t.Errorf("code = %s, want %s", resp.Code, CodeOverloaded)
+	}
+
+	close(release)
+	wg.Wait()
+	if rec := doJSON(t, h, http.MethodGet, "/fast", "", nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("after release status = %d", rec.Code)
+	}
+}
+
+// TestLimitURLLength — слишком длинная строка запроса или адрес
+// получают 414 до обработчика, ровно на пределе — проходят
+func TestLimitURLLength(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{MaxURLLength: 200, MaxQueryLength: 100})
+
+	ids := "?ids=" + strings.Repeat("pay_x,", 20) // 5 + 120 байт
+	rec := doJSON(t, s, http.MethodGet, "/payments"+ids, "", nil)
+	var resp ErrorResponse
+	decodeBody(t, rec, &resp)
+	if rec.Code != http.StatusRequestURITooLong || resp.Code != CodeURITooLong {
+		t.Fatalf("long query: %d %+v", rec.Code, resp)
+	}
+
+	atLimit := "?ids=" + strings.Repeat("x", 96) // ровно 100 байт после "?"
+	if rec := doJSON(t, s, http.MethodGet, "/payments"+atLimit, "", nil); rec.Code == http.StatusRequestURITooLong {
+		t.Fatalf("query at limit = %d", rec.Code)
+	}
+
+	// Длинный путь без строки запроса упирается в MaxURLLength
+	if rec := doJSON(t, s, http.MethodGet, "/payments/pay_"+strings.Repeat("x", 200), "", nil); rec.Code != http.StatusRequestURITooLong {
+		t.Fatalf("long path = %d", rec.Code)
+	}
+
+	// Без лимитов длина не проверяется
+	open := NewServer(NewMemoryStore(), nil, nil, Config{})
+	if rec := doJSON(t, open, http.MethodGet, "/payments"+ids, "", nil); rec.Code == http.StatusRequestURITooLong {
+		t.Fatalf("no limit = %d", rec.Code)
+	}
+}
@@ -0,0 +1,49 @@
+package payments
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ===== МИНИМАЛЬНАЯ СУММА СПИСАНИЯ =====
+//
+// Слишком маленькое списание невыгодно: фиксированная часть комиссии
+// шлюза съедает его целиком. Минимумы задаются по валютам
+// (Config.MinAmounts, формат — см. ParseCurrencyAmounts)
+
+// ParseCurrencyAmounts разбирает суммы по валютам — например,
+// минимальные суммы списания (Config.MinAmounts) или пороги
+// предупреждений (Config.WarnAmounts)
+// Формат: "RUB:10.00,USD:0.50,JPY:50" (в основных единицах валюты)
+// Результат — суммы в минорных единицах: RUB:10.00 → 1000
+func ParseCurrencyAmounts(s string) (map[string]int64, error) {
+	amounts := make(map[string]int64)
+	if strings.TrimSpace(s) == "" {
+		return amounts, nil
+	}
+	for _, entry := range strings.Split(s, ",") {
+		code, amount, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if !ok || !isCurrencyCode(code) {
+			return nil, fmt.Errorf("invalid entry %q: expected CUR:amount", entry)
+		}
+		minor, err := parseAmountLiteral(strings.TrimSpace(amount), code)
+		if err != nil {
+			return nil, fmt.Errorf("invalid amount in %q: %v", entry, err)
+		}
+		amounts[code] = minor
+	}
+	return amounts, nil
+}
+
+// belowMinimum сообщает, что сумма меньше минимума валюты
+// Валюта без записи = минимума нет
+func (s *Server) belowMinimum(amountMinor int64, currency string) (int64, bool) {
+	minimum, ok := s.minAmounts[currency]
+	return minimum, ok && amountMinor < minimum
+}
+
+// minimumMessage — текст ошибки "сумма меньше минимума"
+func minimumMessage(minimumMinor int64, currency string) string {
+	return "Amount is below the minimum of " + formatAmountDisplay(minimumMinor, currency)
+}
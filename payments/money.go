@@ -0,0 +1,103 @@
+package payments
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ===== ДЕНЬГИ =====
+
+// ErrCurrencyMismatch — операция над суммами в разных валютах
+var ErrCurrencyMismatch = errors.New("currency mismatch")
+
+// ErrAmountOverflow — результат не помещается в int64
+var ErrAmountOverflow = errors.New("amount overflows int64")
+
+// addMinor складывает суммы в минорных единицах с проверкой переполнения
+//
+// Обычное a + b при выходе за int64 молча "заворачивается" в
+// отрицательное число — для денег это недопустимо
+// Переполнение возможно, только если знаки слагаемых совпадают,
+// а знак результата — другой
+func addMinor(a, b int64) (int64, error) {
+	sum := a + b
+	if (a >= 0) == (b >= 0) && (sum >= 0) != (a >= 0) {
+		return 0, ErrAmountOverflow
+	}
+	return sum, nil
+}
+
+// Money — сумма в минорных единицах вместе с валютой
+//
+// Голые int64 легко сложить "не глядя": 100 USD + 100 RUB = 200 чего?
+// Методы Money сначала сверяют валюты и при расхождении возвращают
+// ErrCurrencyMismatch, а не молча считают ерунду
+type Money struct {
+	AmountMinor int64
+	Currency    string
+}
+
+// checkCurrency проверяет, что у сумм одна валюта
+func (m Money) checkCurrency(other Money) error {
+	if m.Currency != other.Currency {
+		return fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	return nil
+}
+
+// Add возвращает m + other
+func (m Money) Add(other Money) (Money, error) {
+	if err := m.checkCurrency(other); err != nil {
+		return Money{}, err
+	}
+	sum, err := addMinor(m.AmountMinor, other.AmountMinor)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{AmountMinor: sum, Currency: m.Currency}, nil
+}
+
+// Sub возвращает m - other
+func (m Money) Sub(other Money) (Money, error) {
+	if err := m.checkCurrency(other); err != nil {
+		return Money{}, err
+	}
+	// -MinInt64 не помещается в int64 — проверяем отдельно
+	if other.AmountMinor == math.MinInt64 {
+		return Money{}, ErrAmountOverflow
+	}
+	diff, err := addMinor(m.AmountMinor, -other.AmountMinor)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{AmountMinor: diff, Currency: m.Currency}, nil
+}
+
+// Equal сообщает, равны ли суммы
+// Суммы в разных валютах не сравниваются: это ошибка, а не false
+func (m Money) Equal(other Money) (bool, error) {
+	if err := m.checkCurrency(other); err != nil {
+		return false, err
+	}
+	return m.AmountMinor == other.AmountMinor, nil
+}
+
+// Cmp сравнивает суммы: -1 если m < other, 0 если равны, +1 если m > other
+func (m Money) Cmp(other Money) (int, error) {
+	if err := m.checkCurrency(other); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.AmountMinor < other.AmountMinor:
+		return -1, nil
+	case m.AmountMinor > other.AmountMinor:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// IsZero сообщает, что сумма равна нулю
+func (m Money) IsZero() bool {
+	return m.AmountMinor == 0
+}
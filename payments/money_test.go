@@ -0,0 +1,74 @@
+package payments
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestMoneyArithmetic(t *testing.T) {
+	a := Money{AmountMinor: 150, Currency: "RUB"}
+	b := Money{AmountMinor: 50, Currency: "RUB"}
+
+	if sum, err := a.Add(b); err != nil || sum != (Money{200, "RUB"}) {
+		t.Errorf("Add = %+v, %v", sum, err)
+	}
+	if diff, err := a.Sub(b); err != nil || diff != (Money{100, "RUB"}) {
+		t.Errorf("Sub = %+v, %v", diff, err)
+	}
+	if eq, err := a.Equal(Money{150, "RUB"}); err != nil || !eq {
+		t.Errorf("Equal = %v, %v", eq, err)
+	}
+	if cmp, err := b.Cmp(a); err != nil || cmp != -1 {
+		t.Errorf("Cmp = %d, %v", cmp, err)
+	}
+}
+
+// TestMoneyCurrencyMismatch — операции над разными валютами — ошибка
+func TestMoneyCurrencyMismatch(t *testing.T) {
+	rub := Money{AmountMinor: 100, Currency: "RUB"}
+	usd := Money{AmountMinor: 100, Currency: "USD"}
+
+	if _, err := rub.Add(usd); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Add: err = %v", err)
+	}
+	if _, err := rub.Sub(usd); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Sub: err = %v", err)
+	}
+	if eq, err := rub.Equal(usd); !errors.Is(err, ErrCurrencyMismatch) || eq {
+		t.Errorf("Equal = %v, %v", eq, err)
+	}
+	if _, err := rub.Cmp(usd); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Cmp: err = %v", err)
+	}
+}
+
+func TestMoneyOverflow(t *testing.T) {
+	top := Money{AmountMinor: math.MaxInt64, Currency: "RUB"}
+	if _, err := top.Add(Money{1, "RUB"}); !errors.Is(err, ErrAmountOverflow) {
+		t.Errorf("Add overflow: err = %v", err)
+	}
+	if _, err := (Money{-2, "RUB"}).Sub(top); !errors.Is(err, ErrAmountOverflow) {
+		t.Errorf("Sub overflow: err = %v", err)
+	}
+	if _, err := (Money{0, "RUB"}).Sub(Money{math.MinInt64, "RUB"}); !errors.Is(err, ErrAmountOverflow) {
+		t.Errorf("Sub MinInt64: err = %v", err)
+	}
+}
+
+// TestApplyRefundCurrencyMismatch — возврат в чужой валюте не меняет платеж
+func TestApplyRefundCurrencyMismatch(t *testing.T) {
+	p := Payment{Status: StatusSucceeded, Currency: "RUB", AmountMinor: 1000, AmountRefundableMinor: 1000}
+	got, err := applyRefund(p, Refund{AmountMinor: 100, Currency: "USD"})
+	if !errors.Is(err, ErrCurrencyMismatch) {
+		t.Fatalf("err = %v, want currency mismatch", err)
+	}
+	if got.AmountRefundableMinor != 1000 || got.AmountRefundedMinor != 0 {
+		t.Fatalf("payment changed: %+v", got)
+	}
+
+	got, err = applyRefund(p, Refund{AmountMinor: 1000, Currency: "RUB"})
+	if err != nil || got.Status != StatusRefunded || got.AmountRefundableMinor != 0 || got.AmountRefundedMinor != 1000 {
+		t.Fatalf("full refund = %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,78 @@
+package payments
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ===== СОГЛАСОВАНИЕ ФОРМАТА (CONTENT NEGOTIATION) =====
+
+// Типы содержимого, которые умеет отдавать API
+const (
+	mediaJSON    = "application/json"
+	mediaCSV     = "text/csv"
+	mediaText    = "text/plain"
+	mediaProblem = "application/problem+json"
+	mediaNDJSON  = "application/x-ndjson"
+)
+
+// negotiate выбирает формат ответа по заголовку Accept
+//
+// offers — форматы, которые умеет отдавать обработчик (первый = по умолчанию)
+// Возвращает выбранный формат или "", если клиент не принимает ни один
+// (тогда правильный ответ — 406 Not Acceptable)
+//
+// Пример заголовка: Accept: text/csv;q=0.9, application/json
+// q = "вес" предпочтения от 0 до 1 (по умолчанию 1), q=0 = "не присылать"
+// Нет заголовка = клиенту подходит что угодно
+func negotiate(accept string, offers ...string) string {
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+
+	type mediaRange struct {
+		value string
+		q     float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		// "text/csv;q=0.9" → тип "text/csv" и параметры ";q=0.9"
+		value, params, _ := strings.Cut(part, ";")
+		value = strings.ToLower(strings.TrimSpace(value))
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			name, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.TrimSpace(name) == "q" {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if value != "" && q > 0 {
+			ranges = append(ranges, mediaRange{value: value, q: q})
+		}
+	}
+
+	// Сначала самые предпочтительные; при равном весе — в порядке заголовка
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+	for _, mr := range ranges {
+		for _, offer := range offers {
+			if mediaMatches(mr.value, offer) {
+				return offer
+			}
+		}
+	}
+	return ""
+}
+
+// mediaMatches проверяет, подходит ли offer под диапазон из Accept
+// Диапазон может быть точным (text/csv), по группе (text/*) или любым (*/*)
+func mediaMatches(mediaRange, offer string) bool {
+	if mediaRange == "*/*" || mediaRange == offer {
+		return true
+	}
+	group, ok := strings.CutSuffix(mediaRange, "/*")
+	return ok && strings.HasPrefix(offer, group+"/")
+}
@@ -0,0 +1,126 @@
+package payments
+
+import (
+	_ "embed"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+)
+
+// ===== OPENAPI =====
+
+// openAPISpec — описание API в формате OpenAPI 3 (openapi.json рядом)
+//
+// go:embed вшивает файл в бинарник при сборке: спецификация всегда
+// соответствует версии кода и не нужна отдельно при деплое
+// Меняете запрос или ответ — обновите и openapi.json
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// loadOpenAPI разбирает и проверяет встроенную спецификацию
+func loadOpenAPI() (*openapi3.T, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(openAPISpec)
+	if err != nil {
+		return nil, err
+	}
+	if err := doc.Validate(openapi3.NewLoader().Context); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// handleOpenAPI отдает встроенную спецификацию как есть
+// GET /openapi.json
+//
+// Для генераторов клиентов и прочих инструментов: им нужен сам
+// документ, а не страница с ним. Доступен без ключа API, как /health:
+// в спецификации нет ничего секретного
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	w.Header().Set("Content-Type", mediaJSON)
+	w.Write(openAPISpec)
+}
+
+// validateRequests — middleware, проверяющее тело запроса по схеме OpenAPI
+// (включается Config.ValidateRequests)
+//
+// Несоответствие схеме (не тот тип поля, нет обязательного поля)
+// отклоняется с 400 schema_violation еще до обработчика.
+// Проверки, которых нет в схеме (поддерживаемая валюта, формат суммы),
+// по-прежнему делают обработчики
+//
+// Запросы, которых нет в спецификации (неизвестный путь или метод),
+// и запросы с пустым телом пропускаются: на них обработчики сами
+// ответят 404, 405 или body_required
+//
+// Тело читается целиком, а затем подменяется копией,
+// поэтому обработчик получает его нетронутым
+func validateRequests(doc *openapi3.T, next http.Handler) (http.Handler, error) {
+	router, err := legacy.NewRouter(doc)
+	if err != nil {
+		return nil, err
+	}
+	options := &openapi3filter.Options{
+		ExcludeRequestQueryParams: true,
+		// Не подставлять в тело значения по умолчанию из схемы:
+		// обработчик должен видеть то, что прислал клиент
+		SkipSettingDefaults: true,
+		AuthenticationFunc:  openapi3filter.NoopAuthenticationFunc,
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		route, pathParams, err := router.FindRoute(r)
+		if errors.Is(err, routers.ErrPathNotFound) || errors.Is(err, routers.ErrMethodNotAllowed) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err == nil {
+			err = openapi3filter.ValidateRequest(r.Context(), &openapi3filter.RequestValidationInput{
+				Request:    r,
+				PathParams: pathParams,
+				Route:      route,
+				Options:    options,
+			})
+		}
+		if err != nil {
+			writeFieldErrors(w, r, []FieldError{schemaFieldError(err)})
+			return
+		}
+		next.ServeHTTP(w, r)
+	}), nil
+}
+
+// schemaFieldError переводит ошибку kin-openapi в FieldError
+// Field — путь к полю через точку ("metadata.order"), если он известен
+func schemaFieldError(err error) FieldError {
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		return FieldError{
+			Field:   strings.Join(schemaErr.JSONPointer(), "."),
+			Code:    CodeSchemaViolation,
+			Message: schemaErr.Reason,
+		}
+	}
+	var reqErr *openapi3filter.RequestError
+	if errors.As(err, &reqErr) {
+		field := "body"
+		if reqErr.Parameter != nil {
+			field = reqErr.Parameter.Name
+		}
+		return FieldError{Field: field, Code: CodeSchemaViolation, Message: reqErr.Error()}
+	}
+	return FieldError{Code: CodeSchemaViolation, Message: err.Error()}
+}
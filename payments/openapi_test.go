@@ -0,0 +1,77 @@
+package payments
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestValidateRequestsRejectsSchemaViolation — тело не по схеме
+// отклоняется до обработчика; без флага проверку делает сам обработчик
+func TestValidateRequestsRejectsSchemaViolation(t *testing.T) {
+	body := `{"amount": 10, "currency": "RUB", "description": 42}`
+
+	s := NewServer(NewMemoryStore(), nil, nil, Config{ValidateRequests: true})
+	rec := doJSON(t, s, http.MethodPost, "/payments", body, nil)
+	var resp ErrorResponse
+	decodeBody(t, rec, &resp)
+	if rec.Code != http.StatusBadRequest || resp.Code != CodeSchemaViolation {
+		t.Fatalf("validated: %d %+v", rec.Code, resp)
+	}
+	if len(resp.Fields) != 1 || resp.Fields[0].Field != "description" {
+		t.Fatalf("fields = %+v, want description", resp.Fields)
+	}
+
+	// Корректное тело проходит до обработчика
+	if rec := doJSON(t, s, http.MethodPost, "/payments", `{"amount": 10, "currency": "RUB"}`, nil); rec.Code != http.StatusCreated {
+		t.Fatalf("valid body = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Без ValidateRequests схема не проверяется
+	off := NewServer(NewMemoryStore(), nil, nil, Config{})
+	rec = doJSON(t, off, http.MethodPost, "/payments", body, nil)
+	decodeBody(t, rec, &resp)
+	if resp.Code == CodeSchemaViolation {
+		t.Fatalf("schema checked with ValidateRequests off: %+v", resp)
+	}
+}
+
+// TestValidateRequestsSkipsEmptyBody — пустое тело проверяет обработчик
+func TestValidateRequestsSkipsEmptyBody(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{ValidateRequests: true})
+	rec := doJSON(t, s, http.MethodPost, "/payments", "", nil)
+	var resp ErrorResponse
+	decodeBody(t, rec, &resp)
+	if resp.Code != CodeBodyRequired {
+		t.Fatalf("empty body: %d %+v", rec.Code, resp)
+	}
+}
+
+// TestOpenAPIDocument — /openapi.json отдает встроенный документ как
+// есть: JSON с ключом openapi, без ключа API и с правильным типом
+func TestOpenAPIDocument(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{APIKeys: []string{"alice"}})
+	rec := doJSON(t, s, http.MethodGet, "/openapi.json", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /openapi.json = %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != mediaJSON {
+		t.Fatalf("Content-Type = %q, want %s", ct, mediaJSON)
+	}
+	var doc map[string]any
+	decodeBody(t, rec, &doc)
+	if version, _ := doc["openapi"].(string); !strings.HasPrefix(version, "3.") {
+		t.Fatalf("openapi = %v", doc["openapi"])
+	}
+	if paths, _ := doc["paths"].(map[string]any); paths["/openapi.json"] == nil || paths["/payments"] == nil {
+		t.Fatalf("paths missing from document")
+	}
+	if !bytes.Equal(rec.Body.Bytes(), openAPISpec) {
+		t.Fatal("served document differs from the embedded one")
+	}
+
+	if rec := doJSON(t, s, http.MethodPost, "/openapi.json", "", nil); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("POST = %d", rec.Code)
+	}
+}
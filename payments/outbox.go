@@ -0,0 +1,266 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ===== OUTBOX: НАДЕЖНАЯ ДОСТАВКА СОБЫТИЙ =====
+//
+// ПАТТЕРН "TRANSACTIONAL OUTBOX":
+// Обработчик не отправляет уведомление сам ("выстрелил и забыл" —
+// при сбое получателя событие потеряно), а записывает событие в outbox.
+// Отдельный фоновый воркер читает outbox и доставляет события,
+// повторяя попытки с растущей задержкой, пока получатель не ответит 2xx
+//
+// Outbox — интерфейс: сейчас есть реализация в памяти, но ее можно
+// заменить таблицей в базе данных, и события переживут перезапуск
+
+// EventPaymentStatus — тип события "изменился статус платежа"
+const EventPaymentStatus = "payment.status_changed"
+
+// OutboxEvent — событие, ожидающее доставки
+type OutboxEvent struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Payment   Payment   `json:"data"`
+
+	// Служебные поля доставки (получателю не отправляются)
+	Attempts      int       `json:"-"`
+	NextAttemptAt time.Time `json:"-"`
+	LastError     string    `json:"-"`
+}
+
+// Outbox — хранилище событий, ожидающих доставки
+type Outbox interface {
+	// Enqueue добавляет событие в очередь
+	Enqueue(ctx context.Context, e OutboxEvent) error
+
+	// Due возвращает до limit событий, время доставки которых наступило,
+	// в порядке создания
+	Due(ctx context.Context, now time.Time, limit int) ([]OutboxEvent, error)
+
+	// MarkDelivered отмечает событие доставленным (оно покидает очередь)
+	MarkDelivered(ctx context.Context, id string) error
+
+	// MarkFailed записывает неудачную попытку и время следующей
+	MarkFailed(ctx context.Context, id string, next time.Time, reason string) error
+
+	// Depth возвращает число недоставленных событий (для метрик)
+	Depth(ctx context.Context) (int, error)
+}
+
+// newEventID генерирует ID события вида "evt_<uuid v4>"
+func newEventID() string {
+	return newID("evt_")
+}
+
+// ===== OUTBOX В ПАМЯТИ =====
+
+// MemoryOutbox — потокобезопасный outbox в памяти процесса
+// Доставленные события удаляются, поэтому память не растет
+type MemoryOutbox struct {
+	mu     sync.Mutex
+	events map[string]OutboxEvent
+}
+
+// Проверка на этапе компиляции, что MemoryOutbox реализует Outbox
+var _ Outbox = (*MemoryOutbox)(nil)
+
+// NewMemoryOutbox создает пустой outbox
+func NewMemoryOutbox() *MemoryOutbox {
+	return &MemoryOutbox{events: make(map[string]OutboxEvent)}
+}
+
+// Enqueue реализует Outbox
+func (o *MemoryOutbox) Enqueue(ctx context.Context, e OutboxEvent) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.events[e.ID] = e
+	return nil
+}
+
+// Due реализует Outbox
+func (o *MemoryOutbox) Due(ctx context.Context, now time.Time, limit int) ([]OutboxEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var due []OutboxEvent
+	for _, e := range o.events {
+		if !e.NextAttemptAt.After(now) {
+			due = append(due, e)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].CreatedAt.Before(due[j].CreatedAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+// MarkDelivered реализует Outbox
+func (o *MemoryOutbox) MarkDelivered(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.events, id)
+	return nil
+}
+
+// MarkFailed реализует Outbox
+func (o *MemoryOutbox) MarkFailed(ctx context.Context, id string, next time.Time, reason string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	e, ok := o.events[id]
+	if !ok {
+		return nil
+	}
+	e.Attempts++
+	e.NextAttemptAt = next
+	e.LastError = reason
+	o.events[id] = e
+	return nil
+}
+
+// Depth реализует Outbox
+func (o *MemoryOutbox) Depth(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.events), nil
+}
+
+// ===== ВОРКЕР ДОСТАВКИ =====
+
+// OutboxWorker доставляет события из outbox на URL получателя
+// POST с JSON телом события; любой 2xx = доставлено
+//
+// Порядок доставки не гарантирован при повторах: если событие
+// не доставилось, следующее может прийти раньше него.
+// Получатель должен сравнивать data.version и игнорировать старые версии
+type OutboxWorker struct {
+	outbox Outbox
+	url    string
+	client *http.Client
+
+	// Задержка перед повтором: BaseDelay * 2^(попытка), но не больше MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// BatchSize — сколько событий брать за один проход
+	BatchSize int
+}
+
+// NewOutboxWorker создает воркер с настройками по умолчанию:
+// повторы через 1s, 2s, 4s... до 10 минут, по 100 событий за проход
+func NewOutboxWorker(outbox Outbox, url string) *OutboxWorker {
+	return &OutboxWorker{
+		outbox:    outbox,
+		url:       url,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		BaseDelay: time.Second,
+		MaxDelay:  10 * time.Minute,
+		BatchSize: 100,
+	}
+}
+
+// Run доставляет события каждые interval, пока не отменен ctx
+// Функция блокирующая: запускайте в отдельной горутине
+func (wk *OutboxWorker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			wk.DrainOnce(ctx)
+		}
+	}
+}
+
+// DrainOnce делает один проход: пытается доставить все события,
+// время которых наступило. Возвращает число доставленных
+func (wk *OutboxWorker) DrainOnce(ctx context.Context) int {
+	events, err := wk.outbox.Due(ctx, time.Now(), wk.BatchSize)
+	if err != nil {
+		log.Printf("Outbox: error loading events: %v", err)
+		return 0
+	}
+
+	delivered := 0
+	for _, e := range events {
+		if err := wk.deliver(ctx, e); err != nil {
+			next := time.Now().Add(wk.backoff(e.Attempts))
+			log.Printf("Outbox: delivery failed: Event=%s, Attempt=%d, Error=%v", e.ID, e.Attempts+1, err)
+			if err := wk.outbox.MarkFailed(ctx, e.ID, next, err.Error()); err != nil {
+				log.Printf("Outbox: error recording failure: %v", err)
+			}
+			continue
+		}
+		if err := wk.outbox.MarkDelivered(ctx, e.ID); err != nil {
+			log.Printf("Outbox: error marking delivered: %v", err)
+			continue
+		}
+		delivered++
+	}
+	return delivered
+}
+
+// deliver отправляет одно событие получателю
+func (wk *OutboxWorker) deliver(ctx context.Context, e OutboxEvent) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wk.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := wk.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// backoff — задержка перед повтором после attempts неудачных попыток
+func (wk *OutboxWorker) backoff(attempts int) time.Duration {
+	d := wk.BaseDelay
+	for range attempts {
+		d *= 2
+		if d >= wk.MaxDelay {
+			return wk.MaxDelay
+		}
+	}
+	return d
+}
@@ -0,0 +1,123 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newReceiver — получатель уведомлений; status решает, что ответить
+// на очередную (n-ю, с единицы) доставку
+func newReceiver(t *testing.T, status func(n int32) int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e OutboxEvent
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil || e.Type != EventPaymentStatus {
+			t.Errorf("bad delivery: %+v, %v", e, err)
+		}
+		w.WriteHeader(status(calls.Add(1)))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func enqueueT(t *testing.T, o Outbox, id string) {
+	t.Helper()
+	e := OutboxEvent{ID: id, Type: EventPaymentStatus, CreatedAt: time.Now(), Payment: Payment{ID: "pay_1"}}
+	if err := o.Enqueue(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOutboxDelivery(t *testing.T) {
+	srv, calls := newReceiver(t, func(int32) int { return http.StatusNoContent })
+	outbox := NewMemoryOutbox()
+	enqueueT(t, outbox, "evt_1")
+	enqueueT(t, outbox, "evt_2")
+
+	wk := NewOutboxWorker(outbox, srv.URL)
+	if n := wk.DrainOnce(context.Background()); n != 2 {
+		t.Fatalf("delivered = %d, want 2", n)
+	}
+	if depth, _ := outbox.Depth(context.Background()); depth != 0 || calls.Load() != 2 {
+		t.Fatalf("depth = %d, calls = %d", depth, calls.Load())
+	}
+	// Доставленное не отправляется повторно
+	if n := wk.DrainOnce(context.Background()); n != 0 || calls.Load() != 2 {
+		t.Fatalf("second drain delivered %d", n)
+	}
+}
+
+// TestOutboxRetry — ошибка получателя оставляет событие в очереди
+// с отложенной повторной попыткой; повтор после задержки доставляет
+func TestOutboxRetry(t *testing.T) {
+	srv, calls := newReceiver(t, func(n int32) int {
+		if n == 1 {
+			return http.StatusInternalServerError
+		}
+		return http.StatusOK
+	})
+	outbox := NewMemoryOutbox()
+	enqueueT(t, outbox, "evt_1")
+	wk := NewOutboxWorker(outbox, srv.URL)
+	wk.BaseDelay = time.Hour
+
+	if n := wk.DrainOnce(context.Background()); n != 0 {
+		t.Fatalf("delivered = %d on 500", n)
+	}
+	all, _ := outbox.Due(context.Background(), time.Now().Add(2*time.Hour), 10)
+	if len(all) != 1 || all[0].Attempts != 1 || !strings.Contains(all[0].LastError, "500") {
+		t.Fatalf("after failure = %+v", all)
+	}
+	// Задержка еще не прошла — событие не берется
+	if n := wk.DrainOnce(context.Background()); n != 0 || calls.Load() != 1 {
+		t.Fatalf("retried before backoff: delivered %d, calls %d", n, calls.Load())
+	}
+
+	// Переносим попытку в прошлое, как будто задержка истекла
+	if err := outbox.MarkFailed(context.Background(), "evt_1", time.Now().Add(-time.Second), "test"); err != nil {
+		t.Fatal(err)
+	}
+	if n := wk.DrainOnce(context.Background()); n != 1 {
+		t.Fatalf("retry delivered = %d", n)
+	}
+	if depth, _ := outbox.Depth(context.Background()); depth != 0 {
+		t.Fatalf("depth = %d after delivery", depth)
+	}
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	wk := &OutboxWorker{BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+	for attempts, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second} {
+		if got := wk.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
+
+// TestOutboxStatusChangeAndMetric — смена статуса попадает в outbox,
+// глубина очереди видна в /metrics
+func TestOutboxStatusChangeAndMetric(t *testing.T) {
+	outbox := NewMemoryOutbox()
+	s := NewServer(NewMemoryStore(), nil, nil, Config{Outbox: outbox})
+	p := createPaymentT(t, s, `{"amount": 10, "currency": "RUB"}`)
+	if rec := doJSON(t, s, http.MethodPatch, "/payments/"+p.ID, `{"status":"succeeded"}`, nil); rec.Code != http.StatusOK {
+		t.Fatalf("PATCH = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	depth, _ := outbox.Depth(context.Background())
+	if depth == 0 {
+		t.Fatal("status change not enqueued")
+	}
+	rec := doJSON(t, s, http.MethodGet, "/metrics", "", nil)
+	if want := "payments_outbox_depth " + strconv.Itoa(depth) + "\n"; !strings.Contains(rec.Body.String(), want) {
+		t.Fatalf("metrics missing %q:\n%s", want, rec.Body.String())
+	}
+}
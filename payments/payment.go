@@ -0,0 +1,375 @@
+// Package payments — ядро платежной системы: модель платежа, хранилище
+// и HTTP обработчики.
+//
+// ЗАЧЕМ ОТДЕЛЬНЫЙ ПАКЕТ:
+// Код в package main нельзя импортировать из других пакетов и тестов.
+// Библиотечный пакет payments можно подключить где угодно:
+//
+//	import "github.com/namestnikoff/payment-system/payments"
+//
+// cmd/api/main.go только собирает зависимости (конфиг, хранилище)
+// и запускает HTTP сервер.
+package payments
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ===== СТРУКТУРЫ ДАННЫХ =====
+
+// Payment представляет платеж в системе
+//
+// ЧТО ТАКОЕ СТРУКТУРА (struct):
+// - Это способ группировки связанных данных
+// - Похоже на класс без методов (методы добавляются отдельно)
+// - Похоже на словарь с фиксированными ключами и типами
+//
+// СИНТАКСИС:
+// type ИмяСтруктуры struct { поля }
+type Payment struct {
+	// ПОЛЯ СТРУКТУРЫ:
+	// ИмяПоля ТипДанных `тег`
+
+	// ID — уникальный идентификатор платежа
+	// string = текстовая строка (как str в Python)
+	// `json:"id"` = JSON тег, указывает:
+	//   - При конвертации в JSON это поле будет называться "id" (маленькими)
+	//   - При парсинге JSON ключ "id" попадет в это поле
+	// ВАЖНО: ID с большой буквы = публичное поле (видно из других пакетов)
+	//         id с маленькой = приватное (только внутри этого пакета)
+	//         Payment экспортируется, поэтому его поля доступны в cmd/api
+	ID string `json:"id"`
+
+	// SequenceNumber — короткий порядковый номер платежа (1, 2, 3...)
+	// Удобно называть в обращениях в поддержку вместо длинного UUID
+	// Уникален и строго возрастает в порядке создания (см. Store.NextSequenceNumber)
+	SequenceNumber int64 `json:"sequence_number"`
+
+	// Amount — сумма платежа
+	// float64 = число с плавающей точкой, 64 бита точности
+	// Для денег в продакшене лучше использовать int64 (копейки/центы)
+	// Причина: float64 имеет ошибки округления (0.1 + 0.2 ≠ 0.3)
+	// Пример: вместо 100.50 RUB хранить 10050 копеек
+	Amount float64 `json:"amount"`
+
+	// AmountMinor — та же сумма в минорных единицах (копейках, центах)
+	// Вычисляется сервером при создании; все проверки и расчеты
+	// (лимиты, суммы, возвраты) ведутся в целых числах без ошибок округления
+	// `json:"-"` = поле не разбирается из запроса; в ответ его вместе
+	// с десятичной строкой добавляет MarshalJSON
+	AmountMinor int64 `json:"-"`
+
+	// amountLiteral — сумма из запроса, если клиент прислал ее строкой
+	// ("amount": "100.50"). Строка переводится в минорные единицы точно,
+	// без промежуточного float64 (см. UnmarshalJSON и parseAmountLiteral)
+	// Поле с маленькой буквы в JSON не попадает
+	amountLiteral string
+
+	// amountNumber — сумма из запроса, если клиент прислал ее числом,
+	// в исходной записи ("amount": 100.29 → "100.29"). По ней сумма
+	// переводится в минорные единицы без промежуточного float64
+	// (см. numberToMinor)
+	amountNumber json.Number
+
+	// amountMinorValue и amountCurrency — сумма из запроса в виде
+	// объекта {"amount": {"value": 10050, "currency": "RUB"}}: value уже
+	// в минорных единицах (исходная запись числа), currency — валюта
+	// объекта. Проверяются и переводятся в AmountMinor в validatePayment
+	amountMinorValue json.Number
+	amountCurrency   string
+
+	// manualCapture — клиент прислал "capture": false: платеж только
+	// авторизуется (статус authorized), списание — отдельным запросом
+	// POST /payments/{id}/capture. Только во входящем запросе
+	manualCapture bool
+
+	// AmountDisplay — сумма для показа человеку: "1,000.50 RUB"
+	// Не хранится: заполняется в ответе по ?include_display=true
+	AmountDisplay string `json:"amount_display,omitempty"`
+
+	// DisplayAmounts — сумма в других валютах по текущему курсу:
+	// {"USD": 1.09}. Не хранится: заполняется в ответе
+	// по ?display_currencies=USD,EUR (см. withDisplayAmounts)
+	DisplayAmounts map[string]float64 `json:"display_amounts,omitempty"`
+
+	// Currency — код валюты
+	// Формат: ISO 4217 (USD, EUR, RUB, GBP и т.д.)
+	// 3 буквы, всегда в верхнем регистре
+	Currency string `json:"currency"`
+
+	// Status — статус платежа
+	// Возможные значения: константы Status* ниже
+	Status      string `json:"status"`
+	Description string `json:"description,omitempty"`
+
+	// FailureReason — почему платеж не прошел (только для failed):
+	// "insufficient_funds", "card_expired" и т.д. (см. failure.go)
+	// Заполняет сервер по коду ошибки шлюза
+	FailureReason string `json:"failure_reason,omitempty"`
+
+	// RetryCount — сколько раз отклоненный платеж отправляли в шлюз
+	// повторно (см. retry.go). Ведет сервер, значение клиента игнорируется
+	RetryCount int `json:"retry_count,omitempty"`
+
+	// RefundCount — сколько возвратов проведено по платежу
+	// (см. applyRefund). Ведет сервер, значение клиента игнорируется
+	RefundCount int `json:"refund_count,omitempty"`
+
+	// ExternalID — ID платежа в системе клиента (номер заказа и т.п.)
+	// Необязательное поле; если задано, уникально среди всех платежей:
+	// повторное создание с тем же external_id получает 409 со ссылкой
+	// на существующий платеж (идемпотентность без заголовка Idempotency-Key)
+	ExternalID string `json:"external_id,omitempty"`
+
+	// CustomerID — владелец платежа (необязательно)
+	// Если указан, клиент с таким ID должен существовать (см. Customer)
+	CustomerID string `json:"customer_id,omitempty"`
+
+	// ParentID — платеж, частью которого является этот (см. split.go)
+	// Заполняет сервер при разбиении, значение клиента игнорируется
+	ParentID string `json:"parent_id,omitempty"`
+
+	// CorrelationID — сквозной ID из заголовка X-Correlation-ID
+	// при создании; возвращается во всех запросах к платежу
+	// (см. correlation.go). Значение из тела игнорируется
+	CorrelationID string `json:"correlation_id,omitempty"`
+
+	// CreatedByKey — ID ключа API, которым создан платеж (см. apiKeyID)
+	// Заполняет сервер; при включенных ключах платеж видит только
+	// этот ключ и администратор (см. apikey.go)
+	CreatedByKey string `json:"created_by_key,omitempty"`
+
+	// Metadata — произвольные метки интеграции ("order_id": "A-17")
+	// Сервер их не интерпретирует: сохраняет и возвращает как есть
+	// Ограничения на размер — см. validateMetadata
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// FeeMinor — комиссия процессинга (см. Config.Fees)
+	// NetAmountMinor — сумма к зачислению: AmountMinor - FeeMinor
+	// Оба поля в минорных единицах, вычисляются сервером при создании
+	FeeMinor       int64 `json:"fee_minor"`
+	NetAmountMinor int64 `json:"net_amount_minor"`
+
+	// AmountRefundedMinor — сколько уже возвращено (сумма всех возвратов)
+	// AmountRefundableMinor — сколько еще можно вернуть
+	// (AmountMinor - AmountRefundedMinor). Оба поля в минорных единицах,
+	// их ведет сервер (см. Store.CreateRefund), значения клиента игнорируются
+	AmountRefundedMinor   int64 `json:"amount_refunded_minor"`
+	AmountRefundableMinor int64 `json:"amount_refundable_minor"`
+
+	// AmountAuthorizedMinor — заблокированная сумма двухшагового платежа
+	// ("capture": false). Списать можно ее или меньше; после списания
+	// AmountMinor = списанная сумма, а это поле остается как было
+	// Для обычных платежей 0 (в JSON не выводится)
+	AmountAuthorizedMinor int64 `json:"amount_authorized_minor,omitempty"`
+
+	// CaptureExpiresAt — до какого момента можно списать авторизацию
+	// (Config.CaptureExpiry после создания). Позже списание отклоняется,
+	// а фоновая проверка аннулирует платеж (см. expiry.go)
+	// Только у двухшаговых платежей в статусе authorized
+	CaptureExpiresAt *time.Time `json:"capture_expires_at,omitempty"`
+
+	// SettlementCurrency — валюта, в которой клиент хочет получить расчет
+	// Необязательное поле запроса. Основные Amount/Currency НЕ меняются,
+	// дополнительно сохраняется сконвертированная сумма в минорных единицах
+	// (копейках/центах) и примененный курс
+	SettlementCurrency    string  `json:"settlement_currency,omitempty"`
+	SettlementAmountMinor int64   `json:"settlement_amount_minor,omitempty"`
+	FXRate                float64 `json:"fx_rate,omitempty"`
+
+	// ProcessAt — когда провести платеж (необязательно, RFC3339)
+	// В будущем — платеж создается в статусе scheduled и проводится
+	// планировщиком в это время (см. schedule.go); в прошлом — сразу
+	ProcessAt *time.Time `json:"process_at,omitempty"`
+
+	// CreatedAt — время создания платежа (UTC)
+	// time.Time автоматически сериализуется в JSON как RFC3339 строка
+	CreatedAt time.Time `json:"created_at"`
+
+	// Deleted — признак "мягкого" удаления (soft delete)
+	// Платеж физически НЕ удаляется из хранилища:
+	// - ID нельзя переиспользовать
+	// - история для аудита сохраняется
+	// DeletedAt — указатель, чтобы при nil поле не попадало в JSON
+	Deleted   bool       `json:"deleted,omitempty"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+
+	// Warnings — предупреждения проверки (см. paymentWarnings): платеж
+	// создан, но в нем есть что-то необычное. Не хранится: только
+	// в ответе на создание, и только если предупреждения есть
+	Warnings []FieldError `json:"warnings,omitempty"`
+
+	// Version — номер версии платежа для оптимистичной блокировки
+	// Новый платеж получает версию 1, каждое изменение увеличивает ее на 1
+	// Клиент передает версию в заголовке If-Match, чтобы не затереть
+	// чужое изменение (см. handleUpdatePaymentStatus)
+	Version int `json:"version"`
+}
+
+// MarshalJSON записывает платеж в JSON
+//
+// К обычным полям добавляются точные формы суммы — для клиентов,
+// которым нельзя терять точность на float64 "amount":
+//   - amount_minor — целое в минорных единицах: 10050
+//   - amount_decimal — десятичная строка: "100.50" (см. formatAmountDecimal)
+//
+// Из запроса эти поля не читаются: сумму считает сервер
+func (p Payment) MarshalJSON() ([]byte, error) {
+	// plain — без метода MarshalJSON, иначе бесконечная рекурсия
+	type plain Payment
+	return json.Marshal(struct {
+		plain
+		AmountMinor   int64  `json:"amount_minor"`
+		AmountDecimal string `json:"amount_decimal"`
+	}{plain(p), p.AmountMinor, formatAmountDecimal(p.AmountMinor, p.Currency)})
+}
+
+// UnmarshalJSON разбирает платеж из JSON
+//
+// Сумма принимается и числом ("amount": 100.5), и строкой
+// ("amount": "100.50"): в JavaScript все числа — float64, поэтому
+// клиенты на JS часто передают деньги строкой, чтобы не терять точность
+// Третий вариант — объект с суммой в минорных единицах:
+//
+//	{"amount": {"value": 10050, "currency": "RUB"}}
+//
+// Он приводится к тем же полям, что и плоская форма: валюта объекта
+// становится Currency (см. validatePayment), в ответе сумма — как всегда
+// Остальные поля разбираются стандартно
+func (p *Payment) UnmarshalJSON(data []byte) error {
+	// plain — тот же набор полей, но БЕЗ метода UnmarshalJSON
+	// (иначе json.Unmarshal вызвал бы этот метод снова — бесконечная рекурсия)
+	type plain Payment
+	aux := struct {
+		*plain
+		// Поле внешней структуры "перекрывает" одноименное поле plain
+		Amount json.RawMessage `json:"amount"`
+		// capture — только во входящем запросе (см. manualCapture)
+		Capture *bool `json:"capture"`
+	}{plain: (*plain)(p)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	p.manualCapture = aux.Capture != nil && !*aux.Capture
+
+	if raw := bytes.TrimSpace(aux.Amount); len(raw) > 0 && raw[0] == '{' {
+		var money struct {
+			Value    json.Number `json:"value"`
+			Currency string      `json:"currency"`
+		}
+		if err := json.Unmarshal(raw, &money); err != nil {
+			return err
+		}
+		p.Amount, p.amountLiteral, p.amountNumber = 0, "", ""
+		p.amountMinorValue, p.amountCurrency = money.Value, money.Currency
+		return nil
+	}
+
+	var err error
+	p.Amount, p.amountLiteral, p.amountNumber, err = decodeAmount(aux.Amount)
+	return err
+}
+
+// decodeAmount разбирает сумму запроса: число или строку
+// Для строки возвращает literal (без разбора: точность строки зависит
+// от валюты, см. parseAmountLiteral), для числа — number в исходной
+// записи и amount (то же число в float64, для поля Payment.Amount)
+// Отсутствующая сумма и null = нулевые значения
+func decodeAmount(raw json.RawMessage) (amount float64, literal string, number json.Number, err error) {
+	raw = bytes.TrimSpace(raw)
+	switch {
+	case len(raw) == 0 || bytes.Equal(raw, []byte("null")):
+		// Суммы нет — дальше ее отклонит проверка "сумма > 0"
+	case raw[0] == '"':
+		// Пустая строка = "нет суммы", ее отклонит проверка "сумма > 0"
+		err = json.Unmarshal(raw, &literal)
+	default:
+		// UseNumber: число остается текстом (json.Number), а не
+		// превращается в float64 — 0.29 не станет 0.28999999999999998
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		var v any
+		if err = dec.Decode(&v); err != nil {
+			return 0, "", "", err
+		}
+		var ok bool
+		if number, ok = v.(json.Number); !ok {
+			return 0, "", "", fmt.Errorf("amount must be a number or a string, got %s", raw)
+		}
+		amount, err = number.Float64()
+	}
+	return amount, literal, number, err
+}
+
+// ===== СТАТУСЫ ПЛАТЕЖА =====
+
+// const объявляет константы — значения, которые нельзя изменить
+// Константы вместо "сырых" строк защищают от опечаток:
+// компилятор поймает StatusSucceded, но не "succeded"
+const (
+	StatusPending    = "pending"    // создан, ожидает обработки
+	StatusScheduled  = "scheduled"  // ждет времени process_at (см. schedule.go)
+	StatusProcessing = "processing" // шлюз проводит платеж в фоне (см. async.go)
+	StatusAuthorized = "authorized" // средства заблокированы, ждет списания (см. capture.go)
+	StatusSucceeded  = "succeeded"  // успешно проведен
+	StatusFailed     = "failed"     // отклонен
+	StatusCanceled   = "canceled"   // отменен до списания
+	StatusVoided     = "voided"     // авторизация аннулирована, блокировка снята (см. capture.go)
+	StatusRefunded   = "refunded"   // возвращен полностью (см. refund.go)
+	StatusSplit      = "split"      // разбит на части, проводятся они (см. split.go)
+)
+
+// allowedTransitions — разрешенные переходы между статусами
+// Ключ = текущий статус, значение = множество допустимых новых статусов
+// map[string]bool используется как "множество" (set)
+// Из конечных статусов (succeeded, failed, canceled, voided, refunded) переходов нет:
+// succeeded → refunded происходит только через возврат денег
+// (Store.CreateRefund), сменить статус через PATCH нельзя
+// Так же authorized → succeeded — только через списание
+// (Store.CapturePayment): при нем фиксируется списанная сумма,
+// а pending → split — только вместе с созданием частей (Store.SplitPayment)
+// processing нельзя отменить: деньги уже в пути, ждем ответа шлюза
+var allowedTransitions = map[string]map[string]bool{
+	StatusPending:    {StatusProcessing: true, StatusSucceeded: true, StatusFailed: true, StatusCanceled: true},
+	StatusProcessing: {StatusSucceeded: true, StatusFailed: true},
+	StatusScheduled:  {StatusPending: true, StatusSucceeded: true, StatusFailed: true, StatusCanceled: true},
+	StatusAuthorized: {StatusCanceled: true, StatusVoided: true},
+}
+
+// canTransition проверяет, можно ли перевести платеж из from в to
+// Обращение к отсутствующему ключу map возвращает нулевое значение,
+// поэтому для неизвестных статусов результат просто false
+func canTransition(from, to string) bool {
+	return allowedTransitions[from][to]
+}
+
+// isKnownStatus проверяет, что строка — один из статусов платежа
+func isKnownStatus(status string) bool {
+	switch status {
+	case StatusPending, StatusScheduled, StatusProcessing, StatusAuthorized, StatusSucceeded, StatusFailed, StatusCanceled, StatusVoided, StatusRefunded, StatusSplit:
+		return true
+	}
+	return false
+}
+
+// newPaymentID генерирует ID платежа вида "pay_<uuid v4>"
+func newPaymentID() string {
+	return newID("pay_")
+}
+
+// newID генерирует ID вида "<prefix><uuid v4>"
+// Префикс (pay_, cus_) сразу показывает, к какой сущности относится ID
+// UUID v4 = 16 случайных байт, в которых выставлены биты версии и варианта
+func newID(prefix string) string {
+	var b [16]byte
+	// rand.Read заполняет массив случайными байтами
+	// Ошибка на практике невозможна (ОС всегда отдает энтропию)
+	rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40 // версия 4
+	b[8] = (b[8] & 0x3f) | 0x80 // вариант RFC 4122
+	return fmt.Sprintf("%s%x-%x-%x-%x-%x", prefix, b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
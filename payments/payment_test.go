@@ -0,0 +1,115 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestAmountNumberAndStringAgree — "amount": 100.29 и "amount": "100.29"
+// дают одну и ту же сумму в минорных единицах
+func TestAmountNumberAndStringAgree(t *testing.T) {
+	store := NewMemoryStore()
+	s := NewServer(store, nil, nil, Config{})
+
+	var minors []int64
+	for _, amount := range []string{`100.29`, `"100.29"`, `{"value": 10029, "currency": "RUB"}`} {
+		p := createPaymentT(t, s, `{"amount": `+amount+`, "currency": "RUB"}`)
+		stored, err := store.Get(context.Background(), p.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		minors = append(minors, stored.AmountMinor)
+	}
+	for i, m := range minors {
+		if m != 10029 {
+			t.Fatalf("amount form %d stored %d, want 10029", i, m)
+		}
+	}
+}
+
+func TestAmountInvalidString(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	for _, amount := range []string{`"abc"`, `"100.505"`, `"-5"`, `"1e3"`} {
+		rec := doJSON(t, s, http.MethodPost, "/payments", `{"amount": `+amount+`, "currency": "RUB"}`, nil)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("amount %s = %d, want 400", amount, rec.Code)
+			continue
+		}
+		var resp ErrorResponse
+		decodeBody(t, rec, &resp)
+		if !strings.Contains(resp.Message, "amount") {
+			t.Errorf("amount %s: unclear message %q", amount, resp.Message)
+		}
+	}
+}
+
+func TestDecodeAmount(t *testing.T) {
+	amount, literal, number, err := decodeAmount(json.RawMessage(`0.29`))
+	if err != nil || number != "0.29" || literal != "" || amount != 0.29 {
+		t.Fatalf("number: %v %q %q %v", amount, literal, number, err)
+	}
+	_, literal, number, err = decodeAmount(json.RawMessage(`"0.29"`))
+	if err != nil || literal != "0.29" || number != "" {
+		t.Fatalf("string: %q %q %v", literal, number, err)
+	}
+	if _, _, _, err := decodeAmount(json.RawMessage(`[1]`)); err == nil {
+		t.Fatal("array amount must fail")
+	}
+}
+
+// TestUnmarshalAmountObject — UnmarshalJSON принимает и плоскую форму,
+// и объект {"value", "currency"} с суммой в минорных единицах
+func TestUnmarshalAmountObject(t *testing.T) {
+	var flat Payment
+	if err := json.Unmarshal([]byte(`{"amount": "100.50", "currency": "RUB"}`), &flat); err != nil {
+		t.Fatal(err)
+	}
+	if flat.amountLiteral != "100.50" || flat.Currency != "RUB" || flat.amountMinorValue != "" {
+		t.Fatalf("flat = %+v", flat)
+	}
+
+	var nested Payment
+	if err := json.Unmarshal([]byte(`{"amount": {"value": 10050, "currency": "USD"}}`), &nested); err != nil {
+		t.Fatal(err)
+	}
+	if nested.amountMinorValue != "10050" || nested.amountCurrency != "USD" || nested.amountLiteral != "" {
+		t.Fatalf("nested = %+v", nested)
+	}
+
+	if err := json.Unmarshal([]byte(`{"amount": {"value": "x"}}`), &nested); err == nil {
+		t.Fatal("malformed amount object must fail")
+	}
+}
+
+// TestCreateAmountObject — валюта объекта заменяет currency, а ответ
+// остается плоским
+func TestCreateAmountObject(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+
+	rec := doJSON(t, s, http.MethodPost, "/payments", `{"amount": {"value": 10050, "currency": "RUB"}}`, nil)
+	var created map[string]any
+	decodeBody(t, rec, &created)
+	if rec.Code != http.StatusCreated || created["currency"] != "RUB" || created["amount_minor"] != float64(10050) {
+		t.Fatalf("object only: %d %v", rec.Code, created)
+	}
+	if _, nested := created["amount"].(map[string]any); nested {
+		t.Fatalf("response amount is an object: %v", created["amount"])
+	}
+
+	for body, field := range map[string]string{
+		`{"amount": {"value": 100, "currency": "USD"}, "currency": "RUB"}`: "amount.currency",
+		`{"amount": {"value": 100.5, "currency": "RUB"}}`:                  "amount.value",
+		`{"amount": {"value": -100, "currency": "RUB"}}`:                   "amount.value",
+		`{"amount": {"value": 99999999999999999999, "currency": "RUB"}}`:   "amount.value",
+	} {
+		rec := doJSON(t, s, http.MethodPost, "/payments", body, nil)
+		var resp ErrorResponse
+		decodeBody(t, rec, &resp)
+		if rec.Code != http.StatusBadRequest || len(resp.Fields) == 0 || resp.Fields[0].Field != field {
+			t.Errorf("%s: %d %+v, want error on %s", body, rec.Code, resp, field)
+		}
+	}
+}
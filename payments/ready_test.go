@@ -0,0 +1,113 @@
+package payments
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestReadyReportsDraining — /ready отвечает 200, пока сервер не начал
+// "сливать" трафик, и 503 draining после StartDraining; /health
+// при этом по-прежнему 200
+func TestReadyReportsDraining(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	if rec := doJSON(t, s, http.MethodGet, "/ready", "", nil); rec.Code != http.StatusOK {
+		t.Fatalf("ready = %d", rec.Code)
+	}
+	s.StartDraining()
+	rec := doJSON(t, s, http.MethodGet, "/ready", "", nil)
+	var resp ErrorResponse
+	decodeBody(t, rec, &resp)
+	if rec.Code != http.StatusServiceUnavailable || resp.Code != CodeDraining {
+		t.Fatalf("draining ready = %d %+v", rec.Code, resp)
+	}
+	if rec := doJSON(t, s, http.MethodGet, "/health", "", nil); rec.Code != http.StatusOK {
+		t.Fatalf("health while draining = %d", rec.Code)
+	}
+}
+
+// TestDrainThenShutdown повторяет остановку из cmd/api: запрос уже
+// в работе, сервер начинает "слив" (/ready = 503, новые запросы еще
+// обслуживаются), затем Shutdown дожидается начатого запроса
+func TestDrainThenShutdown(t *testing.T) {
+	charging := make(chan struct{})
+	release := make(chan struct{})
+	s := NewServer(NewMemoryStore(), nil, gatewayFunc(func(_ context.Context, p Payment) error {
+		if p.Description == "slow" {
+			close(charging)
+			<-release
+		}
+		return nil
+	}), Config{})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: s}
+	go srv.Serve(ln)
+	base := "http://" + ln.Addr().String()
+
+	post := func(description string) (int, error) {
+		resp, err := http.Post(base+"/payments", "application/json",
+			strings.NewReader(`{"amount": 100, "currency": "RUB", "description": "`+description+`"}`))
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+	get := func(path string) int {
+		resp, err := http.Get(base + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Запрос, который будет в работе во время остановки
+	inflight := make(chan int, 1)
+	go func() {
+		code, err := post("slow")
+		if err != nil {
+			code = -1
+		}
+		inflight <- code
+	}()
+	<-charging
+
+	s.StartDraining()
+	srv.SetKeepAlivesEnabled(false)
+	if code := get("/ready"); code != http.StatusServiceUnavailable {
+		t.Fatalf("ready while draining = %d", code)
+	}
+	// Пока балансировщик не вывел экземпляр, запросы обслуживаются
+	if code, err := post("during drain"); err != nil || code != http.StatusCreated {
+		t.Fatalf("request while draining = %d, %v", code, err)
+	}
+
+	shutdownDone := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdownDone <- srv.Shutdown(ctx)
+	}()
+	// Shutdown ждет начатый запрос
+	select {
+	case err := <-shutdownDone:
+		t.Fatalf("shutdown returned before in-flight request finished: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if code := <-inflight; code != http.StatusCreated {
+		t.Fatalf("in-flight request = %d", code)
+	}
+	if err := <-shutdownDone; err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+}
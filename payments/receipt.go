@@ -0,0 +1,114 @@
+package payments
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ===== ЧЕК ПО ПЛАТЕЖУ =====
+
+// Receipt — чек по проведенному платежу
+// Пример: {"payment_id":"pay_…","amount_display":"1,000.50 RUB",…}
+type Receipt struct {
+	PaymentID     string    `json:"payment_id"`
+	AmountMinor   int64     `json:"amount_minor"`
+	AmountDisplay string    `json:"amount_display"`
+	Currency      string    `json:"currency"`
+	Status        string    `json:"status"`
+	CreatedAt     time.Time `json:"created_at"`
+	Description   string    `json:"description,omitempty"`
+}
+
+// newReceipt собирает чек из платежа
+func newReceipt(p Payment) Receipt {
+	return Receipt{
+		PaymentID:     p.ID,
+		AmountMinor:   p.AmountMinor,
+		AmountDisplay: formatAmountDisplay(p.AmountMinor, p.Currency),
+		Currency:      p.Currency,
+		Status:        p.Status,
+		CreatedAt:     p.CreatedAt,
+		Description:   p.Description,
+	}
+}
+
+// text возвращает чек в виде текста для печати или письма:
+//
+//	Payment:     pay_…
+//	Amount:      1,000.50 RUB
+//	Currency:    RUB
+//	Status:      succeeded
+//	Created:     2024-01-15T10:30:00Z
+//	Description: Order #42
+func (rc Receipt) text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Payment:     %s\n", rc.PaymentID)
+	fmt.Fprintf(&b, "Amount:      %s\n", rc.AmountDisplay)
+	fmt.Fprintf(&b, "Currency:    %s\n", rc.Currency)
+	fmt.Fprintf(&b, "Status:      %s\n", rc.Status)
+	fmt.Fprintf(&b, "Created:     %s\n", rc.CreatedAt.Format(time.RFC3339))
+	if rc.Description != "" {
+		fmt.Fprintf(&b, "Description: %s\n", rc.Description)
+	}
+	return b.String()
+}
+
+// handlePaymentReceipt возвращает чек по платежу
+// GET /payments/{id}/receipt
+//
+// Формат ответа выбирается по заголовку Accept:
+// - application/json (по умолчанию) = чек объектом Receipt
+// - text/plain = чек текстом
+// - другое = 406 Not Acceptable
+//
+// Коды ответа:
+// - 200 OK = чек
+// - 404 Not Found = платежа нет
+// - 409 Conflict = платеж не succeeded: чек выдается только за списанные деньги
+func (s *Server) handlePaymentReceipt(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	id := r.PathValue("id")
+	if !isValidPaymentID(id) {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidID, "Invalid payment ID: must start with "+paymentIDPrefix)
+		return
+	}
+	format := negotiate(r.Header.Get("Accept"), mediaJSON, mediaText)
+	if format == "" {
+		writeError(w, r, http.StatusNotAcceptable, CodeNotAcceptable, "Supported formats: application/json, text/plain")
+		return
+	}
+
+	payment, err := s.store.Get(r.Context(), id)
+	if errors.Is(err, ErrPaymentNotFound) || (err == nil && payment.Deleted) {
+		writeError(w, r, http.StatusNotFound, CodePaymentNotFound, "Payment not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Error loading payment: %v", err)
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+		return
+	}
+	if payment.Status != StatusSucceeded {
+		writeError(w, r, http.StatusConflict, CodeNoReceipt,
+			fmt.Sprintf("Receipt is available only for succeeded payments, payment is %s", payment.Status))
+		return
+	}
+
+	receipt := newReceipt(payment)
+	if format == mediaText {
+		w.Header().Set("Content-Type", mediaText+"; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write([]byte(receipt.text())); err != nil {
+			log.Printf("Error writing receipt: %v", err)
+		}
+		return
+	}
+	writeJSON(w, r, http.StatusOK, receipt)
+}
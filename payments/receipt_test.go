@@ -0,0 +1,72 @@
+package payments
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newReceiptServer(t *testing.T) *Server {
+	t.Helper()
+	store := NewMemoryStore()
+	created := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	savePaymentT(t, store, Payment{ID: "pay_paid", Amount: 1000.5, AmountMinor: 100050, Currency: "RUB",
+		Status: StatusSucceeded, Description: "Order #42", CreatedAt: created, Version: 1})
+	savePaymentT(t, store, Payment{ID: "pay_open", Amount: 10, AmountMinor: 1000, Currency: "RUB",
+		Status: StatusPending, CreatedAt: created, Version: 1})
+	return NewServer(store, nil, nil, Config{})
+}
+
+func TestReceiptJSON(t *testing.T) {
+	s := newReceiptServer(t)
+	rec := doJSON(t, s, http.MethodGet, "/payments/pay_paid/receipt", "", nil)
+	var got Receipt
+	decodeBody(t, rec, &got)
+	want := Receipt{
+		PaymentID:     "pay_paid",
+		AmountMinor:   100050,
+		AmountDisplay: "1,000.50 RUB",
+		Currency:      "RUB",
+		Status:        StatusSucceeded,
+		CreatedAt:     time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC),
+		Description:   "Order #42",
+	}
+	if rec.Code != http.StatusOK || got != want {
+		t.Fatalf("receipt = %d %+v, want %+v", rec.Code, got, want)
+	}
+}
+
+func TestReceiptText(t *testing.T) {
+	s := newReceiptServer(t)
+	rec := doJSON(t, s, http.MethodGet, "/payments/pay_paid/receipt", "", map[string]string{"Accept": "text/plain"})
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") {
+		t.Fatalf("text receipt = %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	want := "Payment:     pay_paid\n" +
+		"Amount:      1,000.50 RUB\n" +
+		"Currency:    RUB\n" +
+		"Status:      succeeded\n" +
+		"Created:     2024-01-15T10:30:00Z\n" +
+		"Description: Order #42\n"
+	if rec.Body.String() != want {
+		t.Fatalf("text receipt:\n%s\nwant:\n%s", rec.Body.String(), want)
+	}
+}
+
+func TestReceiptErrors(t *testing.T) {
+	s := newReceiptServer(t)
+	for _, tc := range []struct {
+		path, accept string
+		status       int
+	}{
+		{"/payments/pay_open/receipt", "", http.StatusConflict},
+		{"/payments/pay_missing/receipt", "", http.StatusNotFound},
+		{"/payments/pay_paid/receipt", "application/pdf", http.StatusNotAcceptable},
+	} {
+		rec := doJSON(t, s, http.MethodGet, tc.path, "", map[string]string{"Accept": tc.accept})
+		if rec.Code != tc.status {
+			t.Errorf("%s (Accept %q) = %d, want %d", tc.path, tc.accept, rec.Code, tc.status)
+		}
+	}
+}
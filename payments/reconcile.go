@@ -0,0 +1,172 @@
+package payments
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// ===== СВЕРКА С ОТЧЕТОМ ШЛЮЗА =====
+
+// maxReconcileItems — сколько записей отчета можно сверить за раз
+const maxReconcileItems = 10000
+
+// reconcileItem — запись отчета шлюза: платеж и его статус у шлюза
+type reconcileItem struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+// reconcileMismatch — платеж, статус которого у нас и у шлюза разный
+// С ?apply=true Applied сообщает, удалось ли принять статус шлюза,
+// а Error — почему нет (например, недопустимый переход)
+type reconcileMismatch struct {
+	ID            string `json:"id"`
+	StoredStatus  string `json:"stored_status"`
+	GatewayStatus string `json:"gateway_status"`
+	Applied       bool   `json:"applied,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// reconcileResult — ответ POST /payments/reconcile
+//   - Matched — сколько платежей совпали
+//   - Mismatched — платежи с разным статусом
+//   - MissingLocally — ID из отчета, которых у нас нет
+//   - MissingInReport — наши платежи, которых нет в отчете
+type reconcileResult struct {
+	Matched         int                 `json:"matched"`
+	Mismatched      []reconcileMismatch `json:"mismatched"`
+	MissingLocally  []string            `json:"missing_locally"`
+	MissingInReport []string            `json:"missing_in_report"`
+}
+
+// handleReconcile сверяет платежи с отчетом шлюза
+// POST /payments/reconcile [{"id":"pay_…","status":"succeeded"}, …]
+//
+// По умолчанию только сообщает расхождения и ничего не меняет
+// С ?apply=true статусы расходящихся платежей меняются на статусы
+// шлюза — по обычным правилам переходов (см. allowedTransitions):
+// succeeded не станет failed, такой платеж остается в отчете с ошибкой
+//
+// Коды ответа:
+// - 200 OK = результат сверки
+// - 400 Bad Request = некорректный отчет (все ошибки в fields)
+func (s *Server) handleReconcile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	var report []reconcileItem
+	if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+	if errs := validateReconcileReport(report); len(errs) > 0 {
+		writeFieldErrors(w, r, errs)
+		return
+	}
+	apply := r.URL.Query().Get("apply") == "true"
+
+	stored, err := s.store.List(r.Context(), false)
+	if err != nil {
+		log.Printf("Error listing payments: %v", err)
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+		return
+	}
+	// С ключами API сверяются только платежи ключа запроса
+	stored = s.scopePayments(r, stored)
+
+	byID := make(map[string]Payment, len(stored))
+	for _, p := range stored {
+		byID[p.ID] = p
+	}
+
+	// Пустые срезы, а не nil: в JSON [] вместо null
+	result := reconcileResult{
+		Mismatched:      []reconcileMismatch{},
+		MissingLocally:  []string{},
+		MissingInReport: []string{},
+	}
+	inReport := make(map[string]bool, len(report))
+	for _, item := range report {
+		inReport[item.ID] = true
+		p, ok := byID[item.ID]
+		switch {
+		case !ok:
+			result.MissingLocally = append(result.MissingLocally, item.ID)
+		case p.Status == item.Status:
+			result.Matched++
+		default:
+			mismatch := reconcileMismatch{ID: p.ID, StoredStatus: p.Status, GatewayStatus: item.Status}
+			if apply {
+				s.applyReconciled(r, p, &mismatch)
+			}
+			result.Mismatched = append(result.Mismatched, mismatch)
+		}
+	}
+	for _, p := range stored {
+		if !inReport[p.ID] {
+			result.MissingInReport = append(result.MissingInReport, p.ID)
+		}
+	}
+
+	log.Printf("Reconciliation: matched=%d, mismatched=%d, missing_locally=%d, missing_in_report=%d, apply=%t",
+		result.Matched, len(result.Mismatched), len(result.MissingLocally), len(result.MissingInReport), apply)
+	writeJSON(w, r, http.StatusOK, result)
+}
+
+// applyReconciled переводит платеж в статус из отчета шлюза
+// Результат записывается в mismatch (Applied или Error)
+func (s *Server) applyReconciled(r *http.Request, p Payment, mismatch *reconcileMismatch) {
+	// Версия проверяется: если платеж изменился после чтения,
+	// сверка его не трогает — расхождение нужно перепроверить
+	defer s.paymentLocks.lock(p.ID)()
+	// Отчет шлюза не сообщает, почему платеж отклонен
+	updated, err := s.updateStatus(r.Context(), p.ID, mismatch.GatewayStatus, p.Version, FailureProcessingError)
+	switch {
+	case errors.Is(err, ErrInvalidTransition):
+		mismatch.Error = fmt.Sprintf("Cannot change status from %s to %s", p.Status, mismatch.GatewayStatus)
+		return
+	case errors.Is(err, ErrVersionMismatch):
+		mismatch.Error = "Payment was modified during reconciliation"
+		return
+	case err != nil:
+		log.Printf("Error applying reconciled status: %v", err)
+		mismatch.Error = "Internal error"
+		return
+	}
+	mismatch.Applied = true
+	s.audit.record(auditActor(r), AuditUpdate, updated.ID, p.Status, updated.Status)
+	s.publishStatus(r.Context(), updated)
+}
+
+// validateReconcileReport проверяет отчет и собирает ВСЕ ошибки
+// Поле ошибки — позиция записи: "[3].status"
+func validateReconcileReport(report []reconcileItem) []FieldError {
+	if len(report) > maxReconcileItems {
+		return []FieldError{{Field: "body", Code: FieldBodyTooLarge,
+			Message: fmt.Sprintf("Report must have at most %d items", maxReconcileItems)}}
+	}
+	var errs []FieldError
+	seen := make(map[string]bool, len(report))
+	for i, item := range report {
+		field := fmt.Sprintf("[%d]", i)
+		switch {
+		case !isValidPaymentID(item.ID):
+			errs = append(errs, FieldError{Field: field + ".id", Code: FieldIDInvalid,
+				Message: "Invalid payment ID: must start with " + paymentIDPrefix})
+		case seen[item.ID]:
+			errs = append(errs, FieldError{Field: field + ".id", Code: FieldIDDuplicate,
+				Message: "Duplicate payment ID " + item.ID})
+		}
+		seen[item.ID] = true
+		if !isKnownStatus(item.Status) {
+			errs = append(errs, FieldError{Field: field + ".status", Code: FieldStatusUnknown,
+				Message: fmt.Sprintf("Unknown status %q", item.Status)})
+		}
+	}
+	return errs
+}
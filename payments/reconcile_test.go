@@ -0,0 +1,108 @@
+package payments
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func newReconcileServer(t *testing.T) (*Server, Store) {
+	t.Helper()
+	store := NewMemoryStore()
+	for id, status := range map[string]string{
+		"pay_match":   StatusSucceeded,
+		"pay_differs": StatusPending,
+		"pay_final":   StatusSucceeded,
+		"pay_ours":    StatusPending,
+	} {
+		savePaymentT(t, store, Payment{ID: id, AmountMinor: 100, Currency: "RUB", Status: status, Version: 1})
+	}
+	return NewServer(store, nil, nil, Config{}), store
+}
+
+const reconcileReport = `[
+	{"id": "pay_match", "status": "succeeded"},
+	{"id": "pay_differs", "status": "failed"},
+	{"id": "pay_final", "status": "failed"},
+	{"id": "pay_gateway_only", "status": "succeeded"}
+]`
+
+func reconcileT(t *testing.T, s *Server, query string) reconcileResult {
+	t.Helper()
+	rec := doJSON(t, s, http.MethodPost, "/payments/reconcile"+query, reconcileReport, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("reconcile = %d: %s", rec.Code, rec.Body.String())
+	}
+	var result reconcileResult
+	decodeBody(t, rec, &result)
+	slices.SortFunc(result.Mismatched, func(a, b reconcileMismatch) int { return strings.Compare(a.ID, b.ID) })
+	return result
+}
+
+// TestReconcileReportOnly — без ?apply отчет ничего не меняет
+func TestReconcileReportOnly(t *testing.T) {
+	s, store := newReconcileServer(t)
+	got := reconcileT(t, s, "")
+
+	if got.Matched != 1 {
+		t.Errorf("matched = %d, want 1", got.Matched)
+	}
+	want := []reconcileMismatch{
+		{ID: "pay_differs", StoredStatus: StatusPending, GatewayStatus: StatusFailed},
+		{ID: "pay_final", StoredStatus: StatusSucceeded, GatewayStatus: StatusFailed},
+	}
+	if !slices.Equal(got.Mismatched, want) {
+		t.Errorf("mismatched = %+v", got.Mismatched)
+	}
+	if !slices.Equal(got.MissingLocally, []string{"pay_gateway_only"}) {
+		t.Errorf("missing_locally = %v", got.MissingLocally)
+	}
+	if !slices.Equal(got.MissingInReport, []string{"pay_ours"}) {
+		t.Errorf("missing_in_report = %v", got.MissingInReport)
+	}
+
+	p, _ := store.Get(context.Background(), "pay_differs")
+	if p.Status != StatusPending {
+		t.Fatalf("read-only reconcile changed status to %s", p.Status)
+	}
+}
+
+// TestReconcileApply — ?apply=true принимает статус шлюза там, где
+// переход допустим; недопустимый переход остается с ошибкой
+func TestReconcileApply(t *testing.T) {
+	s, store := newReconcileServer(t)
+	got := reconcileT(t, s, "?apply=true")
+
+	if len(got.Mismatched) != 2 {
+		t.Fatalf("mismatched = %+v", got.Mismatched)
+	}
+	if m := got.Mismatched[0]; !m.Applied || m.Error != "" {
+		t.Errorf("pay_differs = %+v, want applied", m)
+	}
+	if m := got.Mismatched[1]; m.Applied || m.Error == "" {
+		t.Errorf("pay_final = %+v, want error", m)
+	}
+
+	ctx := context.Background()
+	if p, _ := store.Get(ctx, "pay_differs"); p.Status != StatusFailed {
+		t.Errorf("pay_differs status = %s, want failed", p.Status)
+	}
+	if p, _ := store.Get(ctx, "pay_final"); p.Status != StatusSucceeded {
+		t.Errorf("pay_final status = %s, succeeded must not become failed", p.Status)
+	}
+}
+
+func TestReconcileInvalidReport(t *testing.T) {
+	s, _ := newReconcileServer(t)
+	for _, body := range []string{
+		`[{"id": "bad", "status": "succeeded"}]`,
+		`[{"id": "pay_match", "status": "unknown"}]`,
+		`[{"id": "pay_match", "status": "succeeded"}, {"id": "pay_match", "status": "failed"}]`,
+	} {
+		if rec := doJSON(t, s, http.MethodPost, "/payments/reconcile", body, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s = %d, want 400", body, rec.Code)
+		}
+	}
+}
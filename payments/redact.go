@@ -0,0 +1,65 @@
+package payments
+
+import "strings"
+
+// ===== СКРЫТИЕ ЧУВСТВИТЕЛЬНЫХ ПОЛЕЙ В ЛОГАХ =====
+//
+// В описании платежа, метках или теле запроса может оказаться email,
+// имя или номер карты. В логи такие значения попадать не должны:
+// логи хранятся дольше и читаются шире, чем база платежей
+//
+// Набор скрываемых полей один на весь сервер (Config.RedactFields)
+// и действует везде, где пишутся поля: журнал тел запросов (logBodies),
+// строки лога обработчиков (logValue) и журнал аудита (auditLog)
+
+// DefaultRedactFields — поля, значения которых не попадают в логи
+// (персональные данные и секреты), если Config.RedactFields не задан
+var DefaultRedactFields = []string{"email", "name", "card_number", "cvv", "cvc", "password", "token", "secret"}
+
+// redactedValue — чем заменяется значение скрытого поля
+const redactedValue = "[REDACTED]"
+
+// newRedactSet строит множество скрываемых полей
+// Имена приводятся к нижнему регистру: "Email" и "email" — одно поле
+func newRedactSet(fields []string) map[string]bool {
+	set := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		set[strings.ToLower(f)] = true
+	}
+	return set
+}
+
+// redactString возвращает value или "[REDACTED]", если поле name скрыто
+func redactString(fields map[string]bool, name, value string) string {
+	if fields[strings.ToLower(name)] {
+		return redactedValue
+	}
+	return value
+}
+
+// logValue — значение поля name для строки лога обработчика
+//
+//	log.Printf("Description=%q", s.logValue("description", p.Description))
+func (s *Server) logValue(name, value string) string {
+	return redactString(s.redact, name, value)
+}
+
+// redactValue рекурсивно заменяет значения полей из fields
+// Имена полей сравниваются без учета регистра
+func redactValue(v any, fields map[string]bool) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, item := range v {
+			if fields[strings.ToLower(k)] {
+				v[k] = redactedValue
+			} else {
+				v[k] = redactValue(item, fields)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = redactValue(item, fields)
+		}
+	}
+	return v
+}
@@ -0,0 +1,74 @@
+package payments
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestRedactRequestLog — описание платежа скрыто в строке лога
+// обработчика, если поле в списке скрываемых
+func TestRedactRequestLog(t *testing.T) {
+	const secret = "ivan.petrov@example.com"
+	body := `{"amount": 10, "currency": "RUB", "description": "` + secret + `"}`
+
+	logs := captureLog(t)
+	s := NewServer(NewMemoryStore(), nil, nil, Config{RedactFields: []string{"Description"}})
+	createPaymentT(t, s, body)
+	if out := logs.String(); strings.Contains(out, secret) || !strings.Contains(out, redactedValue) {
+		t.Fatalf("log = %s", out)
+	}
+
+	// Пустой список (не nil) — скрывать нечего
+	logs.Reset()
+	plain := NewServer(NewMemoryStore(), nil, nil, Config{RedactFields: []string{}})
+	createPaymentT(t, plain, body)
+	if !strings.Contains(logs.String(), secret) {
+		t.Fatalf("description missing without redaction: %s", logs.String())
+	}
+}
+
+// TestRedactAuditLog — скрытые поля не попадают ни в журнал аудита,
+// ни в строку лога обработчика
+func TestRedactAuditLog(t *testing.T) {
+	const secret = "customer Ivan Petrov called"
+	logs := captureLog(t)
+	var audit bytes.Buffer
+	s := NewServer(NewMemoryStore(), nil, nil, Config{
+		AuditLog:     &audit,
+		AdminAPIKeys: []string{"admin-key"},
+		RedactFields: []string{"reason"},
+	})
+	rec := doJSON(t, s, http.MethodPost, "/payments", `{"amount": 10, "currency": "RUB"}`, map[string]string{"X-API-Key": "admin-key"})
+	var p Payment
+	decodeBody(t, rec, &p)
+
+	rec = doJSON(t, s, http.MethodPost, "/payments/bulk-status",
+		`{"ids":["`+p.ID+`"],"status":"canceled","reason":"`+secret+`"}`,
+		map[string]string{"X-API-Key": "admin-key"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("bulk = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if strings.Contains(audit.String(), secret) || strings.Contains(logs.String(), secret) {
+		t.Fatalf("reason leaked:\naudit: %s\nlog: %s", audit.String(), logs.String())
+	}
+	entries := auditEntries(t, &audit)
+	last := entries[len(entries)-1]
+	if last.Reason != redactedValue {
+		t.Fatalf("audit reason = %q, want %s", last.Reason, redactedValue)
+	}
+}
+
+func TestRedactValueNested(t *testing.T) {
+	fields := newRedactSet([]string{"Email"})
+	v := redactValue(map[string]any{
+		"email": "a@b.c",
+		"items": []any{map[string]any{"EMAIL": "x@y.z", "qty": 1}},
+	}, fields).(map[string]any)
+	item := v["items"].([]any)[0].(map[string]any)
+	if v["email"] != redactedValue || item["EMAIL"] != redactedValue || item["qty"] != 1 {
+		t.Fatalf("redacted = %v", v)
+	}
+}
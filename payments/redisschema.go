@@ -0,0 +1,122 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ===== ПРОВЕРКА СХЕМЫ REDIS =====
+//
+// У Redis нет схемы в смысле SQL, но формат данных она задает все равно:
+// тип каждого служебного ключа и набор полей в JSON платежа. Запись,
+// сделанная версией до миграции, разбирается без ошибки — недостающее
+// поле просто становится нулем (например, amount_refundable_minor = 0,
+// и любой возврат отклоняется). Поэтому формат проверяется при старте
+// (SCHEMA_CHECK), а не обнаруживается на первом запросе
+
+// schemaSampleSize — сколько случайных платежей проверить
+// Проверять все нельзя: при миллионах платежей старт занял бы минуты
+const schemaSampleSize = 100
+
+// redisKeyTypes — служебные ключи и их типы (как их называет TYPE)
+// Отсутствующий ключ допустим: в пустой базе его еще нет
+var redisKeyTypes = map[string]string{
+	redisPaymentIndex: "set",
+	redisSequenceKey:  "string",
+}
+
+// redisPaymentFields — поля, которые есть в каждой записи платежа
+// (см. redisPayment); поля с omitempty сюда не входят: их может не быть
+var redisPaymentFields = []string{"payment", "amount_minor"}
+
+// paymentRequiredFields — поля JSON платежа без omitempty
+var paymentRequiredFields = []string{
+	"id", "sequence_number", "amount", "currency", "status",
+	"fee_minor", "net_amount_minor", "amount_refunded_minor", "amount_refundable_minor",
+	"created_at", "version",
+}
+
+// ErrSchemaMismatch — данные в Redis не в том формате, которого ждет
+// эта версия (не применена миграция)
+var ErrSchemaMismatch = errors.New("redis schema mismatch")
+
+// CheckSchema проверяет, что данные в Redis в ожидаемом формате:
+// типы служебных ключей и поля в случайной выборке платежей
+//
+// Возвращает ошибку ErrSchemaMismatch со списком всех расхождений
+// (ключ и поле), чтобы одним запуском увидеть, какую миграцию применить
+func (s *RedisStore) CheckSchema(ctx context.Context) error {
+	var problems []error
+	for key, want := range redisKeyTypes {
+		got, err := s.client.Type(ctx, key).Result()
+		if err != nil {
+			return err
+		}
+		if got != "none" && got != want {
+			problems = append(problems, fmt.Errorf("%s: type %s, want %s", key, got, want))
+		}
+	}
+	if len(problems) > 0 {
+		// С неверным типом индекса платежей выборку не прочитать
+		return fmt.Errorf("%w: %w", ErrSchemaMismatch, errors.Join(problems...))
+	}
+
+	ids, err := s.client.SRandMemberN(ctx, redisPaymentIndex, schemaSampleSize).Result()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		key := redisPaymentKey(id)
+		data, err := s.client.Get(ctx, key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			problems = append(problems, fmt.Errorf("%s: listed in %s but missing", key, redisPaymentIndex))
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		missing, err := missingPaymentFields(data)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("%s: %w", key, err))
+			continue
+		}
+		for _, field := range missing {
+			problems = append(problems, fmt.Errorf("%s: missing field %q", key, field))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %w", ErrSchemaMismatch, errors.Join(problems...))
+	}
+	return nil
+}
+
+// missingPaymentFields возвращает обязательные поля, которых нет
+// в записи платежа ("payment.currency" — поле внутри payment)
+func missingPaymentFields(data []byte) ([]string, error) {
+	var record map[string]json.RawMessage
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("not a JSON object: %w", err)
+	}
+	var missing []string
+	for _, field := range redisPaymentFields {
+		if _, ok := record[field]; !ok {
+			missing = append(missing, field)
+		}
+	}
+	var payment map[string]json.RawMessage
+	if raw, ok := record["payment"]; !ok {
+		return missing, nil
+	} else if err := json.Unmarshal(raw, &payment); err != nil {
+		return nil, fmt.Errorf("payment is not a JSON object: %w", err)
+	}
+	for _, field := range paymentRequiredFields {
+		if _, ok := payment[field]; !ok {
+			missing = append(missing, "payment."+field)
+		}
+	}
+	return missing, nil
+}
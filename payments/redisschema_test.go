@@ -0,0 +1,62 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestCheckSchemaCurrentFormat — пустая база и платежи, записанные
+// этой версией, проверку проходят
+func TestCheckSchemaCurrentFormat(t *testing.T) {
+	store := newRedisStoreT(t)
+	ctx := context.Background()
+	if err := store.CheckSchema(ctx); err != nil {
+		t.Fatalf("empty database: %v", err)
+	}
+
+	if _, err := store.NextSequenceNumber(ctx); err != nil {
+		t.Fatal(err)
+	}
+	p := Payment{ID: "pay_new", AmountMinor: 100, Currency: "RUB", Status: StatusPending, CreatedAt: time.Now(), Version: 1}
+	if err := store.Create(ctx, p); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CheckSchema(ctx); err != nil {
+		t.Fatalf("current format: %v", err)
+	}
+}
+
+// TestCheckSchemaMissingColumn — запись версии до миграции (без
+// amount_refundable_minor) не проходит проверку, а ошибка называет
+// ключ и поле
+func TestCheckSchemaMissingColumn(t *testing.T) {
+	store := newRedisStoreT(t)
+	ctx := context.Background()
+	old := `{"payment":{"id":"pay_old","sequence_number":1,"amount":1,"currency":"RUB","status":"succeeded",` +
+		`"fee_minor":0,"net_amount_minor":100,"amount_refunded_minor":0,"created_at":"2024-01-01T00:00:00Z","version":1},"amount_minor":100}`
+	store.client.Set(ctx, redisPaymentKey("pay_old"), old, 0)
+	store.client.SAdd(ctx, redisPaymentIndex, "pay_old")
+
+	err := store.CheckSchema(ctx)
+	if !errors.Is(err, ErrSchemaMismatch) {
+		t.Fatalf("err = %v, want ErrSchemaMismatch", err)
+	}
+	if want := `payment:pay_old: missing field "payment.amount_refundable_minor"`; !strings.Contains(err.Error(), want) {
+		t.Fatalf("err = %v, want it to mention %s", err, want)
+	}
+}
+
+// TestCheckSchemaKeyType — служебный ключ другого типа
+func TestCheckSchemaKeyType(t *testing.T) {
+	store := newRedisStoreT(t)
+	ctx := context.Background()
+	store.client.Set(ctx, redisPaymentIndex, "legacy", 0)
+
+	err := store.CheckSchema(ctx)
+	if !errors.Is(err, ErrSchemaMismatch) || !strings.Contains(err.Error(), "payments: type string, want set") {
+		t.Fatalf("err = %v", err)
+	}
+}
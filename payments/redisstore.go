@@ -0,0 +1,542 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ===== ХРАНИЛИЩЕ В REDIS =====
+
+// Ключи Redis
+//
+//	payment:{id}       — платеж (JSON)
+//	payments           — множество (SET) ID всех платежей, для List
+//	refunds:{id}       — список (LIST) возвратов платежа (JSON)
+//	customer:{id}      — клиент (JSON)
+//	idempotency:{key}  — ID платежа, за которым закреплен ключ (с TTL)
+//	external_id:{id}   — ID платежа с этим ExternalID
+//	payments:sequence  — счетчик порядковых номеров (INCR)
+//	payments:due:{status} — индекс сроков (ZSET: ID → срок в мс Unix)
+//	                     для scheduled и authorized, см. due.go
+//
+// Индекс сроков заполняется при записи платежа: отложенные платежи
+// и авторизации, записанные версией без индекса, планировщик
+// не увидит, пока их не перезапишут
+const (
+	redisPaymentIndex = "payments"
+	redisSequenceKey  = "payments:sequence"
+)
+
+func redisPaymentKey(id string) string      { return "payment:" + id }
+func redisRefundsKey(id string) string      { return "refunds:" + id }
+func redisCustomerKey(id string) string     { return "customer:" + id }
+func redisIdempotencyKey(key string) string { return "idempotency:" + key }
+func redisExternalIDKey(id string) string   { return "external_id:" + id }
+func redisDueKey(status string) string      { return "payments:due:" + status }
+
+// indexDue добавляет в транзакцию обновление индекса сроков: платеж
+// убирается из индексов всех статусов и, если у него есть срок,
+// попадает в индекс своего статуса
+func indexDue(ctx context.Context, pipe redis.Pipeliner, p Payment) {
+	for _, status := range dueStatuses {
+		pipe.ZRem(ctx, redisDueKey(status), p.ID)
+	}
+	if at, ok := dueAt(p); ok {
+		pipe.ZAdd(ctx, redisDueKey(p.Status), redis.Z{Score: float64(at.UnixMilli()), Member: p.ID})
+	}
+}
+
+// redisMaxTxRetries — сколько раз повторить транзакцию, если ключ
+// изменил другой экземпляр API между чтением и записью
+const redisMaxTxRetries = 10
+
+// RedisStore — хранилище платежей в Redis
+//
+// В отличие от MemoryStore, данные общие для всех экземпляров API:
+// можно запустить несколько процессов за балансировщиком
+// (горизонтальное масштабирование), и все они видят одни платежи
+//
+// Изменения "прочитать → проверить → записать" (статус, удаление,
+// возвраты) выполняются как оптимистичные транзакции WATCH/MULTI:
+// если ключ изменился после чтения, Redis отменяет запись,
+// и транзакция повторяется с актуальными данными
+type RedisStore struct {
+	client *redis.Client
+}
+
+// Проверка на этапе компиляции, что RedisStore реализует Store
+var _ Store = (*RedisStore)(nil)
+
+// NewRedisStore создает хранилище поверх подключения к Redis
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// redisPayment — платеж в том виде, в котором он лежит в Redis
+// AmountMinor в JSON API скрыт (json:"-"), поэтому храним его отдельно
+type redisPayment struct {
+	Payment     Payment `json:"payment"`
+	AmountMinor int64   `json:"amount_minor"`
+}
+
+// encodePayment сериализует платеж для записи в Redis
+func encodePayment(p Payment) ([]byte, error) {
+	return json.Marshal(redisPayment{Payment: p, AmountMinor: p.AmountMinor})
+}
+
+// decodePayment разбирает платеж, прочитанный из Redis
+func decodePayment(data []byte) (Payment, error) {
+	var rp redisPayment
+	if err := json.Unmarshal(data, &rp); err != nil {
+		return Payment{}, fmt.Errorf("decoding payment: %w", err)
+	}
+	rp.Payment.AmountMinor = rp.AmountMinor
+	return rp.Payment, nil
+}
+
+// Save реализует Store
+func (s *RedisStore) Save(ctx context.Context, p Payment) error {
+	data, err := encodePayment(p)
+	if err != nil {
+		return err
+	}
+	// TxPipelined отправляет команды одним пакетом внутри MULTI/EXEC
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, redisPaymentKey(p.ID), data, 0)
+		pipe.SAdd(ctx, redisPaymentIndex, p.ID)
+		if p.ExternalID != "" {
+			pipe.Set(ctx, redisExternalIDKey(p.ExternalID), p.ID, 0)
+		}
+		indexDue(ctx, pipe, p)
+		return nil
+	})
+	return err
+}
+
+// Create реализует Store
+// SETNX = "записать, только если ключа нет" — атомарно в Redis
+//
+// Сначала занимаем external_id, затем ID платежа; если ID занят,
+// external_id освобождаем обратно
+func (s *RedisStore) Create(ctx context.Context, p Payment) error {
+	data, err := encodePayment(p)
+	if err != nil {
+		return err
+	}
+	if p.ExternalID != "" {
+		ok, err := s.client.SetNX(ctx, redisExternalIDKey(p.ExternalID), p.ID, 0).Result()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrExternalIDExists
+		}
+	}
+	ok, err := s.client.SetNX(ctx, redisPaymentKey(p.ID), data, 0).Result()
+	if err == nil && !ok {
+		err = ErrPaymentExists
+	}
+	if err != nil {
+		if p.ExternalID != "" {
+			s.client.Del(context.WithoutCancel(ctx), redisExternalIDKey(p.ExternalID))
+		}
+		return err
+	}
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SAdd(ctx, redisPaymentIndex, p.ID)
+		indexDue(ctx, pipe, p)
+		return nil
+	})
+	return err
+}
+
+// GetByExternalID реализует Store
+func (s *RedisStore) GetByExternalID(ctx context.Context, externalID string) (Payment, error) {
+	id, err := s.client.Get(ctx, redisExternalIDKey(externalID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return Payment{}, ErrPaymentNotFound
+	}
+	if err != nil {
+		return Payment{}, err
+	}
+	return s.Get(ctx, id)
+}
+
+// Get реализует Store
+func (s *RedisStore) Get(ctx context.Context, id string) (Payment, error) {
+	return s.get(ctx, s.client, id)
+}
+
+// get читает платеж через client или транзакцию (redis.Cmdable)
+// redis.Nil = ключа нет
+func (s *RedisStore) get(ctx context.Context, c redis.Cmdable, id string) (Payment, error) {
+	data, err := c.Get(ctx, redisPaymentKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return Payment{}, ErrPaymentNotFound
+	}
+	if err != nil {
+		return Payment{}, err
+	}
+	return decodePayment(data)
+}
+
+// List реализует Store
+func (s *RedisStore) List(ctx context.Context, includeDeleted bool) ([]Payment, error) {
+	ids, err := s.client.SMembers(ctx, redisPaymentIndex).Result()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Payment, 0, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = redisPaymentKey(id)
+	}
+	// MGET читает все платежи одной командой
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range values {
+		data, ok := v.(string)
+		if !ok {
+			continue // платеж удалили между SMEMBERS и MGET
+		}
+		p, err := decodePayment([]byte(data))
+		if err != nil {
+			return nil, err
+		}
+		if p.Deleted && !includeDeleted {
+			continue
+		}
+		result = append(result, p)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result, nil
+}
+
+// update выполняет "прочитать → изменить → записать" над платежом
+// как оптимистичную транзакцию. fn получает текущий платеж и возвращает
+// измененный; ошибка fn отменяет запись и возвращается вызывающему
+// extra — дополнительные команды в той же транзакции (например, RPUSH)
+func (s *RedisStore) update(ctx context.Context, id string,
+	fn func(p Payment) (Payment, error), extra func(pipe redis.Pipeliner)) (Payment, error) {
+	var result Payment
+	txf := func(tx *redis.Tx) error {
+		p, err := s.get(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		p, err = fn(p)
+		if err != nil {
+			result = p
+			return err
+		}
+		data, err := encodePayment(p)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, redisPaymentKey(id), data, 0)
+			indexDue(ctx, pipe, p)
+			if extra != nil {
+				extra(pipe)
+			}
+			return nil
+		})
+		if err == nil {
+			result = p
+		}
+		return err
+	}
+
+	for range redisMaxTxRetries {
+		err := s.client.Watch(ctx, txf, redisPaymentKey(id))
+		// TxFailedErr = ключ изменили параллельно, пробуем еще раз
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		return result, err
+	}
+	return Payment{}, fmt.Errorf("updating payment %s: too many concurrent modifications", id)
+}
+
+// MarkDeleted реализует Store
+func (s *RedisStore) MarkDeleted(ctx context.Context, id string) error {
+	_, err := s.update(ctx, id, func(p Payment) (Payment, error) {
+		if !p.Deleted {
+			now := time.Now().UTC()
+			p.Deleted = true
+			p.DeletedAt = &now
+			p.Version++
+		}
+		return p, nil
+	}, nil)
+	return err
+}
+
+// UpdateStatus реализует Store
+func (s *RedisStore) UpdateStatus(ctx context.Context, id, status string, expectedVersion int) (Payment, error) {
+	return s.update(ctx, id, func(p Payment) (Payment, error) {
+		if p.Deleted {
+			return Payment{}, ErrPaymentNotFound
+		}
+		if expectedVersion != 0 && p.Version != expectedVersion {
+			return p, ErrVersionMismatch
+		}
+		if !canTransition(p.Status, status) {
+			return p, ErrInvalidTransition
+		}
+		p.Status = status
+		p.Version++
+		return p, nil
+	}, nil)
+}
+
+// ForceStatus реализует Store
+func (s *RedisStore) ForceStatus(ctx context.Context, id, status string, expectedVersion int) (Payment, error) {
+	return s.update(ctx, id, func(p Payment) (Payment, error) {
+		if p.Deleted {
+			return Payment{}, ErrPaymentNotFound
+		}
+		if expectedVersion != 0 && p.Version != expectedVersion {
+			return p, ErrVersionMismatch
+		}
+		p.Status = status
+		p.FailureReason = "" // причина отказа — только у failed (см. FailPayment)
+		p.Version++
+		return p, nil
+	}, nil)
+}
+
+// CreateRefund реализует Store
+// Платеж и список возвратов меняются в одной транзакции
+func (s *RedisStore) CreateRefund(ctx context.Context, refund Refund) (Payment, error) {
+	data, err := json.Marshal(refund)
+	if err != nil {
+		return Payment{}, err
+	}
+	return s.update(ctx, refund.PaymentID, func(p Payment) (Payment, error) {
+		if p.Deleted {
+			return Payment{}, ErrPaymentNotFound
+		}
+		return applyRefund(p, refund)
+	}, func(pipe redis.Pipeliner) {
+		pipe.RPush(ctx, redisRefundsKey(refund.PaymentID), data)
+	})
+}
+
+// CapturePayment реализует Store
+func (s *RedisStore) CapturePayment(ctx context.Context, id string, c Capture) (Payment, error) {
+	return s.update(ctx, id, func(p Payment) (Payment, error) {
+		if p.Deleted {
+			return Payment{}, ErrPaymentNotFound
+		}
+		return c.apply(p)
+	}, nil)
+}
+
+// RetryPayment реализует Store
+func (s *RedisStore) RetryPayment(ctx context.Context, id string, rt Retry) (Payment, error) {
+	return s.update(ctx, id, func(p Payment) (Payment, error) {
+		if p.Deleted {
+			return Payment{}, ErrPaymentNotFound
+		}
+		return rt.apply(p)
+	}, nil)
+}
+
+// CompletePayment реализует Store
+func (s *RedisStore) CompletePayment(ctx context.Context, id string, c Completion) (Payment, error) {
+	return s.update(ctx, id, func(p Payment) (Payment, error) {
+		if p.Deleted {
+			return Payment{}, ErrPaymentNotFound
+		}
+		return c.apply(p)
+	}, nil)
+}
+
+// FailPayment реализует Store
+func (s *RedisStore) FailPayment(ctx context.Context, id string, f Failure) (Payment, error) {
+	return s.update(ctx, id, func(p Payment) (Payment, error) {
+		if p.Deleted {
+			return Payment{}, ErrPaymentNotFound
+		}
+		return f.apply(p)
+	}, nil)
+}
+
+// SplitPayment реализует Store
+// Части записываются в той же транзакции, что и исходный платеж
+// Их ID — свежие UUID, поэтому проверка занятости (SetNX) не нужна
+func (s *RedisStore) SplitPayment(ctx context.Context, id string, expectedVersion int, installments []Payment) (Payment, error) {
+	encoded := make([][]byte, len(installments))
+	for i, part := range installments {
+		data, err := encodePayment(part)
+		if err != nil {
+			return Payment{}, err
+		}
+		encoded[i] = data
+	}
+	return s.update(ctx, id, func(p Payment) (Payment, error) {
+		if p.Deleted {
+			return Payment{}, ErrPaymentNotFound
+		}
+		return applySplit(p, expectedVersion)
+	}, func(pipe redis.Pipeliner) {
+		for i, part := range installments {
+			pipe.Set(ctx, redisPaymentKey(part.ID), encoded[i], 0)
+			pipe.SAdd(ctx, redisPaymentIndex, part.ID)
+			indexDue(ctx, pipe, part)
+		}
+	})
+}
+
+// ListDue реализует Store
+// ZRANGEBYSCORE выдает из индекса ID со сроком <= now, MGET читает
+// сами платежи. Платеж, измененный между этими командами, отсеивается
+// повторной проверкой (isDue)
+func (s *RedisStore) ListDue(ctx context.Context, status string, now time.Time) ([]Payment, error) {
+	ids, err := s.client.ZRangeByScore(ctx, redisDueKey(status), &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(now.UnixMilli(), 10),
+	}).Result()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = redisPaymentKey(id)
+	}
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	var result []Payment
+	for _, v := range values {
+		data, ok := v.(string)
+		if !ok {
+			continue
+		}
+		p, err := decodePayment([]byte(data))
+		if err != nil {
+			return nil, err
+		}
+		if isDue(p, status, now) {
+			result = append(result, p)
+		}
+	}
+	sortByDue(result)
+	return result, nil
+}
+
+// ListRefunds реализует Store
+func (s *RedisStore) ListRefunds(ctx context.Context, paymentID string) ([]Refund, error) {
+	p, err := s.Get(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if p.Deleted {
+		return nil, ErrPaymentNotFound
+	}
+	values, err := s.client.LRange(ctx, redisRefundsKey(paymentID), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	refunds := make([]Refund, 0, len(values))
+	for _, v := range values {
+		var refund Refund
+		if err := json.Unmarshal([]byte(v), &refund); err != nil {
+			return nil, fmt.Errorf("decoding refund: %w", err)
+		}
+		refunds = append(refunds, refund)
+	}
+	return refunds, nil
+}
+
+// GetRefund реализует Store
+func (s *RedisStore) GetRefund(ctx context.Context, paymentID, refundID string) (Refund, error) {
+	refunds, err := s.ListRefunds(ctx, paymentID)
+	if err != nil {
+		return Refund{}, err
+	}
+	for _, refund := range refunds {
+		if refund.ID == refundID {
+			return refund, nil
+		}
+	}
+	return Refund{}, ErrRefundNotFound
+}
+
+// NextSequenceNumber реализует Store
+// INCR атомарен в Redis: номера уникальны для всех экземпляров API
+func (s *RedisStore) NextSequenceNumber(ctx context.Context) (int64, error) {
+	return s.client.Incr(ctx, redisSequenceKey).Result()
+}
+
+// ClaimIdempotencyKey реализует Store
+// SET NX с TTL: ключ получит только первый запрос, Redis сам удалит
+// ключ по истечении ttl
+func (s *RedisStore) ClaimIdempotencyKey(ctx context.Context, key, paymentID string, ttl time.Duration) (string, error) {
+	k := redisIdempotencyKey(key)
+	for range redisMaxTxRetries {
+		ok, err := s.client.SetNX(ctx, k, paymentID, ttl).Result()
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return "", nil
+		}
+		existing, err := s.client.Get(ctx, k).Result()
+		// Ключ истек между SETNX и GET — пробуем занять снова
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		return existing, err
+	}
+	return "", fmt.Errorf("claiming idempotency key: too many concurrent modifications")
+}
+
+// ReleaseIdempotencyKey реализует Store
+func (s *RedisStore) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	return s.client.Del(ctx, redisIdempotencyKey(key)).Err()
+}
+
+// SaveCustomer реализует Store
+func (s *RedisStore) SaveCustomer(ctx context.Context, c Customer) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, redisCustomerKey(c.ID), data, 0).Err()
+}
+
+// GetCustomer реализует Store
+func (s *RedisStore) GetCustomer(ctx context.Context, id string) (Customer, error) {
+	data, err := s.client.Get(ctx, redisCustomerKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return Customer{}, ErrCustomerNotFound
+	}
+	if err != nil {
+		return Customer{}, err
+	}
+	var c Customer
+	if err := json.Unmarshal(data, &c); err != nil {
+		return Customer{}, fmt.Errorf("decoding customer: %w", err)
+	}
+	return c, nil
+}
@@ -0,0 +1,167 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newRedisStoreT — RedisStore поверх miniredis (Redis в памяти процесса)
+func newRedisStoreT(t *testing.T) *RedisStore {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisStore(client)
+}
+
+// testStores — одни и те же проверки для обоих хранилищ
+func testStores(t *testing.T, test func(t *testing.T, store Store)) {
+	t.Run("memory", func(t *testing.T) { test(t, NewMemoryStore()) })
+	t.Run("redis", func(t *testing.T) { test(t, newRedisStoreT(t)) })
+}
+
+// TestStoreCreateGet — платеж читается таким же, каким был создан,
+// повторный ID и повторный external_id отклоняются
+func TestStoreCreateGet(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		p := Payment{ID: "pay_1", Amount: 12.34, AmountMinor: 1234, Currency: "RUB",
+			Status: StatusPending, ExternalID: "order-1", CreatedAt: created, Version: 1}
+		if err := store.Create(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := store.Get(ctx, "pay_1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.AmountMinor != 1234 || got.Currency != "RUB" || !got.CreatedAt.Equal(created) {
+			t.Fatalf("got = %+v", got)
+		}
+		if byExt, err := store.GetByExternalID(ctx, "order-1"); err != nil || byExt.ID != "pay_1" {
+			t.Fatalf("GetByExternalID = %s, %v", byExt.ID, err)
+		}
+
+		sameID := p
+		sameID.ExternalID = ""
+		if err := store.Create(ctx, sameID); !errors.Is(err, ErrPaymentExists) {
+			t.Errorf("duplicate ID: err = %v", err)
+		}
+		other := p
+		other.ID = "pay_2"
+		if err := store.Create(ctx, other); !errors.Is(err, ErrExternalIDExists) {
+			t.Errorf("duplicate external_id: err = %v", err)
+		}
+		if _, err := store.Get(ctx, "pay_2"); !errors.Is(err, ErrPaymentNotFound) {
+			t.Errorf("rejected payment stored: err = %v", err)
+		}
+	})
+}
+
+// TestStoreListAndDelete — список по времени создания, удаленные скрыты
+func TestStoreListAndDelete(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		for i, id := range []string{"pay_c", "pay_a", "pay_b"} {
+			savePaymentT(t, store, Payment{ID: id, AmountMinor: 100, Currency: "RUB",
+				Status: StatusPending, CreatedAt: base.Add(time.Duration(2-i) * time.Hour), Version: 1})
+		}
+		if err := store.MarkDeleted(ctx, "pay_a"); err != nil {
+			t.Fatal(err)
+		}
+
+		list, err := store.List(ctx, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := paymentIDs(list); len(got) != 2 || got[0] != "pay_b" || got[1] != "pay_c" {
+			t.Fatalf("List = %v, want [pay_b pay_c]", got)
+		}
+		all, _ := store.List(ctx, true)
+		if len(all) != 3 {
+			t.Fatalf("List(includeDeleted) = %v", paymentIDs(all))
+		}
+	})
+}
+
+// TestStoreUpdateStatus — версия и переходы проверяются атомарно
+func TestStoreUpdateStatus(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		savePaymentT(t, store, Payment{ID: "pay_1", AmountMinor: 100, Currency: "RUB", Status: StatusPending, Version: 1})
+
+		if _, err := store.UpdateStatus(ctx, "pay_1", StatusSucceeded, 7); !errors.Is(err, ErrVersionMismatch) {
+			t.Fatalf("stale version: err = %v", err)
+		}
+		p, err := store.UpdateStatus(ctx, "pay_1", StatusSucceeded, 1)
+		if err != nil || p.Status != StatusSucceeded || p.Version != 2 {
+			t.Fatalf("UpdateStatus = %+v, %v", p, err)
+		}
+		if _, err := store.UpdateStatus(ctx, "pay_1", StatusPending, 0); !errors.Is(err, ErrInvalidTransition) {
+			t.Fatalf("succeeded → pending: err = %v", err)
+		}
+		if _, err := store.UpdateStatus(ctx, "pay_missing", StatusFailed, 0); !errors.Is(err, ErrPaymentNotFound) {
+			t.Fatalf("missing: err = %v", err)
+		}
+	})
+}
+
+func TestStoreIdempotencyKeys(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		if owner, err := store.ClaimIdempotencyKey(ctx, "key-1", "pay_1", time.Hour); err != nil || owner != "" {
+			t.Fatalf("first claim = %q, %v", owner, err)
+		}
+		if owner, _ := store.ClaimIdempotencyKey(ctx, "key-1", "pay_2", time.Hour); owner != "pay_1" {
+			t.Fatalf("second claim owner = %q, want pay_1", owner)
+		}
+		if err := store.ReleaseIdempotencyKey(ctx, "key-1"); err != nil {
+			t.Fatal(err)
+		}
+		if owner, _ := store.ClaimIdempotencyKey(ctx, "key-1", "pay_2", time.Hour); owner != "" {
+			t.Fatalf("claim after release owner = %q", owner)
+		}
+	})
+}
+
+// TestRedisStoreSharedAcrossInstances — два экземпляра API на одном Redis
+// видят платежи друг друга, а повтор с тем же Idempotency-Key на другом
+// экземпляре не создает второй платеж
+func TestRedisStoreSharedAcrossInstances(t *testing.T) {
+	mr := miniredis.RunT(t)
+	newInstance := func() *Server {
+		client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		t.Cleanup(func() { client.Close() })
+		return NewServer(NewRedisStore(client), nil, nil, Config{})
+	}
+	a, b := newInstance(), newInstance()
+
+	body := `{"amount": 100, "currency": "RUB"}`
+	key := map[string]string{"Idempotency-Key": "order-42"}
+	recA := doJSON(t, a, http.MethodPost, "/payments", body, key)
+	recB := doJSON(t, b, http.MethodPost, "/payments", body, key)
+	var first, retry Payment
+	decodeBody(t, recA, &first)
+	decodeBody(t, recB, &retry)
+	if recA.Code != http.StatusCreated || first.ID == "" || retry.ID != first.ID {
+		t.Fatalf("create = %d %s, retry = %d %s", recA.Code, first.ID, recB.Code, retry.ID)
+	}
+
+	if rec := doJSON(t, b, http.MethodGet, "/payments/"+first.ID, "", nil); rec.Code != http.StatusOK {
+		t.Fatalf("GET on other instance = %d", rec.Code)
+	}
+	if n := len(mr.Keys()); n == 0 {
+		t.Fatal("nothing stored in Redis")
+	}
+	if ids, _ := mr.Members(redisPaymentIndex); len(ids) != 1 {
+		t.Fatalf("index = %v, want one payment", ids)
+	}
+}
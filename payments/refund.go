@@ -0,0 +1,259 @@
+package payments
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// ===== ВОЗВРАТЫ =====
+
+// Refund — возврат денег по платежу (полный или частичный)
+//
+// Платеж можно вернуть несколькими частями, пока сумма возвратов
+// не достигнет суммы платежа. Тогда платеж переходит в статус refunded
+type Refund struct {
+	ID        string `json:"id"`
+	PaymentID string `json:"payment_id"`
+
+	// Amount — сумма возврата в валюте платежа
+	// AmountMinor — она же в минорных единицах (по ней ведется учет)
+	Amount      float64 `json:"amount"`
+	AmountMinor int64   `json:"amount_minor"`
+	Currency    string  `json:"currency"`
+
+	CreatedAt time.Time `json:"created_at"`
+
+	// maxCount — сколько возвратов можно провести по платежу
+	// (Config.MaxRefunds, 0 = без ограничения). Заполняет обработчик,
+	// проверяет applyRefund; в JSON и хранилище не попадает
+	maxCount int
+}
+
+// newRefundID генерирует ID возврата вида "re_<uuid v4>"
+func newRefundID() string {
+	return newID(refundIDPrefix)
+}
+
+// refundRequest — тело запроса на возврат
+// Amount, как и у платежа, — число или строка; без суммы
+// возвращается весь остаток
+type refundRequest struct {
+	Amount json.RawMessage `json:"amount"`
+}
+
+// handlePaymentRefunds обрабатывает запросы к /payments/{id}/refunds
+func (s *Server) handlePaymentRefunds(w http.ResponseWriter, r *http.Request) {
+	if !isValidPaymentID(r.PathValue("id")) {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidID, "Invalid payment ID: must start with "+paymentIDPrefix)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.handleListRefunds(w, r)
+	case http.MethodPost:
+		s.handleCreateRefund(w, r)
+	default:
+		writeMethodNotAllowed(w, r, http.MethodGet, http.MethodPost)
+	}
+}
+
+// handleCreateRefund проводит возврат по платежу
+// POST /payments/{id}/refunds {"amount": "25.00"}
+//
+// Коды ответа:
+// - 201 Created = возврат проведен, в ответе возврат
+// - 404 Not Found = платежа нет
+// - 422 Unprocessable Entity = платеж не succeeded, сумма больше остатка
+// или исчерпан лимит числа возвратов (Config.MaxRefunds)
+func (s *Server) handleCreateRefund(w http.ResponseWriter, r *http.Request) {
+	var req refundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+	amount, literal, number, err := decodeAmount(req.Amount)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidJSON, "Invalid JSON")
+		return
+	}
+
+	id := r.PathValue("id")
+	payment, err := s.store.Get(r.Context(), id)
+	if errors.Is(err, ErrPaymentNotFound) || (err == nil && payment.Deleted) {
+		writeError(w, r, http.StatusNotFound, CodePaymentNotFound, "Payment not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Error loading payment: %v", err)
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+		return
+	}
+
+	// Сумма возврата в минорных единицах валюты платежа
+	// Без суммы — весь остаток (полный возврат); явный 0 — ошибка,
+	// а не полный возврат, поэтому смотрим на наличие поля, а не на значение
+	refund := Refund{
+		ID:        newRefundID(),
+		PaymentID: id,
+		Currency:  payment.Currency,
+		CreatedAt: time.Now().UTC(),
+		maxCount:  s.maxRefunds,
+	}
+	if number == "" && literal == "" {
+		refund.AmountMinor = payment.AmountRefundableMinor
+	} else {
+		refund.AmountMinor, err = requestAmountMinor(amount, s.amountLiteral(literal), number, payment.Currency, s.rounding.modeFor(payment.Currency), s.maxFloatMinor)
+		if errors.Is(err, ErrAmountTooLarge) {
+			// Сумма больше системного потолка заведомо больше остатка
+			writeError(w, r, http.StatusUnprocessableEntity, CodeRefundExceedsBalance,
+				fmt.Sprintf("Refund exceeds refundable balance of %d minor units", payment.AmountRefundableMinor))
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, CodeInvalidAmount, err.Error())
+			return
+		}
+		if refund.AmountMinor <= 0 {
+			writeError(w, r, http.StatusBadRequest, CodeInvalidAmount, "Amount must be positive")
+			return
+		}
+	}
+	refund.Amount = minorToAmount(refund.AmountMinor, refund.Currency)
+
+	before := payment.Status
+	payment, err = s.store.CreateRefund(r.Context(), refund)
+	switch {
+	case errors.Is(err, ErrPaymentNotFound):
+		writeError(w, r, http.StatusNotFound, CodePaymentNotFound, "Payment not found")
+		return
+	case errors.Is(err, ErrNotRefundable):
+		writeError(w, r, http.StatusUnprocessableEntity, CodeNotRefundable,
+			fmt.Sprintf("Only succeeded payments can be refunded, payment is %s", payment.Status))
+		return
+	case errors.Is(err, ErrRefundExceedsBalance):
+		writeError(w, r, http.StatusUnprocessableEntity, CodeRefundExceedsBalance,
+			fmt.Sprintf("Refund exceeds refundable balance of %d minor units", payment.AmountRefundableMinor))
+		return
+	case errors.Is(err, ErrRefundLimitReached):
+		writeError(w, r, http.StatusUnprocessableEntity, CodeRefundLimitReached,
+			fmt.Sprintf("Payment already has the maximum of %d refunds", s.maxRefunds))
+		return
+	case err != nil:
+		log.Printf("Error creating refund: %v", err)
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+		return
+	}
+
+	log.Printf("Refund created: ID=%s, Payment=%s, AmountMinor=%d, Refunded=%d/%d",
+		refund.ID, payment.ID, refund.AmountMinor, payment.AmountRefundedMinor, payment.AmountMinor)
+	s.audit.record(auditActor(r), AuditRefund, payment.ID, before, payment.Status)
+
+	// Версия платежа изменилась — сообщаем подписчикам потока
+	s.publishStatus(r.Context(), payment)
+
+	writeJSON(w, r, http.StatusCreated, refund)
+}
+
+// handleListRefunds возвращает возвраты платежа
+// GET /payments/{id}/refunds
+func (s *Server) handleListRefunds(w http.ResponseWriter, r *http.Request) {
+	refunds, err := s.store.ListRefunds(r.Context(), r.PathValue("id"))
+	if errors.Is(err, ErrPaymentNotFound) {
+		writeError(w, r, http.StatusNotFound, CodePaymentNotFound, "Payment not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Error listing refunds: %v", err)
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, refunds)
+}
+
+// handleGetRefund возвращает один возврат платежа
+// GET /payments/{id}/refunds/{refundId}
+func (s *Server) handleGetRefund(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	if !isValidPaymentID(r.PathValue("id")) {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidID, "Invalid payment ID: must start with "+paymentIDPrefix)
+		return
+	}
+	if !isValidRefundID(r.PathValue("refundId")) {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidID, "Invalid refund ID: must start with "+refundIDPrefix)
+		return
+	}
+
+	refund, err := s.store.GetRefund(r.Context(), r.PathValue("id"), r.PathValue("refundId"))
+	switch {
+	case errors.Is(err, ErrPaymentNotFound):
+		writeError(w, r, http.StatusNotFound, CodePaymentNotFound, "Payment not found")
+		return
+	case errors.Is(err, ErrRefundNotFound):
+		writeError(w, r, http.StatusNotFound, CodeRefundNotFound, "Refund not found")
+		return
+	case err != nil:
+		log.Printf("Error loading refund: %v", err)
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, refund)
+}
+
+// money возвращает сумму возврата вместе с валютой
+func (r Refund) money() Money {
+	return Money{AmountMinor: r.AmountMinor, Currency: r.Currency}
+}
+
+// applyRefund проверяет, что возврат можно провести, и возвращает
+// платеж с обновленными суммами возвратов
+// Вызывается хранилищем под блокировкой (или в транзакции)
+//
+// Лимит числа возвратов (refund.maxCount) проверяется отдельно
+// от остатка: при исчерпанном лимите возврат отклоняется
+// с ErrRefundLimitReached, даже если деньги еще остались
+//
+// Суммы считаются через Money: возврат в чужой валюте — ошибка
+// ErrCurrencyMismatch, а не тихое вычитание долларов из рублей
+func applyRefund(p Payment, refund Refund) (Payment, error) {
+	if p.Status != StatusSucceeded {
+		return p, ErrNotRefundable
+	}
+	if refund.maxCount > 0 && p.RefundCount >= refund.maxCount {
+		return p, ErrRefundLimitReached
+	}
+	amount := refund.money()
+	refundable := Money{AmountMinor: p.AmountRefundableMinor, Currency: p.Currency}
+	cmp, err := amount.Cmp(refundable)
+	if err != nil {
+		return p, err
+	}
+	if cmp > 0 {
+		return p, ErrRefundExceedsBalance
+	}
+
+	refunded, err := Money{AmountMinor: p.AmountRefundedMinor, Currency: p.Currency}.Add(amount)
+	if err != nil {
+		return p, err
+	}
+	refundable, err = refundable.Sub(amount)
+	if err != nil {
+		return p, err
+	}
+	p.AmountRefundedMinor = refunded.AmountMinor
+	p.AmountRefundableMinor = refundable.AmountMinor
+	p.RefundCount++
+	// Вернули все — платеж полностью возвращен
+	if refundable.IsZero() {
+		p.Status = StatusRefunded
+	}
+	p.Version++
+	return p, nil
+}
@@ -0,0 +1,135 @@
+package payments
+
+import (
+	"net/http"
+	"testing"
+)
+
+// succeededPaymentT сохраняет успешный платеж на amountMinor копеек
+func succeededPaymentT(t *testing.T, store Store, id string, amountMinor int64) {
+	t.Helper()
+	savePaymentT(t, store, Payment{ID: id, AmountMinor: amountMinor, AmountRefundableMinor: amountMinor,
+		Currency: "RUB", Status: StatusSucceeded, Version: 1})
+}
+
+// createRefundT проводит возврат и возвращает его
+func createRefundT(t *testing.T, s *Server, paymentID, body string) Refund {
+	t.Helper()
+	rec := doJSON(t, s, http.MethodPost, "/payments/"+paymentID+"/refunds", body, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST refunds = %d: %s", rec.Code, rec.Body.String())
+	}
+	var refund Refund
+	decodeBody(t, rec, &refund)
+	return refund
+}
+
+func TestListAndGetRefunds(t *testing.T) {
+	store := NewMemoryStore()
+	s := NewServer(store, nil, nil, Config{})
+	succeededPaymentT(t, store, "pay_r", 10000)
+	refund := createRefundT(t, s, "pay_r", `{"amount": 30}`)
+
+	var list []Refund
+	decodeBody(t, doJSON(t, s, http.MethodGet, "/payments/pay_r/refunds", "", nil), &list)
+	if len(list) != 1 || list[0].ID != refund.ID || list[0].AmountMinor != 3000 {
+		t.Fatalf("list = %+v", list)
+	}
+
+	var got Refund
+	rec := doJSON(t, s, http.MethodGet, "/payments/pay_r/refunds/"+refund.ID, "", nil)
+	decodeBody(t, rec, &got)
+	if rec.Code != http.StatusOK || got.ID != refund.ID || got.PaymentID != "pay_r" {
+		t.Fatalf("get = %d %+v", rec.Code, got)
+	}
+
+	cases := map[string]string{
+		"/payments/pay_missing/refunds":              CodePaymentNotFound,
+		"/payments/pay_r/refunds/re_missing":         CodeRefundNotFound,
+		"/payments/pay_missing/refunds/" + refund.ID: CodePaymentNotFound,
+	}
+	for path, code := range cases {
+		rec := doJSON(t, s, http.MethodGet, path, "", nil)
+		var resp ErrorResponse
+		decodeBody(t, rec, &resp)
+		if rec.Code != http.StatusNotFound || resp.Code != code {
+			t.Errorf("%s = %d %s, want 404 %s", path, rec.Code, resp.Code, code)
+		}
+	}
+	// Некорректный ID возврата — 400, а не 404
+	if rec := doJSON(t, s, http.MethodGet, "/payments/pay_r/refunds/bogus", "", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("malformed refund ID = %d, want 400", rec.Code)
+	}
+}
+
+// Пустой список — [] а не null
+func TestListRefundsEmpty(t *testing.T) {
+	store := NewMemoryStore()
+	s := NewServer(store, nil, nil, Config{})
+	succeededPaymentT(t, store, "pay_empty", 100)
+	if body := doJSON(t, s, http.MethodGet, "/payments/pay_empty/refunds", "", nil).Body.String(); body != "[]\n" {
+		t.Fatalf("body = %q", body)
+	}
+}
+
+// Явный 0 — ошибка суммы, а не полный возврат; полный возврат
+// только когда поля amount нет вовсе
+func TestRefundZeroAmount(t *testing.T) {
+	store := NewMemoryStore()
+	s := NewServer(store, nil, nil, Config{})
+	succeededPaymentT(t, store, "pay_zero", 10000)
+
+	for _, body := range []string{`{"amount": 0}`, `{"amount": "0"}`, `{"amount": 0.00}`} {
+		rec := doJSON(t, s, http.MethodPost, "/payments/pay_zero/refunds", body, nil)
+		var resp ErrorResponse
+		decodeBody(t, rec, &resp)
+		if rec.Code != http.StatusBadRequest || resp.Code != CodeInvalidAmount {
+			t.Errorf("%s = %d %s, want 400 %s", body, rec.Code, resp.Code, CodeInvalidAmount)
+		}
+	}
+
+	refund := createRefundT(t, s, "pay_zero", `{}`)
+	if refund.AmountMinor != 10000 {
+		t.Fatalf("full refund amount_minor = %d, want 10000", refund.AmountMinor)
+	}
+}
+
+// TestRefundCountLimit — после MaxRefunds возвратов следующий получает
+// 422, хотя остаток еще есть; в хранилищах лимит работает одинаково
+func TestRefundCountLimit(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		s := NewServer(store, nil, nil, Config{MaxRefunds: 2})
+		succeededPaymentT(t, store, "pay_r", 10000)
+		createRefundT(t, s, "pay_r", `{"amount": 10}`)
+		createRefundT(t, s, "pay_r", `{"amount": 10}`)
+
+		rec := doJSON(t, s, http.MethodPost, "/payments/pay_r/refunds", `{"amount": 10}`, nil)
+		var resp ErrorResponse
+		decodeBody(t, rec, &resp)
+		if rec.Code != http.StatusUnprocessableEntity || resp.Code != CodeRefundLimitReached {
+			t.Fatalf("third refund: %d %+v", rec.Code, resp)
+		}
+
+		var p struct {
+			RefundCount           int   `json:"refund_count"`
+			AmountRefundableMinor int64 `json:"amount_refundable_minor"`
+		}
+		decodeBody(t, doJSON(t, s, http.MethodGet, "/payments/pay_r", "", nil), &p)
+		if p.RefundCount != 2 || p.AmountRefundableMinor != 8000 {
+			t.Fatalf("after limit: %+v", p)
+		}
+	})
+}
+
+// TestRefundCountUnlimited — по умолчанию ограничен только остаток
+func TestRefundCountUnlimited(t *testing.T) {
+	store := NewMemoryStore()
+	s := NewServer(store, nil, nil, Config{})
+	succeededPaymentT(t, store, "pay_r", 1000)
+	for range 10 {
+		createRefundT(t, s, "pay_r", `{"amount": 1}`)
+	}
+	if rec := doJSON(t, s, http.MethodPost, "/payments/pay_r/refunds", `{"amount": 1}`, nil); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("refund beyond balance = %d", rec.Code)
+	}
+}
@@ -0,0 +1,45 @@
+package payments
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// ===== ОТПРАВКА JSON ОТВЕТОВ =====
+
+// writeJSON отправляет успешный ответ в формате JSON
+//
+// По умолчанию JSON компактный (одна строка) — меньше трафика
+// С параметром ?pretty=true ответ форматируется с отступами
+// в 2 пробела — удобно читать при отладке через curl
+//
+// С Accept: application/vnd.api+json платежи, возвраты и клиенты
+// заворачиваются в документ JSON:API (см. jsonapi.go)
+//
+// Заголовки (ETag, Location) нужно выставить ДО вызова:
+// после WriteHeader изменить их уже нельзя
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v any) {
+	contentType := mediaJSON
+	if wantsJSONAPI(r) {
+		doc, ok, err := jsonAPIEnvelope(v)
+		if err != nil {
+			log.Printf("Error building JSON:API document: %v", err)
+		}
+		if ok {
+			v, contentType = doc, mediaJSONAPI
+		}
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+
+	// json.NewEncoder(w) = создает энкодер, пишущий в w (ResponseWriter)
+	enc := json.NewEncoder(w)
+	if r.URL.Query().Get("pretty") == "true" {
+		// SetIndent(префикс строки, отступ)
+		enc.SetIndent("", "  ")
+	}
+	// Если ошибка кодирования — игнорируем (поздно что-то менять:
+	// статус и часть тела уже могли уйти клиенту)
+	enc.Encode(v)
+}
@@ -0,0 +1,23 @@
+package payments
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPrettyJSON(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	p := createPaymentT(t, s, `{"amount": 100, "currency": "RUB"}`)
+
+	for _, path := range []string{"/payments/" + p.ID, "/payments"} {
+		compact := doJSON(t, s, http.MethodGet, path, "", nil).Body.String()
+		if strings.Contains(strings.TrimSuffix(compact, "\n"), "\n") {
+			t.Errorf("%s: compact output has newlines: %q", path, compact)
+		}
+		pretty := doJSON(t, s, http.MethodGet, path+"?pretty=true", "", nil).Body.String()
+		if !strings.Contains(pretty, "\n  ") {
+			t.Errorf("%s: pretty output is not indented: %q", path, pretty)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// ===== ПОВТОР ОТКЛОНЕННОГО ПЛАТЕЖА =====
+//
+// POST /payments/{id}/retry снова отправляет отклоненный (failed) платеж
+// в шлюз с теми же данными — клиенту не нужно создавать новый платеж
+// Удачный повтор переводит платеж в succeeded, неудачный оставляет
+// failed с новой причиной. Каждая попытка увеличивает RetryCount,
+// число попыток ограничено (Config.MaxPaymentRetries)
+//
+// Повтор идет в два шага, как у планировщика (см. processScheduled):
+// 1. попытка "забирается" в хранилище (RetryCount+1, новая версия) —
+//    из двух параллельных повторов шлюз вызовет только один
+// 2. после ответа шлюза результат записывается по версии из шага 1
+
+// defaultMaxPaymentRetries — сколько повторов по умолчанию
+const defaultMaxPaymentRetries = 3
+
+// Retry — шаг повтора, который хранилище применяет к платежу атомарно
+//
+// ClaimedVersion = 0 — забрать попытку (шаг 1): проверяется, что платеж
+// failed и попытки не исчерпаны (MaxRetries)
+// ClaimedVersion > 0 — записать результат (шаг 2): FailureReason = ""
+// значит успех, иначе платеж остается failed с этой причиной
+type Retry struct {
+	MaxRetries     int
+	ClaimedVersion int
+	FailureReason  string
+}
+
+// apply применяет шаг повтора к платежу
+// Вызывается хранилищем под блокировкой (или в транзакции)
+func (rt Retry) apply(p Payment) (Payment, error) {
+	if p.Status != StatusFailed {
+		return p, ErrNotRetryable
+	}
+	if rt.ClaimedVersion == 0 {
+		if p.RetryCount >= rt.MaxRetries {
+			return p, ErrRetriesExhausted
+		}
+		p.RetryCount++
+		p.Version++
+		return p, nil
+	}
+	if p.Version != rt.ClaimedVersion {
+		return p, ErrVersionMismatch
+	}
+	if rt.FailureReason == "" {
+		p.Status = StatusSucceeded
+	}
+	p.FailureReason = rt.FailureReason
+	p.Version++
+	return p, nil
+}
+
+// handleRetryPayment повторяет списание отклоненного платежа
+// POST /payments/{id}/retry
+//
+// Коды ответа:
+//   - 200 OK = попытка сделана, в ответе платеж (succeeded или снова failed)
+//   - 404 Not Found = платежа нет
+//   - 409 Conflict = платеж не failed (payment_not_retryable), попытки
+//     исчерпаны (retries_exhausted) или шлюз не подключен
+func (s *Server) handleRetryPayment(w http.ResponseWriter, r *http.Request) {
+	if !isValidPaymentID(r.PathValue("id")) {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidID, "Invalid payment ID: must start with "+paymentIDPrefix)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+	if s.gateway == nil {
+		writeError(w, r, http.StatusConflict, CodeNotRetryable, "Payments cannot be retried without a payment gateway")
+		return
+	}
+
+	id := r.PathValue("id")
+	claimed, err := s.store.RetryPayment(r.Context(), id, Retry{MaxRetries: s.maxPaymentRetries})
+	if !s.writeRetryError(w, r, claimed, err) {
+		return
+	}
+
+	// Шлюз вызывается уже после того, как попытка забрана
+	reason := ""
+	if err := s.gateway.Charge(r.Context(), claimed); err != nil {
+		reason = s.failureReason(err)
+		log.Printf("Gateway charge failed on retry: ID=%s, Attempt=%d, Reason=%s, Error=%v",
+			id, claimed.RetryCount, reason, err)
+	}
+	// Результат шлюза записываем, даже если клиент уже отключился:
+	// деньги могли списаться
+	payment, err := s.store.RetryPayment(context.WithoutCancel(r.Context()), id,
+		Retry{ClaimedVersion: claimed.Version, FailureReason: reason})
+	if !s.writeRetryError(w, r, payment, err) {
+		return
+	}
+
+	log.Printf("Payment retried: ID=%s, Attempt=%d, Status=%s", payment.ID, payment.RetryCount, payment.Status)
+	s.audit.record(auditActor(r), AuditRetry, payment.ID, StatusFailed, payment.Status)
+	s.publishStatus(r.Context(), payment)
+
+	w.Header().Set("ETag", paymentETag(payment))
+	writeJSON(w, r, http.StatusOK, payment)
+}
+
+// writeRetryError отвечает на ошибку шага повтора
+// Возвращает true, если ошибки нет и обработку можно продолжать
+func (s *Server) writeRetryError(w http.ResponseWriter, r *http.Request, payment Payment, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, ErrPaymentNotFound):
+		writeError(w, r, http.StatusNotFound, CodePaymentNotFound, "Payment not found")
+	case errors.Is(err, ErrNotRetryable):
+		writeError(w, r, http.StatusConflict, CodeNotRetryable,
+			fmt.Sprintf("Only failed payments can be retried, payment is %s", payment.Status))
+	case errors.Is(err, ErrRetriesExhausted):
+		writeError(w, r, http.StatusConflict, CodeRetriesExhausted,
+			fmt.Sprintf("Payment has already been retried %d times", payment.RetryCount))
+	case errors.Is(err, ErrVersionMismatch):
+		writeError(w, r, http.StatusConflict, CodeVersionMismatch, "Payment was modified during retry")
+	default:
+		log.Printf("Error retrying payment: %v", err)
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package payments
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+// newFailedServer кладет в хранилище отклоненный платеж pay_failed
+func newFailedServer(t *testing.T, gateway PaymentGateway, cfg Config) *Server {
+	t.Helper()
+	store := NewMemoryStore()
+	savePaymentT(t, store, Payment{ID: "pay_failed", AmountMinor: 100, Currency: "RUB",
+		Status: StatusFailed, FailureReason: FailureInsufficientFunds, Version: 1})
+	return NewServer(store, nil, gateway, cfg)
+}
+
+// TestRetrySucceeds — первый повтор снова отклонен, второй проходит:
+// платеж succeeded, причина отказа стерта, RetryCount = 2
+func TestRetrySucceeds(t *testing.T) {
+	var calls atomic.Int32
+	s := newFailedServer(t, gatewayFunc(func(context.Context, Payment) error {
+		if calls.Add(1) == 1 {
+			return &GatewayError{Code: FailureInsufficientFunds, Message: "declined by test"}
+		}
+		return nil
+	}), Config{})
+
+	rec := doJSON(t, s, http.MethodPost, "/payments/pay_failed/retry", "", nil)
+	var got Payment
+	decodeBody(t, rec, &got)
+	if rec.Code != http.StatusOK || got.Status != StatusFailed || got.RetryCount != 1 {
+		t.Fatalf("first retry: %d status = %s, retry_count = %d", rec.Code, got.Status, got.RetryCount)
+	}
+
+	rec = doJSON(t, s, http.MethodPost, "/payments/pay_failed/retry", "", nil)
+	got = Payment{}
+	decodeBody(t, rec, &got)
+	if rec.Code != http.StatusOK || got.Status != StatusSucceeded || got.RetryCount != 2 || got.FailureReason != "" {
+		t.Fatalf("second retry: %d %+v", rec.Code, got)
+	}
+
+	// Успешный платеж повторять нечего
+	rec = doJSON(t, s, http.MethodPost, "/payments/pay_failed/retry", "", nil)
+	var resp ErrorResponse
+	decodeBody(t, rec, &resp)
+	if rec.Code != http.StatusConflict || resp.Code != CodeNotRetryable {
+		t.Fatalf("retry succeeded: %d %+v", rec.Code, resp)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Fatalf("gateway calls = %d, want 2", n)
+	}
+}
+
+// TestRetryExhausted — после MaxPaymentRetries попыток шлюз больше
+// не вызывается, ответ 409 retries_exhausted
+func TestRetryExhausted(t *testing.T) {
+	var calls atomic.Int32
+	s := newFailedServer(t, gatewayFunc(func(context.Context, Payment) error {
+		calls.Add(1)
+		return &GatewayError{Code: FailureInsufficientFunds, Message: "declined by test"}
+	}), Config{MaxPaymentRetries: 2})
+
+	for range 2 {
+		if rec := doJSON(t, s, http.MethodPost, "/payments/pay_failed/retry", "", nil); rec.Code != http.StatusOK {
+			t.Fatalf("retry = %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+	rec := doJSON(t, s, http.MethodPost, "/payments/pay_failed/retry", "", nil)
+	var resp ErrorResponse
+	decodeBody(t, rec, &resp)
+	if rec.Code != http.StatusConflict || resp.Code != CodeRetriesExhausted {
+		t.Fatalf("exhausted: %d %+v", rec.Code, resp)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Fatalf("gateway calls = %d, want 2", n)
+	}
+}
+
+func TestRetryWithoutGateway(t *testing.T) {
+	s := newFailedServer(t, nil, Config{})
+	if rec := doJSON(t, s, http.MethodPost, "/payments/pay_failed/retry", "", nil); rec.Code != http.StatusConflict {
+		t.Fatalf("no gateway = %d", rec.Code)
+	}
+}
@@ -0,0 +1,114 @@
+package payments
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ===== ОКРУГЛЕНИЕ =====
+
+// RoundingMode — способ округления дробных минорных единиц
+//
+// При конвертации валют или переводе суммы в копейки бывают "хвосты":
+// 10.125 RUB = 1012.5 копейки. Как округлять — бизнес-решение,
+// поэтому режим настраивается, а не зашит в код
+type RoundingMode string
+
+const (
+	// RoundHalfEven — банковское округление: .5 к ближайшему ЧЕТНОМУ
+	// 1012.5 → 1012, 1013.5 → 1014. В среднем не смещает суммы
+	// ни вверх, ни вниз — режим по умолчанию
+	RoundHalfEven RoundingMode = "half_even"
+
+	// RoundHalfUp — "школьное" округление: .5 от нуля. 1012.5 → 1013
+	RoundHalfUp RoundingMode = "half_up"
+
+	// RoundFloor — всегда вниз. 1012.9 → 1012
+	RoundFloor RoundingMode = "floor"
+)
+
+// round округляет x до целого по режиму
+// Неизвестный или пустой режим = банковское округление
+func (m RoundingMode) round(x float64) float64 {
+	switch m {
+	case RoundHalfUp:
+		return math.Round(x)
+	case RoundFloor:
+		return math.Floor(x)
+	default:
+		return math.RoundToEven(x)
+	}
+}
+
+// divRound делит неотрицательное n на положительное d и округляет
+// частное по режиму — целочисленно, без ошибок float64
+func (m RoundingMode) divRound(n, d int64) int64 {
+	q, rem := n/d, n%d
+	switch m {
+	case RoundFloor:
+		return q
+	case RoundHalfUp:
+		if 2*rem >= d {
+			q++
+		}
+	default:
+		// Ровно половина — к четному
+		if 2*rem > d || (2*rem == d && q%2 == 1) {
+			q++
+		}
+	}
+	return q
+}
+
+// ParseRoundingMode разбирает название режима из конфигурации
+func ParseRoundingMode(s string) (RoundingMode, error) {
+	switch m := RoundingMode(strings.ToLower(strings.TrimSpace(s))); m {
+	case RoundHalfEven, RoundHalfUp, RoundFloor:
+		return m, nil
+	default:
+		return "", fmt.Errorf("invalid rounding mode %q: expected half_even, half_up or floor", s)
+	}
+}
+
+// Rounding — настройки округления: общий режим и исключения по валютам
+type Rounding struct {
+	// Default — режим для валют без отдельной настройки ("" = half_even)
+	Default RoundingMode
+
+	// PerCurrency — режимы для отдельных валют, например {"JPY": "floor"}
+	PerCurrency map[string]RoundingMode
+}
+
+// modeFor возвращает режим округления для валюты
+func (r Rounding) modeFor(currency string) RoundingMode {
+	if m, ok := r.PerCurrency[currency]; ok {
+		return m
+	}
+	if r.Default != "" {
+		return r.Default
+	}
+	return RoundHalfEven
+}
+
+// ParseRoundingModes разбирает режимы по валютам из строки конфигурации
+// Формат: "JPY:floor,USD:half_up"
+func ParseRoundingModes(s string) (map[string]RoundingMode, error) {
+	modes := make(map[string]RoundingMode)
+	if strings.TrimSpace(s) == "" {
+		return modes, nil
+	}
+	for _, entry := range strings.Split(s, ",") {
+		code, mode, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if !ok || !isCurrencyCode(code) {
+			return nil, fmt.Errorf("invalid rounding entry %q: expected CUR:mode", entry)
+		}
+		m, err := ParseRoundingMode(mode)
+		if err != nil {
+			return nil, err
+		}
+		modes[code] = m
+	}
+	return modes, nil
+}
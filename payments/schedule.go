@@ -0,0 +1,99 @@
+package payments
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// ===== ОТЛОЖЕННЫЕ ПЛАТЕЖИ =====
+//
+// Платеж с "process_at" в будущем создается в статусе scheduled
+// и не идет в шлюз. Планировщик (RunScheduler) периодически находит
+// платежи, срок которых наступил, и проводит их так же, как при
+// создании: со шлюзом — succeeded или failed, без шлюза — pending
+// process_at в прошлом или без него = обычная немедленная обработка
+
+// schedulerActor — "кто" меняет платеж в журнале аудита
+const schedulerActor = "scheduler"
+
+// RunScheduler проводит наступившие отложенные платежи каждые interval
+//
+// Функция блокирующая: запускайте в отдельной горутине
+//
+//	go server.RunScheduler(ctx, time.Second)
+//
+// Работает, пока не отменен ctx (например, при остановке сервера)
+func (s *Server) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if n := s.ProcessDueScheduled(ctx, now); n > 0 {
+				log.Printf("Scheduler processed %d payments", n)
+			}
+		}
+	}
+}
+
+// ProcessDueScheduled проводит отложенные платежи с ProcessAt <= now
+// Возвращает, сколько платежей проведено
+// now передается параметром, чтобы тест мог "перевести часы"
+func (s *Server) ProcessDueScheduled(ctx context.Context, now time.Time) int {
+	// Индекс сроков хранилища, а не полный список: на каждом тике
+	// читаются только наступившие отложенные платежи
+	payments, err := s.store.ListDue(ctx, StatusScheduled, now)
+	if err != nil {
+		log.Printf("Error listing scheduled payments: %v", err)
+		return 0
+	}
+	n := 0
+	for _, p := range payments {
+		if s.processScheduled(ctx, p) {
+			n++
+		}
+	}
+	return n
+}
+
+// processScheduled проводит один отложенный платеж
+//
+// Сначала платеж "забирается" переходом scheduled → pending с проверкой
+// версии: если его успели отменить или забрал другой экземпляр API
+// (общее хранилище Redis), UpdateStatus вернет ошибку и мы его пропустим
+// Списание идет уже после этого, поэтому деньги не спишутся дважды
+// Весь путь идет под блокировкой платежа, как изменяющий запрос к нему
+func (s *Server) processScheduled(ctx context.Context, p Payment) bool {
+	defer s.paymentLocks.lock(p.ID)()
+
+	claimed, err := s.store.UpdateStatus(ctx, p.ID, StatusPending, p.Version)
+	if err != nil {
+		return false
+	}
+	s.audit.record(schedulerActor, AuditUpdate, p.ID, p.Status, claimed.Status)
+	if s.gateway == nil {
+		s.publishStatus(ctx, claimed)
+		return true
+	}
+
+	status, reason := StatusSucceeded, ""
+	if err := s.gateway.Charge(ctx, claimed); err != nil {
+		status, reason = StatusFailed, s.failureReason(err)
+		log.Printf("Gateway charge failed: ID=%s, Reason=%s, Error=%v", p.ID, reason, err)
+	}
+	done, err := s.updateStatus(ctx, p.ID, status, claimed.Version, reason)
+	if err != nil {
+		// Платеж изменили, пока шел запрос к шлюзу (например, PATCH):
+		// оставляем как есть, результат шлюза — в логе выше и здесь
+		log.Printf("Error completing scheduled payment %s (gateway result %s): %v", p.ID, status, err)
+		s.publishStatus(ctx, claimed)
+		return true
+	}
+	s.audit.record(schedulerActor, AuditUpdate, p.ID, claimed.Status, done.Status)
+	s.publishStatus(ctx, done)
+	return true
+}
@@ -0,0 +1,567 @@
+package payments
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"path"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ===== HTTP СЕРВЕР =====
+
+// Config — настройки поведения API
+// Заполняется в cmd/api/main.go из переменных окружения
+type Config struct {
+	// DefaultCurrency — валюта, подставляемая если клиент ее не указал
+	// Пустая строка = значения по умолчанию нет (валюта обязательна)
+	DefaultCurrency string
+
+	// SupportedCurrencies — принимаемые валюты (ISO 4217)
+	// nil или пустой срез = DefaultSupportedCurrencies
+	SupportedCurrencies []string
+
+	// BlockedCurrencies — валюты, временно запрещенные для новых платежей
+	// (например, по требованию комплаенса), даже если они поддерживаются
+	// Создание платежа в такой валюте получает 403. nil = запретов нет
+	BlockedCurrencies []string
+
+	// DuplicateWindow — окно поиска случайных дублей: платеж с тем же
+	// customer_id, суммой и валютой в пределах окна отклоняется с 409
+	// Платежи без customer_id не проверяются
+	// 0 = проверка выключена (по умолчанию)
+	DuplicateWindow time.Duration
+
+	// Rounding — режимы округления при переводе в минорные единицы
+	// Нулевое значение = банковское округление для всех валют
+	Rounding Rounding
+
+	// ThousandsSeparators разрешает запятые-разделители разрядов
+	// в строковых суммах: "1,000.50" (см. stripThousandsSeparators)
+	// false = такая сумма — ошибка 400 (по умолчанию)
+	ThousandsSeparators bool
+
+	// Fees — комиссии по валютам (см. ParseFees)
+	// Валюта без записи = без комиссии
+	Fees map[string]Fee
+
+	// MinAmounts — минимальная сумма списания по валютам в минорных
+	// единицах (см. ParseCurrencyAmounts); проверяется при создании платежа
+	// и при списании авторизованного. Валюта без записи = без минимума
+	MinAmounts map[string]int64
+
+	// WarnAmounts — суммы по валютам (в минорных единицах), начиная
+	// с которых платеж создается с предупреждением amount_unusually_high
+	// Валюта без записи = без предупреждения
+	WarnAmounts map[string]int64
+
+	// CaptureExpiry — сколько действует авторизация двухшагового
+	// платежа: потом списать его нельзя, а RunCaptureExpiry его
+	// аннулирует (voided). 0 = 7 дней
+	CaptureExpiry time.Duration
+
+	// AsyncGateway — списывать новые платежи в фоне: POST /payments
+	// сразу отвечает платежом в статусе processing, а succeeded или
+	// failed он становится, когда ответит шлюз (см. async.go)
+	AsyncGateway bool
+
+	// MaxPaymentRetries — сколько раз можно повторить отклоненный
+	// платеж (POST /payments/{id}/retry). 0 = 3
+	MaxPaymentRetries int
+
+	// MaxFloatAmountMinor — наибольшая сумма в минорных единицах,
+	// которую принимаем JSON числом; больше — 400 с просьбой прислать
+	// сумму строкой ("amount": "123.45"). 0 = без проверки (по умолчанию,
+	// см. RecommendedMaxFloatAmountMinor)
+	// Строковые суммы и {"value": …} не ограничиваются
+	// Значение не ниже MaxAmountMinor не действует: раньше срабатывает
+	// системный потолок
+	MaxFloatAmountMinor int64
+
+	// MaxRefunds — сколько возвратов можно провести по одному платежу
+	// Дальше возврат получает 422, даже если остаток не исчерпан
+	// 0 = без ограничения
+	MaxRefunds int
+
+	// FailureReasons — коды ошибок шлюза → причины отказа платежа
+	// (см. ParseFailureReasons). nil = DefaultFailureReasons
+	FailureReasons map[string]string
+
+	// Enrichers — обогатители новых платежей, вызываются по порядку
+	// перед списанием и сохранением (см. enrich.go)
+	// nil = платеж сохраняется как есть
+	Enrichers []PaymentEnricher
+
+	// Outbox — очередь уведомлений об изменении статуса платежей
+	// (доставляет OutboxWorker). nil = уведомления не отправляются
+	Outbox Outbox
+
+	// AuditLog — куда писать журнал аудита изменений платежей
+	// (создание, изменение статуса, удаление), по строке JSON на запись
+	// nil = аудит выключен
+	AuditLog io.Writer
+
+	// BasePath — префикс всех маршрутов, например "/api/v1", когда API
+	// опубликован за reverse proxy не в корне домена
+	// Пустая строка или "/" = маршруты в корне (по умолчанию)
+	BasePath string
+
+	// MaxConcurrency — сколько запросов может выполняться одновременно
+	// Лишние получают 503 с Retry-After (см. limitConcurrency)
+	// 0 = без ограничения
+	MaxConcurrency int
+
+	// MaxURLLength, MaxQueryLength — наибольшая длина адреса запроса
+	// и строки запроса в байтах; длиннее — 414 (см. limitURLLength)
+	// 0 = без ограничения
+	MaxURLLength   int
+	MaxQueryLength int
+
+	// Features — какие группы эндпоинтов включены (см. features.go)
+	// nil = AllFeatures
+	Features *Features
+
+	// RequestTimeout — сколько может обрабатываться один запрос; по
+	// истечении клиент получает 503 (см. timing.go). Поток событий,
+	// выгрузка, загрузка и ожидание ?wait= не ограничиваются
+	// 0 = без ограничения
+	RequestTimeout time.Duration
+
+	// CompressMinSize — ответы от этого размера (в байтах) сжимаются
+	// gzip, если клиент это поддерживает (см. compressResponses)
+	// 0 = без сжатия
+	CompressMinSize int
+
+	// IdempotencyTTL — сколько помнить ключ идемпотентности
+	// (заголовок Idempotency-Key), 0 = 24 часа
+	IdempotencyTTL time.Duration
+
+	// WebhookSecret — общий со шлюзом секрет для проверки подписи
+	// уведомлений POST /webhooks/gateway
+	// Пустая строка = эндпоинт выключен (404)
+	WebhookSecret string
+
+	// ValidateRequests включает проверку тела запросов по встроенной
+	// спецификации OpenAPI (см. validateRequests) — ценой лишнего
+	// разбора JSON на каждый запрос. false = выключено (по умолчанию)
+	ValidateRequests bool
+
+	// DebugBodies включает запись тел запросов и ответов в лог
+	// (см. logBodies). Только для отладки: false = выключено
+	DebugBodies bool
+
+	// DebugBodyLimit — сколько байт тела записывать (0 = 4096)
+	DebugBodyLimit int
+
+	// RedactFields — поля, значения которых скрываются в логах:
+	// в журнале тел запросов, строках лога и журнале аудита (см. redact.go)
+	// nil = DefaultRedactFields
+	RedactFields []string
+
+	// ChaosRate — доля запросов (0.0–1.0) со внедренным сбоем:
+	// 500 или задержка до ChaosLatency (см. injectChaos)
+	// Только для проверки устойчивости клиентов. 0 = выключено
+	ChaosRate    float64
+	ChaosLatency time.Duration
+
+	// APIKeys — ключи API (заголовок X-API-Key); ключ видит только
+	// свои платежи. AdminAPIKeys — ключи, которым видны все платежи
+	// Оба пустые = проверка ключей выключена (см. apikey.go)
+	APIKeys      []string
+	AdminAPIKeys []string
+
+	// RequireHTTPS — отклонять запросы, пришедшие без TLS (см. requireHTTPS)
+	// TrustedProxies — адреса прокси, чьим X-Forwarded-Proto
+	// и X-Forwarded-For верим (см. clientIP)
+	// false = принимаются любые запросы (по умолчанию)
+	RequireHTTPS   bool
+	TrustedProxies []netip.Prefix
+
+	// WebhookReplayWindow — сколько помнить ID обработанных уведомлений
+	// для защиты от повторов (0 = 24 часа)
+	WebhookReplayWindow time.Duration
+}
+
+// Server — HTTP API платежной системы
+//
+// ВНЕДРЕНИЕ ЗАВИСИМОСТЕЙ (dependency injection):
+// Хранилище и провайдер курсов передаются в конструктор, а не лежат
+// в глобальных переменных. Поэтому можно создать несколько независимых
+// серверов (например, в тестах) с разными хранилищами
+//
+// Server реализует http.Handler (метод ServeHTTP), поэтому его можно
+// передать прямо в http.ListenAndServe или httptest.NewServer
+type Server struct {
+	store             Store
+	fx                FXProvider
+	gateway           PaymentGateway
+	defaultCurrency   string
+	currencies        map[string]bool
+	blocked           map[string]bool
+	events            *statusBroker
+	duplicates        *duplicateGuard
+	paymentLocks      *keyedMutex
+	rounding          Rounding
+	thousandsSep      bool
+	maxFloatMinor     int64
+	asyncGateway      bool
+	fees              map[string]Fee
+	minAmounts        map[string]int64
+	warnAmounts       map[string]int64
+	apiKeys           map[[sha256.Size]byte]bool
+	failureReasons    map[string]string
+	maxPaymentRetries int
+	maxRefunds        int
+	audit             *auditLog
+	redact            map[string]bool
+	outbox            Outbox
+	enrichers         []PaymentEnricher
+	basePath          string
+	idempotencyTTL    time.Duration
+	captureExpiry     time.Duration
+	webhookSecret     []byte
+	webhookNonces     *nonceSet
+	features          Features
+	trustedProxies    []netip.Prefix
+	metrics           *requestMetrics
+
+	// draining = сервер готовится к остановке: /ready отвечает 503,
+	// чтобы балансировщик перестал слать новые запросы (см. StartDraining)
+	draining atomic.Bool
+
+	handler http.Handler // dispatch, обернутый в middleware
+	routed  http.Handler // mux, при ValidateRequests — с проверкой OpenAPI
+	mux     *http.ServeMux
+}
+
+// NewServer создает сервер и регистрирует маршруты
+// fx может быть nil — тогда доступна только конвертация X→X
+// gateway может быть nil — тогда платежи создаются в статусе pending
+//
+// Паникует, если при ValidateRequests встроенная спецификация OpenAPI
+// некорректна: это ошибка сборки, а не конфигурации
+func NewServer(store Store, fx FXProvider, gateway PaymentGateway, cfg Config) *Server {
+	if fx == nil {
+		fx = NewStaticFXProvider(nil)
+	}
+	currencies := cfg.SupportedCurrencies
+	if len(currencies) == 0 {
+		currencies = DefaultSupportedCurrencies
+	}
+	replayWindow := cfg.WebhookReplayWindow
+	if replayWindow <= 0 {
+		replayWindow = 24 * time.Hour
+	}
+	idempotencyTTL := cfg.IdempotencyTTL
+	if idempotencyTTL <= 0 {
+		idempotencyTTL = 24 * time.Hour
+	}
+	captureExpiry := cfg.CaptureExpiry
+	if captureExpiry <= 0 {
+		captureExpiry = 7 * 24 * time.Hour
+	}
+	redact := cfg.RedactFields
+	if redact == nil {
+		redact = DefaultRedactFields
+	}
+	redactSet := newRedactSet(redact)
+	// Время хранилища и шлюза попадает в Server-Timing (см. timing.go)
+	// nil-шлюз не оборачиваем: проверка s.gateway == nil должна работать
+	if gateway != nil {
+		gateway = timedGateway{gateway}
+	}
+	maxPaymentRetries := cfg.MaxPaymentRetries
+	if maxPaymentRetries <= 0 {
+		maxPaymentRetries = defaultMaxPaymentRetries
+	}
+	features := AllFeatures
+	if cfg.Features != nil {
+		features = *cfg.Features
+	}
+	failureReasons := cfg.FailureReasons
+	if failureReasons == nil {
+		failureReasons = DefaultFailureReasons
+	}
+	s := &Server{
+		store:             timedStore{store},
+		fx:                fx,
+		gateway:           gateway,
+		defaultCurrency:   cfg.DefaultCurrency,
+		currencies:        newCurrencySet(currencies),
+		blocked:           newCurrencySet(cfg.BlockedCurrencies),
+		events:            newStatusBroker(),
+		duplicates:        newDuplicateGuard(cfg.DuplicateWindow),
+		paymentLocks:      newKeyedMutex(),
+		rounding:          cfg.Rounding,
+		thousandsSep:      cfg.ThousandsSeparators,
+		maxFloatMinor:     cfg.MaxFloatAmountMinor,
+		asyncGateway:      cfg.AsyncGateway,
+		fees:              cfg.Fees,
+		minAmounts:        cfg.MinAmounts,
+		warnAmounts:       cfg.WarnAmounts,
+		apiKeys:           newAPIKeys(cfg.APIKeys, cfg.AdminAPIKeys),
+		failureReasons:    failureReasons,
+		maxPaymentRetries: maxPaymentRetries,
+		maxRefunds:        cfg.MaxRefunds,
+		audit:             newAuditLog(cfg.AuditLog, redactSet),
+		redact:            redactSet,
+		outbox:            cfg.Outbox,
+		enrichers:         cfg.Enrichers,
+		basePath:          strings.TrimSuffix(cfg.BasePath, "/"),
+		idempotencyTTL:    idempotencyTTL,
+		captureExpiry:     captureExpiry,
+		webhookSecret:     []byte(cfg.WebhookSecret),
+		webhookNonces:     newNonceSet(replayWindow),
+		features:          features,
+		trustedProxies:    cfg.TrustedProxies,
+		metrics:           newRequestMetrics(),
+		mux:               http.NewServeMux(),
+	}
+	s.routes()
+	s.routed = s.mux
+	if cfg.ValidateRequests {
+		doc, err := loadOpenAPI()
+		if err == nil {
+			s.routed, err = validateRequests(doc, s.mux)
+		}
+		if err != nil {
+			panic("payments: invalid embedded OpenAPI spec: " + err.Error())
+		}
+	}
+	// Сбои внедряются после отрезания BasePath: исключения (/health)
+	// сравниваются с путем маршрута
+	seed := uint64(time.Now().UnixNano())
+	s.routed = injectChaos(cfg.ChaosRate, cfg.ChaosLatency, rand.New(rand.NewPCG(seed, seed)), s.routed)
+	s.routed = s.requireAPIKey(s.routed)
+	// Метрики снаружи recoverPanic: паника учитывается как 500
+	// Запрос без TLS отклоняется сразу за recoverPanic — раньше любой
+	// другой обработки, в том числе журнала тел запросов
+	s.handler = s.metrics.measureRequests(recoverPanic(
+		requireHTTPS(cfg.RequireHTTPS, cfg.TrustedProxies, s.basePath,
+			limitURLLength(cfg.MaxURLLength, cfg.MaxQueryLength,
+				serverTiming(cfg.RequestTimeout,
+					compressResponses(cfg.CompressMinSize,
+						logBodies(cfg.DebugBodies, cfg.DebugBodyLimit, s.redact,
+							limitConcurrency(cfg.MaxConcurrency, http.HandlerFunc(s.dispatch)))))))))
+	return s
+}
+
+// isSupportedCurrency проверяет, принимает ли сервер валюту
+// Код должен быть в верхнем регистре: "rub" не поддерживается
+func (s *Server) isSupportedCurrency(code string) bool {
+	return s.currencies[code]
+}
+
+// ServeHTTP реализует интерфейс http.Handler
+// Запрос проходит через middleware (см. middleware.go), затем dispatch
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+// dispatch проверяет путь и передает запрос роутеру
+//
+// ПОЛИТИКА "КАНОНИЧЕСКИХ" ПУТЕЙ:
+// Стандартный роутер на путь вида /payments//status отвечает
+// редиректом 301 на очищенный путь, а /payments/ просто не находит.
+// Редирект для API вреден: клиенты по-разному повторяют POST после 301,
+// а ошибка в URL маскируется. Поэтому у каждого ресурса ровно ОДИН адрес:
+// путь с лишним "/" в конце, двойным "//" или сегментами "." и ".."
+// получает 404 с подсказкой канонического пути — без редиректов
+func (s *Server) dispatch(w http.ResponseWriter, r *http.Request) {
+	if canonical := path.Clean("/" + r.URL.Path); canonical != r.URL.Path {
+		writeError(w, r, http.StatusNotFound, CodeNotFound,
+			fmt.Sprintf("Path %q is not canonical; did you mean %q?", r.URL.Path, canonical))
+		return
+	}
+
+	// ПРЕФИКС ПУТИ (BasePath):
+	// Маршруты регистрируются без префикса, а здесь префикс отрезается
+	// от пути запроса (как делает http.StripPrefix). Запросы вне префикса
+	// получают 404
+	if s.basePath != "" {
+		rest, ok := strings.CutPrefix(r.URL.Path, s.basePath)
+		if !ok || (rest != "" && rest[0] != '/') {
+			s.handleNotFound(w, r)
+			return
+		}
+		if rest == "" {
+			rest = "/"
+		}
+		// Запрос копируем, а не меняем: он принадлежит вызывающему коду
+		r2 := r.Clone(r.Context())
+		r2.URL = &url.URL{}
+		*r2.URL = *r.URL
+		r2.URL.Path = rest
+		r2.URL.RawPath = ""
+		r = r2
+	}
+	// Шаблон маршрута ("/payments/{id}") для меток метрик
+	// mux.Handler только ищет маршрут, ничего не вызывая
+	_, pattern := s.mux.Handler(r)
+	setRoute(r.Context(), pattern)
+	s.routed.ServeHTTP(w, r)
+}
+
+// url возвращает внешний адрес ресурса с учетом BasePath
+// Используется для заголовка Location и других ссылок на себя
+func (s *Server) url(p string) string {
+	return s.basePath + p
+}
+
+// handleNotFound отвечает на запросы к неизвестным путям
+// JSON вместо текстового "404 page not found" стандартного роутера
+func (s *Server) handleNotFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, http.StatusNotFound, CodeNotFound, "Resource not found")
+}
+
+// handleHealth — проверка живости для балансировщика и оркестратора
+// GET /health → 200 {"status":"ok"}
+// Не ходит в хранилище: отвечает, пока жив сам процесс
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// StartDraining переводит сервер в режим "слива" перед остановкой:
+// запросы по-прежнему обслуживаются, но /ready отвечает 503
+//
+// Порядок остановки при выкатке (см. cmd/api/main.go):
+//  1. StartDraining — балансировщик видит "не готов" и выводит
+//     экземпляр из ротации (на это ему нужно несколько проверок)
+//  2. пауза DRAIN_TIMEOUT — запросы, уже отправленные сюда
+//     балансировщиком, спокойно обрабатываются
+//  3. http.Server.Shutdown — ожидание текущих запросов и остановка
+func (s *Server) StartDraining() {
+	s.draining.Store(true)
+}
+
+// handleReady — проверка готовности принимать трафик
+// GET /ready → 200 {"status":"ready"}, при остановке — 503 draining
+//
+// В отличие от /health (жив ли процесс) говорит балансировщику,
+// слать ли сюда запросы: при "сливе" процесс жив, но трафик не нужен
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	if s.draining.Load() {
+		writeError(w, r, http.StatusServiceUnavailable, CodeDraining, "Server is shutting down")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+// routes регистрирует маршруты (ROUTING)
+func (s *Server) routes() {
+	// mux.HandleFunc регистрирует обработчик для URL пути
+	// Параметры:
+	// 1. "/payments" = URL путь (pattern)
+	//    Запросы на http://localhost:8080/payments попадут сюда
+	// 2. s.handlePayments = метод-обработчик
+	//    Передаем МЕТОД (не вызываем его!)
+	//    Без скобок () — это важно!
+	//    Метод "помнит" свой получатель s, поэтому видит хранилище
+	s.mux.HandleFunc("/payments", s.handlePayments)
+
+	// Заглушка для получения статуса платежа (mock data)
+	s.mux.HandleFunc("/payments/status", s.handleGetPayment)
+
+	// Итоги по статусам и валютам (статичный путь, как и /payments/status)
+	s.mux.HandleFunc("/payments/summary", s.handlePaymentsSummary)
+
+	// Массовая смена статуса (только ключ администратора)
+	s.mux.HandleFunc("/payments/bulk-status", s.handleBulkStatus)
+
+	// Маршруты ниже, зарегистрированные через handleFeature,
+	// можно выключить в конфиге (Config.Features)
+
+	// Выгрузка всех платежей потоком NDJSON
+	s.handleFeature(s.features.Export, "/payments/export", s.handleExportPayments)
+
+	// Загрузка платежей потоком NDJSON
+	s.handleFeature(s.features.Import, "/payments/import", s.handleImportPayments)
+
+	// Сверка с отчетом шлюза
+	s.handleFeature(s.features.Reconcile, "/payments/reconcile", s.handleReconcile)
+
+	// Маршрут с параметром пути: {id} совпадет с любым сегментом
+	// Например: /payments/pay_1b4e28ba-2fa1-4d3b-a3f5-ef19b5a7633b
+	// Статичный /payments/status важнее шаблона — роутер выберет его
+	// paymentRoute прячет чужие платежи, когда включены ключи API,
+	// и возвращает сквозной ID платежа (см. correlation.go)
+	s.mux.HandleFunc("/payments/{id}", s.paymentRoute(s.handlePaymentByID))
+
+	// Списание авторизованного платежа и отмена до списания
+	s.handleFeature(s.features.Capture, "/payments/{id}/capture", s.paymentRoute(s.handleCapturePayment))
+	s.mux.HandleFunc("/payments/{id}/cancel", s.paymentRoute(s.handleCancelPayment))
+	s.handleFeature(s.features.Capture, "/payments/{id}/void", s.paymentRoute(s.handleVoidPayment))
+
+	// Повтор отклоненного платежа
+	s.handleFeature(s.features.Retry, "/payments/{id}/retry", s.paymentRoute(s.handleRetryPayment))
+
+	// Разбиение на части (рассрочка)
+	s.handleFeature(s.features.Split, "/payments/{id}/split", s.paymentRoute(s.handleSplitPayment))
+
+	// Возвраты по платежу
+	s.handleFeature(s.features.Refunds, "/payments/{id}/refunds", s.paymentRoute(s.handlePaymentRefunds))
+	s.handleFeature(s.features.Refunds, "/payments/{id}/refunds/{refundId}", s.paymentRoute(s.handleGetRefund))
+
+	// Чек по проведенному платежу (JSON или текст)
+	s.handleFeature(s.features.Receipts, "/payments/{id}/receipt", s.paymentRoute(s.handlePaymentReceipt))
+
+	// Поток изменений статуса (Server-Sent Events)
+	s.handleFeature(s.features.Stream, "/payments/{id}/stream", s.paymentRoute(s.handlePaymentStream))
+
+	// Уведомления платежного шлюза (подписанные HMAC)
+	s.handleFeature(s.features.Webhooks, "/webhooks/gateway", s.handleGatewayWebhook)
+
+	// Ручное исправление статуса поддержкой (только ключ администратора)
+	s.handleFeature(s.features.Admin, "/admin/payments/{id}/status", s.handleAdminSetStatus)
+
+	// Принимаемые валюты и их ограничения
+	s.mux.HandleFunc("/currencies", s.handleCurrencies)
+
+	// Проверка живости
+	s.mux.HandleFunc("/health", s.handleHealth)
+
+	// Проверка готовности (503 во время остановки)
+	s.mux.HandleFunc("/ready", s.handleReady)
+
+	// Спецификация OpenAPI для инструментов
+	s.mux.HandleFunc("/openapi.json", s.handleOpenAPI)
+
+	// Метрики для Prometheus
+	s.mux.HandleFunc("/metrics", s.handleMetrics)
+
+	// Клиенты и их платежи
+	s.handleFeature(s.features.Customers, "/customers", s.handleCustomers)
+	s.handleFeature(s.features.Customers, "/customers/{id}/payments", s.handleCustomerPayments)
+
+	// Оглавление API: "/{$}" — только сам корень, без продолжения
+	s.handleFeature(s.features.Index, "/{$}", s.handleIndex)
+
+	// "/" совпадает с ЛЮБЫМ путем, для которого нет более точного
+	// шаблона — так все неизвестные адреса получают JSON 404
+	s.mux.HandleFunc("/", s.handleNotFound)
+}
+
+// handleFeature регистрирует маршрут группы, которую можно выключить
+// (см. Features). Выключенный маршрут все равно регистрируется —
+// с ответом 404: иначе /payments/export достался бы шаблону
+// /payments/{id} и получил бы 400 "неверный ID" вместо 404
+func (s *Server) handleFeature(enabled bool, pattern string, handler http.HandlerFunc) {
+	if !enabled {
+		handler = s.handleNotFound
+	}
+	s.mux.HandleFunc(pattern, handler)
+}
@@ -0,0 +1,95 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// ===== ПОМОЩНИКИ ТЕСТОВ =====
+//
+// Тесты пакета ходят в Server через ServeHTTP, без сети: так видно
+// весь путь запроса (middleware, маршрутизация, обработчик), а сам
+// тест остается быстрым. Внешним пакетам для того же есть testutil
+
+// TestMain прячет журнал сервера (log.Printf на каждый запрос),
+// если тесты запущены без -v: иначе за ним не видно сообщений тестов
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if !testing.Verbose() {
+		log.SetOutput(io.Discard)
+	}
+	os.Exit(m.Run())
+}
+
+// doJSON выполняет запрос к h и возвращает записанный ответ
+// header — дополнительные заголовки запроса (может быть nil)
+func doJSON(t *testing.T, h http.Handler, method, path, body string, header map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+// decodeBody разбирает JSON ответа в v и останавливает тест при ошибке
+func decodeBody(t *testing.T, rec *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("decoding %q: %v", rec.Body.String(), err)
+	}
+}
+
+// createPaymentT создает платеж через POST /payments и возвращает его
+func createPaymentT(t *testing.T, h http.Handler, body string) Payment {
+	t.Helper()
+	rec := doJSON(t, h, http.MethodPost, "/payments", body, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST /payments = %d: %s", rec.Code, rec.Body.String())
+	}
+	var p Payment
+	decodeBody(t, rec, &p)
+	return p
+}
+
+// gatewayFunc — шлюз из функции: тест сам решает, чем ответить
+type gatewayFunc func(ctx context.Context, p Payment) error
+
+func (f gatewayFunc) Charge(ctx context.Context, p Payment) error { return f(ctx, p) }
+
+// declineGateway отклоняет каждое списание с кодом code
+func declineGateway(code string) gatewayFunc {
+	return func(context.Context, Payment) error {
+		return &GatewayError{Code: code, Message: "declined by test"}
+	}
+}
+
+// savePaymentT кладет платеж прямо в хранилище, минуя API
+func savePaymentT(t *testing.T, store Store, p Payment) {
+	t.Helper()
+	if err := store.Save(context.Background(), p); err != nil {
+		t.Fatalf("saving %s: %v", p.ID, err)
+	}
+}
+
+// paymentIDs возвращает ID платежей в том же порядке
+func paymentIDs(payments []Payment) []string {
+	ids := make([]string, len(payments))
+	for i, p := range payments {
+		ids[i] = p.ID
+	}
+	return ids
+}
@@ -0,0 +1,198 @@
+package payments
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ===== РАЗБИЕНИЕ НА ЧАСТИ (РАССРОЧКА) =====
+//
+// POST /payments/{id}/split?n=3 делит платеж на n частей — отдельных
+// платежей со ссылкой на исходный (ParentID). Исходный платеж получает
+// статус split и дальше не проводится: проводятся части
+//
+// Суммы делятся в минорных единицах, поэтому части в сумме дают ровно
+// исходную сумму: 100.00 на 3 = 33.33 + 33.33 + 33.34
+// Остаток от деления достается последней части
+
+// maxInstallments — на сколько частей можно разбить платеж
+const maxInstallments = 24
+
+// splitResponse — ответ на разбиение: исходный платеж и его части
+type splitResponse struct {
+	Payment      Payment   `json:"payment"`
+	Installments []Payment `json:"installments"`
+}
+
+// splitMinor делит total на n частей: все равны total/n, кроме последней,
+// которая забирает остаток. Сумма частей всегда равна total
+func splitMinor(total int64, n int) []int64 {
+	parts := make([]int64, n)
+	base := total / int64(n)
+	for i := range parts {
+		parts[i] = base
+	}
+	parts[n-1] = total - base*int64(n-1)
+	return parts
+}
+
+// applySplit проверяет, что платеж можно разбить, и возвращает его
+// в статусе split. Вызывается хранилищем под блокировкой (или в транзакции)
+//
+// Разбить можно только pending: деньги по нему еще не двигались
+// Переход pending → split не входит в allowedTransitions — через PATCH
+// его не сделать, только вместе с созданием частей (Store.SplitPayment)
+func applySplit(p Payment, expectedVersion int) (Payment, error) {
+	if expectedVersion != 0 && p.Version != expectedVersion {
+		return p, ErrVersionMismatch
+	}
+	if p.Status != StatusPending {
+		return p, ErrNotSplittable
+	}
+	p.Status = StatusSplit
+	p.Version++
+	return p, nil
+}
+
+// handleSplitPayment разбивает платеж на части
+// POST /payments/{id}/split?n=3
+//
+// Части — обычные платежи в статусе pending с той же валютой, описанием,
+// владельцем и метками. Комиссия считается для каждой части отдельно
+//
+// Коды ответа:
+// - 201 Created = разбит, в ответе исходный платеж и части
+// - 400 Bad Request = некорректное n
+// - 404 Not Found = платежа нет
+// - 409 Conflict = платеж не pending или сумма меньше n минорных единиц
+// - 412 Precondition Failed = If-Match не совпал с версией
+func (s *Server) handleSplitPayment(w http.ResponseWriter, r *http.Request) {
+	if !isValidPaymentID(r.PathValue("id")) {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidID, "Invalid payment ID: must start with "+paymentIDPrefix)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+	n, err := strconv.Atoi(r.URL.Query().Get("n"))
+	if err != nil || n < 2 || n > maxInstallments {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidQuery,
+			fmt.Sprintf("n must be an integer from 2 to %d", maxInstallments))
+		return
+	}
+	expectedVersion, ok := parseIfMatch(r)
+	if !ok {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidIfMatch, "Invalid If-Match header")
+		return
+	}
+
+	id := r.PathValue("id")
+	parent, err := s.store.Get(r.Context(), id)
+	if errors.Is(err, ErrPaymentNotFound) || (err == nil && parent.Deleted) {
+		writeError(w, r, http.StatusNotFound, CodePaymentNotFound, "Payment not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Error loading payment: %v", err)
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+		return
+	}
+	// Каждая часть — хотя бы одна минорная единица
+	if parent.AmountMinor < int64(n) {
+		writeError(w, r, http.StatusConflict, CodeNotSplittable,
+			fmt.Sprintf("Amount is too small to split into %d installments", n))
+		return
+	}
+
+	installments, err := s.newInstallments(r, parent, n)
+	if err != nil {
+		log.Printf("Error allocating sequence number: %v", err)
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+		return
+	}
+
+	// Части строятся по прочитанной версии: если платеж успели изменить,
+	// хранилище откажет, даже если клиент не прислал If-Match
+	if expectedVersion == 0 {
+		expectedVersion = parent.Version
+	}
+	payment, err := s.store.SplitPayment(r.Context(), id, expectedVersion, installments)
+	switch {
+	case errors.Is(err, ErrPaymentNotFound):
+		writeError(w, r, http.StatusNotFound, CodePaymentNotFound, "Payment not found")
+		return
+	case errors.Is(err, ErrVersionMismatch):
+		w.Header().Set("ETag", paymentETag(payment))
+		writeError(w, r, http.StatusPreconditionFailed, CodeVersionMismatch, "Payment was modified by another request")
+		return
+	case errors.Is(err, ErrNotSplittable):
+		writeError(w, r, http.StatusConflict, CodeNotSplittable,
+			fmt.Sprintf("Only pending payments can be split, payment is %s", payment.Status))
+		return
+	case errors.Is(err, ErrStoreFull):
+		writeError(w, r, http.StatusServiceUnavailable, CodeStoreFull, "Payment storage is at capacity; retry later")
+		return
+	case err != nil:
+		log.Printf("Error splitting payment: %v", err)
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+		return
+	}
+
+	log.Printf("Payment split: ID=%s, Installments=%d", payment.ID, n)
+	actor := auditActor(r)
+	s.audit.record(actor, AuditSplit, payment.ID, parent.Status, payment.Status)
+	s.publishStatus(r.Context(), payment)
+	for _, p := range installments {
+		s.audit.record(actor, AuditCreate, p.ID, "", p.Status)
+		s.publishStatus(r.Context(), p)
+	}
+
+	w.Header().Set("ETag", paymentETag(payment))
+	writeJSON(w, r, http.StatusCreated, splitResponse{Payment: payment, Installments: installments})
+}
+
+// newInstallments строит части платежа parent (еще не сохраненные)
+// Сумма в валюте расчета делится так же, как основная
+func (s *Server) newInstallments(r *http.Request, parent Payment, n int) ([]Payment, error) {
+	amounts := splitMinor(parent.AmountMinor, n)
+	settlements := splitMinor(parent.SettlementAmountMinor, n)
+	now := time.Now().UTC()
+
+	installments := make([]Payment, n)
+	for i := range installments {
+		seq, err := s.store.NextSequenceNumber(r.Context())
+		if err != nil {
+			return nil, err
+		}
+		p := Payment{
+			ID:                    newPaymentID(),
+			SequenceNumber:        seq,
+			ParentID:              parent.ID,
+			Amount:                minorToAmount(amounts[i], parent.Currency),
+			AmountMinor:           amounts[i],
+			Currency:              parent.Currency,
+			Status:                StatusPending,
+			Description:           parent.Description,
+			CustomerID:            parent.CustomerID,
+			CreatedByKey:          parent.CreatedByKey,
+			CorrelationID:         parent.CorrelationID,
+			Metadata:              maps.Clone(parent.Metadata),
+			AmountRefundableMinor: amounts[i],
+			SettlementCurrency:    parent.SettlementCurrency,
+			SettlementAmountMinor: settlements[i],
+			FXRate:                parent.FXRate,
+			CreatedAt:             now,
+			Version:               1,
+		}
+		p.FeeMinor = s.fees[p.Currency].calculate(p.AmountMinor, s.rounding.modeFor(p.Currency))
+		p.NetAmountMinor = p.AmountMinor - p.FeeMinor
+		installments[i] = p
+	}
+	return installments, nil
+}
@@ -0,0 +1,99 @@
+package payments
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestSplitMinorSumsExactly(t *testing.T) {
+	for _, tc := range []struct {
+		total int64
+		n     int
+	}{
+		{10000, 3}, {1, 1}, {7, 7}, {9999, 24}, {MaxAmountMinor, 7},
+	} {
+		parts := splitMinor(tc.total, tc.n)
+		var sum int64
+		for _, p := range parts[:tc.n-1] {
+			if p != tc.total/int64(tc.n) {
+				t.Fatalf("splitMinor(%d, %d) = %v: only the last part may differ", tc.total, tc.n, parts)
+			}
+			sum += p
+		}
+		if sum+parts[tc.n-1] != tc.total {
+			t.Fatalf("splitMinor(%d, %d) = %v, sum != total", tc.total, tc.n, parts)
+		}
+	}
+}
+
+// TestSplitPaymentInstallments — части в сумме дают ровно исходный
+// платеж, остаток достается последней, исходный платеж получает split
+func TestSplitPaymentInstallments(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	p := createPaymentT(t, s, `{"amount": "100.00", "currency": "RUB"}`)
+
+	rec := doJSON(t, s, http.MethodPost, "/payments/"+p.ID+"/split?n=3", "", nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("split = %d: %s", rec.Code, rec.Body.String())
+	}
+	type part struct {
+		ID          string `json:"id"`
+		ParentID    string `json:"parent_id"`
+		Status      string `json:"status"`
+		AmountMinor int64  `json:"amount_minor"`
+	}
+	var resp struct {
+		Payment      part   `json:"payment"`
+		Installments []part `json:"installments"`
+	}
+	decodeBody(t, rec, &resp)
+	if resp.Payment.Status != StatusSplit || resp.Payment.AmountMinor != 10000 {
+		t.Fatalf("parent = %+v", resp.Payment)
+	}
+
+	want := []int64{3333, 3333, 3334}
+	var sum int64
+	for i, inst := range resp.Installments {
+		if inst.ParentID != p.ID || inst.Status != StatusPending || inst.AmountMinor != want[i] {
+			t.Errorf("installment %d = %+v, want %d", i, inst, want[i])
+		}
+		sum += inst.AmountMinor
+	}
+	if len(resp.Installments) != 3 || sum != resp.Payment.AmountMinor {
+		t.Fatalf("installments = %+v, sum = %d", resp.Installments, sum)
+	}
+
+	// Части — настоящие платежи в хранилище
+	rec = doJSON(t, s, http.MethodGet, "/payments/"+resp.Installments[2].ID, "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET installment = %d", rec.Code)
+	}
+
+	// Разбитый платеж повторно не делится
+	if rec := doJSON(t, s, http.MethodPost, "/payments/"+p.ID+"/split?n=2", "", nil); rec.Code != http.StatusConflict {
+		t.Fatalf("second split = %d", rec.Code)
+	}
+}
+
+func TestSplitPaymentInvalid(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	p := createPaymentT(t, s, `{"amount": "100.00", "currency": "RUB"}`)
+	for _, n := range []string{"", "1", "25", "abc"} {
+		if rec := doJSON(t, s, http.MethodPost, "/payments/"+p.ID+"/split?n="+n, "", nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("n=%q = %d, want 400", n, rec.Code)
+		}
+	}
+
+	// Меньше одной копейки на часть не бывает
+	tiny := createPaymentT(t, s, `{"amount": "0.02", "currency": "RUB"}`)
+	var resp ErrorResponse
+	rec := doJSON(t, s, http.MethodPost, "/payments/"+tiny.ID+"/split?n=3", "", nil)
+	decodeBody(t, rec, &resp)
+	if rec.Code != http.StatusConflict || resp.Code != CodeNotSplittable {
+		t.Fatalf("too small: %d %+v", rec.Code, resp)
+	}
+
+	if rec := doJSON(t, s, http.MethodPost, "/payments/pay_missing/split?n=2", "", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("missing = %d", rec.Code)
+	}
+}
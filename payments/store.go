@@ -0,0 +1,760 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ===== ХРАНИЛИЩЕ ПЛАТЕЖЕЙ =====
+
+// Store — хранилище платежей
+//
+// Обработчики работают только с интерфейсом, поэтому хранилище
+// можно подменить (память, база данных, заглушка в тестах),
+// не меняя код обработчиков
+//
+// Первый параметр каждого метода — context.Context запроса:
+// если клиент отключился или истек дедлайн, хранилище может
+// прервать работу и вернуть ctx.Err()
+type Store interface {
+	// Save сохраняет платеж (создает новый или перезаписывает существующий)
+	Save(ctx context.Context, p Payment) error
+
+	// Create сохраняет НОВЫЙ платеж
+	// Если платеж с таким ID уже есть, возвращает ErrPaymentExists,
+	// если занят его ExternalID — ErrExternalIDExists,
+	// если хранилище заполнено — ErrStoreFull
+	Create(ctx context.Context, p Payment) error
+
+	// Get возвращает платеж по ID или ErrPaymentNotFound
+	Get(ctx context.Context, id string) (Payment, error)
+
+	// GetByExternalID возвращает платеж по ExternalID или ErrPaymentNotFound
+	// Удаленные платежи тоже находятся: external_id остается занятым
+	GetByExternalID(ctx context.Context, externalID string) (Payment, error)
+
+	// List возвращает платежи, отсортированные по времени создания
+	// Удаленные платежи включаются только при includeDeleted = true
+	List(ctx context.Context, includeDeleted bool) ([]Payment, error)
+
+	// MarkDeleted мягко удаляет платеж (повторный вызов ничего не меняет)
+	// Возвращает ErrPaymentNotFound, если платежа не существует
+	MarkDeleted(ctx context.Context, id string) error
+
+	// UpdateStatus атомарно меняет статус платежа
+	// expectedVersion = версия, которую видел клиент (0 = не проверять)
+	UpdateStatus(ctx context.Context, id, status string, expectedVersion int) (Payment, error)
+
+	// ForceStatus меняет статус платежа В ОБХОД правил переходов
+	// (allowedTransitions) — только для ручного исправления поддержкой
+	// expectedVersion = 0 = не проверять версию
+	ForceStatus(ctx context.Context, id, status string, expectedVersion int) (Payment, error)
+
+	// CreateRefund атомарно проводит возврат по платежу: проверяет,
+	// что платеж succeeded и остатка хватает, и обновляет сумму возвратов
+	// Возвращает обновленный платеж; ErrNotRefundable, если платеж
+	// не в статусе succeeded, ErrRefundExceedsBalance, если сумма больше остатка,
+	// ErrRefundLimitReached, если исчерпан лимит числа возвратов
+	CreateRefund(ctx context.Context, refund Refund) (Payment, error)
+
+	// CapturePayment атомарно списывает авторизованный платеж:
+	// переводит его в succeeded с суммой и комиссией из c
+	// ErrNotCapturable, если платеж не в статусе authorized,
+	// ErrCaptureExceedsAuthorized, если сумма больше заблокированной,
+	// ErrAuthorizationExpired, если истек срок списания (CaptureExpiresAt)
+	CapturePayment(ctx context.Context, id string, c Capture) (Payment, error)
+
+	// RetryPayment атомарно применяет шаг повтора отклоненного платежа
+	// (см. Retry): ErrNotRetryable, если платеж не failed,
+	// ErrRetriesExhausted, если попытки кончились,
+	// ErrVersionMismatch, если платеж изменился после шага 1
+	RetryPayment(ctx context.Context, id string, rt Retry) (Payment, error)
+
+	// CompletePayment атомарно записывает результат асинхронного
+	// списания (см. Completion): ErrInvalidTransition, если платеж
+	// уже не processing, ErrVersionMismatch, если он изменился
+	CompletePayment(ctx context.Context, id string, c Completion) (Payment, error)
+
+	// FailPayment атомарно переводит платеж в failed с причиной отказа
+	// (см. Failure): ErrInvalidTransition, если переход в failed
+	// недопустим, ErrVersionMismatch, если версия не совпала
+	FailPayment(ctx context.Context, id string, f Failure) (Payment, error)
+
+	// SplitPayment атомарно разбивает платеж на части: переводит его
+	// в split и сохраняет installments (новые платежи с ParentID = id)
+	// ErrNotSplittable, если платеж не в статусе pending,
+	// ErrVersionMismatch, если версия не совпала (0 = не проверять),
+	// ErrStoreFull, если части не помещаются в хранилище
+	SplitPayment(ctx context.Context, id string, expectedVersion int, installments []Payment) (Payment, error)
+
+	// ListDue возвращает платежи в статусе status (scheduled или
+	// authorized), срок которых наступил к now: ProcessAt или
+	// CaptureExpiresAt <= now. Ранние сроки — первыми
+	// Читается индекс сроков (см. due.go), а не все платежи
+	ListDue(ctx context.Context, status string, now time.Time) ([]Payment, error)
+
+	// ListRefunds возвращает возвраты платежа в порядке создания
+	// ([] если возвратов нет, ErrPaymentNotFound если нет платежа)
+	ListRefunds(ctx context.Context, paymentID string) ([]Refund, error)
+
+	// GetRefund возвращает возврат платежа по ID
+	// ErrPaymentNotFound — нет платежа, ErrRefundNotFound — нет возврата
+	GetRefund(ctx context.Context, paymentID, refundID string) (Refund, error)
+
+	// NextSequenceNumber выдает следующий порядковый номер платежа
+	// Номера уникальны и строго возрастают даже при параллельных вызовах
+	NextSequenceNumber(ctx context.Context) (int64, error)
+
+	// ClaimIdempotencyKey закрепляет ключ идемпотентности за платежом
+	// paymentID на время ttl. Возвращает "" если ключ был свободен
+	// (теперь он наш) или ID платежа, за которым ключ уже закреплен
+	ClaimIdempotencyKey(ctx context.Context, key, paymentID string, ttl time.Duration) (string, error)
+
+	// ReleaseIdempotencyKey освобождает ключ (платеж создать не удалось,
+	// повтор запроса с тем же ключом должен пройти заново)
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+
+	// SaveCustomer сохраняет клиента
+	SaveCustomer(ctx context.Context, c Customer) error
+
+	// GetCustomer возвращает клиента по ID или ErrCustomerNotFound
+	GetCustomer(ctx context.Context, id string) (Customer, error)
+}
+
+// Ошибки хранилища
+// Обработчик сопоставляет их с HTTP кодами через errors.Is
+var (
+	ErrPaymentNotFound   = errors.New("payment not found")
+	ErrPaymentExists     = errors.New("payment already exists")
+	ErrVersionMismatch   = errors.New("payment version mismatch")
+	ErrInvalidTransition = errors.New("invalid status transition")
+
+	ErrNotRefundable        = errors.New("payment is not refundable")
+	ErrRefundExceedsBalance = errors.New("refund exceeds refundable balance")
+	ErrRefundLimitReached   = errors.New("refund limit reached")
+
+	ErrRefundNotFound = errors.New("refund not found")
+
+	ErrNotCapturable            = errors.New("payment is not authorized")
+	ErrCaptureExceedsAuthorized = errors.New("capture exceeds authorized amount")
+	ErrAuthorizationExpired     = errors.New("authorization expired")
+
+	ErrNotSplittable = errors.New("payment is not splittable")
+
+	ErrNotRetryable     = errors.New("payment is not failed")
+	ErrRetriesExhausted = errors.New("payment retries exhausted")
+
+	ErrExternalIDExists = errors.New("payment with this external_id already exists")
+
+	ErrStoreFull = errors.New("payment store is full")
+)
+
+// MemoryStore — потокобезопасное хранилище платежей в памяти
+//
+// map[string]Payment = словарь "ID → платеж"
+// sync.RWMutex = блокировка "много читателей ИЛИ один писатель":
+//   - RLock/RUnlock для чтения (GET) — параллельно
+//   - Lock/Unlock для записи (POST, DELETE) — эксклюзивно
+//
+// Данные живут только пока работает процесс
+// В продакшене здесь была бы база данных (PostgreSQL и т.д.)
+type MemoryStore struct {
+	mu        sync.RWMutex
+	payments  map[string]Payment
+	customers map[string]Customer
+	refunds   map[string][]Refund // ID платежа → его возвраты
+
+	// externalIDs — индекс ExternalID → ID платежа
+	// Без него поиск по external_id перебирал бы все платежи
+	externalIDs map[string]string
+
+	// due — индекс сроков (см. due.go): ID платежа → его срок
+	// Ведется в put, поэтому платежи пишутся только через него
+	due map[string]time.Time
+
+	// idempotency — ключ идемпотентности → платеж и срок действия ключа
+	idempotency map[string]idempotencyEntry
+
+	// sequence — счетчик порядковых номеров платежей (SequenceNumber)
+	// atomic.Int64.Add увеличивает значение атомарно: два параллельных
+	// запроса никогда не получат одинаковый номер
+	sequence atomic.Int64
+
+	// evicted — сколько платежей удалил уборщик (см. RunJanitor)
+	// atomic.Int64 можно читать и увеличивать из разных горутин без мьютекса
+	evicted atomic.Int64
+
+	// maxPayments — сколько платежей можно хранить (0 = без ограничения)
+	maxPayments int
+}
+
+// Проверка на этапе компиляции, что MemoryStore реализует Store
+// Если забыть метод, код просто не соберется
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore создает пустое хранилище
+// map обязательно инициализировать через make, иначе запись вызовет панику
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		payments:  make(map[string]Payment),
+		customers: make(map[string]Customer),
+		refunds:   make(map[string][]Refund),
+
+		idempotency: make(map[string]idempotencyEntry),
+		externalIDs: make(map[string]string),
+		due:         make(map[string]time.Time),
+	}
+}
+
+// SetMaxPayments ограничивает число хранимых платежей: сверх n
+// Create возвращает ErrStoreFull (API отвечает 503), а не съедает
+// память до падения процесса. Место освобождает уборщик (RunJanitor)
+// n = 0 — без ограничения. Вызывайте до начала обработки запросов
+func (s *MemoryStore) SetMaxPayments(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxPayments = n
+}
+
+// put записывает платеж и обновляет индекс сроков
+// Вызывается под s.mu.Lock()
+func (s *MemoryStore) put(p Payment) {
+	s.payments[p.ID] = p
+	if at, ok := dueAt(p); ok {
+		s.due[p.ID] = at
+	} else {
+		delete(s.due, p.ID)
+	}
+}
+
+// Save реализует Store
+func (s *MemoryStore) Save(ctx context.Context, p Payment) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock() // defer = выполнить при выходе из функции
+	s.put(p)
+	if p.ExternalID != "" {
+		s.externalIDs[p.ExternalID] = p.ID
+	}
+	return nil
+}
+
+// Create реализует Store
+// Проверки "ID и external_id свободны" и запись — под одной блокировкой
+func (s *MemoryStore) Create(ctx context.Context, p Payment) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.payments[p.ID]; ok {
+		return ErrPaymentExists
+	}
+	if s.maxPayments > 0 && len(s.payments) >= s.maxPayments {
+		return ErrStoreFull
+	}
+	if p.ExternalID != "" {
+		if _, ok := s.externalIDs[p.ExternalID]; ok {
+			return ErrExternalIDExists
+		}
+		s.externalIDs[p.ExternalID] = p.ID
+	}
+	s.put(p)
+	return nil
+}
+
+// GetByExternalID реализует Store
+func (s *MemoryStore) GetByExternalID(ctx context.Context, externalID string) (Payment, error) {
+	if err := ctx.Err(); err != nil {
+		return Payment{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, ok := s.externalIDs[externalID]
+	if !ok {
+		return Payment{}, ErrPaymentNotFound
+	}
+	return s.payments[id], nil
+}
+
+// Get реализует Store
+func (s *MemoryStore) Get(ctx context.Context, id string) (Payment, error) {
+	if err := ctx.Err(); err != nil {
+		return Payment{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Идиома "comma ok": второе значение = "найден ли ключ"
+	p, ok := s.payments[id]
+	if !ok {
+		return Payment{}, ErrPaymentNotFound
+	}
+	return p, nil
+}
+
+// List реализует Store
+func (s *MemoryStore) List(ctx context.Context, includeDeleted bool) ([]Payment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// make([]Payment, 0, n) = пустой срез с запасом емкости
+	// Пустой (не nil) срез кодируется в JSON как [], а не null
+	result := make([]Payment, 0, len(s.payments))
+	for _, p := range s.payments {
+		if p.Deleted && !includeDeleted {
+			continue
+		}
+		result = append(result, p)
+	}
+
+	// Порядок обхода map в Go СЛУЧАЙНЫЙ — сортируем для предсказуемости
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result, nil
+}
+
+// MarkDeleted реализует Store
+// Повторное удаление ничего не меняет (идемпотентность):
+// DeletedAt остается временем ПЕРВОГО удаления
+func (s *MemoryStore) MarkDeleted(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.payments[id]
+	if !ok {
+		return ErrPaymentNotFound
+	}
+	if !p.Deleted {
+		now := time.Now().UTC()
+		p.Deleted = true
+		p.DeletedAt = &now
+		p.Version++
+		s.put(p)
+	}
+	return nil
+}
+
+// UpdateStatus реализует Store
+//
+// Проверка версии и запись происходят под ОДНОЙ блокировкой,
+// иначе между проверкой и записью успел бы вклиниться другой запрос
+func (s *MemoryStore) UpdateStatus(ctx context.Context, id, status string, expectedVersion int) (Payment, error) {
+	if err := ctx.Err(); err != nil {
+		return Payment{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.payments[id]
+	if !ok || p.Deleted {
+		return Payment{}, ErrPaymentNotFound
+	}
+	if expectedVersion != 0 && p.Version != expectedVersion {
+		return p, ErrVersionMismatch
+	}
+	if !canTransition(p.Status, status) {
+		return p, ErrInvalidTransition
+	}
+
+	p.Status = status
+	p.Version++
+	s.put(p)
+	return p, nil
+}
+
+// ForceStatus реализует Store
+// То же, что UpdateStatus, но без проверки перехода
+func (s *MemoryStore) ForceStatus(ctx context.Context, id, status string, expectedVersion int) (Payment, error) {
+	if err := ctx.Err(); err != nil {
+		return Payment{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.payments[id]
+	if !ok || p.Deleted {
+		return Payment{}, ErrPaymentNotFound
+	}
+	if expectedVersion != 0 && p.Version != expectedVersion {
+		return p, ErrVersionMismatch
+	}
+
+	p.Status = status
+	p.FailureReason = "" // причина отказа — только у failed (см. FailPayment)
+	p.Version++
+	s.put(p)
+	return p, nil
+}
+
+// CreateRefund реализует Store
+// Проверка остатка и запись — под одной блокировкой: два параллельных
+// возврата не смогут вместе вернуть больше суммы платежа
+func (s *MemoryStore) CreateRefund(ctx context.Context, refund Refund) (Payment, error) {
+	if err := ctx.Err(); err != nil {
+		return Payment{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.payments[refund.PaymentID]
+	if !ok || p.Deleted {
+		return Payment{}, ErrPaymentNotFound
+	}
+	p, err := applyRefund(p, refund)
+	if err != nil {
+		return p, err
+	}
+	s.put(p)
+	s.refunds[p.ID] = append(s.refunds[p.ID], refund)
+	return p, nil
+}
+
+// CapturePayment реализует Store
+func (s *MemoryStore) CapturePayment(ctx context.Context, id string, c Capture) (Payment, error) {
+	if err := ctx.Err(); err != nil {
+		return Payment{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.payments[id]
+	if !ok || p.Deleted {
+		return Payment{}, ErrPaymentNotFound
+	}
+	p, err := c.apply(p)
+	if err != nil {
+		return p, err
+	}
+	s.put(p)
+	return p, nil
+}
+
+// RetryPayment реализует Store
+func (s *MemoryStore) RetryPayment(ctx context.Context, id string, rt Retry) (Payment, error) {
+	if err := ctx.Err(); err != nil {
+		return Payment{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.payments[id]
+	if !ok || p.Deleted {
+		return Payment{}, ErrPaymentNotFound
+	}
+	p, err := rt.apply(p)
+	if err != nil {
+		return p, err
+	}
+	s.put(p)
+	return p, nil
+}
+
+// CompletePayment реализует Store
+func (s *MemoryStore) CompletePayment(ctx context.Context, id string, c Completion) (Payment, error) {
+	if err := ctx.Err(); err != nil {
+		return Payment{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.payments[id]
+	if !ok || p.Deleted {
+		return Payment{}, ErrPaymentNotFound
+	}
+	p, err := c.apply(p)
+	if err != nil {
+		return p, err
+	}
+	s.put(p)
+	return p, nil
+}
+
+// FailPayment реализует Store
+func (s *MemoryStore) FailPayment(ctx context.Context, id string, f Failure) (Payment, error) {
+	if err := ctx.Err(); err != nil {
+		return Payment{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.payments[id]
+	if !ok || p.Deleted {
+		return Payment{}, ErrPaymentNotFound
+	}
+	p, err := f.apply(p)
+	if err != nil {
+		return p, err
+	}
+	s.put(p)
+	return p, nil
+}
+
+// SplitPayment реализует Store
+// Исходный платеж и части меняются под одной блокировкой: никто
+// не увидит части без перевода исходного платежа в split
+func (s *MemoryStore) SplitPayment(ctx context.Context, id string, expectedVersion int, installments []Payment) (Payment, error) {
+	if err := ctx.Err(); err != nil {
+		return Payment{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.payments[id]
+	if !ok || p.Deleted {
+		return Payment{}, ErrPaymentNotFound
+	}
+	p, err := applySplit(p, expectedVersion)
+	if err != nil {
+		return p, err
+	}
+	if s.maxPayments > 0 && len(s.payments)+len(installments) > s.maxPayments {
+		return Payment{}, ErrStoreFull
+	}
+	for _, part := range installments {
+		if _, ok := s.payments[part.ID]; ok {
+			return Payment{}, ErrPaymentExists
+		}
+	}
+	for _, part := range installments {
+		s.put(part)
+	}
+	s.put(p)
+	return p, nil
+}
+
+// ListDue реализует Store
+// Обходит только индекс сроков, а не все платежи
+func (s *MemoryStore) ListDue(ctx context.Context, status string, now time.Time) ([]Payment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []Payment
+	for id, at := range s.due {
+		if at.After(now) {
+			continue
+		}
+		if p := s.payments[id]; p.Status == status {
+			result = append(result, p)
+		}
+	}
+	sortByDue(result)
+	return result, nil
+}
+
+// ListRefunds реализует Store
+func (s *MemoryStore) ListRefunds(ctx context.Context, paymentID string) ([]Refund, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if p, ok := s.payments[paymentID]; !ok || p.Deleted {
+		return nil, ErrPaymentNotFound
+	}
+	// Копия среза: вызывающий код не должен видеть последующие append
+	return append([]Refund{}, s.refunds[paymentID]...), nil
+}
+
+// GetRefund реализует Store
+func (s *MemoryStore) GetRefund(ctx context.Context, paymentID, refundID string) (Refund, error) {
+	if err := ctx.Err(); err != nil {
+		return Refund{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if p, ok := s.payments[paymentID]; !ok || p.Deleted {
+		return Refund{}, ErrPaymentNotFound
+	}
+	for _, refund := range s.refunds[paymentID] {
+		if refund.ID == refundID {
+			return refund, nil
+		}
+	}
+	return Refund{}, ErrRefundNotFound
+}
+
+// NextSequenceNumber реализует Store
+func (s *MemoryStore) NextSequenceNumber(ctx context.Context) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return s.sequence.Add(1), nil
+}
+
+// idempotencyEntry — платеж, за которым закреплен ключ идемпотентности
+type idempotencyEntry struct {
+	paymentID string
+	expiresAt time.Time
+}
+
+// ClaimIdempotencyKey реализует Store
+// Проверка и запись — под одной блокировкой: из двух одновременных
+// запросов с одним ключом ключ достанется только одному
+func (s *MemoryStore) ClaimIdempotencyKey(ctx context.Context, key, paymentID string, ttl time.Duration) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if e, ok := s.idempotency[key]; ok && now.Before(e.expiresAt) {
+		return e.paymentID, nil
+	}
+	s.idempotency[key] = idempotencyEntry{paymentID: paymentID, expiresAt: now.Add(ttl)}
+	return "", nil
+}
+
+// ReleaseIdempotencyKey реализует Store
+func (s *MemoryStore) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.idempotency, key)
+	return nil
+}
+
+// SaveCustomer реализует Store
+func (s *MemoryStore) SaveCustomer(ctx context.Context, c Customer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.customers[c.ID] = c
+	return nil
+}
+
+// GetCustomer реализует Store
+func (s *MemoryStore) GetCustomer(ctx context.Context, id string) (Customer, error) {
+	if err := ctx.Err(); err != nil {
+		return Customer{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.customers[id]
+	if !ok {
+		return Customer{}, ErrCustomerNotFound
+	}
+	return c, nil
+}
+
+// ===== ОЧИСТКА СТАРЫХ ПЛАТЕЖЕЙ =====
+
+// RunJanitor периодически удаляет из памяти завершенные платежи
+// (succeeded, failed), созданные раньше чем retention назад,
+// и истекшие ключи идемпотентности
+//
+// Без очистки хранилище долгоживущего процесса растет бесконечно
+// Платежи в незавершенном статусе (pending) НЕ удаляются никогда —
+// по ним еще ожидается результат
+// retention = 0 — платежи хранятся всегда, чистятся только ключи
+//
+// Функция блокирующая: запускайте в отдельной горутине
+//
+//	go store.RunJanitor(ctx, 24*time.Hour, time.Minute)
+//
+// Работает, пока не отменен ctx (например, при остановке сервера)
+func (s *MemoryStore) RunJanitor(ctx context.Context, retention, interval time.Duration) {
+	// Ticker "тикает" в канал C каждые interval
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if retention > 0 {
+				if n := s.EvictExpired(now.Add(-retention)); n > 0 {
+					log.Printf("Janitor evicted %d expired payments", n)
+				}
+			}
+			if n := s.EvictIdempotencyKeys(now); n > 0 {
+				log.Printf("Janitor evicted %d expired idempotency keys", n)
+			}
+		}
+	}
+}
+
+// EvictExpired удаляет завершенные платежи, созданные раньше cutoff
+// Возвращает количество удаленных платежей
+//
+// Мягко удаленные платежи (Deleted) остаются: это "надгробия",
+// которые держат ID занятым. Без них PUT /payments/{id} создал бы
+// новый платеж с ID удаленного
+func (s *MemoryStore) EvictExpired(cutoff time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for id, p := range s.payments {
+		// Удалять элементы map во время обхода в Go безопасно
+		if isTerminalStatus(p.Status) && !p.Deleted && p.CreatedAt.Before(cutoff) {
+			delete(s.payments, id)
+			delete(s.due, id)
+			delete(s.refunds, id)
+			if p.ExternalID != "" {
+				delete(s.externalIDs, p.ExternalID)
+			}
+			n++
+		}
+	}
+	s.evicted.Add(int64(n))
+	return n
+}
+
+// EvictIdempotencyKeys удаляет ключи идемпотентности, истекшие к now
+// Возвращает количество удаленных ключей
+//
+// Истекший ключ и так не действует (см. ClaimIdempotencyKey): повтор
+// с ним создаст новый платеж. Уборка нужна, чтобы map не росла вечно
+func (s *MemoryStore) EvictIdempotencyKeys(now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for key, e := range s.idempotency {
+		if !now.Before(e.expiresAt) {
+			delete(s.idempotency, key)
+			n++
+		}
+	}
+	return n
+}
+
+// Evicted возвращает общее число удаленных уборщиком платежей
+// (для метрик и мониторинга)
+func (s *MemoryStore) Evicted() int64 {
+	return s.evicted.Load()
+}
+
+// evictionCounter — хранилище с уборщиком, чей счетчик отдается
+// в /metrics (payments_evicted_total)
+type evictionCounter interface {
+	Evicted() int64
+}
@@ -0,0 +1,191 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestNextSequenceNumberConcurrent — параллельные вызовы получают
+// разные номера без пропусков
+func TestNextSequenceNumberConcurrent(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		const workers, each = 8, 50
+		var (
+			mu   sync.Mutex
+			seen = make(map[int64]bool)
+			wg   sync.WaitGroup
+		)
+		for range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range each {
+					n, err := store.NextSequenceNumber(context.Background())
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					mu.Lock()
+					if seen[n] {
+						t.Errorf("duplicate sequence number %d", n)
+					}
+					seen[n] = true
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		for n := int64(1); n <= workers*each; n++ {
+			if !seen[n] {
+				t.Fatalf("sequence number %d missing", n)
+			}
+		}
+	})
+}
+
+// TestParallelCreatesUnique — параллельные POST /payments получают
+// уникальные ID и порядковые номера
+func TestParallelCreatesUnique(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	const n = 100
+	created := make(chan Payment, n)
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// t.Fatal нельзя звать из чужой горутины — только t.Error
+			rec := doJSON(t, s, http.MethodPost, "/payments", `{"amount": 100, "currency": "RUB"}`, nil)
+			var p Payment
+			if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil || rec.Code != http.StatusCreated {
+				t.Errorf("POST /payments = %d: %s", rec.Code, rec.Body.String())
+			}
+			created <- p
+		}()
+	}
+	wg.Wait()
+	close(created)
+
+	ids, seqs := make(map[string]bool), make(map[int64]bool)
+	for p := range created {
+		if ids[p.ID] || seqs[p.SequenceNumber] || p.SequenceNumber <= 0 {
+			t.Fatalf("duplicate or missing: ID=%s, sequence=%d", p.ID, p.SequenceNumber)
+		}
+		ids[p.ID], seqs[p.SequenceNumber] = true, true
+	}
+	if len(ids) != n {
+		t.Fatalf("created %d payments, want %d", len(ids), n)
+	}
+}
+
+// TestEvictIdempotencyKeys — уборщик удаляет только истекшие ключи;
+// время передается явно, поэтому тест не ждет настоящих часов
+func TestEvictIdempotencyKeys(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	now := time.Now()
+	store.ClaimIdempotencyKey(ctx, "short", "pay_1", time.Minute)
+	store.ClaimIdempotencyKey(ctx, "long", "pay_2", time.Hour)
+
+	if n := store.EvictIdempotencyKeys(now.Add(30 * time.Second)); n != 0 {
+		t.Fatalf("evicted %d keys before expiry", n)
+	}
+	if n := store.EvictIdempotencyKeys(now.Add(2 * time.Minute)); n != 1 {
+		t.Fatalf("evicted %d keys, want 1", n)
+	}
+	if owner, _ := store.ClaimIdempotencyKey(ctx, "long", "pay_3", time.Hour); owner != "pay_2" {
+		t.Fatalf("unexpired key owner = %q, want pay_2", owner)
+	}
+	if owner, _ := store.ClaimIdempotencyKey(ctx, "short", "pay_3", time.Hour); owner != "" {
+		t.Fatalf("expired key still owned by %q", owner)
+	}
+}
+
+// TestIdempotencyKeyAfterExpiry — повтор с тем же ключом до истечения
+// возвращает тот же платеж, после уборки — создает новый
+func TestIdempotencyKeyAfterExpiry(t *testing.T) {
+	store := NewMemoryStore()
+	s := NewServer(store, nil, nil, Config{IdempotencyTTL: time.Hour})
+	body := `{"amount": 100, "currency": "RUB"}`
+	key := map[string]string{"Idempotency-Key": "order-1"}
+
+	var first, retry, later Payment
+	decodeBody(t, doJSON(t, s, http.MethodPost, "/payments", body, key), &first)
+	decodeBody(t, doJSON(t, s, http.MethodPost, "/payments", body, key), &retry)
+	if retry.ID != first.ID {
+		t.Fatalf("retry inside TTL created %s, want %s", retry.ID, first.ID)
+	}
+
+	// "Часы" уборщика ушли за TTL
+	if n := store.EvictIdempotencyKeys(time.Now().Add(2 * time.Hour)); n != 1 {
+		t.Fatalf("evicted %d keys, want 1", n)
+	}
+	decodeBody(t, doJSON(t, s, http.MethodPost, "/payments", body, key), &later)
+	if later.ID == "" || later.ID == first.ID {
+		t.Fatalf("retry after expiry = %q, want a new payment", later.ID)
+	}
+}
+
+// TestMaxPaymentsBackpressure — сверх лимита создание получает 503,
+// уборка завершенных платежей освобождает место
+func TestMaxPaymentsBackpressure(t *testing.T) {
+	store := NewMemoryStore()
+	store.SetMaxPayments(3)
+	s := NewServer(store, nil, nil, Config{})
+	body := `{"amount": 100, "currency": "RUB"}`
+
+	var first Payment
+	for i := range 3 {
+		p := createPaymentT(t, s, body)
+		if i == 0 {
+			first = p
+		}
+	}
+	rec := doJSON(t, s, http.MethodPost, "/payments", body, nil)
+	var resp ErrorResponse
+	decodeBody(t, rec, &resp)
+	if rec.Code != http.StatusServiceUnavailable || resp.Code != CodeStoreFull {
+		t.Fatalf("over capacity: %d %+v", rec.Code, resp)
+	}
+	if all, _ := store.List(context.Background(), true); len(all) != 3 {
+		t.Fatalf("stored = %d, want 3", len(all))
+	}
+
+	// Завершенный платеж вытесняется уборщиком — место появляется
+	if rec := doJSON(t, s, http.MethodPatch, "/payments/"+first.ID, `{"status":"canceled"}`, nil); rec.Code != http.StatusOK {
+		t.Fatalf("cancel = %d", rec.Code)
+	}
+	if n := store.EvictExpired(time.Now().Add(time.Second)); n != 1 {
+		t.Fatalf("evicted %d, want 1", n)
+	}
+	if rec := doJSON(t, s, http.MethodPost, "/payments", body, nil); rec.Code != http.StatusCreated {
+		t.Fatalf("after eviction = %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+// TestEvictExpiredKeepsTombstones — уборщик не трогает мягко удаленные
+// платежи: их ID остается занятым, и PUT с ним получает 409
+func TestEvictExpiredKeepsTombstones(t *testing.T) {
+	store := NewMemoryStore()
+	s := NewServer(store, nil, nil, Config{})
+	old := time.Now().Add(-time.Hour)
+	savePaymentT(t, store, Payment{ID: "pay_gone", AmountMinor: 100, Currency: "RUB", Status: StatusCanceled, CreatedAt: old, Version: 1})
+	savePaymentT(t, store, Payment{ID: "pay_done", AmountMinor: 100, Currency: "RUB", Status: StatusCanceled, CreatedAt: old, Version: 1})
+	if rec := doJSON(t, s, http.MethodDelete, "/payments/pay_gone", "", nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("DELETE = %d", rec.Code)
+	}
+
+	if n := store.EvictExpired(time.Now()); n != 1 {
+		t.Fatalf("evicted %d, want 1 (only pay_done)", n)
+	}
+	rec := doJSON(t, s, http.MethodPut, "/payments/pay_gone", `{"amount": 1, "currency": "RUB"}`, nil)
+	var resp ErrorResponse
+	decodeBody(t, rec, &resp)
+	if rec.Code != http.StatusConflict || resp.Code != CodePaymentExists {
+		t.Fatalf("PUT deleted ID = %d %+v, want 409 %s", rec.Code, resp, CodePaymentExists)
+	}
+}
@@ -0,0 +1,96 @@
+package payments
+
+import (
+	"errors"
+	"log"
+	"net/http"
+)
+
+// ===== СВОДКА ПО ПЛАТЕЖАМ =====
+
+// summaryBucket — количество платежей и их сумма в минорных единицах
+type summaryBucket struct {
+	Count      int   `json:"count"`
+	TotalMinor int64 `json:"total_minor"`
+}
+
+// paymentsSummary — ответ GET /payments/summary
+//
+// ВНИМАНИЕ: в by_status суммируются платежи в РАЗНЫХ валютах
+// (минорные единицы RUB и USD складываются как есть) — это счетчик
+// объема для дашборда, а не денежная сумма. Деньги смотрите в by_currency
+type paymentsSummary struct {
+	ByStatus   map[string]summaryBucket `json:"by_status"`
+	ByCurrency map[string]summaryBucket `json:"by_currency"`
+}
+
+// handlePaymentsSummary возвращает количество и суммы платежей
+// по статусам и по валютам
+// GET /payments/summary?currency=RUB&from=2024-01-01T00:00:00Z
+//
+// Принимает те же фильтры, что и список (см. listFilter),
+// поэтому клиенту не нужно выгружать все платежи ради итогов
+func (s *Server) handlePaymentsSummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	query := r.URL.Query()
+	filter, err := parseListFilter(query)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidQuery, err.Error())
+		return
+	}
+	payments, err := s.store.List(r.Context(), query.Get("include_deleted") == "true")
+	if err != nil {
+		log.Printf("Error listing payments: %v", err)
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+		return
+	}
+
+	summary, err := summarize(filter.apply(s.scopePayments(r, payments)))
+	if errors.Is(err, ErrAmountOverflow) {
+		writeError(w, r, http.StatusUnprocessableEntity, CodeTotalOverflow,
+			"Total exceeds the representable range; narrow the filter (currency, from, to)")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, summary)
+}
+
+// summarize считает итоги по статусам и валютам
+// Суммы складываются с проверкой переполнения (см. addMinor):
+// вместо "завернувшегося" итога — ошибка ErrAmountOverflow
+func summarize(payments []Payment) (paymentsSummary, error) {
+	summary := paymentsSummary{
+		ByStatus:   make(map[string]summaryBucket),
+		ByCurrency: make(map[string]summaryBucket),
+	}
+	for _, p := range payments {
+		// Значение map нельзя изменить "на месте" (summary.ByStatus[k].Count++
+		// не скомпилируется): достаем копию, меняем, кладем обратно
+		b := summary.ByStatus[p.Status]
+		if err := b.add(p.AmountMinor); err != nil {
+			return paymentsSummary{}, err
+		}
+		summary.ByStatus[p.Status] = b
+
+		b = summary.ByCurrency[p.Currency]
+		if err := b.add(p.AmountMinor); err != nil {
+			return paymentsSummary{}, err
+		}
+		summary.ByCurrency[p.Currency] = b
+	}
+	return summary, nil
+}
+
+// add учитывает в итоге еще один платеж
+func (b *summaryBucket) add(amountMinor int64) error {
+	total, err := addMinor(b.TotalMinor, amountMinor)
+	if err != nil {
+		return err
+	}
+	b.Count++
+	b.TotalMinor = total
+	return nil
+}
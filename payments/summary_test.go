@@ -0,0 +1,98 @@
+package payments
+
+import (
+	"errors"
+	"maps"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func newSummaryServer(t *testing.T) *Server {
+	t.Helper()
+	store := NewMemoryStore()
+	jan, feb := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC)
+	for _, p := range []Payment{
+		{ID: "pay_1", AmountMinor: 1000, Currency: "RUB", Status: StatusSucceeded, CreatedAt: jan},
+		{ID: "pay_2", AmountMinor: 2500, Currency: "RUB", Status: StatusSucceeded, CreatedAt: feb},
+		{ID: "pay_3", AmountMinor: 700, Currency: "USD", Status: StatusFailed, CreatedAt: jan},
+		{ID: "pay_4", AmountMinor: 300, Currency: "USD", Status: StatusSucceeded, CreatedAt: feb},
+	} {
+		p.Version = 1
+		savePaymentT(t, store, p)
+	}
+	return NewServer(store, nil, nil, Config{})
+}
+
+func getSummary(t *testing.T, s *Server, query string) paymentsSummary {
+	t.Helper()
+	rec := doJSON(t, s, http.MethodGet, "/payments/summary"+query, "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("summary%s = %d: %s", query, rec.Code, rec.Body.String())
+	}
+	var summary paymentsSummary
+	decodeBody(t, rec, &summary)
+	return summary
+}
+
+func TestSummaryAggregates(t *testing.T) {
+	s := newSummaryServer(t)
+	got := getSummary(t, s, "")
+	wantStatus := map[string]summaryBucket{
+		StatusSucceeded: {Count: 3, TotalMinor: 3800},
+		StatusFailed:    {Count: 1, TotalMinor: 700},
+	}
+	wantCurrency := map[string]summaryBucket{
+		"RUB": {Count: 2, TotalMinor: 3500},
+		"USD": {Count: 2, TotalMinor: 1000},
+	}
+	if !maps.Equal(got.ByStatus, wantStatus) || !maps.Equal(got.ByCurrency, wantCurrency) {
+		t.Fatalf("summary = %+v", got)
+	}
+}
+
+func TestSummaryFilters(t *testing.T) {
+	s := newSummaryServer(t)
+	got := getSummary(t, s, "?currency=USD&from=2024-02-01T00:00:00Z")
+	want := map[string]summaryBucket{"USD": {Count: 1, TotalMinor: 300}}
+	if !maps.Equal(got.ByCurrency, want) {
+		t.Fatalf("by_currency = %+v", got.ByCurrency)
+	}
+	if rec := doJSON(t, s, http.MethodGet, "/payments/summary?from=yesterday", "", nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid filter = %d", rec.Code)
+	}
+}
+
+// TestSummarizeOverflow — MaxInt64 + 1 в наивной сумме "заворачивается"
+// в отрицательное число; summarize должен вернуть ошибку
+func TestSummarizeOverflow(t *testing.T) {
+	payments := []Payment{
+		{ID: "pay_1", AmountMinor: math.MaxInt64, Currency: "RUB", Status: StatusSucceeded},
+		{ID: "pay_2", AmountMinor: 1, Currency: "RUB", Status: StatusSucceeded},
+	}
+	if _, err := summarize(payments); !errors.Is(err, ErrAmountOverflow) {
+		t.Fatalf("err = %v, want ErrAmountOverflow", err)
+	}
+
+	// Максимум без переполнения считается точно
+	payments[0].AmountMinor = math.MaxInt64 - 1
+	got, err := summarize(payments)
+	if err != nil || got.ByCurrency["RUB"].TotalMinor != math.MaxInt64 {
+		t.Fatalf("summary = %+v, %v", got, err)
+	}
+}
+
+func TestSummaryOverflowHTTP(t *testing.T) {
+	store := NewMemoryStore()
+	for _, id := range []string{"pay_1", "pay_2"} {
+		savePaymentT(t, store, Payment{ID: id, AmountMinor: math.MaxInt64/2 + 1, Currency: "RUB", Status: StatusSucceeded, Version: 1})
+	}
+	s := NewServer(store, nil, nil, Config{})
+	rec := doJSON(t, s, http.MethodGet, "/payments/summary", "", nil)
+	var resp ErrorResponse
+	decodeBody(t, rec, &resp)
+	if rec.Code != http.StatusUnprocessableEntity || resp.Code != CodeTotalOverflow {
+		t.Fatalf("overflow: %d %+v", rec.Code, resp)
+	}
+}
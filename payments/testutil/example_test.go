@@ -0,0 +1,76 @@
+package testutil_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/namestnikoff/payment-system/payments"
+	"github.com/namestnikoff/payment-system/payments/testutil"
+)
+
+// Заготовка кладется прямо в хранилище, а читается через HTTP API
+func Example() {
+	ts := testutil.NewTestServer()
+	defer ts.Close()
+
+	p := ts.AddPayment(
+		testutil.WithID("pay_example"),
+		testutil.WithAmountMinor(25050),
+		testutil.WithStatus(payments.StatusSucceeded),
+	)
+
+	resp, err := http.Get(ts.URL + "/payments/" + p.ID)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer resp.Body.Close()
+
+	var got struct {
+		ID            string `json:"id"`
+		Status        string `json:"status"`
+		AmountDecimal string `json:"amount_decimal"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(resp.StatusCode, got.ID, got.Status, got.AmountDecimal)
+	// Output: 200 pay_example succeeded 250.50
+}
+
+// Номера заготовок и платежей, созданных через API, не совпадают
+func TestAddPaymentSequenceFromStore(t *testing.T) {
+	ts := testutil.NewTestServer()
+	defer ts.Close()
+
+	fixture := ts.AddPayment()
+	resp, err := http.Post(ts.URL+"/payments", "application/json", strings.NewReader(`{"amount": 10, "currency": "RUB"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var created payments.Payment
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("POST /payments = %d", resp.StatusCode)
+	}
+	if created.SequenceNumber <= fixture.SequenceNumber {
+		t.Fatalf("API payment got sequence %d, fixture has %d", created.SequenceNumber, fixture.SequenceNumber)
+	}
+}
+
+func TestNewTestPaymentDerivedFields(t *testing.T) {
+	p := testutil.NewTestPayment(testutil.WithAmountMinor(1500), testutil.WithCurrency("JPY"))
+	if p.Amount != 1500 || p.AmountRefundableMinor != 1500 || p.NetAmountMinor != 1500 {
+		t.Fatalf("derived fields: %+v", p)
+	}
+	if q := testutil.NewTestPayment(); q.ID == p.ID {
+		t.Fatalf("fixtures share ID %s", p.ID)
+	}
+}
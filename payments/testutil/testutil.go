@@ -0,0 +1,137 @@
+// Package testutil — помощники для тестов HTTP API платежей
+//
+// Поднимает настоящий HTTP сервер на случайном порту с хранилищем
+// в памяти и создает платежи-заготовки (fixtures):
+//
+//	ts := testutil.NewTestServer()
+//	defer ts.Close()
+//
+//	p := ts.AddPayment(testutil.WithStatus(payments.StatusSucceeded))
+//	resp, err := http.Get(ts.URL + "/payments/" + p.ID)
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	"github.com/namestnikoff/payment-system/payments"
+)
+
+// TestServer — тестовый сервер API и его хранилище
+// Хранилище доступно напрямую, чтобы готовить данные без HTTP запросов
+type TestServer struct {
+	*httptest.Server
+	Store *payments.MemoryStore
+}
+
+// NewTestServer запускает API на случайном порту
+// Без шлюза и курсов валют, с настройками по умолчанию
+// Сервер нужно остановить через Close
+func NewTestServer() *TestServer {
+	return NewTestServerWithConfig(payments.Config{})
+}
+
+// NewTestServerWithConfig запускает API с заданными настройками
+func NewTestServerWithConfig(cfg payments.Config) *TestServer {
+	store := payments.NewMemoryStore()
+	server := payments.NewServer(store, nil, nil, cfg)
+	return &TestServer{
+		Server: httptest.NewServer(server),
+		Store:  store,
+	}
+}
+
+// AddPayment создает платеж-заготовку и сохраняет его в хранилище
+//
+// Порядковый номер выдает само хранилище (NextSequenceNumber), как
+// при POST /payments: заготовки и платежи, созданные через API
+// в том же тесте, не получат одинаковых номеров
+func (ts *TestServer) AddPayment(opts ...PaymentOption) payments.Payment {
+	p := NewTestPayment(opts...)
+	seq, err := ts.Store.NextSequenceNumber(context.Background())
+	if err != nil {
+		panic(fmt.Sprintf("testutil: allocating sequence number: %v", err))
+	}
+	p.SequenceNumber = seq
+	if err := ts.Store.Save(context.Background(), p); err != nil {
+		// MemoryStore падает только при отмене контекста — в тестах это баг
+		panic(fmt.Sprintf("testutil: saving payment: %v", err))
+	}
+	return p
+}
+
+// ===== ЗАГОТОВКИ ПЛАТЕЖЕЙ =====
+
+// PaymentOption меняет поле платежа-заготовки
+// (паттерн "функциональные опции")
+type PaymentOption func(*payments.Payment)
+
+// sequence — счетчик для уникальных ID заготовок
+// Номера платежей он дает только заготовкам вне хранилища: счетчик
+// общий на все тесты и не знает о номерах, которые выдает
+// Store.NextSequenceNumber (их выдает AddPayment)
+var sequence atomic.Int64
+
+// NewTestPayment создает платеж-заготовку без сохранения
+// По умолчанию: 100.00 RUB, pending, версия 1, уникальный ID
+// SequenceNumber — из счетчика пакета; для платежа в хранилище
+// используйте TestServer.AddPayment, чтобы номер не совпал с номерами API
+func NewTestPayment(opts ...PaymentOption) payments.Payment {
+	n := sequence.Add(1)
+	p := payments.Payment{
+		ID:             fmt.Sprintf("pay_test_%d", n),
+		SequenceNumber: n,
+		AmountMinor:    10000,
+		Currency:       "RUB",
+		Status:         payments.StatusPending,
+		CreatedAt:      time.Now().UTC(),
+		Version:        1,
+	}
+	for _, opt := range opts {
+		opt(&p)
+	}
+	// Производные поля считаем после опций: они зависят от суммы и валюты
+	p.Amount = float64(p.AmountMinor) / math.Pow10(payments.CurrencyDecimals(p.Currency))
+	p.AmountRefundableMinor = p.AmountMinor - p.AmountRefundedMinor
+	p.NetAmountMinor = p.AmountMinor - p.FeeMinor
+	return p
+}
+
+// WithID задает ID платежа
+func WithID(id string) PaymentOption {
+	return func(p *payments.Payment) { p.ID = id }
+}
+
+// WithAmountMinor задает сумму в минорных единицах (копейках)
+func WithAmountMinor(minor int64) PaymentOption {
+	return func(p *payments.Payment) { p.AmountMinor = minor }
+}
+
+// WithCurrency задает валюту
+func WithCurrency(currency string) PaymentOption {
+	return func(p *payments.Payment) { p.Currency = currency }
+}
+
+// WithStatus задает статус
+func WithStatus(status string) PaymentOption {
+	return func(p *payments.Payment) { p.Status = status }
+}
+
+// WithCustomer задает владельца платежа
+func WithCustomer(customerID string) PaymentOption {
+	return func(p *payments.Payment) { p.CustomerID = customerID }
+}
+
+// WithMetadata задает метаданные
+func WithMetadata(metadata map[string]string) PaymentOption {
+	return func(p *payments.Payment) { p.Metadata = metadata }
+}
+
+// WithCreatedAt задает время создания
+func WithCreatedAt(t time.Time) PaymentOption {
+	return func(p *payments.Payment) { p.CreatedAt = t }
+}
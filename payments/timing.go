@@ -0,0 +1,281 @@
+package payments
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ===== ВРЕМЯ ОБРАБОТКИ (SERVER-TIMING) И ДЕДЛАЙН ЗАПРОСА =====
+//
+// Каждый ответ несет заголовок Server-Timing (стандарт W3C) — его
+// показывают инструменты разработчика браузера на вкладке Network:
+//
+//	Server-Timing: store;dur=0.412, gateway;dur=182.305, total;dur=183.129
+//
+// - store — суммарное время в хранилище
+// - gateway — суммарное время в платежном шлюзе
+// - total — от начала запроса до отправки заголовков ответа
+// Время собирается через контекст запроса: хранилище и шлюз обернуты
+// (timedStore, timedGateway) и добавляют свое время к записи в контексте
+//
+// Config.RequestTimeout ограничивает время обработки запроса: по его
+// истечении контекст отменяется, хранилище и шлюз прекращают работу,
+// клиент получает 503 request_timeout (см. writeError)
+
+// timeoutExempt — окончания путей, на которые дедлайн не действует:
+// поток событий, выгрузка и загрузка живут столько, сколько нужно клиенту
+// Так же освобождено долгое ожидание GET /payments/{id}?wait= —
+// его длительность и так ограничена maxLongPollWait (см. isTimeoutExempt)
+var timeoutExempt = []string{"/stream", "/export", "/import"}
+
+// serverTimings — время, накопленное за запрос, по участкам
+type serverTimings struct {
+	mu    sync.Mutex
+	start time.Time
+	spans map[string]time.Duration
+	order []string // порядок появления участков (map его не хранит)
+}
+
+// serverTimingsKey — ключ serverTimings в контексте запроса
+// Свой тип вместо строки: ключи разных пакетов не совпадут
+type serverTimingsKey struct{}
+
+// observeTiming начинает замер участка name и возвращает функцию,
+// которая его заканчивает:
+//
+//	defer observeTiming(ctx, "store")()
+//
+// Вне HTTP запроса (планировщик, уборщик) замер ничего не делает
+func observeTiming(ctx context.Context, name string) func() {
+	t, ok := ctx.Value(serverTimingsKey{}).(*serverTimings)
+	if !ok {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		t.add(name, time.Since(start))
+	}
+}
+
+// add прибавляет d к участку name
+// Несколько обращений к хранилищу за запрос складываются
+func (t *serverTimings) add(name string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.spans[name]; !ok {
+		t.order = append(t.order, name)
+	}
+	t.spans[name] += d
+}
+
+// header собирает значение заголовка Server-Timing
+// dur — в миллисекундах, как требует стандарт
+func (t *serverTimings) header() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	parts := make([]string, 0, len(t.order)+1)
+	for _, name := range t.order {
+		parts = append(parts, fmt.Sprintf("%s;dur=%.3f", name, milliseconds(t.spans[name])))
+	}
+	parts = append(parts, fmt.Sprintf("total;dur=%.3f", milliseconds(time.Since(t.start))))
+	return strings.Join(parts, ", ")
+}
+
+// milliseconds переводит длительность в дробные миллисекунды
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// serverTiming — middleware, которое заводит serverTimings для запроса,
+// ставит дедлайн (timeout > 0) и добавляет заголовок Server-Timing
+//
+// Заголовки уходят клиенту вместе с первым байтом ответа, поэтому
+// total — время до этого момента: для обычного JSON ответа это почти
+// все время обработки
+func serverTiming(timeout time.Duration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t := &serverTimings{start: time.Now(), spans: make(map[string]time.Duration)}
+		ctx := context.WithValue(r.Context(), serverTimingsKey{}, t)
+		if timeout > 0 && !isTimeoutExempt(r) {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		next.ServeHTTP(&timingWriter{ResponseWriter: w, timings: t}, r.WithContext(ctx))
+	})
+}
+
+// isTimeoutExempt сообщает, что дедлайн на запрос не действует
+// ?wait= может быть длиннее RequestTimeout: с дедлайном ожидание
+// обрывалось бы раньше, чем обещано клиенту
+func isTimeoutExempt(r *http.Request) bool {
+	for _, suffix := range timeoutExempt {
+		if strings.HasSuffix(r.URL.Path, suffix) {
+			return true
+		}
+	}
+	return r.Method == http.MethodGet && r.URL.Query().Has("wait")
+}
+
+// timingWriter ставит Server-Timing прямо перед отправкой заголовков
+type timingWriter struct {
+	http.ResponseWriter
+	timings     *serverTimings
+	wroteHeader bool
+}
+
+// WriteHeader реализует http.ResponseWriter
+func (tw *timingWriter) WriteHeader(status int) {
+	if !tw.wroteHeader {
+		tw.wroteHeader = true
+		tw.Header().Set("Server-Timing", tw.timings.header())
+	}
+	tw.ResponseWriter.WriteHeader(status)
+}
+
+// Write реализует http.ResponseWriter
+// Запись тела без WriteHeader означает код 200
+func (tw *timingWriter) Write(b []byte) (int, error) {
+	if !tw.wroteHeader {
+		tw.WriteHeader(http.StatusOK)
+	}
+	return tw.ResponseWriter.Write(b)
+}
+
+// Unwrap возвращает исходный ResponseWriter (см. statusRecorder.Unwrap)
+func (tw *timingWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
+
+// ===== ЗАМЕРЫ ХРАНИЛИЩА И ШЛЮЗА =====
+
+// timedGateway добавляет время списания к участку "gateway"
+type timedGateway struct {
+	PaymentGateway
+}
+
+// Charge реализует PaymentGateway
+func (g timedGateway) Charge(ctx context.Context, p Payment) error {
+	defer observeTiming(ctx, "gateway")()
+	return g.PaymentGateway.Charge(ctx, p)
+}
+
+// timedStore добавляет время каждого метода Store к участку "store"
+// Встроенный Store отвечает за все остальное
+type timedStore struct {
+	Store
+}
+
+func (s timedStore) Save(ctx context.Context, p Payment) error {
+	defer observeTiming(ctx, "store")()
+	return s.Store.Save(ctx, p)
+}
+
+func (s timedStore) Create(ctx context.Context, p Payment) error {
+	defer observeTiming(ctx, "store")()
+	return s.Store.Create(ctx, p)
+}
+
+func (s timedStore) Get(ctx context.Context, id string) (Payment, error) {
+	defer observeTiming(ctx, "store")()
+	return s.Store.Get(ctx, id)
+}
+
+func (s timedStore) GetByExternalID(ctx context.Context, externalID string) (Payment, error) {
+	defer observeTiming(ctx, "store")()
+	return s.Store.GetByExternalID(ctx, externalID)
+}
+
+func (s timedStore) List(ctx context.Context, includeDeleted bool) ([]Payment, error) {
+	defer observeTiming(ctx, "store")()
+	return s.Store.List(ctx, includeDeleted)
+}
+
+func (s timedStore) MarkDeleted(ctx context.Context, id string) error {
+	defer observeTiming(ctx, "store")()
+	return s.Store.MarkDeleted(ctx, id)
+}
+
+func (s timedStore) UpdateStatus(ctx context.Context, id, status string, expectedVersion int) (Payment, error) {
+	defer observeTiming(ctx, "store")()
+	return s.Store.UpdateStatus(ctx, id, status, expectedVersion)
+}
+
+func (s timedStore) ForceStatus(ctx context.Context, id, status string, expectedVersion int) (Payment, error) {
+	defer observeTiming(ctx, "store")()
+	return s.Store.ForceStatus(ctx, id, status, expectedVersion)
+}
+
+func (s timedStore) CreateRefund(ctx context.Context, refund Refund) (Payment, error) {
+	defer observeTiming(ctx, "store")()
+	return s.Store.CreateRefund(ctx, refund)
+}
+
+func (s timedStore) CapturePayment(ctx context.Context, id string, c Capture) (Payment, error) {
+	defer observeTiming(ctx, "store")()
+	return s.Store.CapturePayment(ctx, id, c)
+}
+
+func (s timedStore) RetryPayment(ctx context.Context, id string, rt Retry) (Payment, error) {
+	defer observeTiming(ctx, "store")()
+	return s.Store.RetryPayment(ctx, id, rt)
+}
+
+func (s timedStore) CompletePayment(ctx context.Context, id string, c Completion) (Payment, error) {
+	defer observeTiming(ctx, "store")()
+	return s.Store.CompletePayment(ctx, id, c)
+}
+
+func (s timedStore) FailPayment(ctx context.Context, id string, f Failure) (Payment, error) {
+	defer observeTiming(ctx, "store")()
+	return s.Store.FailPayment(ctx, id, f)
+}
+
+func (s timedStore) ListDue(ctx context.Context, status string, now time.Time) ([]Payment, error) {
+	defer observeTiming(ctx, "store")()
+	return s.Store.ListDue(ctx, status, now)
+}
+
+func (s timedStore) SplitPayment(ctx context.Context, id string, expectedVersion int, installments []Payment) (Payment, error) {
+	defer observeTiming(ctx, "store")()
+	return s.Store.SplitPayment(ctx, id, expectedVersion, installments)
+}
+
+func (s timedStore) ListRefunds(ctx context.Context, paymentID string) ([]Refund, error) {
+	defer observeTiming(ctx, "store")()
+	return s.Store.ListRefunds(ctx, paymentID)
+}
+
+func (s timedStore) GetRefund(ctx context.Context, paymentID, refundID string) (Refund, error) {
+	defer observeTiming(ctx, "store")()
+	return s.Store.GetRefund(ctx, paymentID, refundID)
+}
+
+func (s timedStore) NextSequenceNumber(ctx context.Context) (int64, error) {
+	defer observeTiming(ctx, "store")()
+	return s.Store.NextSequenceNumber(ctx)
+}
+
+func (s timedStore) ClaimIdempotencyKey(ctx context.Context, key, paymentID string, ttl time.Duration) (string, error) {
+	defer observeTiming(ctx, "store")()
+	return s.Store.ClaimIdempotencyKey(ctx, key, paymentID, ttl)
+}
+
+func (s timedStore) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	defer observeTiming(ctx, "store")()
+	return s.Store.ReleaseIdempotencyKey(ctx, key)
+}
+
+func (s timedStore) SaveCustomer(ctx context.Context, c Customer) error {
+	defer observeTiming(ctx, "store")()
+	return s.Store.SaveCustomer(ctx, c)
+}
+
+func (s timedStore) GetCustomer(ctx context.Context, id string) (Customer, error) {
+	defer observeTiming(ctx, "store")()
+	return s.Store.GetCustomer(ctx, id)
+}
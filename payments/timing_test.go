@@ -0,0 +1,93 @@
+package payments
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// parseServerTiming разбирает заголовок Server-Timing в участок → dur
+func parseServerTiming(t *testing.T, header string) map[string]float64 {
+	t.Helper()
+	spans := make(map[string]float64)
+	for _, entry := range strings.Split(header, ",") {
+		name, params, ok := strings.Cut(strings.TrimSpace(entry), ";")
+		dur, found := strings.CutPrefix(params, "dur=")
+		if !ok || !found || name == "" {
+			t.Fatalf("malformed Server-Timing entry %q in %q", entry, header)
+		}
+		v, err := strconv.ParseFloat(dur, 64)
+		if err != nil || v < 0 {
+			t.Fatalf("bad dur in %q: %v", entry, err)
+		}
+		spans[name] = v
+	}
+	return spans
+}
+
+// TestServerTimingHeader — в ответе есть участки хранилища, шлюза и
+// общее время, и общее не меньше любого участка
+func TestServerTimingHeader(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, gatewayFunc(func(context.Context, Payment) error {
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	}), Config{})
+
+	rec := doJSON(t, s, http.MethodPost, "/payments", `{"amount": 100, "currency": "RUB"}`, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST = %d: %s", rec.Code, rec.Body.String())
+	}
+	spans := parseServerTiming(t, rec.Header().Get("Server-Timing"))
+	for _, name := range []string{"store", "gateway", "total"} {
+		if _, ok := spans[name]; !ok {
+			t.Fatalf("span %s missing: %v", name, spans)
+		}
+	}
+	if spans["gateway"] < 5 || spans["total"] < spans["gateway"] || spans["total"] < spans["store"] {
+		t.Fatalf("spans = %v", spans)
+	}
+
+	// Ответ без хранилища и шлюза несет только total
+	rec = doJSON(t, s, http.MethodGet, "/health", "", nil)
+	if spans := parseServerTiming(t, rec.Header().Get("Server-Timing")); len(spans) != 1 {
+		t.Fatalf("health spans = %v", spans)
+	}
+}
+
+// TestRequestTimeout — по истечении RequestTimeout контекст шлюза
+// отменяется, а клиент получает 503 request_timeout
+func TestRequestTimeout(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, gatewayFunc(func(ctx context.Context, _ Payment) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}), Config{RequestTimeout: 20 * time.Millisecond})
+
+	rec := doJSON(t, s, http.MethodPost, "/payments", `{"amount": 100, "currency": "RUB"}`, nil)
+	var resp ErrorResponse
+	decodeBody(t, rec, &resp)
+	if rec.Code != http.StatusServiceUnavailable || resp.Code != CodeRequestTimeout {
+		t.Fatalf("timeout: %d %+v", rec.Code, resp)
+	}
+}
+
+func TestIsTimeoutExempt(t *testing.T) {
+	cases := map[string]bool{
+		"GET /payments/pay_1":          false,
+		"GET /payments/pay_1?wait=5s":  true,
+		"POST /payments/pay_1?wait=5s": false,
+		"GET /payments/pay_1/stream":   true,
+		"GET /payments/export":         true,
+		"POST /payments/import":        true,
+		"POST /payments/pay_1/capture": false,
+	}
+	for req, want := range cases {
+		method, target, _ := strings.Cut(req, " ")
+		r, _ := http.NewRequest(method, target, nil)
+		if got := isTimeoutExempt(r); got != want {
+			t.Errorf("isTimeoutExempt(%s) = %t, want %t", req, got, want)
+		}
+	}
+}
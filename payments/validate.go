@@ -0,0 +1,209 @@
+package payments
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+)
+
+// ===== ПРОВЕРКА ПОЛЕЙ ПЛАТЕЖА =====
+
+// maxDescriptionLength — максимальная длина описания платежа (в символах)
+const maxDescriptionLength = 500
+
+// maxExternalIDLength — максимальная длина external_id (в символах)
+const maxExternalIDLength = 255
+
+// Коды предупреждений (см. paymentWarnings)
+const (
+	WarnAmountUnusuallyHigh = "amount_unusually_high"
+)
+
+// FieldError — ошибка в конкретном поле запроса
+// Пример: {"field":"currency","code":"currency.unsupported","message":"Unsupported currency"}
+//
+// Code — один из кодов Field* ниже: по нему клиент выбирает свой
+// (переведенный) текст, Message — текст по умолчанию на английском
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Коды ошибок полей (FieldError.Code)
+//
+// Формат — "поле.причина". Поле в коде — логическое ("amount"),
+// а путь в FieldError.Field может быть точнее ("amount.value",
+// "[3].id" в отчете сверки). Коды стабильны: новые причины
+// добавляются, существующие не переименовываются
+// Ошибки проверки по спецификации OpenAPI (см. validateRequests)
+// идут с общим кодом schema_violation
+const (
+	FieldAmountInvalid          = "amount.invalid"           // не число или не больше нуля
+	FieldAmountTooSmall         = "amount.too_small"         // меньше минимума валюты
+	FieldAmountTooLarge         = "amount.too_large"         // больше MaxAmountMinor
+	FieldAmountCurrencyMismatch = "amount.currency_mismatch" // amount.currency ≠ currency
+	FieldAmountNeedsString      = "amount.needs_string"      // число больше MaxFloatAmountMinor
+	FieldCurrencyRequired       = "currency.required"        // нет ни валюты, ни валюты по умолчанию
+	FieldCurrencyUnsupported    = "currency.unsupported"     // валюта не принимается сервером
+	FieldDescriptionTooLong     = "description.too_long"     // длиннее maxDescriptionLength
+	FieldExternalIDTooLong      = "external_id.too_long"     // длиннее maxExternalIDLength
+	FieldProcessAtConflict      = "process_at.conflict"      // вместе с "capture": false
+	FieldMetadataInvalid        = "metadata.invalid"         // превышены лимиты метаданных
+	FieldIDsRequired            = "ids.required"             // пустой список ID
+	FieldIDsTooMany             = "ids.too_many"             // слишком много ID за раз
+	FieldIDInvalid              = "id.invalid"               // не похоже на ID платежа
+	FieldIDDuplicate            = "id.duplicate"             // ID повторяется в запросе
+	FieldStatusRequired         = "status.required"          // статус не указан
+	FieldStatusUnknown          = "status.unknown"           // неизвестный статус
+	FieldReasonRequired         = "reason.required"          // причина не указана
+	FieldReasonTooLong          = "reason.too_long"          // длиннее maxReasonLength
+	FieldBodyTooLarge           = "body.too_large"           // слишком много записей
+)
+
+// fieldErrorCodes — код ответа для запроса с единственной ошибкой поля
+// (см. writeFieldErrors): так код ответа остается прежним, каким
+// он был до появления кодов полей. Код без записи = validation_failed
+var fieldErrorCodes = map[string]string{
+	FieldAmountInvalid:       CodeInvalidAmount,
+	FieldAmountTooSmall:      CodeAmountTooSmall,
+	FieldAmountTooLarge:      CodeAmountTooLarge,
+	FieldAmountNeedsString:   CodeInvalidAmount,
+	FieldCurrencyRequired:    CodeCurrencyRequired,
+	FieldCurrencyUnsupported: CodeUnsupportedCurrency,
+	FieldDescriptionTooLong:  CodeInvalidDescription,
+	FieldExternalIDTooLong:   CodeInvalidExternalID,
+	FieldMetadataInvalid:     CodeInvalidMetadata,
+	FieldIDInvalid:           CodeInvalidID,
+	FieldIDDuplicate:         CodeInvalidID,
+	FieldStatusRequired:      CodeStatusRequired,
+	CodeSchemaViolation:      CodeSchemaViolation,
+}
+
+// validatePayment проверяет поля нового платежа и собирает ВСЕ ошибки,
+// чтобы клиент исправил запрос за один раз, а не по одной ошибке за запрос
+//
+// Попутно нормализует платеж: подставляет валюту по умолчанию
+// и вычисляет AmountMinor (для этого и нужны настройки сервера)
+// Пустой результат = платеж корректный
+func (s *Server) validatePayment(p *Payment) []FieldError {
+	var errs []FieldError
+	add := func(field, code, message string) {
+		errs = append(errs, FieldError{Field: field, Code: code, Message: message})
+	}
+
+	// Сумма объектом {"value": …, "currency": …}: валюта объекта
+	// заменяет поле currency, а если заданы обе — они должны совпасть
+	if p.amountCurrency != "" {
+		switch {
+		case p.Currency == "":
+			p.Currency = p.amountCurrency
+		case p.Currency != p.amountCurrency:
+			add("amount.currency", FieldAmountCurrencyMismatch,
+				fmt.Sprintf("amount.currency %s does not match currency %s", p.amountCurrency, p.Currency))
+		}
+	}
+
+	// Валюта. Если клиент ее не указал, а в конфиге задана валюта
+	// по умолчанию (DEFAULT_CURRENCY) — подставляем ее
+	if p.Currency == "" && s.defaultCurrency != "" {
+		p.Currency = s.defaultCurrency
+	}
+	currencyOK := false
+	switch {
+	case p.Currency == "":
+		add("currency", FieldCurrencyRequired, "Currency is required")
+	case !s.isSupportedCurrency(p.Currency):
+		add("currency", FieldCurrencyUnsupported, "Unsupported currency")
+	default:
+		currencyOK = true
+	}
+
+	// Сумма. Число знаков после запятой зависит от валюты, поэтому
+	// без корректной валюты проверяем только, что сумма вообще есть
+	switch {
+	case p.amountMinorValue != "":
+		// Минорные единицы — только целое число, без округления
+		minor, err := strconv.ParseInt(p.amountMinorValue.String(), 10, 64)
+		switch {
+		case errors.Is(err, strconv.ErrRange) || (err == nil && minor > MaxAmountMinor):
+			add("amount.value", FieldAmountTooLarge, fmt.Sprintf("Amount exceeds system maximum of %d minor units", MaxAmountMinor))
+		case err != nil:
+			add("amount.value", FieldAmountInvalid, "amount.value must be an integer number of minor units")
+		case minor <= 0:
+			add("amount.value", FieldAmountInvalid, "Amount must be positive")
+		case currencyOK:
+			if minimum, below := s.belowMinimum(minor, p.Currency); below {
+				add("amount", FieldAmountTooSmall, minimumMessage(minimum, p.Currency))
+			}
+			p.AmountMinor = minor
+			p.Amount = minorToAmount(minor, p.Currency)
+		}
+	case p.amountLiteral == "" && p.Amount <= 0:
+		add("amount", FieldAmountInvalid, "Amount must be positive")
+	case currencyOK:
+		// Строковая сумма ("100.50") разбирается точно, числовая округляется
+		minor, err := requestAmountMinor(p.Amount, s.amountLiteral(p.amountLiteral), p.amountNumber, p.Currency, s.rounding.modeFor(p.Currency), s.maxFloatMinor)
+		switch {
+		case errors.Is(err, ErrAmountTooLarge):
+			add("amount", FieldAmountTooLarge, fmt.Sprintf("Amount exceeds system maximum of %d minor units", MaxAmountMinor))
+		case errors.Is(err, ErrAmountNeedsString):
+			add("amount", FieldAmountNeedsString, err.Error())
+		case err != nil:
+			add("amount", FieldAmountInvalid, err.Error())
+		case minor <= 0:
+			// Сумма меньше копейки (0.001 RUB) после округления превращается в 0
+			add("amount", FieldAmountInvalid, "Amount must be positive")
+		default:
+			if minimum, below := s.belowMinimum(minor, p.Currency); below {
+				add("amount", FieldAmountTooSmall, minimumMessage(minimum, p.Currency))
+			}
+			p.AmountMinor = minor
+			if p.amountLiteral != "" {
+				p.Amount = minorToAmount(minor, p.Currency)
+			}
+		}
+	}
+
+	if utf8.RuneCountInString(p.Description) > maxDescriptionLength {
+		add("description", FieldDescriptionTooLong,
+			fmt.Sprintf("Description must be at most %d characters", maxDescriptionLength))
+	}
+
+	if utf8.RuneCountInString(p.ExternalID) > maxExternalIDLength {
+		add("external_id", FieldExternalIDTooLong,
+			fmt.Sprintf("External ID must be at most %d characters", maxExternalIDLength))
+	}
+
+	// Отложенный платеж проводит планировщик, а двухшаговое списание
+	// ("capture": false) он не поддерживает
+	if p.ProcessAt != nil && p.manualCapture {
+		add("process_at", FieldProcessAtConflict, "process_at cannot be combined with capture: false")
+	}
+
+	// Метаданные ограничены по размеру, чтобы платеж не превратился
+	// в хранилище произвольных данных
+	if err := validateMetadata(p.Metadata); err != nil {
+		add("metadata", FieldMetadataInvalid, err.Error())
+	}
+	return errs
+}
+
+// paymentWarnings проверяет уже корректный платеж на необычные, но
+// допустимые значения. В отличие от validatePayment, не блокирует
+// создание: предупреждения возвращаются клиенту в поле warnings
+//
+// Пороги задаются по валютам (Config.WarnAmounts); валюта без
+// порога не проверяется
+func (s *Server) paymentWarnings(p Payment) []FieldError {
+	var warnings []FieldError
+	if threshold, ok := s.warnAmounts[p.Currency]; ok && p.AmountMinor >= threshold {
+		warnings = append(warnings, FieldError{
+			Field:   "amount",
+			Code:    WarnAmountUnusuallyHigh,
+			Message: "Amount is unusually high (at least " + formatAmountDisplay(threshold, p.Currency) + ")",
+		})
+	}
+	return warnings
+}
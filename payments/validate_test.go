@@ -0,0 +1,152 @@
+package payments
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestValidatePaymentCollectsAllErrors — все ошибки полей приходят
+// одним ответом, а не по одной за запрос
+func TestValidatePaymentCollectsAllErrors(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	body := `{"amount": 100, "currency": "XXX", "description": "` + strings.Repeat("x", maxDescriptionLength+1) +
+		`", "external_id": "` + strings.Repeat("e", maxExternalIDLength+1) + `"}`
+	rec := doJSON(t, s, http.MethodPost, "/payments", body, nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400: %s", rec.Code, rec.Body.String())
+	}
+	var resp ErrorResponse
+	decodeBody(t, rec, &resp)
+
+	want := map[string]string{
+		"currency":    FieldCurrencyUnsupported,
+		"description": FieldDescriptionTooLong,
+		"external_id": FieldExternalIDTooLong,
+	}
+	if len(resp.Fields) != len(want) {
+		t.Fatalf("fields = %+v, want %d entries", resp.Fields, len(want))
+	}
+	for _, f := range resp.Fields {
+		if want[f.Field] != f.Code {
+			t.Errorf("field %s: code = %s, want %s", f.Field, f.Code, want[f.Field])
+		}
+		if f.Message == "" {
+			t.Errorf("field %s: empty message", f.Field)
+		}
+	}
+}
+
+func TestValidatePaymentAmountAndCurrency(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{})
+	for name, tc := range map[string]struct {
+		p    Payment
+		want []string
+	}{
+		"valid":            {Payment{Amount: 10, Currency: "RUB"}, nil},
+		"missing both":     {Payment{}, []string{"currency", "amount"}},
+		"negative amount":  {Payment{Amount: -5, Currency: "RUB"}, []string{"amount"}},
+		"unsupported only": {Payment{Amount: 5, Currency: "ZZZ"}, []string{"currency"}},
+	} {
+		p := tc.p
+		errs := s.validatePayment(&p)
+		var got []string
+		for _, e := range errs {
+			got = append(got, e.Field)
+		}
+		if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("%s: fields = %v, want %v", name, got, tc.want)
+		}
+	}
+}
+
+// TestPaymentWarningDoesNotBlock — крупная сумма создает платеж (201),
+// а в ответе появляется предупреждение
+func TestPaymentWarningDoesNotBlock(t *testing.T) {
+	store := NewMemoryStore()
+	s := NewServer(store, nil, nil, Config{WarnAmounts: map[string]int64{"RUB": 1_000_000}})
+
+	rec := doJSON(t, s, http.MethodPost, "/payments", `{"amount": 10000, "currency": "RUB"}`, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201: %s", rec.Code, rec.Body.String())
+	}
+	var p Payment
+	decodeBody(t, rec, &p)
+	if len(p.Warnings) != 1 || p.Warnings[0].Field != "amount" || p.Warnings[0].Code != WarnAmountUnusuallyHigh {
+		t.Fatalf("warnings = %+v", p.Warnings)
+	}
+	// Предупреждение относится к ответу на создание, а не к платежу
+	stored, err := store.Get(context.Background(), p.ID)
+	if err != nil || len(stored.Warnings) != 0 {
+		t.Fatalf("stored warnings = %+v, %v", stored.Warnings, err)
+	}
+}
+
+// TestPaymentWarningsOmitted — без предупреждений поля warnings нет вовсе
+func TestPaymentWarningsOmitted(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{WarnAmounts: map[string]int64{"RUB": 1_000_000}})
+	for _, body := range []string{
+		`{"amount": 9999.99, "currency": "RUB"}`, // ниже порога
+		`{"amount": 1000000, "currency": "USD"}`, // для валюты порога нет
+	} {
+		rec := doJSON(t, s, http.MethodPost, "/payments", body, nil)
+		if rec.Code != http.StatusCreated || strings.Contains(rec.Body.String(), "warnings") {
+			t.Errorf("%s: %d %s", body, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+// TestFieldErrorCodes — у каждой ошибки поля свой стабильный код,
+// а единственная ошибка сохраняет прежний код ответа
+func TestFieldErrorCodes(t *testing.T) {
+	s := NewServer(NewMemoryStore(), nil, nil, Config{MinAmounts: map[string]int64{"RUB": 1000},
+		MaxFloatAmountMinor: RecommendedMaxFloatAmountMinor})
+	cases := []struct {
+		body, field, code, respCode string
+	}{
+		{`{"amount": -1, "currency": "RUB"}`, "amount", FieldAmountInvalid, CodeInvalidAmount},
+		{`{"amount": "9.99", "currency": "RUB"}`, "amount", FieldAmountTooSmall, CodeAmountTooSmall},
+		{`{"amount": "10000000000.01", "currency": "RUB"}`, "amount", FieldAmountTooLarge, CodeAmountTooLarge},
+		{`{"amount": 100000000.01, "currency": "RUB"}`, "amount", FieldAmountNeedsString, CodeInvalidAmount},
+		{`{"amount": 100}`, "currency", FieldCurrencyRequired, CodeCurrencyRequired},
+		{`{"amount": 100, "currency": "ZZZ"}`, "currency", FieldCurrencyUnsupported, CodeUnsupportedCurrency},
+		{`{"amount": 100, "currency": "RUB", "description": "` + strings.Repeat("d", maxDescriptionLength+1) + `"}`,
+			"description", FieldDescriptionTooLong, CodeInvalidDescription},
+		{`{"amount": 100, "currency": "RUB", "external_id": "` + strings.Repeat("e", maxExternalIDLength+1) + `"}`,
+			"external_id", FieldExternalIDTooLong, CodeInvalidExternalID},
+		{`{"amount": 100, "currency": "RUB", "metadata": {"` + strings.Repeat("k", maxMetadataKeyLength+1) + `": "v"}}`,
+			"metadata", FieldMetadataInvalid, CodeInvalidMetadata},
+		{`{"amount": 100, "currency": "RUB", "capture": false, "process_at": "2099-01-01T00:00:00Z"}`,
+			"process_at", FieldProcessAtConflict, CodeValidationFailed},
+	}
+	for _, c := range cases {
+		rec := doJSON(t, s, http.MethodPost, "/payments", c.body, nil)
+		var resp ErrorResponse
+		decodeBody(t, rec, &resp)
+		if rec.Code != http.StatusBadRequest || len(resp.Fields) != 1 {
+			t.Errorf("%s: %d %+v", c.code, rec.Code, resp)
+			continue
+		}
+		if f := resp.Fields[0]; f.Field != c.field || f.Code != c.code || f.Message == "" {
+			t.Errorf("%s: field error = %+v, want %s/%s", c.code, f, c.field, c.code)
+		}
+		if resp.Code != c.respCode {
+			t.Errorf("%s: response code = %s, want %s", c.code, resp.Code, c.respCode)
+		}
+	}
+}
+
+// TestFieldErrorCodesFormat — коды вида "поле.причина": клиент может
+// разобрать их без списка
+func TestFieldErrorCodesFormat(t *testing.T) {
+	for field := range fieldErrorCodes {
+		if field == CodeSchemaViolation {
+			continue
+		}
+		name, reason, ok := strings.Cut(field, ".")
+		if !ok || name == "" || reason == "" || strings.ToLower(field) != field {
+			t.Errorf("field code %q is not field.reason", field)
+		}
+	}
+}
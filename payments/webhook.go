@@ -0,0 +1,186 @@
+package payments
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ===== ВХОДЯЩИЕ УВЕДОМЛЕНИЯ ШЛЮЗА (WEBHOOKS) =====
+
+// webhookSignatureHeader — заголовок с подписью тела запроса
+// Формат: "sha256=<hex HMAC-SHA256 тела на общем секрете>"
+const webhookSignatureHeader = "X-Signature"
+
+// maxWebhookBody — максимальный размер тела уведомления
+const maxWebhookBody = 1 << 20 // 1 МБ
+
+// Типы событий шлюза и статусы, в которые они переводят платеж
+var webhookEventStatuses = map[string]string{
+	"payment.succeeded": StatusSucceeded,
+	"payment.failed":    StatusFailed,
+}
+
+// webhookEvent — уведомление шлюза о результате платежа
+// ID события уникален: по нему отбрасываются повторные доставки
+// FailureCode — код ошибки шлюза для payment.failed; переводится
+// в причину отказа той же таблицей, что и ошибки списания
+type webhookEvent struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	PaymentID   string `json:"payment_id"`
+	FailureCode string `json:"failure_code,omitempty"`
+}
+
+// webhookActor — "кто" меняет платеж в журнале аудита
+const webhookActor = "gateway"
+
+// handleGatewayWebhook принимает уведомления шлюза
+// POST /webhooks/gateway
+//
+// ЗАЩИТА:
+//   - подпись HMAC: тело подписано общим секретом, подделать уведомление
+//     без секрета нельзя (неверная подпись = 401)
+//   - защита от повторов: ID уже обработанного события запоминается
+//     (на время Config.WebhookReplayWindow); повторная доставка получает 200,
+//     но НЕ обрабатывается второй раз
+//
+// Шлюзы повторяют доставку, пока не получат 2xx, поэтому на повтор
+// отвечаем 200, а не ошибкой
+func (s *Server) handleGatewayWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+	// Без секрета проверить подпись нечем — эндпоинт выключен
+	if len(s.webhookSecret) == 0 {
+		writeError(w, r, http.StatusNotFound, CodeNotFound, "Resource not found")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidJSON, "Cannot read request body")
+		return
+	}
+	if !validWebhookSignature(s.webhookSecret, body, r.Header.Get(webhookSignatureHeader)) {
+		log.Printf("Webhook with invalid signature: IP=%s", s.clientIP(r))
+		writeError(w, r, http.StatusUnauthorized, CodeInvalidSignature, "Invalid webhook signature")
+		return
+	}
+
+	var event webhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidJSON, "Invalid JSON")
+		return
+	}
+	status, ok := webhookEventStatuses[event.Type]
+	if event.ID == "" || !ok || !isValidPaymentID(event.PaymentID) {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidEvent, "Event must have id, known type and payment_id")
+		return
+	}
+
+	// Занимаем ID события ДО обработки: две одновременные доставки
+	// одного события не обработаются обе
+	if !s.webhookNonces.claim(event.ID, time.Now()) {
+		log.Printf("Webhook replay ignored: Event=%s", event.ID)
+		writeJSON(w, r, http.StatusOK, map[string]any{"received": true, "duplicate": true})
+		return
+	}
+
+	// Блокировка — как у изменяющих запросов к /payments/{id}:
+	// before и результат относятся к одному и тому же изменению
+	defer s.paymentLocks.lock(event.PaymentID)()
+	before, _ := s.store.Get(r.Context(), event.PaymentID)
+	payment, err := s.updateStatus(r.Context(), event.PaymentID, status, 0, s.failureReasonForCode(event.FailureCode))
+	switch {
+	case errors.Is(err, ErrPaymentNotFound):
+		s.webhookNonces.release(event.ID)
+		writeError(w, r, http.StatusNotFound, CodePaymentNotFound, "Payment not found")
+		return
+	case errors.Is(err, ErrInvalidTransition):
+		// Платеж уже в конечном статусе (например, изменен через PATCH) —
+		// для шлюза это не ошибка, повторять доставку незачем
+		log.Printf("Webhook ignored: Event=%s, Payment=%s is %s", event.ID, payment.ID, payment.Status)
+		writeJSON(w, r, http.StatusOK, map[string]bool{"received": true})
+		return
+	case err != nil:
+		// Событие не обработано — освобождаем ID, чтобы повтор шлюза прошел
+		s.webhookNonces.release(event.ID)
+		log.Printf("Error processing webhook: %v", err)
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Internal error")
+		return
+	}
+
+	log.Printf("Webhook processed: Event=%s, Payment=%s, Status=%s", event.ID, payment.ID, payment.Status)
+	s.audit.record(webhookActor, AuditUpdate, payment.ID, before.Status, payment.Status)
+	s.publishStatus(r.Context(), payment)
+
+	writeJSON(w, r, http.StatusOK, map[string]bool{"received": true})
+}
+
+// validWebhookSignature проверяет подпись "sha256=<hex>"
+// hmac.Equal сравнивает за постоянное время: по времени ответа
+// нельзя подобрать подпись побайтно
+func validWebhookSignature(secret, body []byte, header string) bool {
+	hexSig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	sig, err := hex.DecodeString(hexSig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hmac.Equal(sig, mac.Sum(nil))
+}
+
+// ===== ЗАЩИТА ОТ ПОВТОРОВ =====
+
+// nonceSet — множество недавно обработанных ID событий с временем жизни
+type nonceSet struct {
+	ttl time.Duration
+
+	mu   sync.Mutex
+	seen map[string]time.Time // ID события → когда получено
+}
+
+// newNonceSet создает множество, помнящее ID в течение ttl
+func newNonceSet(ttl time.Duration) *nonceSet {
+	return &nonceSet{ttl: ttl, seen: make(map[string]time.Time)}
+}
+
+// claim запоминает ID и возвращает true, если его еще не было
+// false = событие уже обработано (или обрабатывается прямо сейчас)
+func (n *nonceSet) claim(id string, now time.Time) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	// Выбрасываем устаревшие записи, чтобы map не рос бесконечно
+	for key, at := range n.seen {
+		if now.Sub(at) > n.ttl {
+			delete(n.seen, key)
+		}
+	}
+	if _, ok := n.seen[id]; ok {
+		return false
+	}
+	n.seen[id] = now
+	return true
+}
+
+// release забывает ID: обработка не удалась, повтор должен пройти
+func (n *nonceSet) release(id string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.seen, id)
+}
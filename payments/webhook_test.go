@@ -0,0 +1,112 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// signWebhook — заголовок подписи, как его формирует шлюз
+func signWebhook(secret, body string) map[string]string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return map[string]string{webhookSignatureHeader: "sha256=" + hex.EncodeToString(mac.Sum(nil))}
+}
+
+func newWebhookServer(t *testing.T) (*Server, Store) {
+	t.Helper()
+	store := NewMemoryStore()
+	for _, id := range []string{"pay_a", "pay_b"} {
+		savePaymentT(t, store, Payment{ID: id, AmountMinor: 100, Currency: "RUB", Status: StatusPending, Version: 1})
+	}
+	return NewServer(store, nil, nil, Config{WebhookSecret: "secret"}), store
+}
+
+func TestWebhookValidEvent(t *testing.T) {
+	s, store := newWebhookServer(t)
+	body := `{"id":"evt_1","type":"payment.succeeded","payment_id":"pay_a"}`
+	rec := doJSON(t, s, http.MethodPost, "/webhooks/gateway", body, signWebhook("secret", body))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("webhook = %d: %s", rec.Code, rec.Body.String())
+	}
+	got, _ := store.Get(context.Background(), "pay_a")
+	if got.Status != StatusSucceeded {
+		t.Fatalf("status = %s, want succeeded", got.Status)
+	}
+}
+
+func TestWebhookInvalidSignature(t *testing.T) {
+	s, store := newWebhookServer(t)
+	body := `{"id":"evt_1","type":"payment.succeeded","payment_id":"pay_a"}`
+	for name, header := range map[string]map[string]string{
+		"missing":    nil,
+		"wrong key":  signWebhook("other", body),
+		"not hex":    {webhookSignatureHeader: "sha256=zz"},
+		"bad prefix": {webhookSignatureHeader: "md5=00"},
+	} {
+		rec := doJSON(t, s, http.MethodPost, "/webhooks/gateway", body, header)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s: status = %d, want 401", name, rec.Code)
+		}
+	}
+	got, _ := store.Get(context.Background(), "pay_a")
+	if got.Status != StatusPending {
+		t.Fatalf("status = %s, unsigned event must not be applied", got.Status)
+	}
+}
+
+// TestWebhookReplayIgnored — повтор события с тем же ID получает 200,
+// но второй раз не обрабатывается
+func TestWebhookReplayIgnored(t *testing.T) {
+	s, store := newWebhookServer(t)
+	first := `{"id":"evt_1","type":"payment.succeeded","payment_id":"pay_a"}`
+	if rec := doJSON(t, s, http.MethodPost, "/webhooks/gateway", first, signWebhook("secret", first)); rec.Code != http.StatusOK {
+		t.Fatalf("first delivery = %d", rec.Code)
+	}
+
+	// Тот же ID, но другой платеж: если бы событие обработалось, pay_b изменился бы
+	replay := `{"id":"evt_1","type":"payment.failed","payment_id":"pay_b"}`
+	rec := doJSON(t, s, http.MethodPost, "/webhooks/gateway", replay, signWebhook("secret", replay))
+	var resp map[string]bool
+	decodeBody(t, rec, &resp)
+	if rec.Code != http.StatusOK || !resp["duplicate"] {
+		t.Fatalf("replay = %d %v", rec.Code, resp)
+	}
+	got, _ := store.Get(context.Background(), "pay_b")
+	if got.Status != StatusPending {
+		t.Fatalf("replayed event was processed: pay_b is %s", got.Status)
+	}
+}
+
+// TestWebhookNotFoundReleasesNonce — неудачная обработка не запоминает ID:
+// повтор шлюза после появления платежа проходит
+func TestWebhookNotFoundReleasesNonce(t *testing.T) {
+	s, store := newWebhookServer(t)
+	body := `{"id":"evt_1","type":"payment.succeeded","payment_id":"pay_c"}`
+	if rec := doJSON(t, s, http.MethodPost, "/webhooks/gateway", body, signWebhook("secret", body)); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown payment = %d, want 404", rec.Code)
+	}
+	savePaymentT(t, store, Payment{ID: "pay_c", AmountMinor: 100, Currency: "RUB", Status: StatusPending, Version: 1})
+	if rec := doJSON(t, s, http.MethodPost, "/webhooks/gateway", body, signWebhook("secret", body)); rec.Code != http.StatusOK {
+		t.Fatalf("retry = %d: %s", rec.Code, rec.Body.String())
+	}
+	got, _ := store.Get(context.Background(), "pay_c")
+	if got.Status != StatusSucceeded {
+		t.Fatalf("status = %s", got.Status)
+	}
+}
+
+func TestNonceSetExpires(t *testing.T) {
+	n := newNonceSet(time.Minute)
+	now := time.Now()
+	if !n.claim("evt", now) || n.claim("evt", now.Add(30*time.Second)) {
+		t.Fatal("second claim inside the window must fail")
+	}
+	if !n.claim("evt", now.Add(2*time.Minute)) {
+		t.Fatal("claim after the window must succeed")
+	}
+}